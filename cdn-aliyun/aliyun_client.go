@@ -0,0 +1,78 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package aliyun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/apache/incubator-answer-plugins/util/cdnsync"
+)
+
+// Client is the bucket of the static files, it reuses one OSS client for all the requests.
+type Client struct {
+	bucket *oss.Bucket
+}
+
+func NewOSSClient(endpoint, accessKeyID, accessKeySecret, bucketName string) (*Client, error) {
+	client, err := oss.New(endpoint, accessKeyID, accessKeySecret)
+	if err != nil {
+		return nil, err
+	}
+	bucket, err := client.Bucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{bucket: bucket}, nil
+}
+
+// GetObject reads the object, such as the manifest of the published files.
+func (c *Client) GetObject(ctx context.Context, key string) ([]byte, error) {
+	body, err := c.bucket.GetObject(key, oss.WithContext(ctx))
+	if err != nil {
+		var serr oss.ServiceError
+		if errors.As(err, &serr) && serr.StatusCode == http.StatusNotFound {
+			return nil, cdnsync.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get object, %s", err.Error())
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// PutObject writes the object with the headers.
+func (c *Client) PutObject(ctx context.Context, key string, body io.ReadSeeker, headers cdnsync.Headers) error {
+	options := []oss.Option{
+		oss.WithContext(ctx),
+		oss.ContentType(headers.ContentType),
+		oss.CacheControl(headers.CacheControl),
+	}
+	if headers.ContentEncoding != "" {
+		options = append(options, oss.ContentEncoding(headers.ContentEncoding))
+	}
+	if err := c.bucket.PutObject(key, body, options...); err != nil {
+		return fmt.Errorf("failed to put object, %s", err.Error())
+	}
+	return nil
+}
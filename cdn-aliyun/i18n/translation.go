@@ -38,6 +38,34 @@ const (
 	ConfigMaxFileSizeTitle           = "plugin.aliyun_cdn.backend.config.max_file_size.title"
 	ConfigMaxFileSizeDescription     = "plugin.aliyun_cdn.backend.config.max_file_size.description"
 
+	ConfigCacheMaxAgeTitle       = "plugin.aliyun_cdn.backend.config.cache_max_age.title"
+	ConfigCacheMaxAgeDescription = "plugin.aliyun_cdn.backend.config.cache_max_age.description"
+
+	ConfigPrecompressionTitle        = "plugin.aliyun_cdn.backend.config.precompression.title"
+	ConfigPrecompressionDescription  = "plugin.aliyun_cdn.backend.config.precompression.description"
+	ConfigPrecompressionOptionNone   = "plugin.aliyun_cdn.backend.config.precompression.options.none"
+	ConfigPrecompressionOptionGzip   = "plugin.aliyun_cdn.backend.config.precompression.options.gzip"
+	ConfigPrecompressionOptionBr     = "plugin.aliyun_cdn.backend.config.precompression.options.br"
+	ConfigPrecompressionOptionGzipBr = "plugin.aliyun_cdn.backend.config.precompression.options.gzip_br"
+
+	ConfigPurgeProviderTitle           = "plugin.aliyun_cdn.backend.config.purge_provider.title"
+	ConfigPurgeProviderDescription     = "plugin.aliyun_cdn.backend.config.purge_provider.description"
+	ConfigPurgeProviderOptionNone      = "plugin.aliyun_cdn.backend.config.purge_provider.options.none"
+	ConfigPurgeProviderOptionAliyunCDN = "plugin.aliyun_cdn.backend.config.purge_provider.options.aliyun_cdn"
+	ConfigPurgeProviderOptionWebhook   = "plugin.aliyun_cdn.backend.config.purge_provider.options.webhook"
+
+	ConfigPurgePathsTitle       = "plugin.aliyun_cdn.backend.config.purge_paths.title"
+	ConfigPurgePathsDescription = "plugin.aliyun_cdn.backend.config.purge_paths.description"
+
+	ConfigPurgeWebhookURLTitle       = "plugin.aliyun_cdn.backend.config.purge_webhook_url.title"
+	ConfigPurgeWebhookURLDescription = "plugin.aliyun_cdn.backend.config.purge_webhook_url.description"
+
+	ConfigPurgeWebhookSecretTitle       = "plugin.aliyun_cdn.backend.config.purge_webhook_secret.title"
+	ConfigPurgeWebhookSecretDescription = "plugin.aliyun_cdn.backend.config.purge_webhook_secret.description"
+
+	ConfigRewriteRulesTitle       = "plugin.aliyun_cdn.backend.config.rewrite_rules.title"
+	ConfigRewriteRulesDescription = "plugin.aliyun_cdn.backend.config.rewrite_rules.description"
+
 	ErrMisStorageConfig    = "plugin.aliyun_cdn.backend.err.mis_storage_config"
 	ErrUnsupportedFileType = "plugin.aliyun_cdn.backend.err.unsupported_file_type"
 	ErrOverFileSizeLimit   = "plugin.aliyun_cdn.backend.err.over_file_size_limit"
@@ -0,0 +1,127 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package aliyun
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/apache/incubator-answer-plugins/util/aliyunrpc"
+	"github.com/apache/incubator-answer-plugins/util/cdnsync"
+)
+
+// The purge providers of the purge_provider config, empty is off.
+const (
+	PurgeProviderAliyunCDN = "aliyun_cdn"
+	PurgeProviderWebhook   = "webhook"
+)
+
+// aliyunCDNBatchSize is the number of the URLs of a refresh, Aliyun CDN allows 100 directories at most.
+const aliyunCDNBatchSize = 100
+
+// aliyunCDNEndpoint is the endpoint of the Aliyun CDN API, overridden by the tests.
+var aliyunCDNEndpoint = "https://cdn.aliyuncs.com/"
+
+// AliyunCDNPurger refreshes the caches of Aliyun CDN with the access key of the bucket.
+type AliyunCDNPurger struct {
+	accessKeyID     string
+	accessKeySecret string
+	client          *http.Client
+}
+
+func NewAliyunCDNPurger(accessKeyID, accessKeySecret string) *AliyunCDNPurger {
+	return &AliyunCDNPurger{
+		accessKeyID:     accessKeyID,
+		accessKeySecret: accessKeySecret,
+		client:          &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Purge refreshes the files and the directories of the URLs, which are refreshed separately.
+func (a *AliyunCDNPurger) Purge(ctx context.Context, urls []string) error {
+	var files, dirs []string
+	for _, u := range urls {
+		if strings.HasSuffix(u, "/") {
+			dirs = append(dirs, u)
+		} else {
+			files = append(files, u)
+		}
+	}
+	for _, batch := range cdnsync.Batches(files, aliyunCDNBatchSize) {
+		if err := a.refresh(ctx, "File", batch); err != nil {
+			return err
+		}
+	}
+	for _, batch := range cdnsync.Batches(dirs, aliyunCDNBatchSize) {
+		if err := a.refresh(ctx, "Directory", batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refresh calls RefreshObjectCaches, an RPC API
+func (a *AliyunCDNPurger) refresh(ctx context.Context, objectType string, urls []string) error {
+	query := aliyunrpc.SignedQuery(a.accessKeyID, a.accessKeySecret, map[string]string{
+		"Action":     "RefreshObjectCaches",
+		"Version":    "2018-05-10",
+		"ObjectPath": strings.Join(urls, "\n"),
+		"ObjectType": objectType,
+	})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, aliyunCDNEndpoint+"?"+query, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		result := &struct {
+			Code    string
+			Message string
+		}{}
+		_ = json.Unmarshal(body, result)
+		return fmt.Errorf("refresh object caches failed, status %d: %s %s", resp.StatusCode, result.Code, result.Message)
+	}
+	return nil
+}
+
+// newPurger returns the purger of the purge provider, or nil if purging is off
+func newPurger(config *CDNConfig) (cdnsync.Purger, error) {
+	switch config.PurgeProvider {
+	case PurgeProviderAliyunCDN:
+		return NewAliyunCDNPurger(config.AccessKeyID, config.AccessKeySecret), nil
+	case PurgeProviderWebhook:
+		return &cdnsync.WebhookPurger{URL: config.PurgeWebhookURL, Secret: config.PurgeWebhookSecret}, nil
+	}
+	return nil, nil
+}
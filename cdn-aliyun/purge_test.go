@@ -0,0 +1,80 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package aliyun
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/apache/incubator-answer-plugins/util/aliyunrpc"
+)
+
+func TestAliyunCDNPurger(t *testing.T) {
+	var refreshed []url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		signature := query.Get("Signature")
+		params := map[string]string{}
+		for name := range query {
+			if name != "Signature" {
+				params[name] = query.Get(name)
+			}
+		}
+		if signature != aliyunrpc.Signature("secret", http.MethodGet, aliyunrpc.CanonicalQuery(params)) {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"Code":"SignatureDoesNotMatch","Message":"signature mismatch"}`))
+			return
+		}
+		refreshed = append(refreshed, query)
+		_, _ = w.Write([]byte(`{"RefreshTaskId":"1","RequestId":"2"}`))
+	}))
+	defer srv.Close()
+	endpoint := aliyunCDNEndpoint
+	aliyunCDNEndpoint = srv.URL + "/"
+	defer func() { aliyunCDNEndpoint = endpoint }()
+
+	urls := []string{
+		"https://cdn.example.com/answer/asset-manifest.json",
+		"https://cdn.example.com/answer/static/js/",
+		"https://cdn.example.com/answer/favicon.ico",
+	}
+	if err := NewAliyunCDNPurger("id", "secret").Purge(context.Background(), urls); err != nil {
+		t.Fatal(err)
+	}
+	if len(refreshed) != 2 {
+		t.Fatalf("expected a refresh of the files and one of the directories, got %d", len(refreshed))
+	}
+	if refreshed[0].Get("Action") != "RefreshObjectCaches" || refreshed[0].Get("ObjectType") != "File" ||
+		refreshed[0].Get("ObjectPath") != urls[0]+"\n"+urls[2] {
+		t.Errorf("unexpected file refresh %v", refreshed[0])
+	}
+	if refreshed[1].Get("ObjectType") != "Directory" || refreshed[1].Get("ObjectPath") != urls[1] {
+		t.Errorf("unexpected directory refresh %v", refreshed[1])
+	}
+
+	err := NewAliyunCDNPurger("id", "wrong").Purge(context.Background(), urls[:1])
+	if err == nil || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Fatalf("expected the signature error, got %v", err)
+	}
+}
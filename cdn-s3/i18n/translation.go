@@ -44,6 +44,37 @@ const (
 	ConfigDisableSSLTitle            = "plugin.s3_cdn.backend.config.disable_ssl.title"
 	ConfigDisableSSLDescription      = "plugin.s3_cdn.backend.config.disable_ssl.description"
 
+	ConfigCacheMaxAgeTitle       = "plugin.s3_cdn.backend.config.cache_max_age.title"
+	ConfigCacheMaxAgeDescription = "plugin.s3_cdn.backend.config.cache_max_age.description"
+
+	ConfigPrecompressionTitle        = "plugin.s3_cdn.backend.config.precompression.title"
+	ConfigPrecompressionDescription  = "plugin.s3_cdn.backend.config.precompression.description"
+	ConfigPrecompressionOptionNone   = "plugin.s3_cdn.backend.config.precompression.options.none"
+	ConfigPrecompressionOptionGzip   = "plugin.s3_cdn.backend.config.precompression.options.gzip"
+	ConfigPrecompressionOptionBr     = "plugin.s3_cdn.backend.config.precompression.options.br"
+	ConfigPrecompressionOptionGzipBr = "plugin.s3_cdn.backend.config.precompression.options.gzip_br"
+
+	ConfigPurgeProviderTitle            = "plugin.s3_cdn.backend.config.purge_provider.title"
+	ConfigPurgeProviderDescription      = "plugin.s3_cdn.backend.config.purge_provider.description"
+	ConfigPurgeProviderOptionNone       = "plugin.s3_cdn.backend.config.purge_provider.options.none"
+	ConfigPurgeProviderOptionCloudFront = "plugin.s3_cdn.backend.config.purge_provider.options.cloudfront"
+	ConfigPurgeProviderOptionWebhook    = "plugin.s3_cdn.backend.config.purge_provider.options.webhook"
+
+	ConfigCloudFrontDistributionIDTitle       = "plugin.s3_cdn.backend.config.cloudfront_distribution_id.title"
+	ConfigCloudFrontDistributionIDDescription = "plugin.s3_cdn.backend.config.cloudfront_distribution_id.description"
+
+	ConfigPurgePathsTitle       = "plugin.s3_cdn.backend.config.purge_paths.title"
+	ConfigPurgePathsDescription = "plugin.s3_cdn.backend.config.purge_paths.description"
+
+	ConfigPurgeWebhookURLTitle       = "plugin.s3_cdn.backend.config.purge_webhook_url.title"
+	ConfigPurgeWebhookURLDescription = "plugin.s3_cdn.backend.config.purge_webhook_url.description"
+
+	ConfigPurgeWebhookSecretTitle       = "plugin.s3_cdn.backend.config.purge_webhook_secret.title"
+	ConfigPurgeWebhookSecretDescription = "plugin.s3_cdn.backend.config.purge_webhook_secret.description"
+
+	ConfigRewriteRulesTitle       = "plugin.s3_cdn.backend.config.rewrite_rules.title"
+	ConfigRewriteRulesDescription = "plugin.s3_cdn.backend.config.rewrite_rules.description"
+
 	ErrFileNotFound        = "plugin.s3_cdn.backend.err.file_not_found"
 	ErrUnsupportedFileType = "plugin.s3_cdn.backend.err.unsupported_file_type"
 	ErrOverFileSizeLimit   = "plugin.s3_cdn.backend.err.over_file_size_limit"
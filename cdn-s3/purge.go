@@ -0,0 +1,113 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package s3
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/incubator-answer-plugins/util/cdnsync"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudfront"
+)
+
+// The purge providers of the purge_provider config, empty is off.
+const (
+	PurgeProviderCloudFront = "cloudfront"
+	PurgeProviderWebhook    = "webhook"
+)
+
+// cloudFrontBatchSize is the number of the paths of an invalidation, CloudFront allows 3000 at most.
+const cloudFrontBatchSize = 1000
+
+// cloudFrontEndpoint overrides the endpoint of CloudFront, for the tests.
+var cloudFrontEndpoint = ""
+
+// CloudFrontPurger creates the invalidations of a CloudFront distribution.
+type CloudFrontPurger struct {
+	client         *cloudfront.CloudFront
+	distributionID string
+}
+
+func NewCloudFrontPurger(id, secret, token, distributionID string) (*CloudFrontPurger, error) {
+	config := &aws.Config{
+		Credentials: credentials.NewStaticCredentials(id, secret, token),
+		Region:      aws.String("us-east-1"),
+	}
+	if cloudFrontEndpoint != "" {
+		config.Endpoint = aws.String(cloudFrontEndpoint)
+	}
+	newSession, err := session.NewSession(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session, %s", err.Error())
+	}
+	return &CloudFrontPurger{client: cloudfront.New(newSession), distributionID: distributionID}, nil
+}
+
+// Purge invalidates the paths of the URLs, the directories with a wildcard.
+func (c *CloudFrontPurger) Purge(ctx context.Context, urls []string) error {
+	paths := make([]string, 0, len(urls))
+	for _, u := range urls {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			return err
+		}
+		p := parsed.EscapedPath()
+		if strings.HasSuffix(p, "/") {
+			p += "*"
+		}
+		paths = append(paths, p)
+	}
+
+	for _, batch := range cdnsync.Batches(paths, cloudFrontBatchSize) {
+		_, err := c.client.CreateInvalidationWithContext(ctx, &cloudfront.CreateInvalidationInput{
+			DistributionId: aws.String(c.distributionID),
+			InvalidationBatch: &cloudfront.InvalidationBatch{
+				CallerReference: aws.String(strconv.FormatInt(time.Now().UnixNano(), 10)),
+				Paths: &cloudfront.Paths{
+					Quantity: aws.Int64(int64(len(batch))),
+					Items:    aws.StringSlice(batch),
+				},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create invalidation, %s", err.Error())
+		}
+	}
+	return nil
+}
+
+// newPurger returns the purger of the purge provider, or nil if purging is off
+func newPurger(config *CDNConfig) (cdnsync.Purger, error) {
+	switch config.PurgeProvider {
+	case PurgeProviderCloudFront:
+		return NewCloudFrontPurger(config.AccessKeyID, config.AccessKeySecret, config.AccessToken,
+			config.CloudFrontDistributionID)
+	case PurgeProviderWebhook:
+		return &cdnsync.WebhookPurger{URL: config.PurgeWebhookURL, Secret: config.PurgeWebhookSecret}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,73 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCloudFrontPurger(t *testing.T) {
+	var (
+		requestPath string
+		paths       []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestPath = r.URL.Path
+		body, _ := io.ReadAll(r.Body)
+		batch := &struct {
+			Paths []string `xml:"Paths>Items>Path"`
+		}{}
+		if err := xml.Unmarshal(body, batch); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		paths = batch.Paths
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`<Invalidation><Id>I1</Id><Status>InProgress</Status></Invalidation>`))
+	}))
+	defer srv.Close()
+	cloudFrontEndpoint = srv.URL
+	defer func() { cloudFrontEndpoint = "" }()
+
+	purger, err := NewCloudFrontPurger("id", "secret", "", "E123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = purger.Purge(context.Background(), []string{
+		"https://d111.cloudfront.net/answer/asset-manifest.json",
+		"https://d111.cloudfront.net/answer/static/js/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(requestPath, "/distribution/E123/invalidation") {
+		t.Errorf("unexpected request path %s", requestPath)
+	}
+	if want := []string{"/answer/asset-manifest.json", "/answer/static/js/*"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("got paths %v, want %v", paths, want)
+	}
+}
@@ -20,6 +20,7 @@
 package s3
 
 import (
+	"context"
 	"crypto/rand"
 	"embed"
 	"encoding/hex"
@@ -27,15 +28,17 @@ import (
 	"fmt"
 	"github.com/apache/incubator-answer-plugins/cdn-s3/i18n"
 	"github.com/apache/incubator-answer-plugins/util"
+	"github.com/apache/incubator-answer-plugins/util/cdnsync"
 	"github.com/apache/incubator-answer/ui"
+	"github.com/gin-gonic/gin"
 	"github.com/segmentfault/pacman/log"
-	"io"
 	"io/fs"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/apache/incubator-answer/plugin"
@@ -43,7 +46,8 @@ import (
 
 var (
 	staticPath = os.Getenv("ANSWER_STATIC_PATH")
-	enable     = false
+	// enable is set by the publications, which run in the background
+	enable atomic.Bool
 )
 
 //go:embed  info.yaml
@@ -55,26 +59,45 @@ const (
 )
 
 type CDN struct {
-	Config *CDNConfig
-	Client *Client
+	mu      sync.RWMutex
+	current *publication
+	tracker *cdnsync.Tracker
+}
+
+// publication is the config and the clients built from it, replaced as a whole when a valid config is saved,
+// so a running publication keeps working with the config it started with.
+type publication struct {
+	config *CDNConfig
+	client *Client
+	purger cdnsync.Purger
+	rules  []cdnsync.Rule
 }
 
 type CDNConfig struct {
-	Endpoint        string `json:"endpoint"`
-	BucketName      string `json:"bucket_name"`
-	ObjectKeyPrefix string `json:"object_key_prefix"`
-	AccessKeyID     string `json:"access_key_id"`
-	AccessKeySecret string `json:"access_key_secret"`
-	AccessToken     string `json:"access_token"`
-	VisitUrlPrefix  string `json:"visit_url_prefix"`
-	MaxFileSize     string `json:"max_file_size"`
-	Region          string `json:"region"`
-	DisableSSL      bool   `json:"disable_ssl"`
+	Endpoint                 string `json:"endpoint"`
+	BucketName               string `json:"bucket_name"`
+	ObjectKeyPrefix          string `json:"object_key_prefix"`
+	AccessKeyID              string `json:"access_key_id"`
+	AccessKeySecret          string `json:"access_key_secret"`
+	AccessToken              string `json:"access_token"`
+	VisitUrlPrefix           string `json:"visit_url_prefix"`
+	MaxFileSize              string `json:"max_file_size"`
+	CacheMaxAge              string `json:"cache_max_age"`
+	Precompression           string `json:"precompression"`
+	PurgeProvider            string `json:"purge_provider"`
+	CloudFrontDistributionID string `json:"cloudfront_distribution_id"`
+	PurgePaths               string `json:"purge_paths"`
+	PurgeWebhookURL          string `json:"purge_webhook_url"`
+	PurgeWebhookSecret       string `json:"purge_webhook_secret"`
+	RewriteRules             string `json:"rewrite_rules"`
+	Region                   string `json:"region"`
+	DisableSSL               bool   `json:"disable_ssl"`
 }
 
 func init() {
 	plugin.Register(&CDN{
-		Config: &CDNConfig{},
+		current: &publication{config: &CDNConfig{}},
+		tracker: &cdnsync.Tracker{},
 	})
 }
 
@@ -93,266 +116,86 @@ func (c *CDN) Info() plugin.Info {
 
 // GetStaticPrefix get static prefix
 func (c *CDN) GetStaticPrefix() string {
-	if !enable {
+	if !enable.Load() {
 		return ""
 	}
-	return c.Config.VisitUrlPrefix + c.Config.ObjectKeyPrefix
+	config := c.publication().config
+	return config.VisitUrlPrefix + config.ObjectKeyPrefix
 }
 
-// scanFiles scan all the static files in the build directory
-func (c *CDN) scanFiles() {
+// publication returns the current config and clients
+func (c *CDN) publication() *publication {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.current
+}
+
+// scanFiles scan all the static files in the build directory, and publish the changed ones.
+// It is run by the tracker, which cancels it when a new config is saved.
+func (c *CDN) scanFiles(ctx context.Context) {
+	pub := c.publication()
+	var fsys fs.FS = os.DirFS(staticPath)
 	if staticPath == "" {
-		err := c.scanEmbedFiles("build")
+		var err error
+		fsys, err = fs.Sub(ui.Build, "build")
 		if err != nil {
-			enable = false
+			enable.Store(false)
 			log.Error("failed: scan embed files: ", err)
 			return
 		}
-		log.Info("complete: scan embed files")
-		enable = true
-		return
 	}
 
-	err := c.scanStaticPathFiles(staticPath)
+	rewriter, err := cdnsync.NewRewriter(pub.rules, pub.config.VisitUrlPrefix+pub.config.ObjectKeyPrefix)
 	if err != nil {
-		enable = false
-		log.Error("fialed: scan static path files: ", err)
+		enable.Store(false)
+		log.Error("failed: rewrite rules: ", err)
 		return
 	}
-	enable = true
-	log.Info("complete: scan static path files")
-}
 
-// scanStaticPathFiles scan static path files
-func (c *CDN) scanStaticPathFiles(fileName string) (err error) {
-	if len(fileName) == 0 {
+	c.tracker.Begin()
+	p := cdnsync.NewPublisher(ctx, pub.client, cdnsync.Options{
+		Prefix:         pub.config.ObjectKeyPrefix,
+		VisitURLPrefix: pub.config.VisitUrlPrefix,
+		MaxAge:         cdnsync.ParseMaxAge(pub.config.CacheMaxAge),
+		Encodings:      cdnsync.ParseEncodings(pub.config.Precompression),
+		Purger:         pub.purger,
+		PurgePaths:     cdnsync.ParsePaths(pub.config.PurgePaths),
+	})
+	p.Scan(fsys, cdnsync.ScanOptions{
+		Filter:   c.filter(pub.config),
+		Rewriter: rewriter,
+	})
+	report, err := p.Finish()
+	c.tracker.End(report)
+	log.Infof("cdn publish: %d files, %d uploaded, %d unchanged, %d failed, %d verified, %d purged",
+		report.Files, report.Uploaded, report.Unchanged, report.Failed, report.Verified, report.Purged)
+	if ctx.Err() != nil {
+		// replaced by the publication of a newer config
+		log.Info("canceled: scan static files")
 		return
 	}
-
-	// scan static path files
-	entry, err := os.ReadDir(fileName)
 	if err != nil {
-		log.Error("read static dir failed: %v", err)
+		enable.Store(false)
+		log.Error("failed: scan static files: ", err)
 		return
 	}
-	for _, info := range entry {
-		if info.IsDir() {
-			err = c.scanStaticPathFiles(filepath.Join(fileName, info.Name()))
-			if err != nil {
-				return
-			}
-		}
-
-		var file *os.File
-		filePath := filepath.Join(fileName, info.Name())
-		fi, _ := info.Info()
-		size := fi.Size()
-		file, err = os.Open(filePath)
-		if err != nil {
-			log.Error("open file failed: %v", err)
-			return
-		}
-
-		suffix := staticPath[:1]
-		if suffix != "/" {
-			suffix = ""
-		}
-		filePath = strings.TrimPrefix(filePath, staticPath+suffix)
-
-		// rebuild custom io.Reader
-		ns := strings.Split(info.Name(), ".")
-		if info.Name() == "asset-manifest.json" {
-			err = c.Upload(filePath, c.rebuildReader(file, map[string]string{
-				"\"/static": "",
-			}), size)
-			if err != nil {
-				return
-			}
-			continue
-		}
-		if ns[0] == "main" {
-			ext := strings.ToLower(filepath.Ext(filePath))
-			if ext == ".js" || ext == ".map" {
-				err = c.Upload(filePath, c.rebuildReader(file, map[string]string{
-					"\"static": "",
-					"=\"/\",":  "=\"\",",
-				}), size)
-				if err != nil {
-					return
-				}
-				continue
-			}
-
-			if ext == ".css" {
-				err = c.Upload(filePath, c.rebuildReader(file, map[string]string{
-					"url(/static": "url(../../static",
-				}), size)
-				if err != nil {
-					return
-				}
-				continue
-			}
-		}
-
-		err = c.Upload(filePath, file, size)
-		if err != nil {
-			return
-		}
-	}
-	return
+	enable.Store(true)
+	log.Info("complete: scan static files")
 }
 
-func (c *CDN) scanEmbedFiles(fileName string) (err error) {
-	entry, err := ui.Build.ReadDir(fileName)
-	if err != nil {
-		log.Error("read static dir failed: %v", err)
-		return
-	}
-	for _, info := range entry {
-		if info.IsDir() {
-			err = c.scanEmbedFiles(filepath.Join(fileName, info.Name()))
-			if err != nil {
-				return
-			}
-			continue
-		}
-
-		var file fs.File
-		filePath := filepath.Join(fileName, info.Name())
-		fi, _ := info.Info()
-		size := fi.Size()
-		file, err = ui.Build.Open(filePath)
-		defer file.Close()
-		if err != nil {
-			log.Error("open file failed: %v", err)
-			return
+// filter skips the unsupported files and the files over the size limit of the config
+func (c *CDN) filter(config *CDNConfig) func(filePath string, size int64) bool {
+	return func(filePath string, size int64) bool {
+		if !c.CheckFileType(filePath) {
+			log.Error(plugin.MakeTranslator(i18n.ErrUnsupportedFileType), filePath)
+			return false
 		}
-
-		filePath = strings.TrimPrefix(filePath, "build/")
-
-		// rebuild custom io.Reader
-		ns := strings.Split(info.Name(), ".")
-		if info.Name() == "asset-manifest.json" {
-			err = c.Upload(filePath, c.rebuildReader(file, map[string]string{
-				"\"/static": "",
-			}), size)
-			if err != nil {
-				return
-			}
-			continue
+		if size > maxFileSizeLimit(config) {
+			log.Error(plugin.MakeTranslator(i18n.ErrOverFileSizeLimit))
+			return false
 		}
-
-		if ns[0] == "main" {
-			ext := strings.ToLower(filepath.Ext(filePath))
-			if ext == ".js" || ext == ".map" {
-				err = c.Upload(filePath, c.rebuildReader(file, map[string]string{
-					"\"static": "",
-					"=\"/\",":  "=\"\",",
-				}), size)
-				if err != nil {
-					return
-				}
-				continue
-			}
-
-			if ext == ".css" {
-				err = c.Upload(filePath, c.rebuildReader(file, map[string]string{
-					"url(/static": "url(../../static",
-				}), size)
-				if err != nil {
-					return
-				}
-				continue
-			}
-		}
-
-		err = c.Upload(filePath, c.rebuildReader(file, nil), size)
-		if err != nil {
-			return
-		}
-	}
-	return
-}
-
-func (c *CDN) rebuildReader(file io.Reader, replaceMap map[string]string) io.ReadSeeker {
-	var (
-		bufr = make([]byte, 0)
-		res  string
-	)
-
-	for {
-		buf := make([]byte, 1024)
-		n, err := file.Read(buf)
-		if err != nil {
-			break
-		}
-		bufr = append(bufr, buf[:n]...)
-	}
-
-	res = string(bufr)
-
-	if replaceMap != nil {
-		for oldStr, newStr := range replaceMap {
-			if oldStr != "" {
-				if newStr == "" {
-					prefix := c.Config.VisitUrlPrefix + c.Config.ObjectKeyPrefix
-					if prefix[len(prefix)-1:] == "/" {
-						prefix = strings.TrimSuffix(prefix, "/")
-					}
-					newStr = "\"" + prefix + "/static"
-				}
-				res = strings.ReplaceAll(res, oldStr, newStr)
-			}
-		}
-	}
-
-	return strings.NewReader(res)
-}
-func (c *CDN) Upload(filePath string, file io.ReadSeeker, size int64) (err error) {
-
-	if !c.CheckFileType(filePath) {
-		log.Error(plugin.MakeTranslator(i18n.ErrUnsupportedFileType), filePath)
-		return
-	}
-
-	if size > c.maxFileSizeLimit() {
-		log.Error(plugin.MakeTranslator(i18n.ErrOverFileSizeLimit))
-		return
-	}
-
-	objectKey := c.createObjectKey(filePath)
-
-	err = c.Client.PutObject(objectKey, strings.ToLower(filepath.Ext(filePath)), file)
-	if err != nil {
-		log.Error(plugin.MakeTranslator(i18n.ErrUploadFileFailed), err)
-		return
-	}
-	return c.checkCDNAvailable(objectKey)
-}
-
-func (c *CDN) checkCDNAvailable(objectKey string) error {
-	url := c.Config.VisitUrlPrefix + objectKey
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return err
-	}
-
-	client := &http.Client{}
-	response, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer response.Body.Close()
-
-	if response.StatusCode != http.StatusOK {
-		log.Error("check error:", url)
-		return fmt.Errorf("failed to get object, %s", response.Status)
+		return true
 	}
-	return nil
-}
-
-func (c *CDN) createObjectKey(filePath string) string {
-	return c.Config.ObjectKeyPrefix + filePath
 }
 
 func (c *CDN) randomObjectKey() string {
@@ -369,11 +212,11 @@ func (c *CDN) CheckFileType(filePath string) bool {
 	return false
 }
 
-func (c *CDN) maxFileSizeLimit() int64 {
-	if len(c.Config.MaxFileSize) == 0 {
+func maxFileSizeLimit(config *CDNConfig) int64 {
+	if len(config.MaxFileSize) == 0 {
 		return defaultMaxFileSize
 	}
-	limit, _ := strconv.Atoi(c.Config.MaxFileSize)
+	limit, _ := strconv.Atoi(config.MaxFileSize)
 	if limit <= 0 {
 		return defaultMaxFileSize
 	}
@@ -381,6 +224,7 @@ func (c *CDN) maxFileSizeLimit() int64 {
 }
 
 func (c *CDN) ConfigFields() []plugin.ConfigField {
+	config := c.publication().config
 	return []plugin.ConfigField{
 		{
 			Name:        "endpoint",
@@ -391,7 +235,7 @@ func (c *CDN) ConfigFields() []plugin.ConfigField {
 			UIOptions: plugin.ConfigFieldUIOptions{
 				InputType: plugin.InputTypeText,
 			},
-			Value: c.Config.Endpoint,
+			Value: config.Endpoint,
 		},
 		{
 			Name:        "bucket_name",
@@ -402,7 +246,7 @@ func (c *CDN) ConfigFields() []plugin.ConfigField {
 			UIOptions: plugin.ConfigFieldUIOptions{
 				InputType: plugin.InputTypeText,
 			},
-			Value: c.Config.BucketName,
+			Value: config.BucketName,
 		},
 		{
 			Name:        "object_key_prefix",
@@ -413,7 +257,7 @@ func (c *CDN) ConfigFields() []plugin.ConfigField {
 			UIOptions: plugin.ConfigFieldUIOptions{
 				InputType: plugin.InputTypeText,
 			},
-			Value: c.Config.ObjectKeyPrefix,
+			Value: config.ObjectKeyPrefix,
 		},
 		{
 			Name:        "access_key_id",
@@ -424,7 +268,7 @@ func (c *CDN) ConfigFields() []plugin.ConfigField {
 			UIOptions: plugin.ConfigFieldUIOptions{
 				InputType: plugin.InputTypeText,
 			},
-			Value: c.Config.AccessKeyID,
+			Value: config.AccessKeyID,
 		},
 		{
 			Name:        "access_key_secret",
@@ -435,7 +279,7 @@ func (c *CDN) ConfigFields() []plugin.ConfigField {
 			UIOptions: plugin.ConfigFieldUIOptions{
 				InputType: plugin.InputTypeText,
 			},
-			Value: c.Config.AccessKeySecret,
+			Value: config.AccessKeySecret,
 		},
 		{
 			Name:        "access_token",
@@ -446,7 +290,7 @@ func (c *CDN) ConfigFields() []plugin.ConfigField {
 			UIOptions: plugin.ConfigFieldUIOptions{
 				InputType: plugin.InputTypeText,
 			},
-			Value: c.Config.AccessToken,
+			Value: config.AccessToken,
 		},
 		{
 			Name:        "visit_url_prefix",
@@ -457,7 +301,7 @@ func (c *CDN) ConfigFields() []plugin.ConfigField {
 			UIOptions: plugin.ConfigFieldUIOptions{
 				InputType: plugin.InputTypeText,
 			},
-			Value: c.Config.VisitUrlPrefix,
+			Value: config.VisitUrlPrefix,
 		},
 		{
 			Name:        "max_file_size",
@@ -468,7 +312,113 @@ func (c *CDN) ConfigFields() []plugin.ConfigField {
 			UIOptions: plugin.ConfigFieldUIOptions{
 				InputType: plugin.InputTypeNumber,
 			},
-			Value: c.Config.MaxFileSize,
+			Value: config.MaxFileSize,
+		},
+		{
+			Name:        "cache_max_age",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigCacheMaxAgeTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigCacheMaxAgeDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeNumber,
+			},
+			Value: config.CacheMaxAge,
+		},
+		{
+			Name:        "precompression",
+			Type:        plugin.ConfigTypeSelect,
+			Title:       plugin.MakeTranslator(i18n.ConfigPrecompressionTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigPrecompressionDescription),
+			Value:       config.Precompression,
+			Options: []plugin.ConfigFieldOption{
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigPrecompressionOptionNone),
+					Value: "",
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigPrecompressionOptionGzip),
+					Value: cdnsync.EncodingGzip,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigPrecompressionOptionBr),
+					Value: cdnsync.EncodingBrotli,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigPrecompressionOptionGzipBr),
+					Value: cdnsync.EncodingGzip + "," + cdnsync.EncodingBrotli,
+				},
+			},
+		},
+		{
+			Name:        "purge_provider",
+			Type:        plugin.ConfigTypeSelect,
+			Title:       plugin.MakeTranslator(i18n.ConfigPurgeProviderTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigPurgeProviderDescription),
+			Value:       config.PurgeProvider,
+			Options: []plugin.ConfigFieldOption{
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigPurgeProviderOptionNone),
+					Value: "",
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigPurgeProviderOptionCloudFront),
+					Value: PurgeProviderCloudFront,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigPurgeProviderOptionWebhook),
+					Value: PurgeProviderWebhook,
+				},
+			},
+		},
+		{
+			Name:        "cloudfront_distribution_id",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigCloudFrontDistributionIDTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigCloudFrontDistributionIDDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: config.CloudFrontDistributionID,
+		},
+		{
+			Name:        "purge_paths",
+			Type:        plugin.ConfigTypeTextarea,
+			Title:       plugin.MakeTranslator(i18n.ConfigPurgePathsTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigPurgePathsDescription),
+			Required:    false,
+			Value:       config.PurgePaths,
+		},
+		{
+			Name:        "purge_webhook_url",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigPurgeWebhookURLTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigPurgeWebhookURLDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeUrl,
+			},
+			Value: config.PurgeWebhookURL,
+		},
+		{
+			Name:        "purge_webhook_secret",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigPurgeWebhookSecretTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigPurgeWebhookSecretDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypePassword,
+			},
+			Value: config.PurgeWebhookSecret,
+		},
+		{
+			Name:        "rewrite_rules",
+			Type:        plugin.ConfigTypeTextarea,
+			Title:       plugin.MakeTranslator(i18n.ConfigRewriteRulesTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigRewriteRulesDescription),
+			Required:    false,
+			Value:       config.RewriteRules,
 		},
 		{
 			Name:        "region",
@@ -479,13 +429,13 @@ func (c *CDN) ConfigFields() []plugin.ConfigField {
 			UIOptions: plugin.ConfigFieldUIOptions{
 				InputType: plugin.InputTypeText,
 			},
-			Value: c.Config.Region,
+			Value: config.Region,
 		},
 		{
 			Name:  "disable_ssl",
 			Type:  plugin.ConfigTypeSwitch,
 			Title: plugin.MakeTranslator(i18n.ConfigDisableSSLTitle),
-			Value: c.Config.DisableSSL,
+			Value: config.DisableSSL,
 			UIOptions: plugin.ConfigFieldUIOptions{
 				Label: plugin.MakeTranslator(i18n.ConfigDisableSSLDescription),
 			},
@@ -493,19 +443,43 @@ func (c *CDN) ConfigFields() []plugin.ConfigField {
 	}
 }
 
+func (c *CDN) RegisterUnAuthRouter(r *gin.RouterGroup) {
+}
+
+func (c *CDN) RegisterAuthUserRouter(r *gin.RouterGroup) {
+}
+
+func (c *CDN) RegisterAuthAdminRouter(r *gin.RouterGroup) {
+	r.GET("/s3_cdn/status", cdnsync.StatusHandler(c.Info().SlugName, c.tracker))
+}
+
 func (c *CDN) ConfigReceiver(config []byte) error {
 	cfg := &CDNConfig{}
 	_ = json.Unmarshal(config, cfg)
-	c.Config = cfg
-	c.Client = NewS3Client(
-		c.Config.AccessKeyID,
-		c.Config.AccessKeySecret,
-		c.Config.AccessToken,
-		c.Config.Endpoint,
-		c.Config.Region,
-		c.Config.BucketName,
-		c.Config.DisableSSL,
+	// nothing is applied until the whole config is valid, so a failed save keeps the previous one working
+	client, err := NewS3Client(
+		cfg.AccessKeyID,
+		cfg.AccessKeySecret,
+		cfg.AccessToken,
+		cfg.Endpoint,
+		cfg.Region,
+		cfg.BucketName,
+		cfg.DisableSSL,
 	)
-	go c.scanFiles()
+	if err != nil {
+		return err
+	}
+	purger, err := newPurger(cfg)
+	if err != nil {
+		return err
+	}
+	rules, err := cdnsync.ParseRules(cfg.RewriteRules)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.current = &publication{config: cfg, client: client, purger: purger, rules: rules}
+	c.mu.Unlock()
+	c.tracker.Run(c.scanFiles)
 	return nil
 }
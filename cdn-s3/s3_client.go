@@ -20,65 +20,73 @@
 package s3
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+
+	"github.com/apache/incubator-answer-plugins/util/cdnsync"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
-	"io"
-	"strings"
 )
 
+// Client is the bucket of the static files, it reuses one session for all the requests.
 type Client struct {
-	s3Config *aws.Config
-	bucket   string
+	client *s3.S3
+	bucket string
 }
 
-func NewS3Client(id, secret, token, endpoint, region, bucket string, disableSSL bool) *Client {
-	s3Client := &Client{
-		s3Config: &aws.Config{
-			Credentials:      credentials.NewStaticCredentials(id, secret, token),
-			Endpoint:         aws.String(endpoint),
-			Region:           aws.String(region),
-			DisableSSL:       aws.Bool(disableSSL),
-			S3ForcePathStyle: aws.Bool(true),
-		},
-		bucket: bucket,
+func NewS3Client(id, secret, token, endpoint, region, bucket string, disableSSL bool) (*Client, error) {
+	newSession, err := session.NewSession(&aws.Config{
+		Credentials:      credentials.NewStaticCredentials(id, secret, token),
+		Endpoint:         aws.String(endpoint),
+		Region:           aws.String(region),
+		DisableSSL:       aws.Bool(disableSSL),
+		S3ForcePathStyle: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session, %s", err.Error())
 	}
-	return s3Client
+	return &Client{client: s3.New(newSession), bucket: bucket}, nil
 }
 
-func (s *Client) PutObject(key, ext string, file io.ReadSeeker) (err error) {
-	newSession, err := session.NewSession(s.s3Config)
+// GetObject reads the object, such as the manifest of the published files.
+func (s *Client) GetObject(ctx context.Context, key string) ([]byte, error) {
+	output, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
 	if err != nil {
-		return fmt.Errorf("failed to create session, %s", err.Error())
+		var aerr awserr.Error
+		if errors.As(err, &aerr) && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, cdnsync.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get object, %s", err.Error())
 	}
-
-	extType := strings.TrimPrefix(ext, ".")
-	contentType := ""
-	switch extType {
-	case "jpg", "jpeg", "png":
-		contentType = "image/" + extType
-	case "svg":
-		contentType = "image/svg+xml"
-	case "js":
-		contentType = "application/javascript"
-	case "css":
-		contentType = "text/css"
-	case "map":
-		contentType = "application/json"
-	case "woff":
-		contentType = "application/font-woff"
-	case "woff2":
-		contentType = "application/font-woff2"
+	defer output.Body.Close()
+	buf := &bytes.Buffer{}
+	if _, err = io.Copy(buf, output.Body); err != nil {
+		return nil, fmt.Errorf("failed to read object, %s", err.Error())
 	}
+	return buf.Bytes(), nil
+}
 
-	_, err = s3.New(newSession).PutObject(&s3.PutObjectInput{
-		Body:        file,
-		Bucket:      aws.String(s.bucket),
-		Key:         aws.String(key),
-		ContentType: aws.String(contentType),
-	})
+func (s *Client) PutObject(ctx context.Context, key string, file io.ReadSeeker, headers cdnsync.Headers) (err error) {
+	input := &s3.PutObjectInput{
+		Body:         file,
+		Bucket:       aws.String(s.bucket),
+		Key:          aws.String(key),
+		ContentType:  aws.String(headers.ContentType),
+		CacheControl: aws.String(headers.CacheControl),
+	}
+	if headers.ContentEncoding != "" {
+		input.ContentEncoding = aws.String(headers.ContentEncoding)
+	}
+	_, err = s.client.PutObjectWithContext(ctx, input)
 	if err != nil {
 		return fmt.Errorf("failed to put object, %s", err.Error())
 	}
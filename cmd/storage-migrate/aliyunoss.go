@@ -0,0 +1,100 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/apache/incubator-answer-plugins/util/storagemigrate"
+)
+
+// AliyunOSSBackend is a bucket of Aliyun OSS, its SDK takes no context so a request is not cancelled midway
+type AliyunOSSBackend struct {
+	bucket *oss.Bucket
+}
+
+func NewAliyunOSSBackend(conf BackendConfig) (*AliyunOSSBackend, error) {
+	if len(conf.Bucket) == 0 {
+		return nil, errors.New("bucket is required")
+	}
+	var options []oss.ClientOption
+	if len(conf.AccessToken) > 0 {
+		options = append(options, oss.SecurityToken(conf.AccessToken))
+	}
+	client, err := oss.New(conf.Endpoint, conf.AccessKeyID, conf.AccessKeySecret, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client, %s", err.Error())
+	}
+	bucket, err := client.Bucket(conf.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bucket, %s", err.Error())
+	}
+	return &AliyunOSSBackend{bucket: bucket}, nil
+}
+
+func (b *AliyunOSSBackend) List(ctx context.Context, prefix string, fn func(object storagemigrate.Object) error) error {
+	token := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		result, err := b.bucket.ListObjectsV2(oss.Prefix(prefix), oss.ContinuationToken(token))
+		if err != nil {
+			return err
+		}
+		for _, item := range result.Objects {
+			if err = fn(storagemigrate.Object{Key: item.Key, Size: item.Size}); err != nil {
+				return err
+			}
+		}
+		if !result.IsTruncated {
+			return nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+func (b *AliyunOSSBackend) Open(ctx context.Context, key string) (io.ReadCloser, storagemigrate.Object, error) {
+	result, err := b.bucket.DoGetObject(&oss.GetObjectRequest{ObjectKey: key}, nil)
+	var serviceErr oss.ServiceError
+	if errors.As(err, &serviceErr) && serviceErr.StatusCode == http.StatusNotFound {
+		return nil, storagemigrate.Object{}, storagemigrate.ErrNotFound
+	}
+	if err != nil {
+		return nil, storagemigrate.Object{}, err
+	}
+	header := result.Response.Headers
+	size, _ := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	return result.Response.Body, storagemigrate.Object{Key: key, Size: size, ContentType: header.Get("Content-Type")}, nil
+}
+
+func (b *AliyunOSSBackend) Put(ctx context.Context, object storagemigrate.Object, content io.Reader) error {
+	var options []oss.Option
+	if len(object.ContentType) > 0 {
+		options = append(options, oss.ContentType(object.ContentType))
+	}
+	return b.bucket.PutObject(object.Key, content, options...)
+}
@@ -0,0 +1,133 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/apache/incubator-answer-plugins/util/storagemigrate"
+)
+
+// AzureBlobBackend is a container of Azure Blob Storage, Bucket is the container name
+type AzureBlobBackend struct {
+	container *container.Client
+}
+
+// NewAzureBlobBackend creates the backend, like the Azure Blob storage plugin does
+func NewAzureBlobBackend(conf BackendConfig) (*AzureBlobBackend, error) {
+	if len(conf.Bucket) == 0 {
+		return nil, errors.New("bucket is required")
+	}
+	endpoint := strings.TrimSuffix(conf.Endpoint, "/")
+	if len(endpoint) == 0 {
+		if len(conf.AccountName) == 0 {
+			return nil, errors.New("account_name is required")
+		}
+		endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", conf.AccountName)
+	}
+	containerURL := endpoint + "/" + conf.Bucket
+
+	var (
+		client *container.Client
+		err    error
+	)
+	switch {
+	case len(conf.SASToken) > 0:
+		sasToken := strings.TrimPrefix(conf.SASToken, "?")
+		if _, err = url.ParseQuery(sasToken); err != nil {
+			return nil, fmt.Errorf("invalid SAS token, %v", err)
+		}
+		client, err = container.NewClientWithNoCredential(containerURL+"?"+sasToken, nil)
+	case len(conf.AccountName) > 0 && len(conf.AccountKey) > 0:
+		var cred *container.SharedKeyCredential
+		cred, err = container.NewSharedKeyCredential(conf.AccountName, conf.AccountKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid account key, %v", err)
+		}
+		client, err = container.NewClientWithSharedKeyCredential(containerURL, cred, nil)
+	default:
+		return nil, errors.New("account_key or sas_token is required")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client, %s", err.Error())
+	}
+	return &AzureBlobBackend{container: client}, nil
+}
+
+func (b *AzureBlobBackend) List(ctx context.Context, prefix string, fn func(object storagemigrate.Object) error) error {
+	pager := b.container.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{Prefix: &prefix})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, item := range page.Segment.BlobItems {
+			object := storagemigrate.Object{Key: *item.Name}
+			if item.Properties != nil {
+				if item.Properties.ContentLength != nil {
+					object.Size = *item.Properties.ContentLength
+				}
+				if item.Properties.ContentType != nil {
+					object.ContentType = *item.Properties.ContentType
+				}
+			}
+			if err = fn(object); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (b *AzureBlobBackend) Open(ctx context.Context, key string) (io.ReadCloser, storagemigrate.Object, error) {
+	resp, err := b.container.NewBlobClient(key).DownloadStream(ctx, nil)
+	if bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return nil, storagemigrate.Object{}, storagemigrate.ErrNotFound
+	}
+	if err != nil {
+		return nil, storagemigrate.Object{}, err
+	}
+	object := storagemigrate.Object{Key: key}
+	if resp.ContentLength != nil {
+		object.Size = *resp.ContentLength
+	}
+	if resp.ContentType != nil {
+		object.ContentType = *resp.ContentType
+	}
+	return resp.Body, object, nil
+}
+
+func (b *AzureBlobBackend) Put(ctx context.Context, object storagemigrate.Object, content io.Reader) error {
+	options := &blockblob.UploadStreamOptions{BlockSize: 8 * 1024 * 1024, Concurrency: 3}
+	if len(object.ContentType) > 0 {
+		options.HTTPHeaders = &blob.HTTPHeaders{BlobContentType: &object.ContentType}
+	}
+	_, err := b.container.NewBlockBlobClient(object.Key).UploadStream(ctx, content, options)
+	return err
+}
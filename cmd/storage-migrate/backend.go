@@ -0,0 +1,84 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/incubator-answer-plugins/util/storagemigrate"
+)
+
+const (
+	BackendLocal     = "local"
+	BackendS3        = "s3"
+	BackendAliyunOSS = "aliyunoss"
+	BackendAzureBlob = "azureblob"
+	BackendGCS       = "gcs"
+)
+
+// BackendConfig is the config of a backend, with the fields of its storage plugin
+type BackendConfig struct {
+	Type string `json:"type"`
+	// VisitUrlPrefix is the visit URL prefix of the storage plugin, making the URL mapping
+	VisitUrlPrefix string `json:"visit_url_prefix"`
+
+	// local
+	RootDir string `json:"root_dir"`
+
+	// s3, aliyunoss, azureblob and gcs
+	Endpoint string `json:"endpoint"`
+	// Bucket is the bucket, or the container of azureblob
+	Bucket string `json:"bucket"`
+
+	// s3 and aliyunoss
+	Region          string `json:"region"`
+	AccessKeyID     string `json:"access_key_id"`
+	AccessKeySecret string `json:"access_key_secret"`
+	AccessToken     string `json:"access_token"`
+	// VirtualHosted addresses the s3 buckets like https://bucket.endpoint/key instead of https://endpoint/bucket/key
+	VirtualHosted bool `json:"virtual_hosted"`
+
+	// azureblob
+	AccountName string `json:"account_name"`
+	AccountKey  string `json:"account_key"`
+	SASToken    string `json:"sas_token"`
+
+	// gcs
+	CredentialsJSON string `json:"credentials_json"`
+}
+
+// NewBackend returns the backend of the config
+func NewBackend(ctx context.Context, conf BackendConfig) (storagemigrate.Backend, error) {
+	switch conf.Type {
+	case BackendLocal:
+		return NewLocalBackend(conf)
+	case BackendS3:
+		return NewS3Backend(conf)
+	case BackendAliyunOSS:
+		return NewAliyunOSSBackend(conf)
+	case BackendAzureBlob:
+		return NewAzureBlobBackend(conf)
+	case BackendGCS:
+		return NewGCSBackend(ctx, conf)
+	default:
+		return nil, fmt.Errorf("unknown backend type %q", conf.Type)
+	}
+}
@@ -0,0 +1,104 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/apache/incubator-answer-plugins/util/storagemigrate"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+// GCSBackend is a bucket of Google Cloud Storage
+type GCSBackend struct {
+	bucket *storage.BucketHandle
+}
+
+// NewGCSBackend creates the backend, like the GCS storage plugin does. Without credentials_json,
+// the credentials are the application default credentials, or none for a custom endpoint.
+func NewGCSBackend(ctx context.Context, conf BackendConfig) (*GCSBackend, error) {
+	if len(conf.Bucket) == 0 {
+		return nil, errors.New("bucket is required")
+	}
+	var opts []option.ClientOption
+	if len(conf.Endpoint) > 0 {
+		opts = append(opts, option.WithEndpoint(conf.Endpoint))
+	}
+	switch {
+	case len(conf.CredentialsJSON) > 0:
+		credentials := strings.TrimSpace(conf.CredentialsJSON)
+		if !json.Valid([]byte(credentials)) {
+			return nil, errors.New("invalid credentials_json, not JSON")
+		}
+		opts = append(opts, option.WithCredentialsJSON([]byte(credentials)))
+	case len(conf.Endpoint) > 0:
+		opts = append(opts, option.WithoutAuthentication())
+	}
+	client, err := storage.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client, %s", err.Error())
+	}
+	return &GCSBackend{bucket: client.Bucket(conf.Bucket)}, nil
+}
+
+func (b *GCSBackend) List(ctx context.Context, prefix string, fn func(object storagemigrate.Object) error) error {
+	it := b.bucket.Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = fn(storagemigrate.Object{Key: attrs.Name, Size: attrs.Size, ContentType: attrs.ContentType}); err != nil {
+			return err
+		}
+	}
+}
+
+func (b *GCSBackend) Open(ctx context.Context, key string) (io.ReadCloser, storagemigrate.Object, error) {
+	reader, err := b.bucket.Object(key).NewReader(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, storagemigrate.Object{}, storagemigrate.ErrNotFound
+	}
+	if err != nil {
+		return nil, storagemigrate.Object{}, err
+	}
+	return reader, storagemigrate.Object{Key: key, Size: reader.Attrs.Size, ContentType: reader.Attrs.ContentType}, nil
+}
+
+func (b *GCSBackend) Put(ctx context.Context, object storagemigrate.Object, content io.Reader) error {
+	writer := b.bucket.Object(object.Key).NewWriter(ctx)
+	writer.ChunkSize = 8 * 1024 * 1024
+	writer.ContentType = object.ContentType
+	if _, err := io.Copy(writer, content); err != nil {
+		_ = writer.Close()
+		return err
+	}
+	return writer.Close()
+}
@@ -0,0 +1,142 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/apache/incubator-answer-plugins/util/storagemigrate"
+)
+
+// tempPrefix prefixes the files being written, like the local storage plugin does
+const tempPrefix = ".upload-"
+
+// LocalBackend is a directory, such as the root directory of the local storage plugin
+type LocalBackend struct {
+	root string
+}
+
+func NewLocalBackend(conf BackendConfig) (*LocalBackend, error) {
+	if len(conf.RootDir) == 0 {
+		return nil, errors.New("root_dir is required")
+	}
+	root, err := filepath.Abs(conf.RootDir)
+	if err != nil {
+		return nil, err
+	}
+	return &LocalBackend{root: root}, nil
+}
+
+// path returns the path of the key, refusing the keys out of the root
+func (b *LocalBackend) path(key string) (string, error) {
+	for _, part := range strings.Split(key, "/") {
+		if len(part) == 0 || part == "." || part == ".." {
+			return "", fmt.Errorf("invalid key %s", key)
+		}
+	}
+	return filepath.Join(b.root, filepath.FromSlash(key)), nil
+}
+
+func (b *LocalBackend) List(ctx context.Context, prefix string, fn func(object storagemigrate.Object) error) error {
+	return filepath.WalkDir(b.root, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), tempPrefix) {
+			return nil
+		}
+		rel, err := filepath.Rel(b.root, name)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		return fn(storagemigrate.Object{Key: key, Size: info.Size(), ContentType: mime.TypeByExtension(path.Ext(key))})
+	})
+}
+
+func (b *LocalBackend) Open(ctx context.Context, key string) (io.ReadCloser, storagemigrate.Object, error) {
+	name, err := b.path(key)
+	if err != nil {
+		return nil, storagemigrate.Object{}, err
+	}
+	file, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, storagemigrate.Object{}, storagemigrate.ErrNotFound
+	}
+	if err != nil {
+		return nil, storagemigrate.Object{}, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, storagemigrate.Object{}, err
+	}
+	return file, storagemigrate.Object{Key: key, Size: info.Size(), ContentType: mime.TypeByExtension(path.Ext(key))}, nil
+}
+
+// Put writes the file to a temporary file renamed into place, so that it is never read partly written
+func (b *LocalBackend) Put(ctx context.Context, object storagemigrate.Object, content io.Reader) error {
+	name, err := b.path(object.Key)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(name), tempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if _, err = io.Copy(temp, content); err != nil {
+		_ = temp.Close()
+		return err
+	}
+	if err = temp.Sync(); err != nil {
+		_ = temp.Close()
+		return err
+	}
+	if err = temp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(temp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), name)
+}
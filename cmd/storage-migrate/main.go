@@ -0,0 +1,122 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+// Command storage-migrate copies the uploads of Answer from a storage backend to another, keeping their
+// keys, verifies the copies and writes the mapping of the old URLs to the new ones.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/apache/incubator-answer-plugins/util/storagemigrate"
+)
+
+// Config is the config file of a migration
+type Config struct {
+	// Prefix is the key prefix of the objects to migrate, the object key prefix of the old storage plugin
+	Prefix string        `json:"prefix"`
+	From   BackendConfig `json:"from"`
+	To     BackendConfig `json:"to"`
+}
+
+func main() {
+	configPath := flag.String("config", "storage-migrate.json", "config file of the migration")
+	dryRun := flag.Bool("dry-run", false, "list the objects and write the mapping without copying anything")
+	statePath := flag.String("state", "storage-migrate.state.jsonl", "file recording the migrated objects, to resume an interrupted migration")
+	mappingPath := flag.String("mapping", "storage-migrate.mapping.csv", "file of the old and the new URLs of the objects")
+	concurrency := flag.Int("concurrency", storagemigrate.DefaultConcurrency, "number of objects copied at once")
+	flag.Parse()
+
+	if err := run(*configPath, *statePath, *mappingPath, *dryRun, *concurrency); err != nil {
+		fmt.Fprintln(os.Stderr, "storage-migrate:", err)
+		os.Exit(1)
+	}
+}
+
+func run(configPath, statePath, mappingPath string, dryRun bool, concurrency int) error {
+	config, err := loadConfig(configPath)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	src, err := NewBackend(ctx, config.From)
+	if err != nil {
+		return fmt.Errorf("from: %w", err)
+	}
+	dst, err := NewBackend(ctx, config.To)
+	if err != nil {
+		return fmt.Errorf("to: %w", err)
+	}
+
+	var state *storagemigrate.State
+	if !dryRun {
+		state, err = storagemigrate.OpenState(statePath)
+		if err != nil {
+			return err
+		}
+		defer state.Close()
+		if state.Len() > 0 {
+			fmt.Fprintf(os.Stderr, "resuming, %d objects already migrated\n", state.Len())
+		}
+	}
+	mapping, err := os.Create(mappingPath)
+	if err != nil {
+		return fmt.Errorf("create mapping: %w", err)
+	}
+	defer mapping.Close()
+
+	migrator := storagemigrate.NewMigrator(src, dst, storagemigrate.Options{
+		Prefix:      config.Prefix,
+		FromURL:     config.From.VisitUrlPrefix,
+		ToURL:       config.To.VisitUrlPrefix,
+		DryRun:      dryRun,
+		Concurrency: concurrency,
+	}, state)
+	report, err := migrator.Run(ctx, mapping)
+	if report != nil {
+		out, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(out))
+	}
+	if err != nil {
+		return err
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d objects failed, run it again to retry them", report.Failed)
+	}
+	return mapping.Sync()
+}
+
+func loadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	config := &Config{}
+	if err = json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	return config, nil
+}
@@ -0,0 +1,125 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRunLocal(t *testing.T) {
+	dir := t.TempDir()
+	from, to := filepath.Join(dir, "from"), filepath.Join(dir, "to")
+	writeFiles(t, from, map[string]string{
+		"answer/avatar/a.png":       "avatar",
+		"answer/post/b.pdf":         "%PDF-1.7",
+		"answer/post/.upload-12345": "partly written",
+		"backup/c.txt":              "outside the prefix",
+	})
+	config, _ := json.Marshal(&Config{
+		Prefix: "answer/",
+		From:   BackendConfig{Type: BackendLocal, RootDir: from, VisitUrlPrefix: "https://old.example.com/uploads/"},
+		To:     BackendConfig{Type: BackendLocal, RootDir: to, VisitUrlPrefix: "https://cdn.example.com/"},
+	})
+	configPath := filepath.Join(dir, "config.json")
+	statePath := filepath.Join(dir, "state.jsonl")
+	mappingPath := filepath.Join(dir, "mapping.csv")
+	if err := os.WriteFile(configPath, config, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// a dry run copies nothing and records nothing
+	if err := run(configPath, statePath, mappingPath, true, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(to); !os.IsNotExist(err) {
+		t.Errorf("dry run created the destination, %v", err)
+	}
+	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+		t.Errorf("dry run created the state, %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := run(configPath, statePath, mappingPath, false, 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, want := range map[string]string{"answer/avatar/a.png": "avatar", "answer/post/b.pdf": "%PDF-1.7"} {
+		got, err := os.ReadFile(filepath.Join(to, filepath.FromSlash(name)))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v", name, got, err)
+		}
+	}
+	for _, name := range []string{"answer/post/.upload-12345", "backup/c.txt"} {
+		if _, err := os.Stat(filepath.Join(to, filepath.FromSlash(name))); !os.IsNotExist(err) {
+			t.Errorf("%s copied", name)
+		}
+	}
+	mapping, err := os.ReadFile(mappingPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(mapping), "https://old.example.com/uploads/answer/post/b.pdf,https://cdn.example.com/answer/post/b.pdf\n") ||
+		strings.Count(string(mapping), "\n") != 2 {
+		t.Errorf("mapping = %s", mapping)
+	}
+}
+
+func TestLocalBackendInvalidKey(t *testing.T) {
+	backend, err := NewLocalBackend(BackendConfig{RootDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"../etc/passwd", "a//b", "/abs"} {
+		if _, err := backend.path(key); err == nil {
+			t.Errorf("key %s accepted", key)
+		}
+	}
+}
+
+func TestNewBackend(t *testing.T) {
+	for _, conf := range []BackendConfig{
+		{Type: "ftp"},
+		{Type: BackendLocal},
+		{Type: BackendS3},
+		{Type: BackendAzureBlob, Bucket: "uploads", AccountName: "answer"},
+		{Type: BackendGCS, Bucket: "uploads", CredentialsJSON: "not json"},
+	} {
+		if _, err := NewBackend(context.Background(), conf); err == nil {
+			t.Errorf("%+v accepted", conf)
+		}
+	}
+}
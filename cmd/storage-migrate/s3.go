@@ -0,0 +1,120 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/apache/incubator-answer-plugins/util/storagemigrate"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// S3Backend is a bucket of S3, or of a storage compatible with S3 such as Tencent COS or MinIO
+type S3Backend struct {
+	svc      *s3.S3
+	uploader *s3manager.Uploader
+	bucket   string
+}
+
+// NewS3Backend creates the backend, like the S3 storage plugin does. Without static keys, the credentials
+// come from the default AWS credential chain.
+func NewS3Backend(conf BackendConfig) (*S3Backend, error) {
+	if len(conf.Bucket) == 0 {
+		return nil, errors.New("bucket is required")
+	}
+	awsConfig := &aws.Config{
+		Region:           aws.String(conf.Region),
+		S3ForcePathStyle: aws.Bool(!conf.VirtualHosted),
+	}
+	if len(conf.Endpoint) > 0 {
+		awsConfig.Endpoint = aws.String(conf.Endpoint)
+	}
+	if len(conf.AccessKeyID) > 0 && len(conf.AccessKeySecret) > 0 {
+		awsConfig.Credentials = credentials.NewStaticCredentials(conf.AccessKeyID, conf.AccessKeySecret, conf.AccessToken)
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *awsConfig,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session, %s", err.Error())
+	}
+	svc := s3.New(sess)
+	return &S3Backend{svc: svc, uploader: s3manager.NewUploaderWithClient(svc), bucket: conf.Bucket}, nil
+}
+
+func (b *S3Backend) List(ctx context.Context, prefix string, fn func(object storagemigrate.Object) error) error {
+	var fnErr error
+	err := b.svc.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(b.bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, item := range page.Contents {
+			if fnErr = fn(storagemigrate.Object{Key: aws.StringValue(item.Key), Size: aws.Int64Value(item.Size)}); fnErr != nil {
+				return false
+			}
+		}
+		return true
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	return err
+}
+
+func (b *S3Backend) Open(ctx context.Context, key string) (io.ReadCloser, storagemigrate.Object, error) {
+	output, err := b.svc.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(key),
+	})
+	if reqErr, ok := err.(awserr.RequestFailure); ok && reqErr.StatusCode() == http.StatusNotFound {
+		return nil, storagemigrate.Object{}, storagemigrate.ErrNotFound
+	}
+	if err != nil {
+		return nil, storagemigrate.Object{}, err
+	}
+	return output.Body, storagemigrate.Object{
+		Key:         key,
+		Size:        aws.Int64Value(output.ContentLength),
+		ContentType: aws.StringValue(output.ContentType),
+	}, nil
+}
+
+func (b *S3Backend) Put(ctx context.Context, object storagemigrate.Object, content io.Reader) error {
+	input := &s3manager.UploadInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(object.Key),
+		Body:   content,
+	}
+	if len(object.ContentType) > 0 {
+		input.ContentType = aws.String(object.ContentType)
+	}
+	_, err := b.uploader.UploadWithContext(ctx, input)
+	return err
+}
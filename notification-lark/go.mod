@@ -1,6 +1,6 @@
 module github.com/apache/incubator-answer-plugins/notification-lark
 
-go 1.18
+go 1.21
 
 require (
 	github.com/apache/incubator-answer v1.3.6
@@ -22,27 +22,42 @@ package algolia
 import (
 	"context"
 	"embed"
+	"fmt"
 	"github.com/apache/incubator-answer-plugins/util"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/algolia/algoliasearch-client-go/v3/algolia/opt"
 	"github.com/algolia/algoliasearch-client-go/v3/algolia/search"
 	"github.com/apache/incubator-answer-plugins/search-algolia/i18n"
+	"github.com/apache/incubator-answer-plugins/util/searchext"
+	"github.com/apache/incubator-answer-plugins/util/searchquery"
+	"github.com/apache/incubator-answer-plugins/util/searchstats"
+	"github.com/apache/incubator-answer-plugins/util/searchsync"
 	"github.com/apache/incubator-answer/plugin"
+	"github.com/gin-gonic/gin"
 )
 
 //go:embed  info.yaml
 var Info embed.FS
 
 type SearchAlgolia struct {
-	Config *AlgoliaSearchConfig
-	client *search.Client
-	syncer plugin.SearchSyncer
+	Config     *AlgoliaSearchConfig
+	client     *search.Client
+	syncer     plugin.SearchSyncer
+	analytics  *searchstats.Recorder
+	reconciler *searchsync.Reconciler
+	outbox     *searchsync.Outbox
 }
 
 func init() {
-	uc := &SearchAlgolia{Config: &AlgoliaSearchConfig{}}
+	uc := &SearchAlgolia{
+		Config:    &AlgoliaSearchConfig{},
+		analytics: searchstats.NewRecorder(),
+	}
+	uc.reconciler = searchsync.NewReconciler("algolia", uc)
+	uc.outbox = searchsync.NewOutbox("algolia", uc.updateContent, uc.deleteContent)
 	plugin.Register(uc)
 }
 
@@ -68,12 +83,201 @@ func (s *SearchAlgolia) Description() plugin.SearchDesc {
 	return desc
 }
 
+func (s *SearchAlgolia) RegisterUnAuthRouter(r *gin.RouterGroup) {
+}
+
+func (s *SearchAlgolia) RegisterAuthUserRouter(r *gin.RouterGroup) {
+	r.POST("/algolia-search/similar", searchext.SimilarHandler(s.Info().SlugName, s))
+	r.GET("/algolia-search/search", searchext.HighlightHandler(s.Info().SlugName, s))
+	r.POST("/algolia-search/click", searchstats.ClickHandler(s.Info().SlugName, s.analytics))
+}
+
+func (s *SearchAlgolia) RegisterAuthAdminRouter(r *gin.RouterGroup) {
+	r.GET("/algolia-search/analytics", searchstats.ReportHandler(s.Info().SlugName, s.analytics))
+	r.GET("/algolia-search/reconcile", searchsync.StatusHandler(s.Info().SlugName, s.reconciler))
+	r.POST("/algolia-search/reconcile", searchsync.StartHandler(s.Info().SlugName, s.reconciler))
+	r.GET("/algolia-search/outbox", searchsync.OutboxHandler(s.Info().SlugName, s.outbox))
+}
+
 func (s *SearchAlgolia) RegisterSyncer(ctx context.Context, syncer plugin.SearchSyncer) {
 	s.syncer = syncer
+	s.reconciler.SetSyncer(syncer)
 	s.sync()
 }
 
 func (s *SearchAlgolia) SearchContents(ctx context.Context, cond *plugin.SearchBasicCond) (res []plugin.SearchResult, total int64, err error) {
+	results, total, err := s.SearchHighlight(ctx, searchext.ScopeContents, cond)
+	return searchext.ToSearchResults(results), total, err
+}
+
+func (s *SearchAlgolia) SearchQuestions(ctx context.Context, cond *plugin.SearchBasicCond) (res []plugin.SearchResult, total int64, err error) {
+	results, total, err := s.SearchHighlight(ctx, searchext.ScopeQuestions, cond)
+	return searchext.ToSearchResults(results), total, err
+}
+
+func (s *SearchAlgolia) SearchAnswers(ctx context.Context, cond *plugin.SearchBasicCond) (res []plugin.SearchResult, total int64, err error) {
+	results, total, err := s.SearchHighlight(ctx, searchext.ScopeAnswers, cond)
+	return searchext.ToSearchResults(results), total, err
+}
+
+// SearchHighlight searches the contents in scope and returns the matching fragments of each result
+func (s *SearchAlgolia) SearchHighlight(ctx context.Context, scope searchext.SearchScope, cond *plugin.SearchBasicCond) (res []searchext.Result, total int64, err error) {
+	start := time.Now()
+	defer func() { s.analytics.Record(scope, cond, total, err, start) }()
+	if s.client == nil {
+		return nil, 0, fmt.Errorf("algolia client not init")
+	}
+	var (
+		words = searchquery.ParseSearch(ctx, cond.Words)
+		query = words.Text()
+		opts  = s.buildSearchOptions(s.buildFilters(scope, cond)+buildWordsFilters(words), cond, words)
+		qres  search.QueryRes
+	)
+
+	qres, err = s.getIndex(string(cond.Order)).Search(query, opts...)
+	res = s.warpResult(qres)
+	total = int64(qres.NbHits)
+	return res, total, err
+}
+
+// SearchFacets works like SearchHighlight and also counts the facets of all matching contents.
+// The creation time buckets are counted by extra queries sent in the same multiple queries request.
+func (s *SearchAlgolia) SearchFacets(ctx context.Context, scope searchext.SearchScope, cond *plugin.SearchBasicCond) (res []searchext.Result, total int64, facets *searchext.Facets, err error) {
+	start := time.Now()
+	defer func() { s.analytics.Record(scope, cond, total, err, start) }()
+	if s.client == nil {
+		return nil, 0, nil, fmt.Errorf("algolia client not init")
+	}
+	var (
+		words     = searchquery.ParseSearch(ctx, cond.Words)
+		query     = words.Text()
+		filters   = s.buildFilters(scope, cond) + buildWordsFilters(words)
+		indexName = s.getIndexName(string(cond.Order))
+		opts      = append(s.buildSearchOptions(filters, cond, words),
+			opt.Query(query),
+			opt.Facets("tags", "type", "hasAccepted"),
+			opt.MaxValuesPerFacet(searchext.MaxFacetValues))
+		queries = []search.IndexedQuery{search.NewIndexedQuery(indexName, opts...)}
+		now     = time.Now()
+	)
+	for _, bucket := range searchext.CreatedBuckets {
+		bucketOpts := append(buildQueryOptions(words),
+			opt.Query(query),
+			opt.Filters(filters+" AND created>="+strconv.FormatInt(bucket.From(now), 10)),
+			opt.HitsPerPage(0),
+			opt.Analytics(false))
+		queries = append(queries, search.NewIndexedQuery(indexName, bucketOpts...))
+	}
+
+	mres, err := s.client.MultipleQueries(queries, "none")
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	if len(mres.Results) != len(queries) {
+		return nil, 0, nil, fmt.Errorf("algolia returned %d results for %d queries", len(mres.Results), len(queries))
+	}
+	qres := mres.Results[0].QueryRes
+	res = s.warpResult(qres)
+	total = int64(qres.NbHits)
+
+	facets = &searchext.Facets{
+		Tags:        facetCounts(qres.Facets["tags"]),
+		Type:        facetCounts(qres.Facets["type"]),
+		HasAccepted: facetCounts(qres.Facets["hasAccepted"]),
+	}
+	createdCounts := make(map[string]int64)
+	for i, bucket := range searchext.CreatedBuckets {
+		createdCounts[bucket.Name] = int64(mres.Results[i+1].NbHits)
+	}
+	facets.Created = searchext.NewCreatedCounts(createdCounts)
+	return res, total, facets, nil
+}
+
+func (s *SearchAlgolia) buildSearchOptions(filters string, cond *plugin.SearchBasicCond, words *searchquery.Query) []interface{} {
+	return append(buildQueryOptions(words),
+		opt.AttributesToRetrieve("objectID", "type"),
+		opt.AttributesToHighlight("title"),
+		opt.AttributesToSnippet("content:"+strconv.Itoa(searchext.SnippetWords)),
+		opt.HighlightPreTag(searchext.HighlightPreMarker),
+		opt.HighlightPostTag(searchext.HighlightPostMarker),
+		opt.SnippetEllipsisText(searchext.SnippetEllipsis),
+		opt.Filters(filters),
+		opt.Page(cond.Page-1),
+		opt.HitsPerPage(cond.PageSize),
+	)
+}
+
+// buildQueryOptions enables the advanced syntax for the phrases and excluded words of the query.
+// Algolia can't restrict a part of the query to the title, so title terms are phrases of the query,
+// and a query with title terms searches the title only.
+func buildQueryOptions(words *searchquery.Query) []interface{} {
+	opts := []interface{}{
+		opt.AdvancedSyntax(true),
+		opt.AdvancedSyntaxFeatures("exactPhrase", "excludeWords"),
+	}
+	if len(words.TitleTerms) > 0 {
+		opts = append(opts, opt.RestrictSearchableAttributes("title"))
+	}
+	return opts
+}
+
+// buildWordsFilters returns the filters of the search operators, each one prefixed with " AND "
+func buildWordsFilters(words *searchquery.Query) (filters string) {
+	if len(words.UserID) > 0 {
+		filters += " AND userID:" + strconv.Quote(words.UserID)
+	}
+	if len(words.Type) > 0 {
+		filters += " AND type:" + words.Type
+	}
+	if words.Accepted {
+		filters += " AND hasAccepted:true"
+	}
+	filters += buildRangeFilters("score", words.Score)
+	filters += buildRangeFilters("created", words.Created)
+	return filters
+}
+
+func buildRangeFilters(attribute string, r searchquery.Range) (filters string) {
+	if r.Min != nil {
+		filters += " AND " + attribute + ">=" + strconv.FormatInt(*r.Min, 10)
+	}
+	if r.Max != nil {
+		filters += " AND " + attribute + "<=" + strconv.FormatInt(*r.Max, 10)
+	}
+	return filters
+}
+
+func (s *SearchAlgolia) warpResult(qres search.QueryRes) (res []searchext.Result) {
+	for _, hit := range qres.Hits {
+		objectID, ok := hit["objectID"].(string)
+		if !ok {
+			continue
+		}
+		objectType, _ := hit["type"].(string)
+		res = append(res, searchext.Result{
+			ID:   objectID,
+			Type: objectType,
+			Highlight: searchext.Highlight{
+				Title:   hitFragment(hit, "_highlightResult", "title"),
+				Snippet: hitFragment(hit, "_snippetResult", "content"),
+			},
+		})
+	}
+	return res
+}
+
+func (s *SearchAlgolia) buildFilters(scope searchext.SearchScope, cond *plugin.SearchBasicCond) string {
+	switch scope {
+	case searchext.ScopeQuestions:
+		return s.buildQuestionsFilters(cond)
+	case searchext.ScopeAnswers:
+		return s.buildAnswersFilters(cond)
+	default:
+		return s.buildContentsFilters(cond)
+	}
+}
+
+func (s *SearchAlgolia) buildContentsFilters(cond *plugin.SearchBasicCond) string {
 	var (
 		filters      = "status<10"
 		tagFilters   []string
@@ -105,30 +309,10 @@ func (s *SearchAlgolia) SearchContents(ctx context.Context, cond *plugin.SearchB
 		votesFilter = "votes>=" + strconv.Itoa(cond.VoteAmount)
 		filters += " AND " + votesFilter
 	}
-
-	var (
-		query = strings.TrimSpace(strings.Join(cond.Words, " "))
-		opts  = []interface{}{
-			opt.AttributesToRetrieve("objectID", "type"),
-			opt.Filters(filters),
-			opt.Page(cond.Page - 1),
-			opt.HitsPerPage(cond.PageSize),
-		}
-		qres search.QueryRes
-	)
-
-	qres, err = s.getIndex(string(cond.Order)).Search(query, opts...)
-	for _, hit := range qres.Hits {
-		res = append(res, plugin.SearchResult{
-			ID:   hit["objectID"].(string),
-			Type: hit["type"].(string),
-		})
-	}
-	total = int64(qres.NbHits)
-	return res, total, err
+	return filters
 }
 
-func (s *SearchAlgolia) SearchQuestions(ctx context.Context, cond *plugin.SearchBasicCond) (res []plugin.SearchResult, total int64, err error) {
+func (s *SearchAlgolia) buildQuestionsFilters(cond *plugin.SearchBasicCond) string {
 	var (
 		filters       = "status<10 AND type:question"
 		tagFilters    []string
@@ -168,31 +352,10 @@ func (s *SearchAlgolia) SearchQuestions(ctx context.Context, cond *plugin.Search
 		answersFilter = "answers>=" + strconv.Itoa(cond.AnswerAmount)
 		filters += " AND " + answersFilter
 	}
-
-	var (
-		query = strings.TrimSpace(strings.Join(cond.Words, " "))
-		opts  = []interface{}{
-			opt.AttributesToRetrieve("objectID", "type"),
-			opt.Filters(filters),
-			opt.Page(cond.Page - 1),
-			opt.HitsPerPage(cond.PageSize),
-		}
-		qres search.QueryRes
-	)
-
-	qres, err = s.getIndex(string(cond.Order)).Search(query, opts...)
-	for _, hit := range qres.Hits {
-		res = append(res, plugin.SearchResult{
-			ID:   hit["objectID"].(string),
-			Type: hit["type"].(string),
-		})
-	}
-
-	total = int64(qres.NbHits)
-	return res, total, err
+	return filters
 }
 
-func (s *SearchAlgolia) SearchAnswers(ctx context.Context, cond *plugin.SearchBasicCond) (res []plugin.SearchResult, total int64, err error) {
+func (s *SearchAlgolia) buildAnswersFilters(cond *plugin.SearchBasicCond) string {
 	var (
 		filters          = "status<10 AND type:answer"
 		tagFilters       []string
@@ -222,32 +385,41 @@ func (s *SearchAlgolia) SearchAnswers(ctx context.Context, cond *plugin.SearchBa
 		questionIDFilter = "questionID=" + cond.QuestionID
 		filters += questionIDFilter
 	}
+	return filters
+}
 
-	var (
-		query = strings.TrimSpace(strings.Join(cond.Words, " "))
-		opts  = []interface{}{
-			opt.AttributesToRetrieve("objectID", "type"),
-			opt.Filters(filters),
-			opt.Page(cond.Page - 1),
-			opt.HitsPerPage(cond.PageSize),
-		}
-		qres search.QueryRes
-	)
-
-	qres, err = s.getIndex(string(cond.Order)).Search(query, opts...)
-	for _, hit := range qres.Hits {
-		res = append(res, plugin.SearchResult{
-			ID:   hit["objectID"].(string),
-			Type: hit["type"].(string),
-		})
+func facetCounts(values map[string]int) []searchext.FacetCount {
+	counts := make(map[string]int64, len(values))
+	for value, count := range values {
+		counts[value] = int64(count)
 	}
-	total = int64(qres.NbHits)
-	return res, total, err
+	return searchext.NewFacetCounts(counts)
+}
+
+// hitFragment returns the highlighted value of attr in the _highlightResult or _snippetResult of hit
+func hitFragment(hit map[string]interface{}, key, attr string) string {
+	results, _ := hit[key].(map[string]interface{})
+	result, _ := results[attr].(map[string]interface{})
+	value, _ := result["value"].(string)
+	return searchext.BuildHighlight(value)
 }
 
-// UpdateContent updates the content to algolia server
-func (s *SearchAlgolia) UpdateContent(ctx context.Context, content *plugin.SearchContent) (err error) {
-	res, err := s.getIndex("").SaveObject(content)
+// UpdateContent indexes the content, through the outbox when it is enabled
+func (s *SearchAlgolia) UpdateContent(ctx context.Context, content *plugin.SearchContent) error {
+	return s.outbox.Update(ctx, content)
+}
+
+// DeleteContent deletes the content, through the outbox when it is enabled
+func (s *SearchAlgolia) DeleteContent(ctx context.Context, contentID string) error {
+	return s.outbox.Delete(ctx, contentID)
+}
+
+// updateContent updates the content to algolia server
+func (s *SearchAlgolia) updateContent(ctx context.Context, content *plugin.SearchContent) (err error) {
+	if s.client == nil {
+		return fmt.Errorf("algolia client not init")
+	}
+	res, err := s.getIndex("").SaveObject(newSearchObjects([]*plugin.SearchContent{content})[0])
 	if err != nil {
 		return
 	}
@@ -255,8 +427,11 @@ func (s *SearchAlgolia) UpdateContent(ctx context.Context, content *plugin.Searc
 	return
 }
 
-// DeleteContent deletes the content
-func (s *SearchAlgolia) DeleteContent(ctx context.Context, contentID string) (err error) {
+// deleteContent deletes the content
+func (s *SearchAlgolia) deleteContent(ctx context.Context, contentID string) (err error) {
+	if s.client == nil {
+		return fmt.Errorf("algolia client not init")
+	}
 	res, err := s.getIndex("").DeleteObject(contentID)
 	if err != nil {
 		return err
@@ -37,7 +37,9 @@ var AlgoliaSearchServerConfig = []byte(`
     "status",
     "tags",
     "type",
-    "user_id"
+    "user_id",
+    "userID",
+    "hasAccepted"
   ],
   "attributesToSnippet": null,
   "attributesToHighlight": null,
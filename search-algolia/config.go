@@ -23,6 +23,8 @@ import (
 	_ "embed"
 	"encoding/json"
 	"github.com/apache/incubator-answer-plugins/search-algolia/i18n"
+	"github.com/apache/incubator-answer-plugins/util/searchstats"
+	"github.com/apache/incubator-answer-plugins/util/searchsync"
 
 	"github.com/apache/incubator-answer/plugin"
 )
@@ -39,6 +41,17 @@ type AlgoliaSearchConfig struct {
 	APIKey       string `json:"api_key"`
 	Index        string `json:"index"`
 	ShowLogo     bool   `json:"show_logo"`
+
+	AnalyticsEnabled   bool   `json:"analytics_enabled"`
+	AnalyticsHashQuery bool   `json:"analytics_hash_query"`
+	AnalyticsSink      string `json:"analytics_sink"`
+	AnalyticsPath      string `json:"analytics_path"`
+
+	ReconcileInterval string `json:"reconcile_interval"`
+
+	OutboxEnabled bool   `json:"outbox_enabled"`
+	OutboxStore   string `json:"outbox_store"`
+	OutboxPath    string `json:"outbox_path"`
 }
 
 // ConfigFields return config fields
@@ -98,6 +111,100 @@ func (s *SearchAlgolia) ConfigFields() []plugin.ConfigField {
 			},
 			Value: s.Config.ShowLogo,
 		},
+		{
+			Name:        "analytics_enabled",
+			Type:        plugin.ConfigTypeSwitch,
+			Title:       plugin.MakeTranslator(i18n.ConfigAnalyticsEnabledTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigAnalyticsEnabledDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				Label: plugin.MakeTranslator(i18n.ConfigAnalyticsEnabledLabel),
+			},
+			Value: s.Config.AnalyticsEnabled,
+		},
+		{
+			Name:        "analytics_hash_query",
+			Type:        plugin.ConfigTypeSwitch,
+			Title:       plugin.MakeTranslator(i18n.ConfigAnalyticsHashQueryTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigAnalyticsHashQueryDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				Label: plugin.MakeTranslator(i18n.ConfigAnalyticsHashQueryLabel),
+			},
+			Value: s.Config.AnalyticsHashQuery,
+		},
+		{
+			Name:        "analytics_sink",
+			Type:        plugin.ConfigTypeSelect,
+			Title:       plugin.MakeTranslator(i18n.ConfigAnalyticsSinkTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigAnalyticsSinkDescription),
+			Value:       s.Config.AnalyticsSink,
+			Options: []plugin.ConfigFieldOption{
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigAnalyticsSinkOptionFile),
+					Value: searchstats.SinkFile,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigAnalyticsSinkOptionCache),
+					Value: searchstats.SinkCache,
+				},
+			},
+		},
+		{
+			Name:        "analytics_path",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigAnalyticsPathTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigAnalyticsPathDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.AnalyticsPath,
+		},
+		{
+			Name:        "reconcile_interval",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigReconcileIntervalTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigReconcileIntervalDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.ReconcileInterval,
+		},
+		{
+			Name:        "outbox_enabled",
+			Type:        plugin.ConfigTypeSwitch,
+			Title:       plugin.MakeTranslator(i18n.ConfigOutboxEnabledTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigOutboxEnabledDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				Label: plugin.MakeTranslator(i18n.ConfigOutboxEnabledLabel),
+			},
+			Value: s.Config.OutboxEnabled,
+		},
+		{
+			Name:        "outbox_store",
+			Type:        plugin.ConfigTypeSelect,
+			Title:       plugin.MakeTranslator(i18n.ConfigOutboxStoreTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigOutboxStoreDescription),
+			Value:       s.Config.OutboxStore,
+			Options: []plugin.ConfigFieldOption{
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigOutboxStoreOptionFile),
+					Value: searchsync.StoreFile,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigOutboxStoreOptionCache),
+					Value: searchsync.StoreCache,
+				},
+			},
+		},
+		{
+			Name:        "outbox_path",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigOutboxPathTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigOutboxPathDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.OutboxPath,
+		},
 	}
 }
 
@@ -106,7 +213,32 @@ func (s *SearchAlgolia) ConfigReceiver(config []byte) error {
 	c := &AlgoliaSearchConfig{}
 	_ = json.Unmarshal(config, c)
 	s.Config = c
-	err := s.connect()
+
+	err := s.analytics.Configure(searchstats.Config{
+		Enabled:   c.AnalyticsEnabled,
+		HashQuery: c.AnalyticsHashQuery,
+		Sink:      c.AnalyticsSink,
+		Path:      c.AnalyticsPath,
+		SlugName:  s.Info().SlugName,
+	})
+	if err != nil {
+		return err
+	}
+	interval, err := searchsync.ParseInterval(c.ReconcileInterval)
+	if err != nil {
+		return err
+	}
+	s.reconciler.Schedule(interval)
+	err = s.outbox.Configure(searchsync.OutboxConfig{
+		Enabled:  c.OutboxEnabled,
+		Store:    c.OutboxStore,
+		Path:     c.OutboxPath,
+		SlugName: s.Info().SlugName,
+	})
+	if err != nil {
+		return err
+	}
+	err = s.connect()
 	if err != nil {
 		return err
 	}
@@ -38,4 +38,35 @@ const (
 	ConfigShowLogoTitle       = "plugin.algolia-search.backend.config.show_logo.title"
 	ConfigShowLogoDescription = "plugin.algolia-search.backend.config.show_logo.description"
 	ConfigShowLogoLabel       = "plugin.algolia-search.backend.config.show_logo.label"
+
+	ConfigAnalyticsEnabledTitle       = "plugin.algolia-search.backend.config.analytics_enabled.title"
+	ConfigAnalyticsEnabledDescription = "plugin.algolia-search.backend.config.analytics_enabled.description"
+	ConfigAnalyticsEnabledLabel       = "plugin.algolia-search.backend.config.analytics_enabled.label"
+
+	ConfigAnalyticsHashQueryTitle       = "plugin.algolia-search.backend.config.analytics_hash_query.title"
+	ConfigAnalyticsHashQueryDescription = "plugin.algolia-search.backend.config.analytics_hash_query.description"
+	ConfigAnalyticsHashQueryLabel       = "plugin.algolia-search.backend.config.analytics_hash_query.label"
+
+	ConfigAnalyticsSinkTitle       = "plugin.algolia-search.backend.config.analytics_sink.title"
+	ConfigAnalyticsSinkDescription = "plugin.algolia-search.backend.config.analytics_sink.description"
+	ConfigAnalyticsSinkOptionFile  = "plugin.algolia-search.backend.config.analytics_sink.options.file"
+	ConfigAnalyticsSinkOptionCache = "plugin.algolia-search.backend.config.analytics_sink.options.cache"
+
+	ConfigAnalyticsPathTitle       = "plugin.algolia-search.backend.config.analytics_path.title"
+	ConfigAnalyticsPathDescription = "plugin.algolia-search.backend.config.analytics_path.description"
+
+	ConfigReconcileIntervalTitle       = "plugin.algolia-search.backend.config.reconcile_interval.title"
+	ConfigReconcileIntervalDescription = "plugin.algolia-search.backend.config.reconcile_interval.description"
+
+	ConfigOutboxEnabledTitle       = "plugin.algolia-search.backend.config.outbox_enabled.title"
+	ConfigOutboxEnabledDescription = "plugin.algolia-search.backend.config.outbox_enabled.description"
+	ConfigOutboxEnabledLabel       = "plugin.algolia-search.backend.config.outbox_enabled.label"
+
+	ConfigOutboxStoreTitle       = "plugin.algolia-search.backend.config.outbox_store.title"
+	ConfigOutboxStoreDescription = "plugin.algolia-search.backend.config.outbox_store.description"
+	ConfigOutboxStoreOptionFile  = "plugin.algolia-search.backend.config.outbox_store.options.file"
+	ConfigOutboxStoreOptionCache = "plugin.algolia-search.backend.config.outbox_store.options.cache"
+
+	ConfigOutboxPathTitle       = "plugin.algolia-search.backend.config.outbox_path.title"
+	ConfigOutboxPathDescription = "plugin.algolia-search.backend.config.outbox_path.description"
 )
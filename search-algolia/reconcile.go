@@ -0,0 +1,102 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package algolia
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/opt"
+	"github.com/apache/incubator-answer-plugins/util/searchsync"
+	"github.com/apache/incubator-answer/plugin"
+)
+
+const browseBatchSize = 1000
+
+// searchObject is the object of a content, with the fingerprint of the content
+type searchObject struct {
+	*plugin.SearchContent
+	Fingerprint string `json:"fingerprint"`
+}
+
+func newSearchObjects(contents []*plugin.SearchContent) []*searchObject {
+	objects := make([]*searchObject, 0, len(contents))
+	for _, content := range contents {
+		objects = append(objects, &searchObject{SearchContent: content, Fingerprint: searchsync.Fingerprint(content)})
+	}
+	return objects
+}
+
+// ScanDocs browses the ids and fingerprints of all objects in the main index
+func (s *SearchAlgolia) ScanDocs(ctx context.Context, fn func(docs []searchsync.Doc) error) (err error) {
+	if s.client == nil {
+		return fmt.Errorf("algolia client not init")
+	}
+	it, err := s.getIndex("").BrowseObjects(opt.AttributesToRetrieve("objectID", "fingerprint"), ctx)
+	if err != nil {
+		return err
+	}
+	docs := make([]searchsync.Doc, 0, browseBatchSize)
+	for {
+		var object struct {
+			ObjectID    string `json:"objectID"`
+			Fingerprint string `json:"fingerprint"`
+		}
+		_, err = it.Next(&object)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		docs = append(docs, searchsync.Doc{ID: object.ObjectID, Fingerprint: object.Fingerprint})
+		if len(docs) == browseBatchSize {
+			if err = fn(docs); err != nil {
+				return err
+			}
+			docs = docs[:0]
+		}
+	}
+	if len(docs) > 0 {
+		return fn(docs)
+	}
+	return nil
+}
+
+// UpsertContents saves the contents to the index
+func (s *SearchAlgolia) UpsertContents(ctx context.Context, contents []*plugin.SearchContent) error {
+	if s.client == nil {
+		return fmt.Errorf("algolia client not init")
+	}
+	return s.batchUpdateContent(ctx, contents)
+}
+
+// DeleteDocs deletes the objects from the index
+func (s *SearchAlgolia) DeleteDocs(ctx context.Context, ids []string) (err error) {
+	if s.client == nil {
+		return fmt.Errorf("algolia client not init")
+	}
+	res, err := s.getIndex("").DeleteObjects(ids, ctx)
+	if err != nil {
+		return err
+	}
+	return res.Wait()
+}
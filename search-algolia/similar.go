@@ -0,0 +1,56 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package algolia
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/opt"
+	"github.com/apache/incubator-answer-plugins/util/searchext"
+)
+
+// SearchSimilar finds questions similar to the draft using the similarQuery parameter
+func (s *SearchAlgolia) SearchSimilar(ctx context.Context, cond *searchext.SimilarCond) (res []searchext.SimilarResult, err error) {
+	if s.client == nil {
+		return nil, fmt.Errorf("algolia client not init")
+	}
+	opts := []interface{}{
+		opt.SimilarQuery(cond.LikeText()),
+		opt.AttributesToRetrieve("objectID", "title"),
+		opt.Filters("status<10 AND type:question"),
+		opt.Page(0),
+		opt.HitsPerPage(cond.Size),
+	}
+	qres, err := s.getIndex("").Search("", opts...)
+	if err != nil {
+		return nil, err
+	}
+	res = make([]searchext.SimilarResult, 0, len(qres.Hits))
+	for _, hit := range qres.Hits {
+		objectID, ok := hit["objectID"].(string)
+		if !ok {
+			continue
+		}
+		title, _ := hit["title"].(string)
+		res = append(res, searchext.SimilarResult{ID: objectID, Title: title})
+	}
+	return res, nil
+}
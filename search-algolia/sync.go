@@ -73,7 +73,7 @@ func (s *SearchAlgolia) sync() {
 }
 
 func (s *SearchAlgolia) batchUpdateContent(ctx context.Context, contents []*plugin.SearchContent) (err error) {
-	res, err := s.getIndex("").SaveObjects(contents)
+	res, err := s.getIndex("").SaveObjects(newSearchObjects(contents))
 	if err != nil {
 		return
 	}
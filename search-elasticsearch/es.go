@@ -27,9 +27,16 @@ import (
 	"github.com/apache/incubator-answer-plugins/util"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/apache/incubator-answer-plugins/search-elasticsearch/i18n"
+	"github.com/apache/incubator-answer-plugins/util/searchext"
+	"github.com/apache/incubator-answer-plugins/util/searchquery"
+	"github.com/apache/incubator-answer-plugins/util/searchsite"
+	"github.com/apache/incubator-answer-plugins/util/searchstats"
+	"github.com/apache/incubator-answer-plugins/util/searchsync"
 	"github.com/apache/incubator-answer/plugin"
+	"github.com/gin-gonic/gin"
 	"github.com/olivere/elastic/v7"
 	"github.com/segmentfault/pacman/log"
 )
@@ -38,24 +45,47 @@ import (
 var Info embed.FS
 
 type SearchEngine struct {
-	Config   *SearchEngineConfig
-	Operator *Operator
-	syncer   plugin.SearchSyncer
-	syncing  bool
-	lock     sync.Mutex
+	Config     *SearchEngineConfig
+	Operator   *Operator
+	syncer     plugin.SearchSyncer
+	syncing    bool
+	lock       sync.Mutex
+	analytics  *searchstats.Recorder
+	reconciler *searchsync.Reconciler
+	outbox     *searchsync.Outbox
+	site       *searchsite.Guard
+	indexName  string
 }
 
 type SearchEngineConfig struct {
 	Endpoints string `json:"endpoints"`
 	Username  string `json:"username"`
 	Password  string `json:"password"`
+	IndexName string `json:"index_name"`
+	SiteID    string `json:"site_id"`
+
+	AnalyticsEnabled   bool   `json:"analytics_enabled"`
+	AnalyticsHashQuery bool   `json:"analytics_hash_query"`
+	AnalyticsSink      string `json:"analytics_sink"`
+	AnalyticsPath      string `json:"analytics_path"`
+
+	ReconcileInterval string `json:"reconcile_interval"`
+
+	OutboxEnabled bool   `json:"outbox_enabled"`
+	OutboxStore   string `json:"outbox_store"`
+	OutboxPath    string `json:"outbox_path"`
 }
 
 func init() {
-	plugin.Register(&SearchEngine{
-		Config: &SearchEngineConfig{},
-		lock:   sync.Mutex{},
-	})
+	s := &SearchEngine{
+		Config:    &SearchEngineConfig{},
+		lock:      sync.Mutex{},
+		analytics: searchstats.NewRecorder(),
+	}
+	s.reconciler = searchsync.NewReconciler("es", s)
+	s.outbox = searchsync.NewOutbox("es", s.updateContent, s.deleteContent)
+	s.site = searchsite.NewGuard("es", s.siteOwners)
+	plugin.Register(s)
 }
 
 func (s *SearchEngine) Info() plugin.Info {
@@ -79,79 +109,129 @@ func (s *SearchEngine) Description() plugin.SearchDesc {
 func (s *SearchEngine) SearchContents(
 	ctx context.Context, cond *plugin.SearchBasicCond) (
 	res []plugin.SearchResult, total int64, err error) {
-	if s.Operator == nil {
-		return nil, 0, fmt.Errorf("es client not init")
-	}
-	resp, err := s.Operator.QueryDoc(ctx, s.getIndexName(),
-		s.buildQuery(cond), s.buildSort(cond), s.buildCols(), cond.Page, cond.PageSize)
-	if err != nil {
-		return nil, 0, fmt.Errorf("es query error: %w", err)
-	}
-	if resp == nil {
-		return nil, 0, nil
-	}
-	return s.warpResult(resp)
+	results, total, err := s.SearchHighlight(ctx, searchext.ScopeContents, cond)
+	return searchext.ToSearchResults(results), total, err
 }
 
 func (s *SearchEngine) SearchQuestions(
 	ctx context.Context, cond *plugin.SearchBasicCond) (
 	res []plugin.SearchResult, total int64, err error) {
-	if s.Operator == nil {
-		return nil, 0, fmt.Errorf("es client not init")
-	}
-	query := s.buildQuery(cond)
-	query.Must(elastic.NewTermQuery("type", "question"))
-	resp, err := s.Operator.QueryDoc(ctx, s.getIndexName(),
-		query, s.buildSort(cond), s.buildCols(), cond.Page, cond.PageSize)
-	if err != nil {
-		return nil, 0, fmt.Errorf("es query error: %w", err)
-	}
-	if resp == nil {
-		return nil, 0, nil
-	}
-	return s.warpResult(resp)
+	results, total, err := s.SearchHighlight(ctx, searchext.ScopeQuestions, cond)
+	return searchext.ToSearchResults(results), total, err
 }
 
 func (s *SearchEngine) SearchAnswers(
 	ctx context.Context, cond *plugin.SearchBasicCond) (
 	res []plugin.SearchResult, total int64, err error) {
-	if s.Operator == nil {
-		return nil, 0, fmt.Errorf("es client not init")
+	results, total, err := s.SearchHighlight(ctx, searchext.ScopeAnswers, cond)
+	return searchext.ToSearchResults(results), total, err
+}
+
+// SearchHighlight searches the contents in scope and returns the matching fragments of each result
+func (s *SearchEngine) SearchHighlight(
+	ctx context.Context, scope searchext.SearchScope, cond *plugin.SearchBasicCond) (
+	res []searchext.Result, total int64, err error) {
+	start := time.Now()
+	defer func() { s.analytics.Record(scope, cond, total, err, start) }()
+	resp, err := s.search(ctx, scope, cond, nil)
+	if err != nil || resp == nil {
+		return nil, 0, err
 	}
-	query := s.buildQuery(cond)
-	query.Must(elastic.NewTermQuery("type", "answer"))
-	resp, err := s.Operator.QueryDoc(ctx, s.getIndexName(),
-		query, s.buildSort(cond), s.buildCols(), cond.Page, cond.PageSize)
+	return s.warpResult(resp)
+}
+
+// SearchFacets works like SearchHighlight and also counts the facets of all matching contents
+func (s *SearchEngine) SearchFacets(
+	ctx context.Context, scope searchext.SearchScope, cond *plugin.SearchBasicCond) (
+	res []searchext.Result, total int64, facets *searchext.Facets, err error) {
+	start := time.Now()
+	defer func() { s.analytics.Record(scope, cond, total, err, start) }()
+	resp, err := s.search(ctx, scope, cond, s.buildAggregations(time.Now()))
+	if err != nil || resp == nil {
+		return nil, 0, nil, err
+	}
+	res, total, err = s.warpResult(resp)
 	if err != nil {
-		return nil, 0, fmt.Errorf("es query error: %w", err)
+		return nil, 0, nil, err
+	}
+	return res, total, s.warpFacets(resp.Aggregations), nil
+}
+
+func (s *SearchEngine) search(ctx context.Context, scope searchext.SearchScope, cond *plugin.SearchBasicCond,
+	aggs map[string]elastic.Aggregation) (resp *elastic.SearchResult, err error) {
+	if s.Operator == nil {
+		return nil, fmt.Errorf("es client not init")
 	}
-	if resp == nil {
-		return nil, 0, nil
+	query := s.buildQuery(ctx, cond)
+	switch scope {
+	case searchext.ScopeQuestions:
+		query.Must(elastic.NewTermQuery("type", "question"))
+	case searchext.ScopeAnswers:
+		query.Must(elastic.NewTermQuery("type", "answer"))
 	}
-	return s.warpResult(resp)
+	resp, err = s.Operator.QueryDoc(ctx, s.getIndexName(),
+		query, s.buildSort(cond), s.buildCols(), s.buildHighlight(), aggs, cond.Page, cond.PageSize)
+	if err != nil {
+		return nil, fmt.Errorf("es query error: %w", err)
+	}
+	return resp, nil
 }
 
+// UpdateContent indexes the content, through the outbox when it is enabled
 func (s *SearchEngine) UpdateContent(ctx context.Context, content *plugin.SearchContent) error {
+	return s.outbox.Update(ctx, content)
+}
+
+// DeleteContent deletes the content, through the outbox when it is enabled
+func (s *SearchEngine) DeleteContent(ctx context.Context, contentID string) error {
+	return s.outbox.Delete(ctx, contentID)
+}
+
+func (s *SearchEngine) updateContent(ctx context.Context, content *plugin.SearchContent) error {
 	if s.Operator == nil {
 		return fmt.Errorf("es client not init")
 	}
-	return s.Operator.SaveDoc(ctx, s.getIndexName(), content.ObjectID, CreateDocFromSearchContent(content.ObjectID, content))
+	if err := s.site.Err(); err != nil {
+		return err
+	}
+	return s.Operator.SaveDoc(ctx, s.getIndexName(), content.ObjectID,
+		CreateDocFromSearchContent(content.ObjectID, content, s.site.SiteID()))
 }
 
-func (s *SearchEngine) DeleteContent(ctx context.Context, contentID string) error {
+func (s *SearchEngine) deleteContent(ctx context.Context, contentID string) error {
 	if s.Operator == nil {
 		return fmt.Errorf("es client not init")
 	}
+	if err := s.site.Err(); err != nil {
+		return err
+	}
 	return s.Operator.DeleteDoc(ctx, s.getIndexName(), contentID)
 }
 
+func (s *SearchEngine) RegisterUnAuthRouter(r *gin.RouterGroup) {
+}
+
+func (s *SearchEngine) RegisterAuthUserRouter(r *gin.RouterGroup) {
+	r.POST("/es_search/similar", searchext.SimilarHandler(s.Info().SlugName, s))
+	r.GET("/es_search/search", searchext.HighlightHandler(s.Info().SlugName, s))
+	r.POST("/es_search/click", searchstats.ClickHandler(s.Info().SlugName, s.analytics))
+}
+
+func (s *SearchEngine) RegisterAuthAdminRouter(r *gin.RouterGroup) {
+	r.GET("/es_search/analytics", searchstats.ReportHandler(s.Info().SlugName, s.analytics))
+	r.GET("/es_search/reconcile", searchsync.StatusHandler(s.Info().SlugName, s.reconciler))
+	r.POST("/es_search/reconcile", searchsync.StartHandler(s.Info().SlugName, s.reconciler))
+	r.GET("/es_search/outbox", searchsync.OutboxHandler(s.Info().SlugName, s.outbox))
+}
+
 func (s *SearchEngine) RegisterSyncer(ctx context.Context, syncer plugin.SearchSyncer) {
 	s.syncer = syncer
+	s.reconciler.SetSyncer(syncer)
 	s.sync()
 }
 
-func (s *SearchEngine) warpResult(resp *elastic.SearchResult) ([]plugin.SearchResult, int64, error) {
-	res := make([]plugin.SearchResult, 0)
+func (s *SearchEngine) warpResult(resp *elastic.SearchResult) ([]searchext.Result, int64, error) {
+	res := make([]searchext.Result, 0)
 	for _, hit := range resp.Hits.Hits {
 		docByte, err := hit.Source.MarshalJSON()
 		if err != nil {
@@ -166,15 +246,55 @@ func (s *SearchEngine) warpResult(resp *elastic.SearchResult) ([]plugin.SearchRe
 			continue
 		}
 
-		res = append(res, plugin.SearchResult{
+		res = append(res, searchext.Result{
 			ID:   hit.Id,
 			Type: content.Type,
+			Highlight: searchext.Highlight{
+				Title:   firstFragment(hit.Highlight, "title"),
+				Snippet: firstFragment(hit.Highlight, "content"),
+			},
 		})
 	}
 	log.Debugf("search result: %d", len(res))
 	return res, resp.TotalHits(), nil
 }
 
+func (s *SearchEngine) warpFacets(aggs elastic.Aggregations) *searchext.Facets {
+	facets := &searchext.Facets{}
+	if terms, ok := aggs.Terms("tags"); ok {
+		counts := make(map[string]int64)
+		for _, bucket := range terms.Buckets {
+			counts[fmt.Sprint(bucket.Key)] = bucket.DocCount
+		}
+		facets.Tags = searchext.NewFacetCounts(counts)
+	}
+	if filters, ok := aggs.Filters("type"); ok {
+		counts := make(map[string]int64)
+		for name, bucket := range filters.NamedBuckets {
+			counts[name] = bucket.DocCount
+		}
+		facets.Type = searchext.NewFacetCounts(counts)
+	}
+	if terms, ok := aggs.Terms("has_accepted"); ok {
+		counts := make(map[string]int64)
+		for _, bucket := range terms.Buckets {
+			// boolean keys are returned as 1/0, with "true"/"false" in key_as_string
+			if bucket.KeyAsString != nil {
+				counts[*bucket.KeyAsString] = bucket.DocCount
+			}
+		}
+		facets.HasAccepted = searchext.NewFacetCounts(counts)
+	}
+	if ranges, ok := aggs.Range("created"); ok {
+		counts := make(map[string]int64)
+		for _, bucket := range ranges.Buckets {
+			counts[bucket.Key] = bucket.DocCount
+		}
+		facets.Created = searchext.NewCreatedCounts(counts)
+	}
+	return facets
+}
+
 func (s *SearchEngine) ConfigFields() []plugin.ConfigField {
 	return []plugin.ConfigField{
 		{
@@ -210,6 +330,120 @@ func (s *SearchEngine) ConfigFields() []plugin.ConfigField {
 			},
 			Value: s.Config.Password,
 		},
+		{
+			Name:        "index_name",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigIndexNameTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigIndexNameDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.IndexName,
+		},
+		{
+			Name:        "site_id",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigSiteIDTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigSiteIDDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.SiteID,
+		},
+		{
+			Name:        "analytics_enabled",
+			Type:        plugin.ConfigTypeSwitch,
+			Title:       plugin.MakeTranslator(i18n.ConfigAnalyticsEnabledTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigAnalyticsEnabledDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				Label: plugin.MakeTranslator(i18n.ConfigAnalyticsEnabledLabel),
+			},
+			Value: s.Config.AnalyticsEnabled,
+		},
+		{
+			Name:        "analytics_hash_query",
+			Type:        plugin.ConfigTypeSwitch,
+			Title:       plugin.MakeTranslator(i18n.ConfigAnalyticsHashQueryTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigAnalyticsHashQueryDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				Label: plugin.MakeTranslator(i18n.ConfigAnalyticsHashQueryLabel),
+			},
+			Value: s.Config.AnalyticsHashQuery,
+		},
+		{
+			Name:        "analytics_sink",
+			Type:        plugin.ConfigTypeSelect,
+			Title:       plugin.MakeTranslator(i18n.ConfigAnalyticsSinkTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigAnalyticsSinkDescription),
+			Value:       s.Config.AnalyticsSink,
+			Options: []plugin.ConfigFieldOption{
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigAnalyticsSinkOptionFile),
+					Value: searchstats.SinkFile,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigAnalyticsSinkOptionCache),
+					Value: searchstats.SinkCache,
+				},
+			},
+		},
+		{
+			Name:        "analytics_path",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigAnalyticsPathTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigAnalyticsPathDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.AnalyticsPath,
+		},
+		{
+			Name:        "reconcile_interval",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigReconcileIntervalTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigReconcileIntervalDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.ReconcileInterval,
+		},
+		{
+			Name:        "outbox_enabled",
+			Type:        plugin.ConfigTypeSwitch,
+			Title:       plugin.MakeTranslator(i18n.ConfigOutboxEnabledTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigOutboxEnabledDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				Label: plugin.MakeTranslator(i18n.ConfigOutboxEnabledLabel),
+			},
+			Value: s.Config.OutboxEnabled,
+		},
+		{
+			Name:        "outbox_store",
+			Type:        plugin.ConfigTypeSelect,
+			Title:       plugin.MakeTranslator(i18n.ConfigOutboxStoreTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigOutboxStoreDescription),
+			Value:       s.Config.OutboxStore,
+			Options: []plugin.ConfigFieldOption{
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigOutboxStoreOptionFile),
+					Value: searchsync.StoreFile,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigOutboxStoreOptionCache),
+					Value: searchsync.StoreCache,
+				},
+			},
+		},
+		{
+			Name:        "outbox_path",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigOutboxPathTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigOutboxPathDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.OutboxPath,
+		},
 	}
 }
 
@@ -218,6 +452,35 @@ func (s *SearchEngine) ConfigReceiver(config []byte) error {
 	_ = json.Unmarshal(config, conf)
 	s.Config = conf
 
+	siteID := searchsite.ID(conf.SiteID)
+	s.indexName = searchsite.IndexName(conf.IndexName, defaultIndexName, conf.SiteID)
+	s.site.Configure(siteID)
+
+	err := s.analytics.Configure(searchstats.Config{
+		Enabled:   conf.AnalyticsEnabled,
+		HashQuery: conf.AnalyticsHashQuery,
+		Sink:      conf.AnalyticsSink,
+		Path:      conf.AnalyticsPath,
+		SlugName:  s.Info().SlugName,
+	})
+	if err != nil {
+		return err
+	}
+	interval, err := searchsync.ParseInterval(conf.ReconcileInterval)
+	if err != nil {
+		return err
+	}
+	s.reconciler.Schedule(interval)
+	err = s.outbox.Configure(searchsync.OutboxConfig{
+		Enabled:  conf.OutboxEnabled,
+		Store:    conf.OutboxStore,
+		Path:     conf.OutboxPath,
+		SlugName: s.Info().SlugName,
+	})
+	if err != nil {
+		return err
+	}
+
 	log.Debugf("try to init es client: %s", conf.Endpoints)
 
 	operator, err := NewOperator(strings.Split(conf.Endpoints, ","), conf.Username, conf.Password)
@@ -229,11 +492,16 @@ func (s *SearchEngine) ConfigReceiver(config []byte) error {
 	if err != nil {
 		return fmt.Errorf("create index error: %w", err)
 	}
-	return nil
+	// indexes created by older versions have no fingerprint and site_id fields yet
+	err = s.Operator.PutMapping(context.Background(), s.getIndexName(), siteMappingJson)
+	if err != nil {
+		return fmt.Errorf("update index mapping error: %w", err)
+	}
+	return s.site.Check(context.Background())
 }
 
 func (s *SearchEngine) getIndexName() string {
-	return "answer_post"
+	return s.indexName
 }
 
 func (s *SearchEngine) buildSort(cond *plugin.SearchBasicCond) (sort *elastic.FieldSort) {
@@ -253,7 +521,37 @@ func (s *SearchEngine) buildCols() (cols *elastic.FetchSourceContext) {
 	return elastic.NewFetchSourceContext(true).Include("id", "type")
 }
 
-func (s *SearchEngine) buildQuery(cond *plugin.SearchBasicCond) (
+func (s *SearchEngine) buildHighlight() *elastic.Highlight {
+	return elastic.NewHighlight().
+		PreTags(searchext.HighlightPreMarker).
+		PostTags(searchext.HighlightPostMarker).
+		Fields(
+			// the whole title, even if nothing in it matched
+			elastic.NewHighlighterField("title").NumOfFragments(0).NoMatchSize(searchext.SnippetLength),
+			// the best matching fragment of the content, or its beginning if nothing in it matched
+			elastic.NewHighlighterField("content").NumOfFragments(1).
+				FragmentSize(searchext.SnippetLength).NoMatchSize(searchext.SnippetLength),
+		)
+}
+
+func (s *SearchEngine) buildAggregations(now time.Time) map[string]elastic.Aggregation {
+	// type is a text field, so it is counted with filters instead of terms
+	typeAgg := elastic.NewFiltersAggregation().
+		FilterWithName("question", elastic.NewTermQuery("type", "question")).
+		FilterWithName("answer", elastic.NewTermQuery("type", "answer"))
+	createdAgg := elastic.NewRangeAggregation().Field("created")
+	for _, bucket := range searchext.CreatedBuckets {
+		createdAgg = createdAgg.AddUnboundedToWithKey(bucket.Name, bucket.From(now))
+	}
+	return map[string]elastic.Aggregation{
+		"tags":         elastic.NewTermsAggregation().Field("tags.keyword").Size(searchext.MaxFacetValues),
+		"type":         typeAgg,
+		"has_accepted": elastic.NewTermsAggregation().Field("has_accepted"),
+		"created":      createdAgg,
+	}
+}
+
+func (s *SearchEngine) buildQuery(ctx context.Context, cond *plugin.SearchBasicCond) (
 	query *elastic.BoolQuery) {
 
 	log.Debugf("build query: %+v", cond)
@@ -289,13 +587,53 @@ func (s *SearchEngine) buildQuery(cond *plugin.SearchBasicCond) (
 	} else if cond.QuestionAccepted == plugin.AcceptedCondFalse {
 		q.Must(elastic.NewTermQuery("has_accepted", false))
 	}
-	if len(cond.Words) > 0 {
-		q.Must(elastic.NewMultiMatchQuery(strings.Join(cond.Words, " "), "title", "content"))
-	}
+	s.buildWordsQuery(q, searchquery.ParseSearch(ctx, cond.Words))
 	q.Must(elastic.NewTermQuery("status", plugin.SearchContentStatusAvailable))
 	return q
 }
 
+// buildWordsQuery adds the words and the search operators of the query to q
+func (s *SearchEngine) buildWordsQuery(q *elastic.BoolQuery, query *searchquery.Query) {
+	if len(query.Terms) > 0 {
+		q.Must(elastic.NewMultiMatchQuery(strings.Join(query.Terms, " "), "title", "content"))
+	}
+	for _, phrase := range query.Phrases {
+		q.Must(elastic.NewMultiMatchQuery(phrase, "title", "content").Type("phrase"))
+	}
+	for _, title := range query.TitleTerms {
+		q.Must(elastic.NewMatchPhraseQuery("title", title))
+	}
+	for _, term := range append(query.ExcludeTerms, query.ExcludePhrases...) {
+		q.MustNot(elastic.NewMultiMatchQuery(term, "title", "content").Type("phrase"))
+	}
+	if len(query.UserID) > 0 {
+		q.Must(elastic.NewTermQuery("user_id", query.UserID))
+	}
+	if len(query.Type) > 0 {
+		q.Must(elastic.NewTermQuery("type", query.Type))
+	}
+	if query.Accepted {
+		q.Must(elastic.NewTermQuery("has_accepted", true))
+	}
+	if !query.Score.IsZero() {
+		q.Must(buildRangeQuery("score", query.Score))
+	}
+	if !query.Created.IsZero() {
+		q.Must(buildRangeQuery("created", query.Created))
+	}
+}
+
+func buildRangeQuery(field string, r searchquery.Range) *elastic.RangeQuery {
+	q := elastic.NewRangeQuery(field)
+	if r.Min != nil {
+		q.Gte(*r.Min)
+	}
+	if r.Max != nil {
+		q.Lte(*r.Max)
+	}
+	return q
+}
+
 func convertToInterfaceSlice(slice []string) []interface{} {
 	s := make([]interface{}, len(slice))
 	for i, v := range slice {
@@ -303,3 +641,10 @@ func convertToInterfaceSlice(slice []string) []interface{} {
 	}
 	return s
 }
+
+func firstFragment(highlight elastic.SearchHitHighlight, field string) string {
+	if fragments := highlight[field]; len(fragments) > 0 {
+		return searchext.BuildHighlight(fragments[0])
+	}
+	return ""
+}
@@ -19,7 +19,12 @@
 
 package es
 
-import "github.com/apache/incubator-answer/plugin"
+import (
+	"github.com/apache/incubator-answer-plugins/util/searchsync"
+	"github.com/apache/incubator-answer/plugin"
+)
+
+const defaultIndexName = "answer_post"
 
 var indexJson = `
 {
@@ -74,6 +79,13 @@ var indexJson = `
             "has_accepted": {
                 "type": "boolean"
             },
+            "fingerprint": {
+                "type": "keyword",
+                "index": false
+            },
+            "site_id": {
+                "type": "keyword"
+            },
             "tags": {
                 "type": "text",
                 "fields": {
@@ -87,6 +99,20 @@ var indexJson = `
 }
 `
 
+var siteMappingJson = `
+{
+    "properties": {
+        "fingerprint": {
+            "type": "keyword",
+            "index": false
+        },
+        "site_id": {
+            "type": "keyword"
+        }
+    }
+}
+`
+
 type AnswerPostDoc struct {
 	Id          string   `json:"id"`
 	ObjectID    string   `json:"object_id"`
@@ -103,9 +129,11 @@ type AnswerPostDoc struct {
 	Score       int64    `json:"score"`
 	HasAccepted bool     `json:"has_accepted"`
 	Tags        []string `json:"tags"`
+	Fingerprint string   `json:"fingerprint"`
+	SiteID      string   `json:"site_id"`
 }
 
-func CreateDocFromSearchContent(id string, content *plugin.SearchContent) (doc *AnswerPostDoc) {
+func CreateDocFromSearchContent(id string, content *plugin.SearchContent, siteID string) (doc *AnswerPostDoc) {
 	doc = &AnswerPostDoc{}
 	doc.Id = id
 	doc.ObjectID = content.ObjectID
@@ -122,6 +150,8 @@ func CreateDocFromSearchContent(id string, content *plugin.SearchContent) (doc *
 	doc.Score = content.Score
 	doc.HasAccepted = content.HasAccepted
 	doc.Tags = content.Tags
+	doc.Fingerprint = searchsync.Fingerprint(content)
+	doc.SiteID = siteID
 	return
 }
 
@@ -23,6 +23,7 @@ import (
 	"context"
 	"github.com/olivere/elastic/v7"
 	"github.com/segmentfault/pacman/log"
+	"io"
 	"net/http"
 )
 
@@ -67,9 +68,19 @@ func (op *Operator) CreateIndex(ctx context.Context, indexName string, mapping s
 	return nil
 }
 
+func (op *Operator) PutMapping(ctx context.Context, indexName string, mapping string) (err error) {
+	log.Debugf("try to put mapping of index: %s", indexName)
+	_, err = op.C.PutMapping().Index(indexName).BodyString(mapping).Do(ctx)
+	if err != nil {
+		log.Errorf("put mapping of index %s failed: %s", indexName, err.Error())
+		return err
+	}
+	return nil
+}
+
 func (op *Operator) QueryDoc(ctx context.Context, indexName string,
 	query elastic.Query, sort *elastic.FieldSort, cols *elastic.FetchSourceContext,
-	page, size int) (
+	highlight *elastic.Highlight, aggs map[string]elastic.Aggregation, page, size int) (
 	result *elastic.SearchResult, err error) {
 	log.Debugf("try to query doc from index: %s, %d, %d", indexName, page, size)
 	from := (page - 1) * size
@@ -80,6 +91,12 @@ func (op *Operator) QueryDoc(ctx context.Context, indexName string,
 	if sort != nil {
 		service = service.SortBy(sort)
 	}
+	if highlight != nil {
+		service = service.Highlight(highlight)
+	}
+	for name, agg := range aggs {
+		service = service.Aggregation(name, agg)
+	}
 	result, err = service.Do(ctx)
 	if err != nil {
 		log.Errorf("query doc from index %s failed: %s", indexName, err.Error())
@@ -107,3 +124,26 @@ func (op *Operator) DeleteDoc(ctx context.Context, indexName string, id string)
 	}
 	return nil
 }
+
+// ScrollDocs calls fn with each page of the docs in the index
+func (op *Operator) ScrollDocs(ctx context.Context, indexName string, cols *elastic.FetchSourceContext, size int,
+	fn func(hits []*elastic.SearchHit) error) (err error) {
+	log.Debugf("try to scroll docs of index: %s", indexName)
+	scroll := op.C.Scroll(indexName).FetchSourceContext(cols).Size(size)
+	defer func() {
+		_ = scroll.Clear(context.Background())
+	}()
+	for {
+		result, err := scroll.Do(ctx)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			log.Errorf("scroll docs of index %s failed: %s", indexName, err.Error())
+			return err
+		}
+		if err = fn(result.Hits.Hits); err != nil {
+			return err
+		}
+	}
+}
@@ -78,7 +78,7 @@ func TestSearchEngine_QueryDoc(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	doc, err := operator.QueryDoc(context.Background(), testIndex, elastic.NewMatchAllQuery(), nil, nil, 0, 5)
+	doc, err := operator.QueryDoc(context.Background(), testIndex, elastic.NewMatchAllQuery(), nil, nil, nil, nil, 0, 5)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -31,4 +31,41 @@ const (
 
 	ConfigPasswordTitle       = "plugin.es_search.backend.config.password.title"
 	ConfigPasswordDescription = "plugin.es_search.backend.config.password.description"
+
+	ConfigAnalyticsEnabledTitle       = "plugin.es_search.backend.config.analytics_enabled.title"
+	ConfigAnalyticsEnabledDescription = "plugin.es_search.backend.config.analytics_enabled.description"
+	ConfigAnalyticsEnabledLabel       = "plugin.es_search.backend.config.analytics_enabled.label"
+
+	ConfigAnalyticsHashQueryTitle       = "plugin.es_search.backend.config.analytics_hash_query.title"
+	ConfigAnalyticsHashQueryDescription = "plugin.es_search.backend.config.analytics_hash_query.description"
+	ConfigAnalyticsHashQueryLabel       = "plugin.es_search.backend.config.analytics_hash_query.label"
+
+	ConfigAnalyticsSinkTitle       = "plugin.es_search.backend.config.analytics_sink.title"
+	ConfigAnalyticsSinkDescription = "plugin.es_search.backend.config.analytics_sink.description"
+	ConfigAnalyticsSinkOptionFile  = "plugin.es_search.backend.config.analytics_sink.options.file"
+	ConfigAnalyticsSinkOptionCache = "plugin.es_search.backend.config.analytics_sink.options.cache"
+
+	ConfigAnalyticsPathTitle       = "plugin.es_search.backend.config.analytics_path.title"
+	ConfigAnalyticsPathDescription = "plugin.es_search.backend.config.analytics_path.description"
+
+	ConfigReconcileIntervalTitle       = "plugin.es_search.backend.config.reconcile_interval.title"
+	ConfigReconcileIntervalDescription = "plugin.es_search.backend.config.reconcile_interval.description"
+
+	ConfigOutboxEnabledTitle       = "plugin.es_search.backend.config.outbox_enabled.title"
+	ConfigOutboxEnabledDescription = "plugin.es_search.backend.config.outbox_enabled.description"
+	ConfigOutboxEnabledLabel       = "plugin.es_search.backend.config.outbox_enabled.label"
+
+	ConfigOutboxStoreTitle       = "plugin.es_search.backend.config.outbox_store.title"
+	ConfigOutboxStoreDescription = "plugin.es_search.backend.config.outbox_store.description"
+	ConfigOutboxStoreOptionFile  = "plugin.es_search.backend.config.outbox_store.options.file"
+	ConfigOutboxStoreOptionCache = "plugin.es_search.backend.config.outbox_store.options.cache"
+
+	ConfigOutboxPathTitle       = "plugin.es_search.backend.config.outbox_path.title"
+	ConfigOutboxPathDescription = "plugin.es_search.backend.config.outbox_path.description"
+
+	ConfigIndexNameTitle       = "plugin.es_search.backend.config.index_name.title"
+	ConfigIndexNameDescription = "plugin.es_search.backend.config.index_name.description"
+
+	ConfigSiteIDTitle       = "plugin.es_search.backend.config.site_id.title"
+	ConfigSiteIDDescription = "plugin.es_search.backend.config.site_id.description"
 )
@@ -0,0 +1,79 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package es
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/apache/incubator-answer-plugins/util/searchsync"
+	"github.com/apache/incubator-answer/plugin"
+	"github.com/olivere/elastic/v7"
+	"github.com/segmentfault/pacman/log"
+)
+
+const scrollSize = 1000
+
+// ScanDocs scrolls the ids and fingerprints of all docs in the index
+func (s *SearchEngine) ScanDocs(ctx context.Context, fn func(docs []searchsync.Doc) error) error {
+	if s.Operator == nil {
+		return fmt.Errorf("es client not init")
+	}
+	if err := s.site.Check(ctx); err != nil {
+		return err
+	}
+	cols := elastic.NewFetchSourceContext(true).Include("fingerprint")
+	return s.Operator.ScrollDocs(ctx, s.getIndexName(), cols, scrollSize, func(hits []*elastic.SearchHit) error {
+		docs := make([]searchsync.Doc, 0, len(hits))
+		for _, hit := range hits {
+			var doc AnswerPostDoc
+			if err := json.Unmarshal(hit.Source, &doc); err != nil {
+				log.Errorf("es unmarshal error: %v", err)
+			}
+			docs = append(docs, searchsync.Doc{ID: hit.Id, Fingerprint: doc.Fingerprint})
+		}
+		return fn(docs)
+	})
+}
+
+// UpsertContents saves the contents to the index
+func (s *SearchEngine) UpsertContents(ctx context.Context, contents []*plugin.SearchContent) error {
+	if s.Operator == nil {
+		return fmt.Errorf("es client not init")
+	}
+	return s.batchUpdateContent(ctx, contents)
+}
+
+// DeleteDocs deletes the docs from the index
+func (s *SearchEngine) DeleteDocs(ctx context.Context, ids []string) error {
+	if s.Operator == nil {
+		return fmt.Errorf("es client not init")
+	}
+	if err := s.site.Err(); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := s.Operator.DeleteDoc(ctx, s.getIndexName(), id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package es
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/apache/incubator-answer-plugins/util/searchext"
+	"github.com/apache/incubator-answer/plugin"
+	"github.com/olivere/elastic/v7"
+	"github.com/segmentfault/pacman/log"
+)
+
+// SearchSimilar finds questions similar to the draft using a more_like_this query
+func (s *SearchEngine) SearchSimilar(ctx context.Context, cond *searchext.SimilarCond) (
+	res []searchext.SimilarResult, err error) {
+	if s.Operator == nil {
+		return nil, fmt.Errorf("es client not init")
+	}
+	query := elastic.NewBoolQuery()
+	query.Must(elastic.NewMoreLikeThisQuery().
+		Field("title", "content").
+		LikeText(cond.LikeText()).
+		MinTermFreq(1).
+		MinDocFreq(1).
+		MaxQueryTerms(25))
+	query.Filter(elastic.NewTermQuery("type", "question"))
+	query.Filter(elastic.NewTermQuery("status", plugin.SearchContentStatusAvailable))
+
+	cols := elastic.NewFetchSourceContext(true).Include("id", "title")
+	resp, err := s.Operator.QueryDoc(ctx, s.getIndexName(), query, nil, cols, nil, nil, 1, cond.Size)
+	if err != nil {
+		return nil, fmt.Errorf("es query error: %w", err)
+	}
+
+	res = make([]searchext.SimilarResult, 0)
+	for _, hit := range resp.Hits.Hits {
+		var doc AnswerPostDoc
+		if err := json.Unmarshal(hit.Source, &doc); err != nil {
+			log.Errorf("es unmarshal error: %v", err)
+			continue
+		}
+		item := searchext.SimilarResult{ID: hit.Id, Title: doc.Title}
+		if hit.Score != nil {
+			item.Score = *hit.Score
+		}
+		res = append(res, item)
+	}
+	return res, nil
+}
@@ -0,0 +1,51 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package es
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/incubator-answer-plugins/util/searchsite"
+	"github.com/olivere/elastic/v7"
+)
+
+// siteOwners lists the site IDs of the docs in the index, with a terms aggregation
+func (s *SearchEngine) siteOwners(ctx context.Context) ([]string, error) {
+	if s.Operator == nil {
+		return nil, fmt.Errorf("es client not init")
+	}
+	aggs := map[string]elastic.Aggregation{
+		searchsite.Field: elastic.NewTermsAggregation().Field(searchsite.Field).Size(10),
+	}
+	resp, err := s.Operator.QueryDoc(ctx, s.getIndexName(), elastic.NewMatchAllQuery(), nil, nil, nil, aggs, 1, 0)
+	if err != nil {
+		return nil, err
+	}
+	terms, ok := resp.Aggregations.Terms(searchsite.Field)
+	if !ok {
+		return nil, nil
+	}
+	owners := make([]string, 0, len(terms.Buckets))
+	for _, bucket := range terms.Buckets {
+		owners = append(owners, fmt.Sprint(bucket.Key))
+	}
+	return owners, nil
+}
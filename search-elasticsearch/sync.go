@@ -40,6 +40,11 @@ func (s *SearchEngine) sync() {
 			return
 		}
 
+		if err := s.site.Check(context.TODO()); err != nil {
+			log.Error("es: sync skipped", err)
+			return
+		}
+
 		s.syncing = true
 		log.Info("es: start sync questions...")
 		page = 1
@@ -87,8 +92,12 @@ func (s *SearchEngine) sync() {
 }
 
 func (s *SearchEngine) batchUpdateContent(ctx context.Context, contents []*plugin.SearchContent) (err error) {
+	if err = s.site.Err(); err != nil {
+		return err
+	}
 	for _, content := range contents {
-		err = s.Operator.SaveDoc(ctx, s.getIndexName(), content.ObjectID, CreateDocFromSearchContent(content.ObjectID, content))
+		err = s.Operator.SaveDoc(ctx, s.getIndexName(), content.ObjectID,
+			CreateDocFromSearchContent(content.ObjectID, content, s.site.SiteID()))
 		if err != nil {
 			return
 		}
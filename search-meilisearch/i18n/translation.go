@@ -31,4 +31,38 @@ const (
 	ConfigAsyncDescription  = "plugin.meilisearch_search.backend.config.async.description"
 	ConfigApiKeyTitle       = "plugin.meilisearch_search.backend.config.api_key.title"
 	ConfigApiKeyDescription = "plugin.meilisearch_search.backend.config.api_key.description"
+
+	ConfigAnalyticsEnabledTitle       = "plugin.meilisearch_search.backend.config.analytics_enabled.title"
+	ConfigAnalyticsEnabledDescription = "plugin.meilisearch_search.backend.config.analytics_enabled.description"
+	ConfigAnalyticsEnabledLabel       = "plugin.meilisearch_search.backend.config.analytics_enabled.label"
+
+	ConfigAnalyticsHashQueryTitle       = "plugin.meilisearch_search.backend.config.analytics_hash_query.title"
+	ConfigAnalyticsHashQueryDescription = "plugin.meilisearch_search.backend.config.analytics_hash_query.description"
+	ConfigAnalyticsHashQueryLabel       = "plugin.meilisearch_search.backend.config.analytics_hash_query.label"
+
+	ConfigAnalyticsSinkTitle       = "plugin.meilisearch_search.backend.config.analytics_sink.title"
+	ConfigAnalyticsSinkDescription = "plugin.meilisearch_search.backend.config.analytics_sink.description"
+	ConfigAnalyticsSinkOptionFile  = "plugin.meilisearch_search.backend.config.analytics_sink.options.file"
+	ConfigAnalyticsSinkOptionCache = "plugin.meilisearch_search.backend.config.analytics_sink.options.cache"
+
+	ConfigAnalyticsPathTitle       = "plugin.meilisearch_search.backend.config.analytics_path.title"
+	ConfigAnalyticsPathDescription = "plugin.meilisearch_search.backend.config.analytics_path.description"
+
+	ConfigReconcileIntervalTitle       = "plugin.meilisearch_search.backend.config.reconcile_interval.title"
+	ConfigReconcileIntervalDescription = "plugin.meilisearch_search.backend.config.reconcile_interval.description"
+
+	ConfigOutboxEnabledTitle       = "plugin.meilisearch_search.backend.config.outbox_enabled.title"
+	ConfigOutboxEnabledDescription = "plugin.meilisearch_search.backend.config.outbox_enabled.description"
+	ConfigOutboxEnabledLabel       = "plugin.meilisearch_search.backend.config.outbox_enabled.label"
+
+	ConfigOutboxStoreTitle       = "plugin.meilisearch_search.backend.config.outbox_store.title"
+	ConfigOutboxStoreDescription = "plugin.meilisearch_search.backend.config.outbox_store.description"
+	ConfigOutboxStoreOptionFile  = "plugin.meilisearch_search.backend.config.outbox_store.options.file"
+	ConfigOutboxStoreOptionCache = "plugin.meilisearch_search.backend.config.outbox_store.options.cache"
+
+	ConfigOutboxPathTitle       = "plugin.meilisearch_search.backend.config.outbox_path.title"
+	ConfigOutboxPathDescription = "plugin.meilisearch_search.backend.config.outbox_path.description"
+
+	ConfigSiteIDTitle       = "plugin.meilisearch_search.backend.config.site_id.title"
+	ConfigSiteIDDescription = "plugin.meilisearch_search.backend.config.site_id.description"
 )
@@ -27,9 +27,16 @@ import (
 	"github.com/apache/incubator-answer-plugins/util"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/apache/incubator-answer-plugins/search-meilisearch/i18n"
+	"github.com/apache/incubator-answer-plugins/util/searchext"
+	"github.com/apache/incubator-answer-plugins/util/searchquery"
+	"github.com/apache/incubator-answer-plugins/util/searchsite"
+	"github.com/apache/incubator-answer-plugins/util/searchstats"
+	"github.com/apache/incubator-answer-plugins/util/searchsync"
 	"github.com/apache/incubator-answer/plugin"
+	"github.com/gin-gonic/gin"
 	"github.com/meilisearch/meilisearch-go"
 	"github.com/segmentfault/pacman/errors"
 	"github.com/segmentfault/pacman/log"
@@ -48,25 +55,46 @@ var (
 )
 
 type Search struct {
-	Config  *SearchConfig
-	Client  *meilisearch.Client
-	syncer  plugin.SearchSyncer
-	syncing bool
-	lock    sync.Mutex
+	Config     *SearchConfig
+	Client     *meilisearch.Client
+	syncer     plugin.SearchSyncer
+	syncing    bool
+	lock       sync.Mutex
+	analytics  *searchstats.Recorder
+	reconciler *searchsync.Reconciler
+	outbox     *searchsync.Outbox
+	site       *searchsite.Guard
 }
 
 type SearchConfig struct {
 	Host      string `json:"host"`
 	ApiKey    string `json:"api_key"`
 	IndexName string `json:"index_name"`
+	SiteID    string `json:"site_id"`
 	Async     bool   `json:"async"`
+
+	AnalyticsEnabled   bool   `json:"analytics_enabled"`
+	AnalyticsHashQuery bool   `json:"analytics_hash_query"`
+	AnalyticsSink      string `json:"analytics_sink"`
+	AnalyticsPath      string `json:"analytics_path"`
+
+	ReconcileInterval string `json:"reconcile_interval"`
+
+	OutboxEnabled bool   `json:"outbox_enabled"`
+	OutboxStore   string `json:"outbox_store"`
+	OutboxPath    string `json:"outbox_path"`
 }
 
 func init() {
-	plugin.Register(&Search{
-		Config: &SearchConfig{},
-		lock:   sync.Mutex{},
-	})
+	s := &Search{
+		Config:    &SearchConfig{},
+		lock:      sync.Mutex{},
+		analytics: searchstats.NewRecorder(),
+	}
+	s.reconciler = searchsync.NewReconciler("meilisearch", s)
+	s.outbox = searchsync.NewOutbox("meilisearch", s.updateContent, s.deleteContent)
+	s.site = searchsite.NewGuard("meilisearch", s.siteOwners)
+	plugin.Register(s)
 }
 
 func (s *Search) Info() plugin.Info {
@@ -87,33 +115,33 @@ func (s *Search) Description() plugin.SearchDesc {
 	return plugin.SearchDesc{Icon: "PHN2ZyB3aWR0aD0iMjAwIiBoZWlnaHQ9IjMwIiB2aWV3Qm94PSIwIDAgNDk1IDc0IiBmaWxsPSJub25lIiB4bWxucz0iaHR0cDovL3d3dy53My5vcmcvMjAwMC9zdmciPgo8cGF0aCBkPSJNMTgxLjg0IDQyLjUzNDdDMTgxLjg0IDM3LjYxMzYgMTg0LjE5OSAzNC43MTQ5IDE4OC43MTYgMzQuNzE0OUMxOTIuOTYzIDM0LjcxNDkgMTk0LjM3OCAzNy43NDg0IDE5NC4zNzggNDEuNjU4NFY2Mi42MjM3SDIwMy45NTFWNDAuNTc5OEMyMDMuOTUxIDMyLjM1NTQgMTk5LjYzNyAyNi40OTA2IDE5MS4xNDMgMjYuNDkwNkMxODYuMDg3IDI2LjQ5MDYgMTgyLjUxNCAyOC4wNDEgMTc5LjQxMyAzMS40NzkxQzE3Ny4zOSAyOC4zNzgxIDE3My45NTIgMjYuNDkwNiAxNjkuMTY2IDI2LjQ5MDZDMTY0LjExIDI2LjQ5MDYgMTYwLjYwNSAyOC41ODA0IDE1OC45ODcgMzEuNjEzOVYyNy4yOTk1SDE1MC4xNTZWNjIuNjIzN0gxNTkuNzI4VjQyLjMzMjVDMTU5LjcyOCAzNy42MTM2IDE2Mi4xNTUgMzQuNzE0OSAxNjYuNjA0IDM0LjcxNDlDMTcwLjg1MSAzNC43MTQ5IDE3Mi4yNjcgMzcuNzQ4NCAxNzIuMjY3IDQxLjY1ODRWNjIuNjIzN0gxODEuODRWNDIuNTM0N1oiIGZpbGw9IiMyMTAwNEIiLz4KPHBhdGggZD0iTTI0My4yNDIgNDcuNzI1NUMyNDMuMjQyIDQ3LjcyNTUgMjQzLjM3NyA0Ni40NDQ3IDI0My4zNzcgNDQuODk0MkMyNDMuMzc3IDM0LjQ0NTIgMjM2LjI5OSAyNi40OTA2IDIyNS44NSAyNi40OTA2QzIxNS40MDEgMjYuNDkwNiAyMDguMTIgMzQuNDQ1MiAyMDguMTIgNDQuODk0MkMyMDguMTIgNTUuNzQ3NiAyMTUuNDY4IDYzLjQzMjYgMjI1LjkxNyA2My40MzI2QzIzNC4wNzQgNjMuNDMyNiAyNDAuNTQ2IDU4LjUxMTUgMjQyLjYzNiA1MS4zNjU4SDIzMi45OTZDMjMxLjg1IDUzLjkyNzQgMjI5LjA4NiA1NS4yMDgzIDIyNi4xODcgNTUuMjA4M0MyMjEuNDAxIDU1LjIwODMgMjE4LjMgNTIuNTc5MiAyMTcuNjI2IDQ3LjcyNTVIMjQzLjI0MlpNMjI1Ljc4MyAzNC4xNzU2QzIzMC4yMzIgMzQuMTc1NiAyMzMuMTMxIDM2Ljg3MjEgMjMzLjgwNSA0MC44NDk0SDIxNy43NkMyMTguNTY5IDM2LjgwNDcgMjIxLjQwMSAzNC4xNzU2IDIyNS43ODMgMzQuMTc1NloiIGZpbGw9IiMyMTAwNEIiLz4KPHBhdGggZD0iTTI0NC43ODkgMzUuNTIzOEgyNDkuMDM2VjYyLjYyMzdIMjU4LjYwOFYyNy4yOTk1SDI0NC43ODlWMzUuNTIzOFpNMjUzLjgyMiAyMi43MTU1QzI1Ny4xOTMgMjIuNzE1NSAyNTkuNjE5IDIwLjM1NiAyNTkuNjE5IDE2Ljk4NTRDMjU5LjYxOSAxMy42MTQ4IDI1Ny4xOTMgMTEuMTg3OSAyNTMuODIyIDExLjE4NzlDMjUwLjQ1MSAxMS4xODc5IDI0OC4wMjQgMTMuNjE0OCAyNDguMDI0IDE2Ljk4NTRDMjQ4LjAyNCAyMC4zNTYgMjUwLjQ1MSAyMi43MTU1IDI1My44MjIgMjIuNzE1NVoiIGZpbGw9IiMyMTAwNEIiLz4KPHBhdGggZD0iTTI3OC40MyA1NC4zOTkzQzI3OC4xNiA1NC4zOTkzIDI3Ny43NTYgNTQuNDY2NyAyNzcuMTQ5IDU0LjQ2NjdDMjc0Ljk5MiA1NC40NjY3IDI3NC43MjIgNTMuNDU1NiAyNzQuNzIyIDUxLjk3MjVWMTIuMDY0M0gyNjUuMTVWNTIuNjQ2NkMyNjUuMTUgNTkuNjU3NSAyNjcuODQ2IDYyLjc1ODUgMjc1LjQ2NCA2Mi43NTg1QzI3Ni43NDUgNjIuNzU4NSAyNzcuOTU4IDYyLjYyMzcgMjc4LjQzIDYyLjU1NjJWNTQuMzk5M1oiIGZpbGw9IiMyMTAwNEIiLz4KPHBhdGggZD0iTTI3OS41MTkgMzUuNTIzOEgyODMuNzY2VjYyLjYyMzdIMjkzLjMzOVYyNy4yOTk1SDI3OS41MTlWMzUuNTIzOFpNMjg4LjU1MyAyMi43MTU1QzI5MS45MjMgMjIuNzE1NSAyOTQuMzUgMjAuMzU2IDI5NC4zNSAxNi45ODU0QzI5NC4zNSAxMy42MTQ4IDI5MS45MjMgMTEuMTg3OSAyODguNTUzIDExLjE4NzlDMjg1LjE4MiAxMS4xODc5IDI4Mi43NTUgMTMuNjE0OCAyODIuNzU1IDE2Ljk4NTRDMjgyLjc1NSAyMC4zNTYgMjg1LjE4MiAyMi43MTU1IDI4OC41NTMgMjIuNzE1NVoiIGZpbGw9IiMyMTAwNEIiLz4KPHBhdGggZD0iTTMxMi41NTcgNjIuOTkzOUMzMjEuODYgNjIuOTkzOSAzMjYuMjQyIDU4LjA3MjggMzI2LjI0MiA1Mi44ODJDMzI2LjI0MiAzOC40NTU3IDMwNS4wMDcgNDYuNDc3OCAzMDUuMDA3IDM2Ljk3MjZDMzA1LjAwNyAzMy44NzE3IDMwNy42MzYgMzEuMjQyNiAzMTIuOTYyIDMxLjI0MjZDMzE4LjQyMiAzMS4yNDI2IDMyMC45ODQgMzQuMjA4NyAzMjEuMzg4IDM3LjkxNjRIMzI2LjE3NUMzMjUuNzcgMzMuMjY1IDMyMi42MDIgMjcuMDYzIDMxMy4wOTcgMjcuMDYzQzMwNC45NCAyNy4wNjMgMzAwLjM1NiAzMS45MTY3IDMwMC4zNTYgMzcuMTc0OUMzMDAuMzU2IDUxLjI2NDEgMzIxLjU5MSA0My4xNzQ2IDMyMS41OTEgNTMuMDE2OEMzMjEuNTkxIDU2LjQ1NDggMzE4LjM1NSA1OC44MTQzIDMxMi41NTcgNTguODE0M0MzMDYuNjI1IDU4LjgxNDMgMzAzLjY1OSA1NS44NDgxIDMwMy4zMjIgNTEuNDY2M0gyOTguNDY4QzI5OC44NzIgNTcuNDY2IDMwMi42NDggNjIuOTkzOSAzMTIuNTU3IDYyLjk5MzlaIiBmaWxsPSIjMjEwMDRCIi8+CjxwYXRoIGQ9Ik0zNjQuMjU2IDQ2LjQxMDRDMzY0LjI1NiA0Ni40MTA0IDM2NC4zMjQgNDUuMzMxOCAzNjQuMzI0IDQ0LjU5MDNDMzY0LjMyNCAzNC44ODI5IDM1OC4wNTQgMjcuMDYzIDM0Ny44MDggMjcuMDYzQzMzNy40OTQgMjcuMDYzIDMzMC45NTUgMzUuNDg5NiAzMzAuOTU1IDQ0Ljk5NDdDMzMwLjk1NSA1NC42MzQ3IDMzNy4wMjIgNjIuOTkzOSAzNDcuODc1IDYyLjk5MzlDMzU2LjAzMiA2Mi45OTM5IDM2MS42OTUgNTguMDA1MyAzNjMuNzE3IDUxLjQ2NjNIMzU4LjcyOEMzNTcuMjQ1IDU1LjY0NTkgMzUzLjIwMSA1OC42Nzk1IDM0Ny45NDIgNTguNjc5NUMzNDAuNzI5IDU4LjY3OTUgMzM2LjIxMyA1My4zNTM5IDMzNS43NDEgNDYuNDEwNEgzNjQuMjU2Wk0zNDcuODA4IDMxLjM3NzRDMzU0LjU0OSAzMS4zNzc0IDM1OC45MzEgMzUuODk0IDM1OS41MzcgNDIuNTAwNUgzMzUuODc2QzMzNi42ODUgMzYuMTYzNyAzNDEuMTM0IDMxLjM3NzQgMzQ3LjgwOCAzMS4zNzc0WiIgZmlsbD0iIzIxMDA0QiIvPgo8cGF0aCBkPSJNMzk0LjAzNyA0NS44NzExVjQ5LjEwNjlDMzk0LjAzNyA1NC45NzE4IDM4OS43OSA1OS4wMTY1IDM4MS42MzMgNTkuMDE2NUMzNzYuNTc4IDU5LjAxNjUgMzczLjgxNCA1Ni45MjY3IDM3My44MTQgNTIuNDEwMUMzNzMuODE0IDUwLjExODEgMzc0Ljg5MiA0OC4zNjU0IDM3Ni41NzggNDcuNDIxNkMzNzguMzMgNDYuNDc3OCAzODAuNjkgNDUuODcxMSAzOTQuMDM3IDQ1Ljg3MTFaTTM4MS4wOTQgNjIuOTkzOUMzODcuMDI2IDYyLjk5MzkgMzkxLjgxMyA2MS4xMDYzIDM5NC4yNCA1Ny4xOTY0VjYyLjE4NDlIMzk4LjgyNFYzOS43MzY2QzM5OC44MjQgMzIuMTE4OSAzOTQuNDQyIDI3LjA2MyAzODQuNTMyIDI3LjA2M0MzNzUuMDI3IDI3LjA2MyAzNzAuODQ3IDMxLjg0OTMgMzY5Ljk3MSAzNy45ODM4SDM3NC42MjNDMzc1LjU2NiAzMy4xMzAxIDM3OS4yNzQgMzEuMTc1MiAzODQuMzMgMzEuMTc1MkMzOTAuODAyIDMxLjE3NTIgMzk0LjAzNyAzMy44NzE3IDM5NC4wMzcgMzkuNjY5MVY0MS44OTM4QzM4My4xODQgNDEuODkzOCAzNzguNjY3IDQyLjA5NiAzNzUuMjk3IDQzLjQ0NDJDMzcxLjM4NyA0NC45OTQ3IDM2OS4wOTUgNDguNDMyOCAzNjkuMDk1IDUyLjU0NDlDMzY5LjA5NSA1OC41NDQ2IDM3Mi45MzcgNjIuOTkzOSAzODEuMDk0IDYyLjk5MzlaIiBmaWxsPSIjMjEwMDRCIi8+CjxwYXRoIGQ9Ik00MjQuOTkxIDI3LjYwMjNDNDI0Ljk5MSAyNy42MDIzIDQyNC4xODIgMjcuNTM0OSA0MjMuODQ1IDI3LjUzNDlDNDE3LjUwOCAyNy41MzQ5IDQxNC4xMzggMzAuODM4MSA0MTIuODU3IDMzLjE5NzVWMjcuODcySDQwOC4yNzNWNjIuMTg0OUg0MTMuMDU5VjQyLjcwMjdDNDEzLjA1OSAzNS41NTcgNDE3LjQ0MSAzMi4wNTE1IDQyMy4zMDYgMzIuMDUxNUM0MjQuMTgyIDMyLjA1MTUgNDI0Ljk5MSAzMi4xMTg5IDQyNC45OTEgMzIuMTE4OVYyNy42MDIzWiIgZmlsbD0iIzIxMDA0QiIvPgo8cGF0aCBkPSJNNDI1LjgwOSA0NS4wNjIxQzQyNS44MDkgNTQuNDMyNSA0MzIuMjggNjIuOTkzOSA0NDIuNzI5IDYyLjk5MzlDNDUyLjAzMiA2Mi45OTM5IDQ1Ny40MjUgNTYuNzkxOSA0NTguNzczIDQ5Ljk4MzJINDUzLjkyQzQ1Mi41MDQgNTUuMzA4OCA0NDguNTk0IDU4LjY3OTUgNDQyLjcyOSA1OC42Nzk1QzQzNS41MTYgNTguNjc5NSA0MzAuNjYyIDUyLjk0OTQgNDMwLjY2MiA0NS4wNjIxQzQzMC42NjIgMzcuMTA3NSA0MzUuNTE2IDMxLjM3NzQgNDQyLjcyOSAzMS4zNzc0QzQ0OC41OTQgMzEuMzc3NCA0NTIuNTA0IDM0Ljc0OCA0NTMuOTIgNDAuMDczNkg0NTguNzczQzQ1Ny40MjUgMzMuMjY1IDQ1Mi4wMzIgMjcuMDYzIDQ0Mi43MjkgMjcuMDYzQzQzMi4yOCAyNy4wNjMgNDI1LjgwOSAzNS42MjQ0IDQyNS44MDkgNDUuMDYyMVoiIGZpbGw9IiMyMTAwNEIiLz4KPHBhdGggZD0iTTQ3MC4wNDEgMTEuNjI1NUg0NjUuMjU1VjYyLjE4NDlINDcwLjA0MVY0MS44OTM4QzQ3MC4wNDEgMzQuODgyOSA0NzQuNTU4IDMxLjI0MjYgNDgwLjM1NSAzMS4yNDI2QzQ4Ni40OSAzMS4yNDI2IDQ4OS4zODkgMzUuMDE3NyA0ODkuMzg5IDQxLjIxOTZWNjIuMTg0OUg0OTQuMTc1VjQwLjI3NTlDNDk0LjE3NSAzMi42NTgyIDQ4OS42NTggMjcuMDYzIDQ4MS4xNjQgMjcuMDYzQzQ3NC43NiAyNy4wNjMgNDcxLjI1NSAzMC41Njg1IDQ3MC4wNDEgMzIuNjU4MlYxMS42MjU1WiIgZmlsbD0iIzIxMDA0QiIvPgo8cGF0aCBkPSJNMC44MjQ5NTEgNzMuOTkzTDI0LjA2ODggMTQuNTIyNEMyNy4zNDQzIDYuMTQxNzkgMzUuNDIyMyAwLjYyNTk3NyA0NC40MjAyIDAuNjI1OTc3SDU4LjQzMzZMMzUuMTg5OCA2MC4wOTY2QzMxLjkxNDMgNjguNDc3MiAyMy44MzYzIDczLjk5MyAxNC44MzgzIDczLjk5M0gwLjgyNDk1MVoiIGZpbGw9InVybCgjcGFpbnQwX2xpbmVhcl8wXzE1KSIvPgo8cGF0aCBkPSJNMzQuOTI0NiA3My45OTMyTDU4LjE2ODQgMTQuNTIyNkM2MS40NDM5IDYuMTQxOTcgNjkuNTIxOSAwLjYyNjE1MiA3OC41MTk5IDAuNjI2MTUySDkyLjUzMzJMNjkuMjg5NCA2MC4wOTY4QzY2LjAxMzkgNjguNDc3NCA1Ny45MzU5IDczLjk5MzIgNDguOTM3OSA3My45OTMySDM0LjkyNDZaIiBmaWxsPSJ1cmwoI3BhaW50MV9saW5lYXJfMF8xNSkiLz4KPHBhdGggZD0iTTY5LjAyNjIgNzMuOTkzMkw5Mi4yNyAxNC41MjI2Qzk1LjU0NTUgNi4xNDE5NyAxMDMuNjIzIDAuNjI2MTUyIDExMi42MjEgMC42MjYxNTJIMTI2LjYzNUwxMDMuMzkxIDYwLjA5NjhDMTAwLjExNSA2OC40Nzc0IDkyLjAzNzUgNzMuOTkzMiA4My4wMzk1IDczLjk5MzJINjkuMDI2MloiIGZpbGw9InVybCgjcGFpbnQyX2xpbmVhcl8wXzE1KSIvPgo8ZGVmcz4KPGxpbmVhckdyYWRpZW50IGlkPSJwYWludDBfbGluZWFyXzBfMTUiIHgxPSIxMjYuNjM1IiB5MT0iLTQuOTc3OTkiIHgyPSIwLjgyNDk1MiIgeTI9IjY2LjA5NzgiIGdyYWRpZW50VW5pdHM9InVzZXJTcGFjZU9uVXNlIj4KPHN0b3Agc3RvcC1jb2xvcj0iI0ZGNUNBQSIvPgo8c3RvcCBvZmZzZXQ9IjEiIHN0b3AtY29sb3I9IiNGRjRFNjIiLz4KPC9saW5lYXJHcmFkaWVudD4KPGxpbmVhckdyYWRpZW50IGlkPSJwYWludDFfbGluZWFyXzBfMTUiIHgxPSIxMjYuNjM1IiB5MT0iLTQuOTc3OTkiIHgyPSIwLjgyNDk1MiIgeTI9IjY2LjA5NzgiIGdyYWRpZW50VW5pdHM9InVzZXJTcGFjZU9uVXNlIj4KPHN0b3Agc3RvcC1jb2xvcj0iI0ZGNUNBQSIvPgo8c3RvcCBvZmZzZXQ9IjEiIHN0b3AtY29sb3I9IiNGRjRFNjIiLz4KPC9saW5lYXJHcmFkaWVudD4KPGxpbmVhckdyYWRpZW50IGlkPSJwYWludDJfbGluZWFyXzBfMTUiIHgxPSIxMjYuNjM1IiB5MT0iLTQuOTc3OTkiIHgyPSIwLjgyNDk1MiIgeTI9IjY2LjA5NzgiIGdyYWRpZW50VW5pdHM9InVzZXJTcGFjZU9uVXNlIj4KPHN0b3Agc3RvcC1jb2xvcj0iI0ZGNUNBQSIvPgo8c3RvcCBvZmZzZXQ9IjEiIHN0b3AtY29sb3I9IiNGRjRFNjIiLz4KPC9saW5lYXJHcmFkaWVudD4KPC9kZWZzPgo8L3N2Zz4="}
 }
 
-func (s *Search) SearchContents(_ context.Context, cond *plugin.SearchBasicCond) (
+func (s *Search) SearchContents(ctx context.Context, cond *plugin.SearchBasicCond) (
 	res []plugin.SearchResult, total int64, err error) {
-	if s.Client == nil {
-		return nil, 0, configuredErr
-	}
-	query, searchRequest := s.buildQuery(cond)
-	searchRequest.Filter = s.buildFilter(cond)
+	results, total, err := s.SearchHighlight(ctx, searchext.ScopeContents, cond)
+	return searchext.ToSearchResults(results), total, err
+}
 
-	index := s.Client.Index(s.Config.IndexName)
-	searchResult, err := index.Search(query, searchRequest)
-	if err != nil {
-		log.Errorf("meilisearch error: %s", err.Error())
-		return nil, 0, err
-	}
-	return s.warpResult(searchResult)
+func (s *Search) SearchQuestions(ctx context.Context, cond *plugin.SearchBasicCond) (
+	res []plugin.SearchResult, total int64, err error) {
+	results, total, err := s.SearchHighlight(ctx, searchext.ScopeQuestions, cond)
+	return searchext.ToSearchResults(results), total, err
 }
 
-func (s *Search) SearchQuestions(_ context.Context, cond *plugin.SearchBasicCond) (
+func (s *Search) SearchAnswers(ctx context.Context, cond *plugin.SearchBasicCond) (
 	res []plugin.SearchResult, total int64, err error) {
+	results, total, err := s.SearchHighlight(ctx, searchext.ScopeAnswers, cond)
+	return searchext.ToSearchResults(results), total, err
+}
+
+// SearchHighlight searches the contents in scope and returns the matching fragments of each result
+func (s *Search) SearchHighlight(ctx context.Context, scope searchext.SearchScope, cond *plugin.SearchBasicCond) (
+	res []searchext.Result, total int64, err error) {
+	start := time.Now()
+	defer func() { s.analytics.Record(scope, cond, total, err, start) }()
 	if s.Client == nil {
 		return nil, 0, configuredErr
 	}
-	query, searchRequest := s.buildQuery(cond)
-
-	filter := s.buildFilter(cond)
-	filter = append(filter, "type = question")
-	searchRequest.Filter = filter
+	query, searchRequest := s.buildScopeQuery(ctx, scope, cond)
 
 	index := s.Client.Index(s.Config.IndexName)
 	searchResult, err := index.Search(query, searchRequest)
@@ -124,37 +152,83 @@ func (s *Search) SearchQuestions(_ context.Context, cond *plugin.SearchBasicCond
 	return s.warpResult(searchResult)
 }
 
-func (s *Search) SearchAnswers(_ context.Context, cond *plugin.SearchBasicCond) (
-	res []plugin.SearchResult, total int64, err error) {
+// SearchFacets works like SearchHighlight and also counts the facets of all matching contents.
+// The creation time buckets are counted by extra queries sent in the same multi-search request.
+func (s *Search) SearchFacets(ctx context.Context, scope searchext.SearchScope, cond *plugin.SearchBasicCond) (
+	res []searchext.Result, total int64, facets *searchext.Facets, err error) {
+	start := time.Now()
+	defer func() { s.analytics.Record(scope, cond, total, err, start) }()
 	if s.Client == nil {
-		return nil, 0, configuredErr
+		return nil, 0, nil, configuredErr
+	}
+	query, searchRequest := s.buildScopeQuery(ctx, scope, cond)
+	searchRequest.IndexUID = s.Config.IndexName
+	searchRequest.Query = query
+	searchRequest.Facets = []string{"tags", "type", "hasAccepted"}
+	queries := []meilisearch.SearchRequest{*searchRequest}
+
+	now := time.Now()
+	filter, _ := searchRequest.Filter.([]string)
+	for _, bucket := range searchext.CreatedBuckets {
+		queries = append(queries, meilisearch.SearchRequest{
+			IndexUID:             s.Config.IndexName,
+			Query:                query,
+			Filter:               append(filter[:len(filter):len(filter)], fmt.Sprintf("created >= %d", bucket.From(now))),
+			AttributesToRetrieve: []string{primaryKey},
+			Page:                 1,
+			HitsPerPage:          1,
+		})
 	}
 
-	query, searchRequest := s.buildQuery(cond)
-	filter := s.buildFilter(cond)
-	filter = append(filter, "type = answer")
-	searchRequest.Filter = filter
-
-	index := s.Client.Index(s.Config.IndexName)
-	searchResult, err := index.Search(query, searchRequest)
+	multiResult, err := s.Client.MultiSearch(&meilisearch.MultiSearchRequest{Queries: queries})
 	if err != nil {
 		log.Errorf("search error: %s", err.Error())
-		return nil, 0, err
+		return nil, 0, nil, err
 	}
-	return s.warpResult(searchResult)
+	if len(multiResult.Results) != len(queries) {
+		return nil, 0, nil, fmt.Errorf("meilisearch returned %d results for %d queries", len(multiResult.Results), len(queries))
+	}
+	searchResult := &multiResult.Results[0]
+	res, total, err = s.warpResult(searchResult)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	facets = s.warpFacets(searchResult.FacetDistribution)
+	createdCounts := make(map[string]int64)
+	for i, bucket := range searchext.CreatedBuckets {
+		createdCounts[bucket.Name] = multiResult.Results[i+1].TotalHits
+	}
+	facets.Created = searchext.NewCreatedCounts(createdCounts)
+	return res, total, facets, nil
+}
+
+// UpdateContent indexes the content, through the outbox when it is enabled
+func (s *Search) UpdateContent(ctx context.Context, content *plugin.SearchContent) error {
+	return s.outbox.Update(ctx, content)
+}
+
+// DeleteContent deletes the content, through the outbox when it is enabled
+func (s *Search) DeleteContent(ctx context.Context, contentID string) error {
+	return s.outbox.Delete(ctx, contentID)
 }
 
-func (s *Search) UpdateContent(_ context.Context, content *plugin.SearchContent) error {
+func (s *Search) updateContent(_ context.Context, content *plugin.SearchContent) error {
 	if s.Client == nil {
 		return configuredErr
 	}
 
+	if err := s.site.Err(); err != nil {
+		return err
+	}
+
 	index := s.Client.Index(s.Config.IndexName)
+	docs := newSearchDocs([]*plugin.SearchContent{content}, s.site.SiteID())
 	if s.Config.Async {
-		_, err := index.AddDocuments([]*plugin.SearchContent{content}, primaryKey)
+		_, err := index.AddDocuments(docs, primaryKey)
 		return err
 	} else {
-		resp, err := index.AddDocuments([]*plugin.SearchContent{content}, primaryKey)
+		resp, err := index.AddDocuments(docs, primaryKey)
 		if err != nil {
 			return err
 		}
@@ -162,11 +236,15 @@ func (s *Search) UpdateContent(_ context.Context, content *plugin.SearchContent)
 	}
 }
 
-func (s *Search) DeleteContent(_ context.Context, contentID string) error {
+func (s *Search) deleteContent(_ context.Context, contentID string) error {
 	if s.Client == nil {
 		return configuredErr
 	}
 
+	if err := s.site.Err(); err != nil {
+		return err
+	}
+
 	index := s.Client.Index(s.Config.IndexName)
 	if s.Config.Async {
 		_, err := index.DeleteDocument(contentID)
@@ -180,8 +258,25 @@ func (s *Search) DeleteContent(_ context.Context, contentID string) error {
 	}
 }
 
+func (s *Search) RegisterUnAuthRouter(r *gin.RouterGroup) {
+}
+
+func (s *Search) RegisterAuthUserRouter(r *gin.RouterGroup) {
+	r.POST("/meilisearch_search/similar", searchext.SimilarHandler(s.Info().SlugName, s))
+	r.GET("/meilisearch_search/search", searchext.HighlightHandler(s.Info().SlugName, s))
+	r.POST("/meilisearch_search/click", searchstats.ClickHandler(s.Info().SlugName, s.analytics))
+}
+
+func (s *Search) RegisterAuthAdminRouter(r *gin.RouterGroup) {
+	r.GET("/meilisearch_search/analytics", searchstats.ReportHandler(s.Info().SlugName, s.analytics))
+	r.GET("/meilisearch_search/reconcile", searchsync.StatusHandler(s.Info().SlugName, s.reconciler))
+	r.POST("/meilisearch_search/reconcile", searchsync.StartHandler(s.Info().SlugName, s.reconciler))
+	r.GET("/meilisearch_search/outbox", searchsync.OutboxHandler(s.Info().SlugName, s.outbox))
+}
+
 func (s *Search) RegisterSyncer(ctx context.Context, syncer plugin.SearchSyncer) {
 	s.syncer = syncer
+	s.reconciler.SetSyncer(syncer)
 	go s.sync(ctx)
 }
 
@@ -220,6 +315,16 @@ func (s *Search) ConfigFields() []plugin.ConfigField {
 			},
 			Value: s.Config.IndexName,
 		},
+		{
+			Name:        "site_id",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigSiteIDTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigSiteIDDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.SiteID,
+		},
 		{
 			Name:        "async",
 			Type:        plugin.ConfigTypeSwitch,
@@ -228,6 +333,100 @@ func (s *Search) ConfigFields() []plugin.ConfigField {
 			Required:    false,
 			Value:       s.Config.Async,
 		},
+		{
+			Name:        "analytics_enabled",
+			Type:        plugin.ConfigTypeSwitch,
+			Title:       plugin.MakeTranslator(i18n.ConfigAnalyticsEnabledTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigAnalyticsEnabledDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				Label: plugin.MakeTranslator(i18n.ConfigAnalyticsEnabledLabel),
+			},
+			Value: s.Config.AnalyticsEnabled,
+		},
+		{
+			Name:        "analytics_hash_query",
+			Type:        plugin.ConfigTypeSwitch,
+			Title:       plugin.MakeTranslator(i18n.ConfigAnalyticsHashQueryTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigAnalyticsHashQueryDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				Label: plugin.MakeTranslator(i18n.ConfigAnalyticsHashQueryLabel),
+			},
+			Value: s.Config.AnalyticsHashQuery,
+		},
+		{
+			Name:        "analytics_sink",
+			Type:        plugin.ConfigTypeSelect,
+			Title:       plugin.MakeTranslator(i18n.ConfigAnalyticsSinkTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigAnalyticsSinkDescription),
+			Value:       s.Config.AnalyticsSink,
+			Options: []plugin.ConfigFieldOption{
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigAnalyticsSinkOptionFile),
+					Value: searchstats.SinkFile,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigAnalyticsSinkOptionCache),
+					Value: searchstats.SinkCache,
+				},
+			},
+		},
+		{
+			Name:        "analytics_path",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigAnalyticsPathTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigAnalyticsPathDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.AnalyticsPath,
+		},
+		{
+			Name:        "reconcile_interval",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigReconcileIntervalTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigReconcileIntervalDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.ReconcileInterval,
+		},
+		{
+			Name:        "outbox_enabled",
+			Type:        plugin.ConfigTypeSwitch,
+			Title:       plugin.MakeTranslator(i18n.ConfigOutboxEnabledTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigOutboxEnabledDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				Label: plugin.MakeTranslator(i18n.ConfigOutboxEnabledLabel),
+			},
+			Value: s.Config.OutboxEnabled,
+		},
+		{
+			Name:        "outbox_store",
+			Type:        plugin.ConfigTypeSelect,
+			Title:       plugin.MakeTranslator(i18n.ConfigOutboxStoreTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigOutboxStoreDescription),
+			Value:       s.Config.OutboxStore,
+			Options: []plugin.ConfigFieldOption{
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigOutboxStoreOptionFile),
+					Value: searchsync.StoreFile,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigOutboxStoreOptionCache),
+					Value: searchsync.StoreCache,
+				},
+			},
+		},
+		{
+			Name:        "outbox_path",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigOutboxPathTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigOutboxPathDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.OutboxPath,
+		},
 	}
 }
 
@@ -235,11 +434,36 @@ func (s *Search) ConfigReceiver(config []byte) error {
 	conf := &SearchConfig{}
 	_ = json.Unmarshal(config, conf)
 
-	// if index name is empty, use default index name
-	if conf.IndexName == "" {
-		conf.IndexName = defaultIndexName
-	}
+	// if index name is empty, use default index name of the site
+	siteID := searchsite.ID(conf.SiteID)
+	conf.IndexName = searchsite.IndexName(conf.IndexName, defaultIndexName, conf.SiteID)
 	s.Config = conf
+	s.site.Configure(siteID)
+
+	err := s.analytics.Configure(searchstats.Config{
+		Enabled:   conf.AnalyticsEnabled,
+		HashQuery: conf.AnalyticsHashQuery,
+		Sink:      conf.AnalyticsSink,
+		Path:      conf.AnalyticsPath,
+		SlugName:  s.Info().SlugName,
+	})
+	if err != nil {
+		return err
+	}
+	interval, err := searchsync.ParseInterval(conf.ReconcileInterval)
+	if err != nil {
+		return err
+	}
+	s.reconciler.Schedule(interval)
+	err = s.outbox.Configure(searchsync.OutboxConfig{
+		Enabled:  conf.OutboxEnabled,
+		Store:    conf.OutboxStore,
+		Path:     conf.OutboxPath,
+		SlugName: s.Info().SlugName,
+	})
+	if err != nil {
+		return err
+	}
 
 	log.Debugf("try to init meilisearch client: %s", conf.Host)
 
@@ -251,12 +475,12 @@ func (s *Search) ConfigReceiver(config []byte) error {
 	s.tryToCreateIndex()
 
 	index := s.Client.Index(conf.IndexName)
-	_, err := index.UpdateSearchableAttributes(&[]string{"title", "content"})
+	_, err = index.UpdateSearchableAttributes(&[]string{"title", "content"})
 	if err != nil {
 		log.Errorf("update searchable attributes error: %s", err.Error())
 		return err
 	}
-	_, err = index.UpdateFilterableAttributes(&[]string{"title", "content", "tags", "status", "answers", "type", "questionID", "userID", "views", "created", "active", "score", "hasAccepted"})
+	filterableTask, err := index.UpdateFilterableAttributes(&[]string{"title", "content", "tags", "status", "answers", "type", "questionID", "userID", "views", "created", "active", "score", "hasAccepted", searchsite.Field})
 	if err != nil {
 		log.Errorf("update filterable attributes error: %s", err.Error())
 		return err
@@ -266,19 +490,24 @@ func (s *Search) ConfigReceiver(config []byte) error {
 		log.Errorf("update sortable attributes error: %s", err.Error())
 		return err
 	}
-	_, err = index.UpdateDisplayedAttributes(&[]string{"title", "content", "objectID", "type"})
+	_, err = index.UpdateDisplayedAttributes(&[]string{"title", "content", "objectID", "type", "fingerprint"})
 	if err != nil {
 		log.Errorf("update displayed attributes error: %s", err.Error())
 		return err
 	}
-	return nil
+	// the site facet of the guard needs the filterable attributes
+	if err = waitForTask(s.Client, filterableTask); err != nil {
+		log.Errorf("update filterable attributes error: %s", err.Error())
+		return err
+	}
+	return s.site.Check(context.Background())
 }
 
-func (s *Search) warpResult(resp *meilisearch.SearchResponse) ([]plugin.SearchResult, int64, error) {
-	res := make([]plugin.SearchResult, 0)
+func (s *Search) warpResult(resp *meilisearch.SearchResponse) ([]searchext.Result, int64, error) {
+	res := make([]searchext.Result, 0)
 	for _, hit := range resp.Hits {
 
-		var content plugin.SearchContent
+		var content searchHit
 		bytes, err := json.Marshal(hit)
 		if err != nil {
 			log.Errorf("marshal hit error: %s", err.Error())
@@ -290,16 +519,64 @@ func (s *Search) warpResult(resp *meilisearch.SearchResponse) ([]plugin.SearchRe
 			return nil, 0, err
 		}
 
-		res = append(res, plugin.SearchResult{
+		res = append(res, searchext.Result{
 			ID:   content.ObjectID,
 			Type: content.Type,
+			Highlight: searchext.Highlight{
+				Title:   searchext.BuildHighlight(content.Formatted.Title),
+				Snippet: searchext.BuildHighlight(content.Formatted.Content),
+			},
 		})
 	}
 	log.Debugf("search result: %d", len(res))
 	return res, resp.TotalHits, nil
 }
 
-func (s *Search) buildQuery(cond *plugin.SearchBasicCond) (string, *meilisearch.SearchRequest) {
+// searchHit is a document returned by meilisearch, with its highlighted and cropped attributes
+type searchHit struct {
+	plugin.SearchContent
+	Formatted struct {
+		Title   string `json:"title"`
+		Content string `json:"content"`
+	} `json:"_formatted"`
+}
+
+func (s *Search) warpFacets(distribution interface{}) *searchext.Facets {
+	// facetDistribution is {"tags": {"tag_id": count}, "type": {"question": count}, ...}
+	attrs, _ := distribution.(map[string]interface{})
+	counts := func(attr string) []searchext.FacetCount {
+		values, _ := attrs[attr].(map[string]interface{})
+		res := make(map[string]int64, len(values))
+		for value, count := range values {
+			if n, ok := count.(float64); ok {
+				res[value] = int64(n)
+			}
+		}
+		return searchext.NewFacetCounts(res)
+	}
+	return &searchext.Facets{
+		Tags:        counts("tags"),
+		Type:        counts("type"),
+		HasAccepted: counts("hasAccepted"),
+	}
+}
+
+func (s *Search) buildScopeQuery(ctx context.Context, scope searchext.SearchScope, cond *plugin.SearchBasicCond) (string, *meilisearch.SearchRequest) {
+	words := searchquery.ParseSearch(ctx, cond.Words)
+	query, searchRequest := s.buildQuery(cond, words)
+
+	filter := s.buildFilter(cond, words)
+	switch scope {
+	case searchext.ScopeQuestions:
+		filter = append(filter, "type = question")
+	case searchext.ScopeAnswers:
+		filter = append(filter, "type = answer")
+	}
+	searchRequest.Filter = filter
+	return query, searchRequest
+}
+
+func (s *Search) buildQuery(cond *plugin.SearchBasicCond, words *searchquery.Query) (string, *meilisearch.SearchRequest) {
 	searchRequest := meilisearch.SearchRequest{}
 
 	// page
@@ -320,14 +597,24 @@ func (s *Search) buildQuery(cond *plugin.SearchBasicCond) (string, *meilisearch.
 		searchRequest.Sort = []string{"score:desc"}
 	}
 
-	var query string
-	if cond.Words != nil && len(cond.Words) > 0 {
-		query = strings.Join(cond.Words, " ")
+	// highlight
+	searchRequest.AttributesToHighlight = []string{"title", "content"}
+	searchRequest.AttributesToCrop = []string{"content"}
+	searchRequest.CropLength = searchext.SnippetWords
+	searchRequest.CropMarker = searchext.SnippetEllipsis
+	searchRequest.HighlightPreTag = searchext.HighlightPreMarker
+	searchRequest.HighlightPostTag = searchext.HighlightPostMarker
+
+	// phrases and excluded words are in the query, excluded words need Meilisearch v1.10 or later.
+	// Meilisearch can't restrict a part of the query to the title, so a query with title terms
+	// searches the title only.
+	if len(words.TitleTerms) > 0 {
+		searchRequest.AttributesToSearchOn = []string{"title"}
 	}
-	return query, &searchRequest
+	return words.Text(), &searchRequest
 }
 
-func (s *Search) buildFilter(cond *plugin.SearchBasicCond) []string {
+func (s *Search) buildFilter(cond *plugin.SearchBasicCond, words *searchquery.Query) []string {
 	var filter []string
 	if cond.TagIDs != nil && len(cond.TagIDs) > 0 {
 		for _, tagGroup := range cond.TagIDs {
@@ -372,6 +659,29 @@ func (s *Search) buildFilter(cond *plugin.SearchBasicCond) []string {
 	if cond.AnswerAmount > 0 {
 		filter = append(filter, fmt.Sprintf("answerAmount >= %d", cond.AnswerAmount))
 	}
+
+	// search operators
+	if words.UserID != "" {
+		filter = append(filter, fmt.Sprintf("userID = %q", words.UserID))
+	}
+	if words.Type != "" {
+		filter = append(filter, fmt.Sprintf("type = %s", words.Type))
+	}
+	if words.Accepted {
+		filter = append(filter, "hasAccepted = true")
+	}
+	filter = append(filter, buildRangeFilter("score", words.Score)...)
+	filter = append(filter, buildRangeFilter("created", words.Created)...)
+	return filter
+}
+
+func buildRangeFilter(attribute string, r searchquery.Range) (filter []string) {
+	if r.Min != nil {
+		filter = append(filter, fmt.Sprintf("%s >= %d", attribute, *r.Min))
+	}
+	if r.Max != nil {
+		filter = append(filter, fmt.Sprintf("%s <= %d", attribute, *r.Max))
+	}
 	return filter
 }
 
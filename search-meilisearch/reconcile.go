@@ -0,0 +1,121 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package meilisearch
+
+import (
+	"context"
+
+	"github.com/apache/incubator-answer-plugins/util/searchsync"
+	"github.com/apache/incubator-answer/plugin"
+	"github.com/meilisearch/meilisearch-go"
+)
+
+// searchDoc is the document of a content, with the fingerprint of the content and the site that indexed it
+type searchDoc struct {
+	*plugin.SearchContent
+	Fingerprint string `json:"fingerprint"`
+	SiteID      string `json:"site_id"`
+}
+
+func newSearchDocs(contents []*plugin.SearchContent, siteID string) []*searchDoc {
+	docs := make([]*searchDoc, 0, len(contents))
+	for _, content := range contents {
+		docs = append(docs, &searchDoc{
+			SearchContent: content,
+			Fingerprint:   searchsync.Fingerprint(content),
+			SiteID:        siteID,
+		})
+	}
+	return docs
+}
+
+// ScanDocs pages through the ids and fingerprints of all documents in the index
+func (s *Search) ScanDocs(ctx context.Context, fn func(docs []searchsync.Doc) error) error {
+	if s.Client == nil {
+		return configuredErr
+	}
+	if err := s.site.Check(ctx); err != nil {
+		return err
+	}
+	index := s.Client.Index(s.Config.IndexName)
+	for offset := int64(0); ; offset += MaxGetPageSize {
+		var result meilisearch.DocumentsResult
+		err := index.GetDocuments(&meilisearch.DocumentsQuery{
+			Offset: offset,
+			Limit:  MaxGetPageSize,
+			Fields: []string{primaryKey, "fingerprint"},
+		}, &result)
+		if err != nil {
+			return err
+		}
+		if len(result.Results) == 0 {
+			return nil
+		}
+		docs := make([]searchsync.Doc, 0, len(result.Results))
+		for _, hit := range result.Results {
+			id, _ := hit[primaryKey].(string)
+			fingerprint, _ := hit["fingerprint"].(string)
+			docs = append(docs, searchsync.Doc{ID: id, Fingerprint: fingerprint})
+		}
+		if err = fn(docs); err != nil {
+			return err
+		}
+	}
+}
+
+// UpsertContents adds the contents to the index and waits for the tasks
+func (s *Search) UpsertContents(_ context.Context, contents []*plugin.SearchContent) error {
+	if s.Client == nil {
+		return configuredErr
+	}
+	if err := s.site.Err(); err != nil {
+		return err
+	}
+	docs := newSearchDocs(contents, s.site.SiteID())
+	for i := 0; i < len(docs); i += MaxPutPerSize {
+		end := i + MaxPutPerSize
+		if end > len(docs) {
+			end = len(docs)
+		}
+		resp, err := s.Client.Index(s.Config.IndexName).AddDocuments(docs[i:end], primaryKey)
+		if err != nil {
+			return err
+		}
+		if err = waitForTask(s.Client, resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteDocs deletes the documents from the index and waits for the task
+func (s *Search) DeleteDocs(_ context.Context, ids []string) error {
+	if s.Client == nil {
+		return configuredErr
+	}
+	if err := s.site.Err(); err != nil {
+		return err
+	}
+	resp, err := s.Client.Index(s.Config.IndexName).DeleteDocuments(ids)
+	if err != nil {
+		return err
+	}
+	return waitForTask(s.Client, resp)
+}
@@ -0,0 +1,67 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package meilisearch
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/incubator-answer-plugins/util/searchext"
+	"github.com/apache/incubator-answer/plugin"
+	"github.com/meilisearch/meilisearch-go"
+	"github.com/segmentfault/pacman/log"
+)
+
+// SearchSimilar finds questions similar to the draft.
+// The draft words are optional, so questions that match only part of them are still returned.
+func (s *Search) SearchSimilar(_ context.Context, cond *searchext.SimilarCond) (
+	res []searchext.SimilarResult, err error) {
+	if s.Client == nil {
+		return nil, configuredErr
+	}
+	searchRequest := &meilisearch.SearchRequest{
+		Limit:                int64(cond.Size),
+		AttributesToRetrieve: []string{primaryKey, "title"},
+		MatchingStrategy:     "last",
+		Filter: []string{
+			"type = question",
+			fmt.Sprintf("status = %d", plugin.SearchContentStatusAvailable),
+		},
+	}
+
+	index := s.Client.Index(s.Config.IndexName)
+	searchResult, err := index.Search(cond.LikeText(), searchRequest)
+	if err != nil {
+		log.Errorf("meilisearch error: %s", err.Error())
+		return nil, err
+	}
+
+	res = make([]searchext.SimilarResult, 0)
+	for _, hit := range searchResult.Hits {
+		doc, ok := hit.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		objectID, _ := doc[primaryKey].(string)
+		title, _ := doc["title"].(string)
+		res = append(res, searchext.SimilarResult{ID: objectID, Title: title})
+	}
+	return res, nil
+}
@@ -0,0 +1,49 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package meilisearch
+
+import (
+	"context"
+
+	"github.com/apache/incubator-answer-plugins/util/searchsite"
+	"github.com/meilisearch/meilisearch-go"
+)
+
+// siteOwners lists the site IDs of the documents in the index, with the facet distribution of the site field
+func (s *Search) siteOwners(_ context.Context) ([]string, error) {
+	if s.Client == nil {
+		return nil, configuredErr
+	}
+	resp, err := s.Client.Index(s.Config.IndexName).Search("", &meilisearch.SearchRequest{
+		Facets:               []string{searchsite.Field},
+		AttributesToRetrieve: []string{primaryKey},
+		Limit:                1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	attrs, _ := resp.FacetDistribution.(map[string]interface{})
+	values, _ := attrs[searchsite.Field].(map[string]interface{})
+	owners := make([]string, 0, len(values))
+	for value := range values {
+		owners = append(owners, value)
+	}
+	return owners, nil
+}
@@ -49,6 +49,11 @@ func (s *Search) sync(ctx context.Context) {
 		return
 	}
 
+	if err := s.site.Check(ctx); err != nil {
+		log.Errorf("sync skipped: %s", err)
+		return
+	}
+
 	s.syncing = true
 	for _, fn := range syncFns {
 		s.syncQuestionAndAnswerData(ctx, fn)
@@ -77,7 +82,7 @@ func (s *Search) syncQuestionAndAnswerData(ctx context.Context,
 				end = len(dataList)
 			}
 			resp, err := s.Client.Index(s.Config.IndexName).AddDocuments(
-				dataList[i:end], primaryKey)
+				newSearchDocs(dataList[i:end], s.site.SiteID()), primaryKey)
 			if err != nil {
 				log.Errorf("add documents failed %s", err)
 				return
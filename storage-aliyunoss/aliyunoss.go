@@ -20,20 +20,20 @@
 package aliyunoss
 
 import (
-	"crypto/rand"
 	"embed"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/apache/incubator-answer-plugins/util"
 	"path/filepath"
 	"strconv"
 	"strings"
-	"time"
 
-	"github.com/aliyun/aliyun-oss-go-sdk/oss"
 	"github.com/apache/incubator-answer-plugins/storage-aliyunoss/i18n"
+	"github.com/apache/incubator-answer-plugins/util/clamav"
+	"github.com/apache/incubator-answer-plugins/util/storageext"
+	"github.com/apache/incubator-answer-plugins/util/storagegc"
 	"github.com/apache/incubator-answer/plugin"
+	"github.com/gin-gonic/gin"
 )
 
 //go:embed  info.yaml
@@ -44,24 +44,65 @@ const (
 	defaultMaxFileSize int64 = 10 * 1024 * 1024
 )
 
+// uploadMessages are the upload errors shown to the users
+var uploadMessages = storageext.Messages{
+	FileNotFound:        i18n.ErrFileNotFound,
+	UnsupportedFileType: i18n.ErrUnsupportedFileType,
+	OverFileSizeLimit:   i18n.ErrOverFileSizeLimit,
+	UploadFileFailed:    i18n.ErrUploadFileFailed,
+	InfectedFile:        i18n.ErrInfectedFile,
+	ScanUnavailable:     i18n.ErrScanUnavailable,
+}
+
 type Storage struct {
-	Config *StorageConfig
+	Config    *StorageConfig
+	Client    *Client
+	keys      *storageext.ObjectKeys
+	scanner   *clamav.Guard
+	collector *storagegc.Collector
 }
 
 type StorageConfig struct {
-	Endpoint        string `json:"endpoint"`
-	BucketName      string `json:"bucket_name"`
-	ObjectKeyPrefix string `json:"object_key_prefix"`
-	AccessKeyID     string `json:"access_key_id"`
-	AccessKeySecret string `json:"access_key_secret"`
-	VisitUrlPrefix  string `json:"visit_url_prefix"`
-	MaxFileSize     string `json:"max_file_size"`
+	Endpoint             string `json:"endpoint"`
+	BucketName           string `json:"bucket_name"`
+	ObjectKeyPrefix      string `json:"object_key_prefix"`
+	ObjectKeyScheme      string `json:"object_key_scheme"`
+	ObjectKeyTemplate    string `json:"object_key_template"`
+	EndpointType         string `json:"endpoint_type"`
+	CredentialMode       string `json:"credential_mode"`
+	AccessKeyID          string `json:"access_key_id"`
+	AccessKeySecret      string `json:"access_key_secret"`
+	SecurityToken        string `json:"security_token"`
+	RoleArn              string `json:"role_arn"`
+	RoleSessionName      string `json:"role_session_name"`
+	ECSRAMRoleName       string `json:"ecs_ram_role_name"`
+	VisitUrlPrefix       string `json:"visit_url_prefix"`
+	AccessMode           string `json:"access_mode"`
+	SignedURLExpiry      string `json:"signed_url_expiry"`
+	MaxFileSize          string `json:"max_file_size"`
+	ImageProcessing      bool   `json:"image_processing"`
+	AvatarMaxDimension   string `json:"avatar_max_dimension"`
+	PostMaxDimension     string `json:"post_max_dimension"`
+	BrandingMaxDimension string `json:"branding_max_dimension"`
+	ThumbnailSize        string `json:"thumbnail_size"`
+	ConvertWebP          bool   `json:"convert_webp"`
+	MalwareScan          bool   `json:"malware_scan"`
+	ClamdAddress         string `json:"clamd_address"`
+	ScanFailurePolicy    string `json:"scan_failure_policy"`
+	GCMode               string `json:"gc_mode"`
+	GCInterval           string `json:"gc_interval"`
+	GCGracePeriod        string `json:"gc_grace_period"`
+	GCQuarantinePeriod   string `json:"gc_quarantine_period"`
 }
 
 func init() {
-	plugin.Register(&Storage{
+	keys, _ := storageext.NewObjectKeys("", storageext.KeySchemeRandom, "")
+	s := &Storage{
 		Config: &StorageConfig{},
-	})
+		keys:   keys,
+	}
+	s.collector = storagegc.NewCollector("aliyunoss_storage", s)
+	plugin.Register(s)
 }
 
 func (s *Storage) Info() plugin.Info {
@@ -79,83 +120,44 @@ func (s *Storage) Info() plugin.Info {
 }
 
 func (s *Storage) UploadFile(ctx *plugin.GinContext, source plugin.UploadSource) (resp plugin.UploadFileResponse) {
-	resp = plugin.UploadFileResponse{}
-	client, err := oss.New(s.Config.Endpoint, s.Config.AccessKeyID, s.Config.AccessKeySecret)
-	if err != nil {
-		resp.OriginalError = err
+	if s.Client == nil {
+		resp.OriginalError = fmt.Errorf("storage is not configured")
 		resp.DisplayErrorMsg = plugin.MakeTranslator(i18n.ErrMisStorageConfig)
 		return resp
 	}
+	return s.uploader().Upload(ctx, source)
+}
 
-	bucket, err := client.Bucket(s.Config.BucketName)
-	if err != nil {
-		resp.OriginalError = fmt.Errorf("create oss client failed: %v", err)
-		resp.DisplayErrorMsg = plugin.MakeTranslator(i18n.ErrMisStorageConfig)
-		return resp
-	}
-
-	file, err := ctx.FormFile("file")
-	if err != nil {
-		resp.OriginalError = fmt.Errorf("get bucket failed: %v", err)
-		resp.DisplayErrorMsg = plugin.MakeTranslator(i18n.ErrFileNotFound)
-		return resp
-	}
-
-	if !s.CheckFileType(file.Filename, source) {
-		resp.OriginalError = fmt.Errorf("file type not allowed")
-		resp.DisplayErrorMsg = plugin.MakeTranslator(i18n.ErrUnsupportedFileType)
-		return resp
-	}
-
-	if file.Size > s.maxFileSizeLimit() {
-		resp.OriginalError = fmt.Errorf("file size too large")
-		resp.DisplayErrorMsg = plugin.MakeTranslator(i18n.ErrOverFileSizeLimit)
-		return resp
-	}
-
-	open, err := file.Open()
-	if err != nil {
-		resp.OriginalError = fmt.Errorf("get file failed: %v", err)
-		resp.DisplayErrorMsg = plugin.MakeTranslator(i18n.ErrFileNotFound)
-		return resp
+// uploader returns the upload pipeline with the current config, only the bucket belongs to the plugin
+func (s *Storage) uploader() *storageext.Uploader {
+	return &storageext.Uploader{
+		Rules: &storageext.Rules{MaxSize: s.maxFileSizeLimit()},
+		Keys:  s.keys,
+		Images: storageext.ImageConfig{
+			Enabled:              s.Config.ImageProcessing,
+			WebP:                 s.Config.ConvertWebP,
+			AvatarMaxDimension:   s.Config.AvatarMaxDimension,
+			PostMaxDimension:     s.Config.PostMaxDimension,
+			BrandingMaxDimension: s.Config.BrandingMaxDimension,
+			ThumbnailSize:        s.Config.ThumbnailSize,
+		},
+		Scanner:  s.scanner,
+		Messages: uploadMessages,
+		Store:    s.Client,
+		URL:      s.fileURL,
 	}
-	defer open.Close()
+}
 
-	objectKey := s.createObjectKey(file.Filename, source)
-	request := &oss.PutObjectRequest{
-		ObjectKey: objectKey,
-		Reader:    open,
-	}
-	respBody, err := bucket.DoPutObject(request, nil)
-	if err != nil {
-		resp.OriginalError = fmt.Errorf("get file failed: %v", err)
-		resp.DisplayErrorMsg = plugin.MakeTranslator(i18n.ErrUploadFileFailed)
-		return resp
-	}
-	defer respBody.Close()
-	resp.FullURL = s.Config.VisitUrlPrefix + objectKey
-	return resp
+func (s *Storage) RegisterUnAuthRouter(r *gin.RouterGroup) {
+	r.GET("/aliyunoss_storage/file/*object_key", s.serveFile)
 }
 
-func (s *Storage) createObjectKey(originalFilename string, source plugin.UploadSource) string {
-	ext := strings.ToLower(filepath.Ext(originalFilename))
-	randomString := s.randomObjectKey()
-	switch source {
-	case plugin.UserAvatar:
-		return s.Config.ObjectKeyPrefix + "avatar/" + randomString + ext
-	case plugin.UserPost:
-		return s.Config.ObjectKeyPrefix + "post/" + randomString + ext
-	case plugin.AdminBranding:
-		return s.Config.ObjectKeyPrefix + "branding/" + randomString + ext
-	default:
-		return s.Config.ObjectKeyPrefix + "other/" + randomString + ext
-	}
+func (s *Storage) RegisterAuthUserRouter(r *gin.RouterGroup) {
 }
 
-func (s *Storage) randomObjectKey() string {
-	bytes := make([]byte, 4)
-	_, _ = rand.Read(bytes)
-	return fmt.Sprintf("%d", time.Now().UnixNano()) + hex.EncodeToString(bytes)
+func (s *Storage) RegisterAuthAdminRouter(r *gin.RouterGroup) {
+	r.GET("/aliyunoss_storage/gc", storagegc.StatusHandler(s.Info().SlugName, s.collector))
+	r.POST("/aliyunoss_storage/gc", storagegc.StartHandler(s.Info().SlugName, s.collector))
 }
 
 func (s *Storage) CheckFileType(originalFilename string, source plugin.UploadSource) bool {
@@ -190,6 +192,27 @@ func (s *Storage) ConfigFields() []plugin.ConfigField {
 			},
 			Value: s.Config.Endpoint,
 		},
+		{
+			Name:        "endpoint_type",
+			Type:        plugin.ConfigTypeSelect,
+			Title:       plugin.MakeTranslator(i18n.ConfigEndpointTypeTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigEndpointTypeDescription),
+			Value:       s.Config.EndpointType,
+			Options: []plugin.ConfigFieldOption{
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigEndpointTypeOptionPublic),
+					Value: EndpointPublic,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigEndpointTypeOptionInternal),
+					Value: EndpointInternal,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigEndpointTypeOptionAccelerate),
+					Value: EndpointAccelerate,
+				},
+			},
+		},
 		{
 			Name:        "bucket_name",
 			Type:        plugin.ConfigTypeInput,
@@ -212,6 +235,55 @@ func (s *Storage) ConfigFields() []plugin.ConfigField {
 			},
 			Value: s.Config.ObjectKeyPrefix,
 		},
+		{
+			Name:        "object_key_scheme",
+			Type:        plugin.ConfigTypeSelect,
+			Title:       plugin.MakeTranslator(i18n.ConfigObjectKeySchemeTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigObjectKeySchemeDescription),
+			Value:       s.Config.ObjectKeyScheme,
+			Options: []plugin.ConfigFieldOption{
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigObjectKeySchemeOptionRandom),
+					Value: storageext.KeySchemeRandom,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigObjectKeySchemeOptionContent),
+					Value: storageext.KeySchemeContent,
+				},
+			},
+		},
+		{
+			Name:        "object_key_template",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigObjectKeyTemplateTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigObjectKeyTemplateDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.ObjectKeyTemplate,
+		},
+		{
+			Name:        "credential_mode",
+			Type:        plugin.ConfigTypeSelect,
+			Title:       plugin.MakeTranslator(i18n.ConfigCredentialModeTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigCredentialModeDescription),
+			Value:       s.Config.CredentialMode,
+			Options: []plugin.ConfigFieldOption{
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigCredentialModeOptionStatic),
+					Value: CredentialStatic,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigCredentialModeOptionAssumeRole),
+					Value: CredentialAssumeRole,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigCredentialModeOptionECSRAMRole),
+					Value: CredentialECSRAMRole,
+				},
+			},
+		},
 		{
 			Name:        "access_key_id",
 			Type:        plugin.ConfigTypeInput,
@@ -234,6 +306,50 @@ func (s *Storage) ConfigFields() []plugin.ConfigField {
 			},
 			Value: s.Config.AccessKeySecret,
 		},
+		{
+			Name:        "security_token",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigSecurityTokenTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigSecurityTokenDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.SecurityToken,
+		},
+		{
+			Name:        "role_arn",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigRoleArnTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigRoleArnDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.RoleArn,
+		},
+		{
+			Name:        "role_session_name",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigRoleSessionNameTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigRoleSessionNameDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.RoleSessionName,
+		},
+		{
+			Name:        "ecs_ram_role_name",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigECSRAMRoleNameTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigECSRAMRoleNameDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.ECSRAMRoleName,
+		},
 		{
 			Name:        "visit_url_prefix",
 			Type:        plugin.ConfigTypeInput,
@@ -245,6 +361,34 @@ func (s *Storage) ConfigFields() []plugin.ConfigField {
 			},
 			Value: s.Config.VisitUrlPrefix,
 		},
+		{
+			Name:        "access_mode",
+			Type:        plugin.ConfigTypeSelect,
+			Title:       plugin.MakeTranslator(i18n.ConfigAccessModeTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigAccessModeDescription),
+			Value:       s.Config.AccessMode,
+			Options: []plugin.ConfigFieldOption{
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigAccessModeOptionPublic),
+					Value: accessModePublic,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigAccessModeOptionPrivate),
+					Value: accessModePrivate,
+				},
+			},
+		},
+		{
+			Name:        "signed_url_expiry",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigSignedURLExpiryTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigSignedURLExpiryDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeNumber,
+			},
+			Value: s.Config.SignedURLExpiry,
+		},
 		{
 			Name:        "max_file_size",
 			Type:        plugin.ConfigTypeInput,
@@ -256,12 +400,201 @@ func (s *Storage) ConfigFields() []plugin.ConfigField {
 			},
 			Value: s.Config.MaxFileSize,
 		},
+		{
+			Name:        "image_processing",
+			Type:        plugin.ConfigTypeSwitch,
+			Title:       plugin.MakeTranslator(i18n.ConfigImageProcessingTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigImageProcessingDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				Label: plugin.MakeTranslator(i18n.ConfigImageProcessingLabel),
+			},
+			Value: s.Config.ImageProcessing,
+		},
+		{
+			Name:        "avatar_max_dimension",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigAvatarMaxDimensionTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigAvatarMaxDimensionDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeNumber,
+			},
+			Value: s.Config.AvatarMaxDimension,
+		},
+		{
+			Name:        "post_max_dimension",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigPostMaxDimensionTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigPostMaxDimensionDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeNumber,
+			},
+			Value: s.Config.PostMaxDimension,
+		},
+		{
+			Name:        "branding_max_dimension",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigBrandingMaxDimensionTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigBrandingMaxDimensionDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeNumber,
+			},
+			Value: s.Config.BrandingMaxDimension,
+		},
+		{
+			Name:        "thumbnail_size",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigThumbnailSizeTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigThumbnailSizeDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeNumber,
+			},
+			Value: s.Config.ThumbnailSize,
+		},
+		{
+			Name:        "convert_webp",
+			Type:        plugin.ConfigTypeSwitch,
+			Title:       plugin.MakeTranslator(i18n.ConfigConvertWebPTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigConvertWebPDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				Label: plugin.MakeTranslator(i18n.ConfigConvertWebPLabel),
+			},
+			Value: s.Config.ConvertWebP,
+		},
+		{
+			Name:        "malware_scan",
+			Type:        plugin.ConfigTypeSwitch,
+			Title:       plugin.MakeTranslator(i18n.ConfigMalwareScanTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigMalwareScanDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				Label: plugin.MakeTranslator(i18n.ConfigMalwareScanLabel),
+			},
+			Value: s.Config.MalwareScan,
+		},
+		{
+			Name:        "clamd_address",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigClamdAddressTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigClamdAddressDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.ClamdAddress,
+		},
+		{
+			Name:        "scan_failure_policy",
+			Type:        plugin.ConfigTypeSelect,
+			Title:       plugin.MakeTranslator(i18n.ConfigScanFailurePolicyTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigScanFailurePolicyDescription),
+			Value:       s.Config.ScanFailurePolicy,
+			Options: []plugin.ConfigFieldOption{
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigScanFailurePolicyOptionClosed),
+					Value: clamav.PolicyFailClosed,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigScanFailurePolicyOptionOpen),
+					Value: clamav.PolicyFailOpen,
+				},
+			},
+		},
+		{
+			Name:        "gc_mode",
+			Type:        plugin.ConfigTypeSelect,
+			Title:       plugin.MakeTranslator(i18n.ConfigGCModeTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigGCModeDescription),
+			Value:       s.Config.GCMode,
+			Options: []plugin.ConfigFieldOption{
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigGCModeOptionOff),
+					Value: storagegc.ModeOff,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigGCModeOptionReport),
+					Value: storagegc.ModeReport,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigGCModeOptionQuarantine),
+					Value: storagegc.ModeQuarantine,
+				},
+			},
+		},
+		{
+			Name:        "gc_interval",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigGCIntervalTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigGCIntervalDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeNumber,
+			},
+			Value: s.Config.GCInterval,
+		},
+		{
+			Name:        "gc_grace_period",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigGCGracePeriodTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigGCGracePeriodDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeNumber,
+			},
+			Value: s.Config.GCGracePeriod,
+		},
+		{
+			Name:        "gc_quarantine_period",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigGCQuarantinePeriodTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigGCQuarantinePeriodDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeNumber,
+			},
+			Value: s.Config.GCQuarantinePeriod,
+		},
 	}
 }
 
 func (s *Storage) ConfigReceiver(config []byte) error {
 	c := &StorageConfig{}
 	_ = json.Unmarshal(config, c)
+	// nothing is applied until the whole config is valid, so a failed save keeps the previous one working
+	keys, err := storageext.NewObjectKeys(c.ObjectKeyPrefix, c.ObjectKeyScheme, c.ObjectKeyTemplate)
+	if err != nil {
+		return err
+	}
+	scanner, err := storageext.NewScanner(s.Info().SlugName, c.MalwareScan, c.ClamdAddress, c.ScanFailurePolicy)
+	if err != nil {
+		return err
+	}
+	if c.AccessMode == accessModePrivate {
+		if err = storageext.CheckLoginCache(); err != nil {
+			return err
+		}
+	}
+	client, err := NewOSSClient(ClientConfig{
+		Endpoint:        c.Endpoint,
+		EndpointType:    c.EndpointType,
+		Bucket:          c.BucketName,
+		CredentialMode:  c.CredentialMode,
+		AccessKeyID:     c.AccessKeyID,
+		AccessKeySecret: c.AccessKeySecret,
+		SecurityToken:   c.SecurityToken,
+		RoleArn:         c.RoleArn,
+		RoleSessionName: c.RoleSessionName,
+		ECSRAMRoleName:  c.ECSRAMRoleName,
+	})
+	if err != nil {
+		return err
+	}
 	s.Config = c
+	s.keys = keys
+	s.scanner = scanner
+	s.Client = client
+	s.configureCollector()
 	return nil
 }
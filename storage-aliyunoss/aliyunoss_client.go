@@ -0,0 +1,210 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package aliyunoss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+const (
+	EndpointPublic     = "public"
+	EndpointInternal   = "internal"
+	EndpointAccelerate = "accelerate"
+
+	accelerateHost = "oss-accelerate.aliyuncs.com"
+)
+
+// regionEndpoint matches the public and internal endpoints of a region, such as oss-cn-hangzhou.aliyuncs.com
+var regionEndpoint = regexp.MustCompile(`^oss-([a-z0-9-]+?)(-internal)?\.aliyuncs\.com$`)
+
+// ClientConfig is the connection config of the OSS client
+type ClientConfig struct {
+	Endpoint string
+	// EndpointType picks the internal endpoint of the region for the servers in it,
+	// or the transfer acceleration endpoint
+	EndpointType    string
+	Bucket          string
+	CredentialMode  string
+	AccessKeyID     string
+	AccessKeySecret string
+	// SecurityToken makes the static access key an STS one, which is not refreshed
+	SecurityToken   string
+	RoleArn         string
+	RoleSessionName string
+	ECSRAMRoleName  string
+}
+
+// Client is a long-lived client of the bucket, safe for concurrent use
+type Client struct {
+	bucket *oss.Bucket
+	// signBucket signs the URLs read by the browsers, on the public endpoint when the uploads use the internal one
+	signBucket *oss.Bucket
+}
+
+// NewOSSClient creates the client, with the credentials of the mode which are refreshed before they expire
+func NewOSSClient(conf ClientConfig) (*Client, error) {
+	if len(conf.Endpoint) == 0 || len(conf.Bucket) == 0 {
+		return nil, errors.New("endpoint and bucket name are required")
+	}
+	endpoint, signEndpoint, err := resolveEndpoint(conf.Endpoint, conf.EndpointType)
+	if err != nil {
+		return nil, err
+	}
+
+	var options []oss.ClientOption
+	switch conf.CredentialMode {
+	case CredentialAssumeRole:
+		provider, err := newAssumeRoleProvider(conf.AccessKeyID, conf.AccessKeySecret, conf.RoleArn, conf.RoleSessionName)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, oss.SetCredentialsProvider(provider))
+	case CredentialECSRAMRole:
+		options = append(options, oss.SetCredentialsProvider(newECSRAMRoleProvider(conf.ECSRAMRoleName)))
+	default:
+		if len(conf.SecurityToken) > 0 {
+			options = append(options, oss.SecurityToken(conf.SecurityToken))
+		}
+	}
+
+	client := &Client{}
+	client.bucket, err = newBucket(endpoint, conf, options)
+	if err != nil {
+		return nil, err
+	}
+	client.signBucket = client.bucket
+	if signEndpoint != endpoint {
+		client.signBucket, err = newBucket(signEndpoint, conf, options)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+func newBucket(endpoint string, conf ClientConfig, options []oss.ClientOption) (*oss.Bucket, error) {
+	client, err := oss.New(endpoint, conf.AccessKeyID, conf.AccessKeySecret, options...)
+	if err != nil {
+		return nil, fmt.Errorf("create oss client failed: %v", err)
+	}
+	bucket, err := client.Bucket(conf.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("get bucket failed: %v", err)
+	}
+	return bucket, nil
+}
+
+// resolveEndpoint returns the endpoint of the uploads and the one of the signed URLs.
+// The internal and accelerate types need the endpoint of a region, the public one is used as it is.
+func resolveEndpoint(endpoint, endpointType string) (upload, sign string, err error) {
+	if endpointType != EndpointInternal && endpointType != EndpointAccelerate {
+		return endpoint, endpoint, nil
+	}
+	scheme := ""
+	host := endpoint
+	if i := strings.Index(endpoint, "://"); i >= 0 {
+		scheme, host = endpoint[:i+3], endpoint[i+3:]
+	}
+	host = strings.TrimSuffix(host, "/")
+	match := regionEndpoint.FindStringSubmatch(host)
+	if match == nil {
+		return "", "", fmt.Errorf("endpoint %s is not the endpoint of a region like oss-cn-hangzhou.aliyuncs.com", endpoint)
+	}
+	public := scheme + "oss-" + match[1] + ".aliyuncs.com"
+	if endpointType == EndpointAccelerate {
+		return scheme + accelerateHost, scheme + accelerateHost, nil
+	}
+	return scheme + "oss-" + match[1] + "-internal.aliyuncs.com", public, nil
+}
+
+// PutObject uploads the object with its content type and disposition
+func (c *Client) PutObject(key, contentType, contentDisposition string, file io.Reader) error {
+	options := []oss.Option{oss.ContentType(contentType)}
+	if len(contentDisposition) > 0 {
+		options = append(options, oss.ContentDisposition(contentDisposition))
+	}
+	if err := c.bucket.PutObject(key, file, options...); err != nil {
+		return fmt.Errorf("put object failed: %v", err)
+	}
+	return nil
+}
+
+// ObjectExists reports whether the object exists
+func (c *Client) ObjectExists(key string) (bool, error) {
+	exists, err := c.bucket.IsObjectExist(key)
+	if err != nil {
+		return false, fmt.Errorf("head object failed: %v", err)
+	}
+	return exists, nil
+}
+
+// SignGetURL signs a GET of the object on the public endpoint
+func (c *Client) SignGetURL(key string, expiry time.Duration) (string, error) {
+	url, err := c.signBucket.SignURL(key, oss.HTTPGet, int64(expiry.Seconds()))
+	if err != nil {
+		return "", fmt.Errorf("sign url failed: %v", err)
+	}
+	return url, nil
+}
+
+// ListObjects calls fn with each page of the objects under the prefix
+func (c *Client) ListObjects(ctx context.Context, prefix string, fn func(objects []oss.ObjectProperties) error) error {
+	token := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		result, err := c.bucket.ListObjectsV2(oss.Prefix(prefix), oss.ContinuationToken(token), oss.MaxKeys(1000))
+		if err != nil {
+			return fmt.Errorf("list objects failed: %v", err)
+		}
+		if err = fn(result.Objects); err != nil {
+			return err
+		}
+		if !result.IsTruncated {
+			return nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// CopyObject copies the object in the bucket, with its metadata
+func (c *Client) CopyObject(from, to string) error {
+	if _, err := c.bucket.CopyObject(from, to); err != nil {
+		return fmt.Errorf("copy object failed: %v", err)
+	}
+	return nil
+}
+
+// DeleteObject deletes the object
+func (c *Client) DeleteObject(key string) error {
+	if err := c.bucket.DeleteObject(key); err != nil {
+		return fmt.Errorf("delete object failed: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package aliyunoss
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/incubator-answer-plugins/util/aliyunrpc"
+)
+
+func TestResolveEndpoint(t *testing.T) {
+	cases := []struct {
+		endpoint, endpointType, upload, sign string
+	}{
+		{"oss-cn-hangzhou.aliyuncs.com", EndpointPublic, "oss-cn-hangzhou.aliyuncs.com", "oss-cn-hangzhou.aliyuncs.com"},
+		{"https://oss-cn-hangzhou.aliyuncs.com", EndpointInternal, "https://oss-cn-hangzhou-internal.aliyuncs.com", "https://oss-cn-hangzhou.aliyuncs.com"},
+		{"oss-cn-hangzhou-internal.aliyuncs.com", EndpointInternal, "oss-cn-hangzhou-internal.aliyuncs.com", "oss-cn-hangzhou.aliyuncs.com"},
+		{"https://oss-us-west-1.aliyuncs.com/", EndpointAccelerate, "https://oss-accelerate.aliyuncs.com", "https://oss-accelerate.aliyuncs.com"},
+		{"files.example.com", "", "files.example.com", "files.example.com"},
+	}
+	for _, c := range cases {
+		upload, sign, err := resolveEndpoint(c.endpoint, c.endpointType)
+		if err != nil || upload != c.upload || sign != c.sign {
+			t.Errorf("resolveEndpoint(%s, %s) = %s, %s, %v", c.endpoint, c.endpointType, upload, sign, err)
+		}
+	}
+	if _, _, err := resolveEndpoint("files.example.com", EndpointInternal); err == nil {
+		t.Error("internal endpoint of a custom domain accepted")
+	}
+}
+
+// fakeSTS answers AssumeRole with credentials expiring after ttl, checking the signature
+func fakeSTS(t *testing.T, ttl time.Duration) (*httptest.Server, *int) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		params := make(map[string]string)
+		for name := range query {
+			if name != "Signature" {
+				params[name] = query.Get(name)
+			}
+		}
+		if query.Get("Action") != "AssumeRole" || query.Get("RoleArn") != "acs:ram::1:role/answer" ||
+			query.Get("Signature") != aliyunrpc.Signature("secret", http.MethodGet, aliyunrpc.CanonicalQuery(params)) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"Code":"SignatureDoesNotMatch"}`))
+			return
+		}
+		calls++
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"Credentials": map[string]interface{}{
+			"AccessKeyId":     "STS.key",
+			"AccessKeySecret": "sts-secret",
+			"SecurityToken":   fmt.Sprintf("token-%d", calls),
+			"Expiration":      time.Now().Add(ttl).UTC().Format(time.RFC3339),
+		}})
+	}))
+	t.Cleanup(server.Close)
+	stsEndpoint = server.URL + "/"
+	return server, &calls
+}
+
+func TestAssumeRoleRefresh(t *testing.T) {
+	_, calls := fakeSTS(t, time.Hour)
+	provider, err := newAssumeRoleProvider("key", "secret", "acs:ram::1:role/answer", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := provider.GetCredentials()
+	second := provider.GetCredentials()
+	if first.GetSecurityToken() != "token-1" || second.GetSecurityToken() != "token-1" || *calls != 1 {
+		t.Errorf("tokens %s %s after %d calls", first.GetSecurityToken(), second.GetSecurityToken(), *calls)
+	}
+
+	// credentials in the refresh window are replaced
+	provider.cache.Expiration = time.Now().Add(time.Minute)
+	if token := provider.GetCredentials().GetSecurityToken(); token != "token-2" {
+		t.Errorf("token after refresh = %s", token)
+	}
+
+	// a failed refresh keeps the cached credentials
+	bad, err := newAssumeRoleProvider("key", "wrong", "acs:ram::1:role/answer", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad.cache = &credentials{SecurityToken: "cached", Expiration: time.Now().Add(time.Minute)}
+	if token := bad.GetCredentials().GetSecurityToken(); token != "cached" {
+		t.Errorf("token after failed refresh = %s", token)
+	}
+	if _, err = newAssumeRoleProvider("key", "secret", "", ""); err == nil {
+		t.Error("empty role ARN accepted")
+	}
+}
+
+func TestECSRAMRole(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/latest/api/token":
+			_, _ = w.Write([]byte("metadata-token"))
+		case r.Header.Get("X-aliyun-ecs-metadata-token") != "metadata-token":
+			w.WriteHeader(http.StatusForbidden)
+		case r.URL.Path == "/latest/meta-data/ram/security-credentials/":
+			_, _ = w.Write([]byte("answer-role\n"))
+		case r.URL.Path == "/latest/meta-data/ram/security-credentials/answer-role":
+			_, _ = fmt.Fprintf(w, `{"Code":"Success","AccessKeyId":"STS.ecs","AccessKeySecret":"s","SecurityToken":"ecs-token","Expiration":"%s"}`,
+				time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	ecsMetadataURL = server.URL
+
+	creds := newECSRAMRoleProvider("").GetCredentials()
+	if creds.GetAccessKeyID() != "STS.ecs" || creds.GetSecurityToken() != "ecs-token" {
+		t.Errorf("credentials = %+v", creds)
+	}
+	if creds := newECSRAMRoleProvider("missing").GetCredentials(); creds.GetAccessKeyID() != "" {
+		t.Errorf("credentials of a missing role = %+v", creds)
+	}
+}
+
+// fakeOSS records the objects put into the bucket, path-style as the SDK addresses an IP endpoint
+type fakeOSS struct {
+	lock    sync.Mutex
+	headers map[string]http.Header
+}
+
+func (f *fakeOSS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	_, _ = io.Copy(io.Discard, r.Body)
+	f.lock.Lock()
+	f.headers[strings.TrimPrefix(r.URL.Path, "/answer/")] = r.Header
+	f.lock.Unlock()
+	w.Header().Set("ETag", `"d41d8cd98f00b204e9800998ecf8427e"`)
+}
+
+func TestClient(t *testing.T) {
+	fakeSTS(t, time.Hour)
+	oss := &fakeOSS{headers: make(map[string]http.Header)}
+	server := httptest.NewServer(oss)
+	defer server.Close()
+
+	client, err := NewOSSClient(ClientConfig{
+		Endpoint:        server.URL,
+		Bucket:          "answer",
+		CredentialMode:  CredentialAssumeRole,
+		AccessKeyID:     "key",
+		AccessKeySecret: "secret",
+		RoleArn:         "acs:ram::1:role/answer",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.PutObject("post/a.zip", "application/zip", `attachment; filename="a.zip"`, strings.NewReader("zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := oss.headers["post/a.zip"]
+	if header.Get("Content-Type") != "application/zip" || header.Get("Content-Disposition") != `attachment; filename="a.zip"` ||
+		header.Get("X-Oss-Security-Token") != "token-1" || !strings.HasPrefix(header.Get("Authorization"), "OSS STS.key:") {
+		t.Errorf("headers = %v", header)
+	}
+
+	signed, err := client.SignGetURL("post/a.zip", 15*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(signed)
+	if u.Query().Get("OSSAccessKeyId") != "STS.key" || u.Query().Get("security-token") != "token-1" || len(u.Query().Get("Signature")) == 0 {
+		t.Errorf("signed URL = %s", signed)
+	}
+}
@@ -0,0 +1,226 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package aliyunoss
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/apache/incubator-answer-plugins/util/aliyunrpc"
+	"github.com/segmentfault/pacman/log"
+)
+
+const (
+	CredentialStatic     = "static"
+	CredentialAssumeRole = "assume_role"
+	CredentialECSRAMRole = "ecs_ram_role"
+
+	// credentialRefreshWindow is how long before their expiration the temporary credentials are refreshed
+	credentialRefreshWindow = 5 * time.Minute
+	// assumeRoleDuration is the lifetime of the credentials of the assumed role, one hour at most by default
+	assumeRoleDuration = time.Hour
+	defaultSessionName = "answer"
+)
+
+var (
+	// stsEndpoint and ecsMetadataURL are variables for the tests
+	stsEndpoint    = "https://sts.aliyuncs.com/"
+	ecsMetadataURL = "http://100.100.100.200"
+
+	credentialHTTPClient = &http.Client{Timeout: 10 * time.Second}
+)
+
+// credentials are the temporary credentials of a role, which implement oss.Credentials
+type credentials struct {
+	AccessKeyID     string
+	AccessKeySecret string
+	SecurityToken   string
+	Expiration      time.Time
+}
+
+func (c *credentials) GetAccessKeyID() string     { return c.AccessKeyID }
+func (c *credentials) GetAccessKeySecret() string { return c.AccessKeySecret }
+func (c *credentials) GetSecurityToken() string   { return c.SecurityToken }
+
+// refreshingProvider caches the temporary credentials and fetches new ones before they expire.
+// The SDK asks for the credentials on every request.
+type refreshingProvider struct {
+	name  string
+	fetch func() (*credentials, error)
+	lock  sync.Mutex
+	cache *credentials
+}
+
+func (p *refreshingProvider) GetCredentials() oss.Credentials {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.cache != nil && time.Until(p.cache.Expiration) > credentialRefreshWindow {
+		return p.cache
+	}
+	fresh, err := p.fetch()
+	if err != nil {
+		log.Errorf("aliyunoss_storage: refresh %s credentials failed: %v", p.name, err)
+		if p.cache != nil {
+			// the cached credentials may still be valid for a few minutes
+			return p.cache
+		}
+		return &credentials{}
+	}
+	p.cache = fresh
+	return fresh
+}
+
+// newAssumeRoleProvider returns the credentials of the RAM role assumed with the access key of a RAM user
+func newAssumeRoleProvider(accessKeyID, accessKeySecret, roleArn, sessionName string) (*refreshingProvider, error) {
+	if len(accessKeyID) == 0 || len(accessKeySecret) == 0 || len(roleArn) == 0 {
+		return nil, errors.New("access key and role ARN are required to assume a role")
+	}
+	if len(sessionName) == 0 {
+		sessionName = defaultSessionName
+	}
+	return &refreshingProvider{name: "assume role", fetch: func() (*credentials, error) {
+		return assumeRole(accessKeyID, accessKeySecret, roleArn, sessionName)
+	}}, nil
+}
+
+// newECSRAMRoleProvider returns the credentials of the RAM role of the ECS instance, the role attached
+// to the instance when the name is empty
+func newECSRAMRoleProvider(roleName string) *refreshingProvider {
+	return &refreshingProvider{name: "ECS RAM role", fetch: func() (*credentials, error) {
+		return ecsRoleCredentials(roleName)
+	}}
+}
+
+// assumeRole calls AssumeRole of STS, an RPC API
+func assumeRole(accessKeyID, accessKeySecret, roleArn, sessionName string) (*credentials, error) {
+	query := aliyunrpc.SignedQuery(accessKeyID, accessKeySecret, map[string]string{
+		"Action":          "AssumeRole",
+		"Version":         "2015-04-01",
+		"RoleArn":         roleArn,
+		"RoleSessionName": sessionName,
+		"DurationSeconds": fmt.Sprintf("%d", int(assumeRoleDuration.Seconds())),
+	})
+
+	resp, err := credentialHTTPClient.Get(stsEndpoint + "?" + query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("assume role failed, status %d: %s", resp.StatusCode, body)
+	}
+	result := &struct {
+		Credentials struct {
+			AccessKeyId     string
+			AccessKeySecret string
+			SecurityToken   string
+			Expiration      time.Time
+		}
+	}{}
+	if err = json.Unmarshal(body, result); err != nil {
+		return nil, fmt.Errorf("parse assume role response failed: %v", err)
+	}
+	c := result.Credentials
+	return &credentials{AccessKeyID: c.AccessKeyId, AccessKeySecret: c.AccessKeySecret,
+		SecurityToken: c.SecurityToken, Expiration: c.Expiration}, nil
+}
+
+// ecsRoleCredentials reads the credentials of the RAM role from the metadata service of the instance,
+// with a token when the instance enforces the hardened mode
+func ecsRoleCredentials(roleName string) (*credentials, error) {
+	header := http.Header{}
+	if token, err := ecsMetadataToken(); err == nil {
+		header.Set("X-aliyun-ecs-metadata-token", token)
+	}
+	base := ecsMetadataURL + "/latest/meta-data/ram/security-credentials/"
+	if len(roleName) == 0 {
+		name, err := ecsMetadataGet(base, header)
+		if err != nil {
+			return nil, fmt.Errorf("get ECS RAM role name failed: %v", err)
+		}
+		roleName = strings.TrimSpace(string(name))
+	}
+	body, err := ecsMetadataGet(base+url.PathEscape(roleName), header)
+	if err != nil {
+		return nil, fmt.Errorf("get ECS RAM role credentials failed: %v", err)
+	}
+	result := &struct {
+		Code            string
+		AccessKeyId     string
+		AccessKeySecret string
+		SecurityToken   string
+		Expiration      time.Time
+	}{}
+	if err = json.Unmarshal(body, result); err != nil {
+		return nil, fmt.Errorf("parse ECS RAM role credentials failed: %v", err)
+	}
+	if result.Code != "Success" {
+		return nil, fmt.Errorf("get ECS RAM role credentials failed, code %s", result.Code)
+	}
+	return &credentials{AccessKeyID: result.AccessKeyId, AccessKeySecret: result.AccessKeySecret,
+		SecurityToken: result.SecurityToken, Expiration: result.Expiration}, nil
+}
+
+func ecsMetadataToken() (string, error) {
+	req, err := http.NewRequest(http.MethodPut, ecsMetadataURL+"/latest/api/token", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-aliyun-ecs-metadata-token-ttl-seconds", "21600")
+	resp, err := credentialHTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status %d", resp.StatusCode)
+	}
+	token, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return string(token), err
+}
+
+func ecsMetadataGet(url string, header http.Header) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+	resp, err := credentialHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+}
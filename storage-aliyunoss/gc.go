@@ -0,0 +1,70 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package aliyunoss
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/apache/incubator-answer-plugins/util/storagegc"
+)
+
+// RegisterReferencer registers the contents referencing the uploads, which the orphan collection needs.
+func (s *Storage) RegisterReferencer(referencer storagegc.Referencer) {
+	s.collector.SetReferencer(referencer)
+}
+
+// configureCollector configures the orphan collection, the URLs of both access modes are references
+func (s *Storage) configureCollector() {
+	s.collector.Configure(storagegc.Config{
+		Mode:             s.Config.GCMode,
+		Prefix:           s.Config.ObjectKeyPrefix,
+		Keys:             s.keys,
+		URLPrefixes:      []string{s.Config.VisitUrlPrefix, fileRoutePath},
+		GracePeriod:      storagegc.ParseDays(s.Config.GCGracePeriod),
+		QuarantinePeriod: storagegc.ParseDays(s.Config.GCQuarantinePeriod),
+		Interval:         storagegc.ParseInterval(s.Config.GCInterval),
+	})
+}
+
+func (s *Storage) ListObjects(ctx context.Context, prefix string, fn func(objects []storagegc.Object) error) error {
+	if s.Client == nil {
+		return errors.New("storage is not configured")
+	}
+	return s.Client.ListObjects(ctx, prefix, func(items []oss.ObjectProperties) error {
+		objects := make([]storagegc.Object, 0, len(items))
+		for _, item := range items {
+			objects = append(objects, storagegc.Object{Key: item.Key, LastModified: item.LastModified})
+		}
+		return fn(objects)
+	})
+}
+
+func (s *Storage) MoveObject(ctx context.Context, from, to string) error {
+	if err := s.Client.CopyObject(from, to); err != nil {
+		return err
+	}
+	return s.Client.DeleteObject(from)
+}
+
+func (s *Storage) DeleteObject(ctx context.Context, key string) error {
+	return s.Client.DeleteObject(key)
+}
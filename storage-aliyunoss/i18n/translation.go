@@ -38,9 +38,98 @@ const (
 	ConfigMaxFileSizeTitle           = "plugin.aliyunoss_storage.backend.config.max_file_size.title"
 	ConfigMaxFileSizeDescription     = "plugin.aliyunoss_storage.backend.config.max_file_size.description"
 
+	ConfigImageProcessingTitle       = "plugin.aliyunoss_storage.backend.config.image_processing.title"
+	ConfigImageProcessingDescription = "plugin.aliyunoss_storage.backend.config.image_processing.description"
+	ConfigImageProcessingLabel       = "plugin.aliyunoss_storage.backend.config.image_processing.label"
+
+	ConfigAvatarMaxDimensionTitle       = "plugin.aliyunoss_storage.backend.config.avatar_max_dimension.title"
+	ConfigAvatarMaxDimensionDescription = "plugin.aliyunoss_storage.backend.config.avatar_max_dimension.description"
+
+	ConfigPostMaxDimensionTitle       = "plugin.aliyunoss_storage.backend.config.post_max_dimension.title"
+	ConfigPostMaxDimensionDescription = "plugin.aliyunoss_storage.backend.config.post_max_dimension.description"
+
+	ConfigBrandingMaxDimensionTitle       = "plugin.aliyunoss_storage.backend.config.branding_max_dimension.title"
+	ConfigBrandingMaxDimensionDescription = "plugin.aliyunoss_storage.backend.config.branding_max_dimension.description"
+
+	ConfigThumbnailSizeTitle       = "plugin.aliyunoss_storage.backend.config.thumbnail_size.title"
+	ConfigThumbnailSizeDescription = "plugin.aliyunoss_storage.backend.config.thumbnail_size.description"
+
+	ConfigConvertWebPTitle       = "plugin.aliyunoss_storage.backend.config.convert_webp.title"
+	ConfigConvertWebPDescription = "plugin.aliyunoss_storage.backend.config.convert_webp.description"
+	ConfigConvertWebPLabel       = "plugin.aliyunoss_storage.backend.config.convert_webp.label"
+
+	ConfigObjectKeySchemeTitle         = "plugin.aliyunoss_storage.backend.config.object_key_scheme.title"
+	ConfigObjectKeySchemeDescription   = "plugin.aliyunoss_storage.backend.config.object_key_scheme.description"
+	ConfigObjectKeySchemeOptionRandom  = "plugin.aliyunoss_storage.backend.config.object_key_scheme.options.random"
+	ConfigObjectKeySchemeOptionContent = "plugin.aliyunoss_storage.backend.config.object_key_scheme.options.content"
+
+	ConfigObjectKeyTemplateTitle       = "plugin.aliyunoss_storage.backend.config.object_key_template.title"
+	ConfigObjectKeyTemplateDescription = "plugin.aliyunoss_storage.backend.config.object_key_template.description"
+
+	ConfigMalwareScanTitle       = "plugin.aliyunoss_storage.backend.config.malware_scan.title"
+	ConfigMalwareScanDescription = "plugin.aliyunoss_storage.backend.config.malware_scan.description"
+	ConfigMalwareScanLabel       = "plugin.aliyunoss_storage.backend.config.malware_scan.label"
+
+	ConfigClamdAddressTitle       = "plugin.aliyunoss_storage.backend.config.clamd_address.title"
+	ConfigClamdAddressDescription = "plugin.aliyunoss_storage.backend.config.clamd_address.description"
+
+	ConfigScanFailurePolicyTitle        = "plugin.aliyunoss_storage.backend.config.scan_failure_policy.title"
+	ConfigScanFailurePolicyDescription  = "plugin.aliyunoss_storage.backend.config.scan_failure_policy.description"
+	ConfigScanFailurePolicyOptionClosed = "plugin.aliyunoss_storage.backend.config.scan_failure_policy.options.closed"
+	ConfigScanFailurePolicyOptionOpen   = "plugin.aliyunoss_storage.backend.config.scan_failure_policy.options.open"
+
+	ConfigGCModeTitle            = "plugin.aliyunoss_storage.backend.config.gc_mode.title"
+	ConfigGCModeDescription      = "plugin.aliyunoss_storage.backend.config.gc_mode.description"
+	ConfigGCModeOptionOff        = "plugin.aliyunoss_storage.backend.config.gc_mode.options.off"
+	ConfigGCModeOptionReport     = "plugin.aliyunoss_storage.backend.config.gc_mode.options.report"
+	ConfigGCModeOptionQuarantine = "plugin.aliyunoss_storage.backend.config.gc_mode.options.quarantine"
+
+	ConfigGCIntervalTitle       = "plugin.aliyunoss_storage.backend.config.gc_interval.title"
+	ConfigGCIntervalDescription = "plugin.aliyunoss_storage.backend.config.gc_interval.description"
+
+	ConfigGCGracePeriodTitle       = "plugin.aliyunoss_storage.backend.config.gc_grace_period.title"
+	ConfigGCGracePeriodDescription = "plugin.aliyunoss_storage.backend.config.gc_grace_period.description"
+
+	ConfigGCQuarantinePeriodTitle       = "plugin.aliyunoss_storage.backend.config.gc_quarantine_period.title"
+	ConfigGCQuarantinePeriodDescription = "plugin.aliyunoss_storage.backend.config.gc_quarantine_period.description"
+
+	ConfigEndpointTypeTitle            = "plugin.aliyunoss_storage.backend.config.endpoint_type.title"
+	ConfigEndpointTypeDescription      = "plugin.aliyunoss_storage.backend.config.endpoint_type.description"
+	ConfigEndpointTypeOptionPublic     = "plugin.aliyunoss_storage.backend.config.endpoint_type.options.public"
+	ConfigEndpointTypeOptionInternal   = "plugin.aliyunoss_storage.backend.config.endpoint_type.options.internal"
+	ConfigEndpointTypeOptionAccelerate = "plugin.aliyunoss_storage.backend.config.endpoint_type.options.accelerate"
+
+	ConfigCredentialModeTitle            = "plugin.aliyunoss_storage.backend.config.credential_mode.title"
+	ConfigCredentialModeDescription      = "plugin.aliyunoss_storage.backend.config.credential_mode.description"
+	ConfigCredentialModeOptionStatic     = "plugin.aliyunoss_storage.backend.config.credential_mode.options.static"
+	ConfigCredentialModeOptionAssumeRole = "plugin.aliyunoss_storage.backend.config.credential_mode.options.assume_role"
+	ConfigCredentialModeOptionECSRAMRole = "plugin.aliyunoss_storage.backend.config.credential_mode.options.ecs_ram_role"
+
+	ConfigSecurityTokenTitle       = "plugin.aliyunoss_storage.backend.config.security_token.title"
+	ConfigSecurityTokenDescription = "plugin.aliyunoss_storage.backend.config.security_token.description"
+
+	ConfigRoleArnTitle       = "plugin.aliyunoss_storage.backend.config.role_arn.title"
+	ConfigRoleArnDescription = "plugin.aliyunoss_storage.backend.config.role_arn.description"
+
+	ConfigRoleSessionNameTitle       = "plugin.aliyunoss_storage.backend.config.role_session_name.title"
+	ConfigRoleSessionNameDescription = "plugin.aliyunoss_storage.backend.config.role_session_name.description"
+
+	ConfigECSRAMRoleNameTitle       = "plugin.aliyunoss_storage.backend.config.ecs_ram_role_name.title"
+	ConfigECSRAMRoleNameDescription = "plugin.aliyunoss_storage.backend.config.ecs_ram_role_name.description"
+
+	ConfigAccessModeTitle         = "plugin.aliyunoss_storage.backend.config.access_mode.title"
+	ConfigAccessModeDescription   = "plugin.aliyunoss_storage.backend.config.access_mode.description"
+	ConfigAccessModeOptionPublic  = "plugin.aliyunoss_storage.backend.config.access_mode.options.public"
+	ConfigAccessModeOptionPrivate = "plugin.aliyunoss_storage.backend.config.access_mode.options.private"
+
+	ConfigSignedURLExpiryTitle       = "plugin.aliyunoss_storage.backend.config.signed_url_expiry.title"
+	ConfigSignedURLExpiryDescription = "plugin.aliyunoss_storage.backend.config.signed_url_expiry.description"
+
 	ErrMisStorageConfig    = "plugin.aliyunoss_storage.backend.err.mis_storage_config"
 	ErrFileNotFound        = "plugin.aliyunoss_storage.backend.err.file_not_found"
 	ErrUnsupportedFileType = "plugin.aliyunoss_storage.backend.err.unsupported_file_type"
 	ErrOverFileSizeLimit   = "plugin.aliyunoss_storage.backend.err.over_file_size_limit"
 	ErrUploadFileFailed    = "plugin.aliyunoss_storage.backend.err.upload_file_failed"
+	ErrInfectedFile        = "plugin.aliyunoss_storage.backend.err.infected_file"
+	ErrScanUnavailable     = "plugin.aliyunoss_storage.backend.err.scan_unavailable"
 )
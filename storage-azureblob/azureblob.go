@@ -0,0 +1,442 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package azureblob
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"github.com/apache/incubator-answer-plugins/util"
+
+	"github.com/apache/incubator-answer-plugins/storage-azureblob/i18n"
+	"github.com/apache/incubator-answer-plugins/util/clamav"
+	"github.com/apache/incubator-answer-plugins/util/storageext"
+	"github.com/apache/incubator-answer/plugin"
+)
+
+//go:embed  info.yaml
+var Info embed.FS
+
+// uploadMessages are the upload errors shown to the users
+var uploadMessages = storageext.Messages{
+	FileNotFound:        i18n.ErrFileNotFound,
+	UnsupportedFileType: i18n.ErrUnsupportedFileType,
+	OverFileSizeLimit:   i18n.ErrOverFileSizeLimit,
+	UploadFileFailed:    i18n.ErrUploadFileFailed,
+	InfectedFile:        i18n.ErrInfectedFile,
+	ScanUnavailable:     i18n.ErrScanUnavailable,
+}
+
+type Storage struct {
+	Config  *StorageConfig
+	Client  *Client
+	rules   *storageext.Rules
+	keys    *storageext.ObjectKeys
+	scanner *clamav.Guard
+}
+
+type StorageConfig struct {
+	Endpoint             string `json:"endpoint"`
+	AccountName          string `json:"account_name"`
+	AccountKey           string `json:"account_key"`
+	SASToken             string `json:"sas_token"`
+	ContainerName        string `json:"container_name"`
+	ObjectKeyPrefix      string `json:"object_key_prefix"`
+	ObjectKeyScheme      string `json:"object_key_scheme"`
+	ObjectKeyTemplate    string `json:"object_key_template"`
+	VisitUrlPrefix       string `json:"visit_url_prefix"`
+	MaxFileSize          string `json:"max_file_size"`
+	MaxAvatarSize        string `json:"max_avatar_size"`
+	MaxBrandingSize      string `json:"max_branding_size"`
+	AllowedExts          string `json:"allowed_extensions"`
+	ImageProcessing      bool   `json:"image_processing"`
+	AvatarMaxDimension   string `json:"avatar_max_dimension"`
+	PostMaxDimension     string `json:"post_max_dimension"`
+	BrandingMaxDimension string `json:"branding_max_dimension"`
+	ThumbnailSize        string `json:"thumbnail_size"`
+	ConvertWebP          bool   `json:"convert_webp"`
+	MalwareScan          bool   `json:"malware_scan"`
+	ClamdAddress         string `json:"clamd_address"`
+	ScanFailurePolicy    string `json:"scan_failure_policy"`
+}
+
+func init() {
+	keys, _ := storageext.NewObjectKeys("", storageext.KeySchemeRandom, "")
+	plugin.Register(&Storage{
+		Config: &StorageConfig{},
+		rules:  &storageext.Rules{},
+		keys:   keys,
+	})
+}
+
+func (s *Storage) Info() plugin.Info {
+	info := &util.Info{}
+	info.GetInfo(Info)
+
+	return plugin.Info{
+		Name:        plugin.MakeTranslator(i18n.InfoName),
+		SlugName:    info.SlugName,
+		Description: plugin.MakeTranslator(i18n.InfoDescription),
+		Author:      info.Author,
+		Version:     info.Version,
+		Link:        info.Link,
+	}
+}
+
+func (s *Storage) UploadFile(ctx *plugin.GinContext, source plugin.UploadSource) (resp plugin.UploadFileResponse) {
+	if s.Client == nil {
+		resp.OriginalError = errors.New("azure blob client not configured")
+		resp.DisplayErrorMsg = plugin.MakeTranslator(i18n.ErrMisStorageConfig)
+		return resp
+	}
+	return s.uploader().Upload(ctx, source)
+}
+
+// uploader returns the upload pipeline with the current config, only the bucket belongs to the plugin
+func (s *Storage) uploader() *storageext.Uploader {
+	return &storageext.Uploader{
+		Rules: s.rules,
+		Keys:  s.keys,
+		Images: storageext.ImageConfig{
+			Enabled:              s.Config.ImageProcessing,
+			WebP:                 s.Config.ConvertWebP,
+			AvatarMaxDimension:   s.Config.AvatarMaxDimension,
+			PostMaxDimension:     s.Config.PostMaxDimension,
+			BrandingMaxDimension: s.Config.BrandingMaxDimension,
+			ThumbnailSize:        s.Config.ThumbnailSize,
+		},
+		Scanner:  s.scanner,
+		Messages: uploadMessages,
+		Store:    s.Client,
+		URL:      s.fileURL,
+	}
+}
+
+// fileURL returns the URL of the object, under the container URL when no visit URL prefix is set
+func (s *Storage) fileURL(objectKey string) string {
+	if len(s.Config.VisitUrlPrefix) == 0 {
+		return s.Client.URL(objectKey)
+	}
+	return s.Config.VisitUrlPrefix + objectKey
+}
+
+// buildRules builds the upload rules from the config, the empty fields keep the defaults
+func (s *Storage) buildRules() *storageext.Rules {
+	rules := &storageext.Rules{
+		Extensions: make(map[plugin.UploadSource]map[string]bool),
+		MaxSizes:   make(map[plugin.UploadSource]int64),
+		MaxSize:    storageext.ParseSize(s.Config.MaxFileSize),
+	}
+	if exts := storageext.ParseExtensions(s.Config.AllowedExts); exts != nil {
+		rules.Extensions[plugin.UserPost] = exts
+	}
+	if size := storageext.ParseSize(s.Config.MaxAvatarSize); size > 0 {
+		rules.MaxSizes[plugin.UserAvatar] = size
+	}
+	if size := storageext.ParseSize(s.Config.MaxBrandingSize); size > 0 {
+		rules.MaxSizes[plugin.AdminBranding] = size
+	}
+	return rules
+}
+
+func (s *Storage) ConfigFields() []plugin.ConfigField {
+	return []plugin.ConfigField{
+		{
+			Name:        "endpoint",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigEndpointTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigEndpointDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.Endpoint,
+		},
+		{
+			Name:        "account_name",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigAccountNameTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigAccountNameDescription),
+			Required:    true,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.AccountName,
+		},
+		{
+			Name:        "account_key",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigAccountKeyTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigAccountKeyDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypePassword,
+			},
+			Value: s.Config.AccountKey,
+		},
+		{
+			Name:        "sas_token",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigSASTokenTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigSASTokenDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypePassword,
+			},
+			Value: s.Config.SASToken,
+		},
+		{
+			Name:        "container_name",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigContainerNameTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigContainerNameDescription),
+			Required:    true,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.ContainerName,
+		},
+		{
+			Name:        "object_key_prefix",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigObjectKeyPrefixTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigObjectKeyPrefixDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.ObjectKeyPrefix,
+		},
+		{
+			Name:        "object_key_scheme",
+			Type:        plugin.ConfigTypeSelect,
+			Title:       plugin.MakeTranslator(i18n.ConfigObjectKeySchemeTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigObjectKeySchemeDescription),
+			Value:       s.Config.ObjectKeyScheme,
+			Options: []plugin.ConfigFieldOption{
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigObjectKeySchemeOptionRandom),
+					Value: storageext.KeySchemeRandom,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigObjectKeySchemeOptionContent),
+					Value: storageext.KeySchemeContent,
+				},
+			},
+		},
+		{
+			Name:        "object_key_template",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigObjectKeyTemplateTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigObjectKeyTemplateDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.ObjectKeyTemplate,
+		},
+		{
+			Name:        "visit_url_prefix",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigVisitUrlPrefixTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigVisitUrlPrefixDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.VisitUrlPrefix,
+		},
+		{
+			Name:        "max_file_size",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigMaxFileSizeTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigMaxFileSizeDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeNumber,
+			},
+			Value: s.Config.MaxFileSize,
+		},
+		{
+			Name:        "max_avatar_size",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigMaxAvatarSizeTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigMaxAvatarSizeDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeNumber,
+			},
+			Value: s.Config.MaxAvatarSize,
+		},
+		{
+			Name:        "max_branding_size",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigMaxBrandingSizeTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigMaxBrandingSizeDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeNumber,
+			},
+			Value: s.Config.MaxBrandingSize,
+		},
+		{
+			Name:        "allowed_extensions",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigAllowedExtensionsTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigAllowedExtensionsDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.AllowedExts,
+		},
+		{
+			Name:        "image_processing",
+			Type:        plugin.ConfigTypeSwitch,
+			Title:       plugin.MakeTranslator(i18n.ConfigImageProcessingTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigImageProcessingDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				Label: plugin.MakeTranslator(i18n.ConfigImageProcessingLabel),
+			},
+			Value: s.Config.ImageProcessing,
+		},
+		{
+			Name:        "avatar_max_dimension",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigAvatarMaxDimensionTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigAvatarMaxDimensionDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeNumber,
+			},
+			Value: s.Config.AvatarMaxDimension,
+		},
+		{
+			Name:        "post_max_dimension",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigPostMaxDimensionTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigPostMaxDimensionDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeNumber,
+			},
+			Value: s.Config.PostMaxDimension,
+		},
+		{
+			Name:        "branding_max_dimension",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigBrandingMaxDimensionTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigBrandingMaxDimensionDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeNumber,
+			},
+			Value: s.Config.BrandingMaxDimension,
+		},
+		{
+			Name:        "thumbnail_size",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigThumbnailSizeTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigThumbnailSizeDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeNumber,
+			},
+			Value: s.Config.ThumbnailSize,
+		},
+		{
+			Name:        "convert_webp",
+			Type:        plugin.ConfigTypeSwitch,
+			Title:       plugin.MakeTranslator(i18n.ConfigConvertWebPTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigConvertWebPDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				Label: plugin.MakeTranslator(i18n.ConfigConvertWebPLabel),
+			},
+			Value: s.Config.ConvertWebP,
+		},
+		{
+			Name:        "malware_scan",
+			Type:        plugin.ConfigTypeSwitch,
+			Title:       plugin.MakeTranslator(i18n.ConfigMalwareScanTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigMalwareScanDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				Label: plugin.MakeTranslator(i18n.ConfigMalwareScanLabel),
+			},
+			Value: s.Config.MalwareScan,
+		},
+		{
+			Name:        "clamd_address",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigClamdAddressTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigClamdAddressDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.ClamdAddress,
+		},
+		{
+			Name:        "scan_failure_policy",
+			Type:        plugin.ConfigTypeSelect,
+			Title:       plugin.MakeTranslator(i18n.ConfigScanFailurePolicyTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigScanFailurePolicyDescription),
+			Value:       s.Config.ScanFailurePolicy,
+			Options: []plugin.ConfigFieldOption{
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigScanFailurePolicyOptionClosed),
+					Value: clamav.PolicyFailClosed,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigScanFailurePolicyOptionOpen),
+					Value: clamav.PolicyFailOpen,
+				},
+			},
+		},
+	}
+}
+
+func (s *Storage) ConfigReceiver(config []byte) error {
+	c := &StorageConfig{}
+	_ = json.Unmarshal(config, c)
+	s.Config = c
+	s.rules = s.buildRules()
+	keys, err := storageext.NewObjectKeys(s.Config.ObjectKeyPrefix, s.Config.ObjectKeyScheme, s.Config.ObjectKeyTemplate)
+	if err != nil {
+		return err
+	}
+	s.keys = keys
+	scanner, err := storageext.NewScanner(s.Info().SlugName, s.Config.MalwareScan, s.Config.ClamdAddress, s.Config.ScanFailurePolicy)
+	if err != nil {
+		return err
+	}
+	s.scanner = scanner
+	client, err := NewAzureClient(ClientConfig{
+		Endpoint:    s.Config.Endpoint,
+		AccountName: s.Config.AccountName,
+		AccountKey:  s.Config.AccountKey,
+		SASToken:    s.Config.SASToken,
+		Container:   s.Config.ContainerName,
+	})
+	if err != nil {
+		return err
+	}
+	s.Client = client
+	return nil
+}
@@ -0,0 +1,134 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package azureblob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+)
+
+const (
+	// uploadBlockSize is the block size of the uploads, smaller files are sent in one block
+	uploadBlockSize = 8 * 1024 * 1024
+	// uploadConcurrency is the number of blocks uploaded at once
+	uploadConcurrency = 3
+)
+
+// ClientConfig is the connection config of the Azure Blob client
+type ClientConfig struct {
+	// Endpoint is the blob service URL, https://<account>.blob.core.windows.net by default
+	Endpoint    string
+	AccountName string
+	// AccountKey is the shared key of the account, SASToken replaces it when set
+	AccountKey string
+	SASToken   string
+	Container  string
+}
+
+// Client is a long-lived client of a container, safe for concurrent use
+type Client struct {
+	container *container.Client
+	// containerURL is the URL of the container without the SAS token
+	containerURL string
+}
+
+// NewAzureClient creates the client, authorized with the SAS token when it is set, or with the shared key
+func NewAzureClient(conf ClientConfig) (*Client, error) {
+	if len(conf.Container) == 0 {
+		return nil, errors.New("container name is required")
+	}
+	endpoint := strings.TrimSuffix(conf.Endpoint, "/")
+	if len(endpoint) == 0 {
+		if len(conf.AccountName) == 0 {
+			return nil, errors.New("account name is required")
+		}
+		endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", conf.AccountName)
+	}
+	if _, err := url.ParseRequestURI(endpoint); err != nil {
+		return nil, fmt.Errorf("invalid endpoint %s, %v", endpoint, err)
+	}
+	client := &Client{containerURL: endpoint + "/" + conf.Container}
+
+	var err error
+	switch {
+	case len(conf.SASToken) > 0:
+		sasToken := strings.TrimPrefix(conf.SASToken, "?")
+		if _, err = url.ParseQuery(sasToken); err != nil {
+			return nil, fmt.Errorf("invalid SAS token, %v", err)
+		}
+		client.container, err = container.NewClientWithNoCredential(client.containerURL+"?"+sasToken, nil)
+	case len(conf.AccountName) > 0 && len(conf.AccountKey) > 0:
+		var cred *container.SharedKeyCredential
+		cred, err = container.NewSharedKeyCredential(conf.AccountName, conf.AccountKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid account key, %v", err)
+		}
+		client.container, err = container.NewClientWithSharedKeyCredential(client.containerURL, cred, nil)
+	default:
+		return nil, errors.New("account key or SAS token is required")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client, %s", err.Error())
+	}
+	return client, nil
+}
+
+// PutObject uploads the blob, in blocks when it is large
+func (c *Client) PutObject(key, contentType, contentDisposition string, file io.Reader) error {
+	headers := &blob.HTTPHeaders{BlobContentType: &contentType}
+	if len(contentDisposition) > 0 {
+		headers.BlobContentDisposition = &contentDisposition
+	}
+	_, err := c.container.NewBlockBlobClient(key).UploadStream(context.Background(), file, &blockblob.UploadStreamOptions{
+		BlockSize:   uploadBlockSize,
+		Concurrency: uploadConcurrency,
+		HTTPHeaders: headers,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put blob, %s", err.Error())
+	}
+	return nil
+}
+
+// ObjectExists reports whether the blob exists
+func (c *Client) ObjectExists(key string) (bool, error) {
+	_, err := c.container.NewBlobClient(key).GetProperties(context.Background(), nil)
+	if err == nil {
+		return true, nil
+	}
+	if bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return false, nil
+	}
+	return false, fmt.Errorf("failed to get blob properties, %s", err.Error())
+}
+
+// URL returns the URL of the blob, readable when the container allows public access
+func (c *Client) URL(key string) string {
+	return c.containerURL + "/" + key
+}
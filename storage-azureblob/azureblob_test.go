@@ -0,0 +1,196 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package azureblob
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
+	"github.com/apache/incubator-answer/plugin"
+	"github.com/gin-gonic/gin"
+)
+
+// the well-known development account of Azurite
+const (
+	azuriteAccountName = "devstoreaccount1"
+	azuriteAccountKey  = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IfsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+)
+
+func TestNewAzureClient(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    ClientConfig
+		wantURL string
+		wantErr bool
+	}{
+		{"shared key", ClientConfig{AccountName: "answer", AccountKey: azuriteAccountKey, Container: "uploads"},
+			"https://answer.blob.core.windows.net/uploads/post/a.png", false},
+		{"sas", ClientConfig{Endpoint: "http://127.0.0.1:10000/devstoreaccount1/", SASToken: "?sv=2021-08-06&sig=x", Container: "uploads"},
+			"http://127.0.0.1:10000/devstoreaccount1/uploads/post/a.png", false},
+		{"no container", ClientConfig{AccountName: "answer", AccountKey: azuriteAccountKey}, "", true},
+		{"no credential", ClientConfig{AccountName: "answer", Container: "uploads"}, "", true},
+		{"invalid key", ClientConfig{AccountName: "answer", AccountKey: "not base64!", Container: "uploads"}, "", true},
+		{"invalid endpoint", ClientConfig{Endpoint: "blob.local", AccountName: "answer", AccountKey: azuriteAccountKey, Container: "uploads"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewAzureClient(tt.conf)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewAzureClient() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && client.URL("post/a.png") != tt.wantURL {
+				t.Errorf("URL() = %s, want %s", client.URL("post/a.png"), tt.wantURL)
+			}
+		})
+	}
+}
+
+// azurite returns the blob endpoint of Azurite, such as http://127.0.0.1:10000/devstoreaccount1,
+// and skips the test when AZURITE_BLOB_ENDPOINT is not set
+func azurite(t *testing.T) string {
+	endpoint := os.Getenv("AZURITE_BLOB_ENDPOINT")
+	if len(endpoint) == 0 {
+		t.Skip("AZURITE_BLOB_ENDPOINT not set")
+	}
+	return strings.TrimSuffix(endpoint, "/")
+}
+
+// createContainer creates a container for the test
+func createContainer(t *testing.T, endpoint string) *container.Client {
+	cred, _ := container.NewSharedKeyCredential(azuriteAccountName, azuriteAccountKey)
+	name := fmt.Sprintf("answer-test-%d", time.Now().UnixNano())
+	client, err := container.NewClientWithSharedKeyCredential(endpoint+"/"+name, cred, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.Create(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _, _ = client.Delete(context.Background(), nil) })
+	return client
+}
+
+func newTestStorage(t *testing.T, config map[string]interface{}) *Storage {
+	s := &Storage{Config: &StorageConfig{}}
+	data, _ := json.Marshal(config)
+	if err := s.ConfigReceiver(data); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func upload(t *testing.T, s *Storage, filename, content string) string {
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	part, _ := form.CreateFormFile("file", filename)
+	_, _ = part.Write([]byte(content))
+	_ = form.Close()
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/upload", body)
+	ctx.Request.Header.Set("Content-Type", form.FormDataContentType())
+	resp := s.UploadFile(ctx, plugin.UserPost)
+	if resp.OriginalError != nil {
+		t.Fatalf("UploadFile(%s) = %v", filename, resp.OriginalError)
+	}
+	return resp.FullURL
+}
+
+// readBlob returns the content and the content type of the blob at the URL
+func readBlob(t *testing.T, client *container.Client, url string) (string, string) {
+	key := strings.TrimPrefix(url, client.URL()+"/")
+	output, err := client.NewBlobClient(key).DownloadStream(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("download %s: %v", key, err)
+	}
+	defer output.Body.Close()
+	data, _ := io.ReadAll(output.Body)
+	return string(data), *output.ContentType
+}
+
+func TestAzuriteSharedKey(t *testing.T) {
+	endpoint := azurite(t)
+	client := createContainer(t, endpoint)
+	containerName := strings.TrimPrefix(client.URL(), endpoint+"/")
+	s := newTestStorage(t, map[string]interface{}{
+		"endpoint":           endpoint,
+		"account_name":       azuriteAccountName,
+		"account_key":        azuriteAccountKey,
+		"container_name":     containerName,
+		"object_key_prefix":  "answer/",
+		"object_key_scheme":  "content",
+		"allowed_extensions": "pdf",
+	})
+
+	url := upload(t, s, "report.pdf", "%PDF-1.7\n1 0 obj")
+	if !strings.HasPrefix(url, client.URL()+"/answer/post/") || !strings.HasSuffix(url, ".pdf") {
+		t.Fatalf("url = %s", url)
+	}
+	if content, contentType := readBlob(t, client, url); content != "%PDF-1.7\n1 0 obj" || contentType != "application/pdf" {
+		t.Errorf("blob = %q, %s", content, contentType)
+	}
+	if again := upload(t, s, "copy.pdf", "%PDF-1.7\n1 0 obj"); again != url {
+		t.Errorf("same content stored twice: %s, %s", url, again)
+	}
+}
+
+func TestAzuriteSAS(t *testing.T) {
+	endpoint := azurite(t)
+	client := createContainer(t, endpoint)
+	containerName := strings.TrimPrefix(client.URL(), endpoint+"/")
+	cred, _ := container.NewSharedKeyCredential(azuriteAccountName, azuriteAccountKey)
+	// Azurite serves http, so the token can't be restricted to https
+	params, err := sas.BlobSignatureValues{
+		Protocol:      sas.ProtocolHTTPSandHTTP,
+		ContainerName: containerName,
+		Permissions:   (&sas.ContainerPermissions{Read: true, Create: true, Write: true}).String(),
+		ExpiryTime:    time.Now().Add(time.Hour).UTC(),
+	}.SignWithSharedKey(cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newTestStorage(t, map[string]interface{}{
+		"endpoint":           endpoint,
+		"sas_token":          params.Encode(),
+		"container_name":     containerName,
+		"visit_url_prefix":   "https://cdn.example.com/",
+		"allowed_extensions": "txt",
+	})
+
+	url := upload(t, s, "notes.txt", "hello")
+	if !strings.HasPrefix(url, "https://cdn.example.com/post/") {
+		t.Fatalf("url = %s", url)
+	}
+	if content, _ := readBlob(t, client, client.URL()+strings.TrimPrefix(url, "https://cdn.example.com")); content != "hello" {
+		t.Errorf("blob = %q", content)
+	}
+}
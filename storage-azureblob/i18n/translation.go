@@ -0,0 +1,106 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package i18n
+
+const (
+	InfoName        = "plugin.azureblob_storage.backend.info.name"
+	InfoDescription = "plugin.azureblob_storage.backend.info.description"
+
+	ConfigEndpointTitle       = "plugin.azureblob_storage.backend.config.endpoint.title"
+	ConfigEndpointDescription = "plugin.azureblob_storage.backend.config.endpoint.description"
+
+	ConfigAccountNameTitle       = "plugin.azureblob_storage.backend.config.account_name.title"
+	ConfigAccountNameDescription = "plugin.azureblob_storage.backend.config.account_name.description"
+
+	ConfigAccountKeyTitle       = "plugin.azureblob_storage.backend.config.account_key.title"
+	ConfigAccountKeyDescription = "plugin.azureblob_storage.backend.config.account_key.description"
+
+	ConfigSASTokenTitle       = "plugin.azureblob_storage.backend.config.sas_token.title"
+	ConfigSASTokenDescription = "plugin.azureblob_storage.backend.config.sas_token.description"
+
+	ConfigContainerNameTitle       = "plugin.azureblob_storage.backend.config.container_name.title"
+	ConfigContainerNameDescription = "plugin.azureblob_storage.backend.config.container_name.description"
+
+	ConfigObjectKeyPrefixTitle       = "plugin.azureblob_storage.backend.config.object_key_prefix.title"
+	ConfigObjectKeyPrefixDescription = "plugin.azureblob_storage.backend.config.object_key_prefix.description"
+
+	ConfigObjectKeySchemeTitle         = "plugin.azureblob_storage.backend.config.object_key_scheme.title"
+	ConfigObjectKeySchemeDescription   = "plugin.azureblob_storage.backend.config.object_key_scheme.description"
+	ConfigObjectKeySchemeOptionRandom  = "plugin.azureblob_storage.backend.config.object_key_scheme.options.random"
+	ConfigObjectKeySchemeOptionContent = "plugin.azureblob_storage.backend.config.object_key_scheme.options.content"
+
+	ConfigObjectKeyTemplateTitle       = "plugin.azureblob_storage.backend.config.object_key_template.title"
+	ConfigObjectKeyTemplateDescription = "plugin.azureblob_storage.backend.config.object_key_template.description"
+
+	ConfigVisitUrlPrefixTitle       = "plugin.azureblob_storage.backend.config.visit_url_prefix.title"
+	ConfigVisitUrlPrefixDescription = "plugin.azureblob_storage.backend.config.visit_url_prefix.description"
+
+	ConfigMaxFileSizeTitle       = "plugin.azureblob_storage.backend.config.max_file_size.title"
+	ConfigMaxFileSizeDescription = "plugin.azureblob_storage.backend.config.max_file_size.description"
+
+	ConfigMaxAvatarSizeTitle       = "plugin.azureblob_storage.backend.config.max_avatar_size.title"
+	ConfigMaxAvatarSizeDescription = "plugin.azureblob_storage.backend.config.max_avatar_size.description"
+
+	ConfigMaxBrandingSizeTitle       = "plugin.azureblob_storage.backend.config.max_branding_size.title"
+	ConfigMaxBrandingSizeDescription = "plugin.azureblob_storage.backend.config.max_branding_size.description"
+
+	ConfigAllowedExtensionsTitle       = "plugin.azureblob_storage.backend.config.allowed_extensions.title"
+	ConfigAllowedExtensionsDescription = "plugin.azureblob_storage.backend.config.allowed_extensions.description"
+
+	ConfigImageProcessingTitle       = "plugin.azureblob_storage.backend.config.image_processing.title"
+	ConfigImageProcessingDescription = "plugin.azureblob_storage.backend.config.image_processing.description"
+	ConfigImageProcessingLabel       = "plugin.azureblob_storage.backend.config.image_processing.label"
+
+	ConfigAvatarMaxDimensionTitle       = "plugin.azureblob_storage.backend.config.avatar_max_dimension.title"
+	ConfigAvatarMaxDimensionDescription = "plugin.azureblob_storage.backend.config.avatar_max_dimension.description"
+
+	ConfigPostMaxDimensionTitle       = "plugin.azureblob_storage.backend.config.post_max_dimension.title"
+	ConfigPostMaxDimensionDescription = "plugin.azureblob_storage.backend.config.post_max_dimension.description"
+
+	ConfigBrandingMaxDimensionTitle       = "plugin.azureblob_storage.backend.config.branding_max_dimension.title"
+	ConfigBrandingMaxDimensionDescription = "plugin.azureblob_storage.backend.config.branding_max_dimension.description"
+
+	ConfigThumbnailSizeTitle       = "plugin.azureblob_storage.backend.config.thumbnail_size.title"
+	ConfigThumbnailSizeDescription = "plugin.azureblob_storage.backend.config.thumbnail_size.description"
+
+	ConfigConvertWebPTitle       = "plugin.azureblob_storage.backend.config.convert_webp.title"
+	ConfigConvertWebPDescription = "plugin.azureblob_storage.backend.config.convert_webp.description"
+	ConfigConvertWebPLabel       = "plugin.azureblob_storage.backend.config.convert_webp.label"
+
+	ConfigMalwareScanTitle       = "plugin.azureblob_storage.backend.config.malware_scan.title"
+	ConfigMalwareScanDescription = "plugin.azureblob_storage.backend.config.malware_scan.description"
+	ConfigMalwareScanLabel       = "plugin.azureblob_storage.backend.config.malware_scan.label"
+
+	ConfigClamdAddressTitle       = "plugin.azureblob_storage.backend.config.clamd_address.title"
+	ConfigClamdAddressDescription = "plugin.azureblob_storage.backend.config.clamd_address.description"
+
+	ConfigScanFailurePolicyTitle        = "plugin.azureblob_storage.backend.config.scan_failure_policy.title"
+	ConfigScanFailurePolicyDescription  = "plugin.azureblob_storage.backend.config.scan_failure_policy.description"
+	ConfigScanFailurePolicyOptionClosed = "plugin.azureblob_storage.backend.config.scan_failure_policy.options.closed"
+	ConfigScanFailurePolicyOptionOpen   = "plugin.azureblob_storage.backend.config.scan_failure_policy.options.open"
+
+	ErrMisStorageConfig    = "plugin.azureblob_storage.backend.err.mis_storage_config"
+	ErrFileNotFound        = "plugin.azureblob_storage.backend.err.file_not_found"
+	ErrUnsupportedFileType = "plugin.azureblob_storage.backend.err.unsupported_file_type"
+	ErrOverFileSizeLimit   = "plugin.azureblob_storage.backend.err.over_file_size_limit"
+	ErrUploadFileFailed    = "plugin.azureblob_storage.backend.err.upload_file_failed"
+	ErrInfectedFile        = "plugin.azureblob_storage.backend.err.infected_file"
+	ErrScanUnavailable     = "plugin.azureblob_storage.backend.err.scan_unavailable"
+)
@@ -0,0 +1,126 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package gcs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/option"
+)
+
+const (
+	// defaultPublicURL is the host of the public URLs of the objects
+	defaultPublicURL = "https://storage.googleapis.com"
+	// uploadChunkSize is the chunk size of the resumable uploads, smaller files are sent in one request
+	uploadChunkSize = 8 * 1024 * 1024
+)
+
+// ClientConfig is the connection config of the GCS client
+type ClientConfig struct {
+	// Endpoint is the JSON API endpoint, such as the one of fake-gcs-server, empty for GCS
+	Endpoint string
+	Bucket   string
+	// CredentialsJSON is the service account key, empty uses the application default credentials
+	CredentialsJSON string
+}
+
+// Client is a long-lived client of a bucket, safe for concurrent use
+type Client struct {
+	bucket *storage.BucketHandle
+	// publicURL is the URL of the bucket, where the public objects are read
+	publicURL string
+}
+
+// NewGCSClient creates the client. Without a service account key, the credentials come from the
+// application default credentials: GOOGLE_APPLICATION_CREDENTIALS, gcloud, or the metadata server
+// of GCE, GKE and Cloud Run. A custom endpoint without a key is not authenticated, for the emulators.
+func NewGCSClient(conf ClientConfig) (*Client, error) {
+	if len(conf.Bucket) == 0 {
+		return nil, errors.New("bucket name is required")
+	}
+	publicURL := defaultPublicURL
+	var opts []option.ClientOption
+	if len(conf.Endpoint) > 0 {
+		endpoint, err := url.ParseRequestURI(conf.Endpoint)
+		if err != nil || len(endpoint.Host) == 0 {
+			return nil, fmt.Errorf("invalid endpoint %s", conf.Endpoint)
+		}
+		publicURL = endpoint.Scheme + "://" + endpoint.Host
+		opts = append(opts, option.WithEndpoint(conf.Endpoint))
+	}
+	switch {
+	case len(conf.CredentialsJSON) > 0:
+		credentials := strings.TrimSpace(conf.CredentialsJSON)
+		if !json.Valid([]byte(credentials)) {
+			return nil, errors.New("invalid service account key, not JSON")
+		}
+		opts = append(opts, option.WithCredentialsJSON([]byte(credentials)))
+	case len(conf.Endpoint) > 0:
+		opts = append(opts, option.WithoutAuthentication())
+	}
+	client, err := storage.NewClient(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client, %s", err.Error())
+	}
+	return &Client{
+		bucket:    client.Bucket(conf.Bucket),
+		publicURL: publicURL + "/" + conf.Bucket,
+	}, nil
+}
+
+// PutObject uploads the object, in chunks when it is large
+func (c *Client) PutObject(key, contentType, contentDisposition string, file io.Reader) error {
+	writer := c.bucket.Object(key).NewWriter(context.Background())
+	writer.ChunkSize = uploadChunkSize
+	writer.ContentType = contentType
+	writer.ContentDisposition = contentDisposition
+	if _, err := io.Copy(writer, file); err != nil {
+		_ = writer.Close()
+		return fmt.Errorf("failed to put object, %s", err.Error())
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to put object, %s", err.Error())
+	}
+	return nil
+}
+
+// ObjectExists reports whether the object exists
+func (c *Client) ObjectExists(key string) (bool, error) {
+	_, err := c.bucket.Object(key).Attrs(context.Background())
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return false, nil
+	}
+	return false, fmt.Errorf("failed to get object attrs, %s", err.Error())
+}
+
+// URL returns the public URL of the object, readable when the bucket grants allUsers the object viewer role
+func (c *Client) URL(key string) string {
+	return c.publicURL + "/" + key
+}
@@ -0,0 +1,100 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package i18n
+
+const (
+	InfoName        = "plugin.gcs_storage.backend.info.name"
+	InfoDescription = "plugin.gcs_storage.backend.info.description"
+
+	ConfigEndpointTitle       = "plugin.gcs_storage.backend.config.endpoint.title"
+	ConfigEndpointDescription = "plugin.gcs_storage.backend.config.endpoint.description"
+
+	ConfigBucketNameTitle       = "plugin.gcs_storage.backend.config.bucket_name.title"
+	ConfigBucketNameDescription = "plugin.gcs_storage.backend.config.bucket_name.description"
+
+	ConfigCredentialsJSONTitle       = "plugin.gcs_storage.backend.config.credentials_json.title"
+	ConfigCredentialsJSONDescription = "plugin.gcs_storage.backend.config.credentials_json.description"
+
+	ConfigObjectKeyPrefixTitle       = "plugin.gcs_storage.backend.config.object_key_prefix.title"
+	ConfigObjectKeyPrefixDescription = "plugin.gcs_storage.backend.config.object_key_prefix.description"
+
+	ConfigObjectKeySchemeTitle         = "plugin.gcs_storage.backend.config.object_key_scheme.title"
+	ConfigObjectKeySchemeDescription   = "plugin.gcs_storage.backend.config.object_key_scheme.description"
+	ConfigObjectKeySchemeOptionRandom  = "plugin.gcs_storage.backend.config.object_key_scheme.options.random"
+	ConfigObjectKeySchemeOptionContent = "plugin.gcs_storage.backend.config.object_key_scheme.options.content"
+
+	ConfigObjectKeyTemplateTitle       = "plugin.gcs_storage.backend.config.object_key_template.title"
+	ConfigObjectKeyTemplateDescription = "plugin.gcs_storage.backend.config.object_key_template.description"
+
+	ConfigVisitUrlPrefixTitle       = "plugin.gcs_storage.backend.config.visit_url_prefix.title"
+	ConfigVisitUrlPrefixDescription = "plugin.gcs_storage.backend.config.visit_url_prefix.description"
+
+	ConfigMaxFileSizeTitle       = "plugin.gcs_storage.backend.config.max_file_size.title"
+	ConfigMaxFileSizeDescription = "plugin.gcs_storage.backend.config.max_file_size.description"
+
+	ConfigMaxAvatarSizeTitle       = "plugin.gcs_storage.backend.config.max_avatar_size.title"
+	ConfigMaxAvatarSizeDescription = "plugin.gcs_storage.backend.config.max_avatar_size.description"
+
+	ConfigMaxBrandingSizeTitle       = "plugin.gcs_storage.backend.config.max_branding_size.title"
+	ConfigMaxBrandingSizeDescription = "plugin.gcs_storage.backend.config.max_branding_size.description"
+
+	ConfigAllowedExtensionsTitle       = "plugin.gcs_storage.backend.config.allowed_extensions.title"
+	ConfigAllowedExtensionsDescription = "plugin.gcs_storage.backend.config.allowed_extensions.description"
+
+	ConfigImageProcessingTitle       = "plugin.gcs_storage.backend.config.image_processing.title"
+	ConfigImageProcessingDescription = "plugin.gcs_storage.backend.config.image_processing.description"
+	ConfigImageProcessingLabel       = "plugin.gcs_storage.backend.config.image_processing.label"
+
+	ConfigAvatarMaxDimensionTitle       = "plugin.gcs_storage.backend.config.avatar_max_dimension.title"
+	ConfigAvatarMaxDimensionDescription = "plugin.gcs_storage.backend.config.avatar_max_dimension.description"
+
+	ConfigPostMaxDimensionTitle       = "plugin.gcs_storage.backend.config.post_max_dimension.title"
+	ConfigPostMaxDimensionDescription = "plugin.gcs_storage.backend.config.post_max_dimension.description"
+
+	ConfigBrandingMaxDimensionTitle       = "plugin.gcs_storage.backend.config.branding_max_dimension.title"
+	ConfigBrandingMaxDimensionDescription = "plugin.gcs_storage.backend.config.branding_max_dimension.description"
+
+	ConfigThumbnailSizeTitle       = "plugin.gcs_storage.backend.config.thumbnail_size.title"
+	ConfigThumbnailSizeDescription = "plugin.gcs_storage.backend.config.thumbnail_size.description"
+
+	ConfigConvertWebPTitle       = "plugin.gcs_storage.backend.config.convert_webp.title"
+	ConfigConvertWebPDescription = "plugin.gcs_storage.backend.config.convert_webp.description"
+	ConfigConvertWebPLabel       = "plugin.gcs_storage.backend.config.convert_webp.label"
+
+	ConfigMalwareScanTitle       = "plugin.gcs_storage.backend.config.malware_scan.title"
+	ConfigMalwareScanDescription = "plugin.gcs_storage.backend.config.malware_scan.description"
+	ConfigMalwareScanLabel       = "plugin.gcs_storage.backend.config.malware_scan.label"
+
+	ConfigClamdAddressTitle       = "plugin.gcs_storage.backend.config.clamd_address.title"
+	ConfigClamdAddressDescription = "plugin.gcs_storage.backend.config.clamd_address.description"
+
+	ConfigScanFailurePolicyTitle        = "plugin.gcs_storage.backend.config.scan_failure_policy.title"
+	ConfigScanFailurePolicyDescription  = "plugin.gcs_storage.backend.config.scan_failure_policy.description"
+	ConfigScanFailurePolicyOptionClosed = "plugin.gcs_storage.backend.config.scan_failure_policy.options.closed"
+	ConfigScanFailurePolicyOptionOpen   = "plugin.gcs_storage.backend.config.scan_failure_policy.options.open"
+
+	ErrMisStorageConfig    = "plugin.gcs_storage.backend.err.mis_storage_config"
+	ErrFileNotFound        = "plugin.gcs_storage.backend.err.file_not_found"
+	ErrUnsupportedFileType = "plugin.gcs_storage.backend.err.unsupported_file_type"
+	ErrOverFileSizeLimit   = "plugin.gcs_storage.backend.err.over_file_size_limit"
+	ErrUploadFileFailed    = "plugin.gcs_storage.backend.err.upload_file_failed"
+	ErrInfectedFile        = "plugin.gcs_storage.backend.err.infected_file"
+	ErrScanUnavailable     = "plugin.gcs_storage.backend.err.scan_unavailable"
+)
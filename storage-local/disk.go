@@ -0,0 +1,147 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package local
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// tempPrefix starts the names of the files being written, they are never served
+const tempPrefix = ".upload-"
+
+// errInvalidKey is returned for the keys out of the root directory
+var errInvalidKey = errors.New("invalid object key")
+
+// Disk stores the files in a directory, local or mounted like NFS.
+type Disk struct {
+	root string
+}
+
+// NewDisk returns the disk storing the files in the root directory, which is created if missing.
+func NewDisk(root string) (*Disk, error) {
+	root = strings.TrimSpace(root)
+	if len(root) == 0 {
+		return nil, errors.New("root directory is required")
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("create root directory failed: %v", err)
+	}
+	return &Disk{root: root}, nil
+}
+
+// path returns the path of the key, which must stay in the root directory
+func (d *Disk) path(key string) (string, error) {
+	if len(key) == 0 || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", errInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if len(part) == 0 || part == "." || part == ".." || strings.HasPrefix(part, tempPrefix) {
+			return "", errInvalidKey
+		}
+	}
+	return filepath.Join(d.root, filepath.FromSlash(key)), nil
+}
+
+// Write writes the file at the key. The file is written to a temporary file next to it, then renamed,
+// so that a file is never served half written, even by another server sharing the directory.
+func (d *Disk) Write(key string, file io.Reader) (err error) {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create directory failed: %v", err)
+	}
+	temp, err := os.CreateTemp(dir, tempPrefix+"*")
+	if err != nil {
+		return fmt.Errorf("create file failed: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = temp.Close()
+			_ = os.Remove(temp.Name())
+		}
+	}()
+	if _, err = io.Copy(temp, file); err != nil {
+		return fmt.Errorf("write file failed: %v", err)
+	}
+	if err = temp.Sync(); err != nil {
+		return fmt.Errorf("sync file failed: %v", err)
+	}
+	if err = temp.Close(); err != nil {
+		return fmt.Errorf("close file failed: %v", err)
+	}
+	if err = os.Chmod(temp.Name(), 0644); err != nil {
+		return fmt.Errorf("chmod file failed: %v", err)
+	}
+	if err = os.Rename(temp.Name(), path); err != nil {
+		return fmt.Errorf("rename file failed: %v", err)
+	}
+	return nil
+}
+
+// Exists reports whether a file is stored at the key.
+func (d *Disk) Exists(key string) (bool, error) {
+	path, err := d.path(key)
+	if err != nil {
+		return false, err
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return info.Mode().IsRegular(), nil
+}
+
+// Open opens the file stored at the key, the directories are not files.
+func (d *Disk) Open(key string) (*os.File, fs.FileInfo, error) {
+	path, err := d.path(key)
+	if err != nil {
+		return nil, nil, fs.ErrNotExist
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, nil, err
+	}
+	if !info.Mode().IsRegular() {
+		_ = file.Close()
+		return nil, nil, fs.ErrNotExist
+	}
+	return file, info, nil
+}
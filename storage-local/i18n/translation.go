@@ -0,0 +1,61 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package i18n
+
+const (
+	InfoName        = "plugin.local_storage.backend.info.name"
+	InfoDescription = "plugin.local_storage.backend.info.description"
+
+	ConfigRootDirTitle       = "plugin.local_storage.backend.config.root_dir.title"
+	ConfigRootDirDescription = "plugin.local_storage.backend.config.root_dir.description"
+
+	ConfigVisitUrlPrefixTitle       = "plugin.local_storage.backend.config.visit_url_prefix.title"
+	ConfigVisitUrlPrefixDescription = "plugin.local_storage.backend.config.visit_url_prefix.description"
+
+	ConfigObjectKeySchemeTitle         = "plugin.local_storage.backend.config.object_key_scheme.title"
+	ConfigObjectKeySchemeDescription   = "plugin.local_storage.backend.config.object_key_scheme.description"
+	ConfigObjectKeySchemeOptionRandom  = "plugin.local_storage.backend.config.object_key_scheme.options.random"
+	ConfigObjectKeySchemeOptionContent = "plugin.local_storage.backend.config.object_key_scheme.options.content"
+
+	ConfigObjectKeyTemplateTitle       = "plugin.local_storage.backend.config.object_key_template.title"
+	ConfigObjectKeyTemplateDescription = "plugin.local_storage.backend.config.object_key_template.description"
+
+	ConfigMaxFileSizeTitle       = "plugin.local_storage.backend.config.max_file_size.title"
+	ConfigMaxFileSizeDescription = "plugin.local_storage.backend.config.max_file_size.description"
+
+	ConfigAllowedExtensionsTitle       = "plugin.local_storage.backend.config.allowed_extensions.title"
+	ConfigAllowedExtensionsDescription = "plugin.local_storage.backend.config.allowed_extensions.description"
+
+	ConfigCacheControlTitle       = "plugin.local_storage.backend.config.cache_control.title"
+	ConfigCacheControlDescription = "plugin.local_storage.backend.config.cache_control.description"
+
+	ConfigSignedURLsTitle       = "plugin.local_storage.backend.config.signed_urls.title"
+	ConfigSignedURLsDescription = "plugin.local_storage.backend.config.signed_urls.description"
+	ConfigSignedURLsLabel       = "plugin.local_storage.backend.config.signed_urls.label"
+
+	ConfigSigningSecretTitle       = "plugin.local_storage.backend.config.signing_secret.title"
+	ConfigSigningSecretDescription = "plugin.local_storage.backend.config.signing_secret.description"
+
+	ErrMisStorageConfig    = "plugin.local_storage.backend.err.mis_storage_config"
+	ErrFileNotFound        = "plugin.local_storage.backend.err.file_not_found"
+	ErrUnsupportedFileType = "plugin.local_storage.backend.err.unsupported_file_type"
+	ErrOverFileSizeLimit   = "plugin.local_storage.backend.err.over_file_size_limit"
+	ErrUploadFileFailed    = "plugin.local_storage.backend.err.upload_file_failed"
+)
@@ -0,0 +1,301 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package local
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/apache/incubator-answer-plugins/util"
+	"strings"
+
+	"github.com/apache/incubator-answer-plugins/storage-local/i18n"
+	"github.com/apache/incubator-answer-plugins/util/storageext"
+	"github.com/apache/incubator-answer/plugin"
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed  info.yaml
+var Info embed.FS
+
+type Storage struct {
+	Config *StorageConfig
+	disk   *Disk
+	rules  *storageext.Rules
+	keys   *storageext.ObjectKeys
+}
+
+type StorageConfig struct {
+	RootDir           string `json:"root_dir"`
+	VisitUrlPrefix    string `json:"visit_url_prefix"`
+	ObjectKeyScheme   string `json:"object_key_scheme"`
+	ObjectKeyTemplate string `json:"object_key_template"`
+	MaxFileSize       string `json:"max_file_size"`
+	AllowedExts       string `json:"allowed_extensions"`
+	CacheControl      string `json:"cache_control"`
+	SignedURLs        bool   `json:"signed_urls"`
+	SigningSecret     string `json:"signing_secret"`
+}
+
+func init() {
+	keys, _ := storageext.NewObjectKeys("", storageext.KeySchemeRandom, storageext.ShardedKeyTemplate)
+	plugin.Register(&Storage{
+		Config: &StorageConfig{},
+		rules:  &storageext.Rules{},
+		keys:   keys,
+	})
+}
+
+func (s *Storage) Info() plugin.Info {
+	info := &util.Info{}
+	info.GetInfo(Info)
+
+	return plugin.Info{
+		Name:        plugin.MakeTranslator(i18n.InfoName),
+		SlugName:    info.SlugName,
+		Description: plugin.MakeTranslator(i18n.InfoDescription),
+		Author:      info.Author,
+		Version:     info.Version,
+		Link:        info.Link,
+	}
+}
+
+func (s *Storage) UploadFile(ctx *plugin.GinContext, source plugin.UploadSource) (resp plugin.UploadFileResponse) {
+	resp = plugin.UploadFileResponse{}
+	if s.disk == nil {
+		resp.OriginalError = errors.New("root directory not configured")
+		resp.DisplayErrorMsg = plugin.MakeTranslator(i18n.ErrMisStorageConfig)
+		return resp
+	}
+
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		resp.OriginalError = fmt.Errorf("get file failed: %v", err)
+		resp.DisplayErrorMsg = plugin.MakeTranslator(i18n.ErrFileNotFound)
+		return resp
+	}
+
+	if !s.rules.AllowExt(file.Filename, source) {
+		resp.OriginalError = fmt.Errorf("file type not allowed")
+		resp.DisplayErrorMsg = plugin.MakeTranslator(i18n.ErrUnsupportedFileType)
+		return resp
+	}
+
+	if file.Size > s.rules.MaxFileSize(source) {
+		resp.OriginalError = fmt.Errorf("file size too large")
+		resp.DisplayErrorMsg = plugin.MakeTranslator(i18n.ErrOverFileSizeLimit)
+		return resp
+	}
+
+	openFile, err := file.Open()
+	if err != nil {
+		resp.OriginalError = fmt.Errorf("get file failed: %v", err)
+		resp.DisplayErrorMsg = plugin.MakeTranslator(i18n.ErrFileNotFound)
+		return resp
+	}
+	defer openFile.Close()
+
+	objectKey, err := s.keys.Key(source, file.Filename, openFile)
+	if err != nil {
+		resp.OriginalError = fmt.Errorf("read file failed: %v", err)
+		resp.DisplayErrorMsg = plugin.MakeTranslator(i18n.ErrUploadFileFailed)
+		return resp
+	}
+	if s.keys.ContentAddressed() {
+		// the same content is stored once
+		exists, err := s.disk.Exists(objectKey)
+		if err != nil {
+			resp.OriginalError = err
+			resp.DisplayErrorMsg = plugin.MakeTranslator(i18n.ErrUploadFileFailed)
+			return resp
+		}
+		if exists {
+			resp.FullURL = s.fileURL(objectKey)
+			return resp
+		}
+	}
+	if err = s.disk.Write(objectKey, openFile); err != nil {
+		resp.OriginalError = err
+		resp.DisplayErrorMsg = plugin.MakeTranslator(i18n.ErrUploadFileFailed)
+		return resp
+	}
+	resp.FullURL = s.fileURL(objectKey)
+	return resp
+}
+
+func (s *Storage) RegisterUnAuthRouter(r *gin.RouterGroup) {
+	r.GET("/local_storage/file/*object_key", s.serveFile)
+	r.HEAD("/local_storage/file/*object_key", s.serveFile)
+}
+
+func (s *Storage) RegisterAuthUserRouter(r *gin.RouterGroup) {
+}
+
+func (s *Storage) RegisterAuthAdminRouter(r *gin.RouterGroup) {
+}
+
+// buildRules builds the upload rules from the config, the empty fields keep the defaults
+func (s *Storage) buildRules() *storageext.Rules {
+	rules := &storageext.Rules{
+		Extensions: make(map[plugin.UploadSource]map[string]bool),
+		MaxSizes:   make(map[plugin.UploadSource]int64),
+		MaxSize:    storageext.ParseSize(s.Config.MaxFileSize),
+	}
+	if exts := storageext.ParseExtensions(s.Config.AllowedExts); exts != nil {
+		rules.Extensions[plugin.UserPost] = exts
+	}
+	return rules
+}
+
+func (s *Storage) ConfigFields() []plugin.ConfigField {
+	return []plugin.ConfigField{
+		{
+			Name:        "root_dir",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigRootDirTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigRootDirDescription),
+			Required:    true,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.RootDir,
+		},
+		{
+			Name:        "visit_url_prefix",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigVisitUrlPrefixTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigVisitUrlPrefixDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.VisitUrlPrefix,
+		},
+		{
+			Name:        "object_key_scheme",
+			Type:        plugin.ConfigTypeSelect,
+			Title:       plugin.MakeTranslator(i18n.ConfigObjectKeySchemeTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigObjectKeySchemeDescription),
+			Value:       s.Config.ObjectKeyScheme,
+			Options: []plugin.ConfigFieldOption{
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigObjectKeySchemeOptionRandom),
+					Value: storageext.KeySchemeRandom,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigObjectKeySchemeOptionContent),
+					Value: storageext.KeySchemeContent,
+				},
+			},
+		},
+		{
+			Name:        "object_key_template",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigObjectKeyTemplateTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigObjectKeyTemplateDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.ObjectKeyTemplate,
+		},
+		{
+			Name:        "max_file_size",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigMaxFileSizeTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigMaxFileSizeDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeNumber,
+			},
+			Value: s.Config.MaxFileSize,
+		},
+		{
+			Name:        "allowed_extensions",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigAllowedExtensionsTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigAllowedExtensionsDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.AllowedExts,
+		},
+		{
+			Name:        "cache_control",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigCacheControlTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigCacheControlDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.CacheControl,
+		},
+		{
+			Name:        "signed_urls",
+			Type:        plugin.ConfigTypeSwitch,
+			Title:       plugin.MakeTranslator(i18n.ConfigSignedURLsTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigSignedURLsDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				Label: plugin.MakeTranslator(i18n.ConfigSignedURLsLabel),
+			},
+			Value: s.Config.SignedURLs,
+		},
+		{
+			Name:        "signing_secret",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigSigningSecretTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigSigningSecretDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypePassword,
+			},
+			Value: s.Config.SigningSecret,
+		},
+	}
+}
+
+func (s *Storage) ConfigReceiver(config []byte) error {
+	c := &StorageConfig{}
+	_ = json.Unmarshal(config, c)
+	s.Config = c
+	s.rules = s.buildRules()
+	template := s.Config.ObjectKeyTemplate
+	if len(strings.TrimSpace(template)) == 0 {
+		// a directory holding all the files would be slow to list, and to back up
+		template = storageext.ShardedKeyTemplate
+	}
+	keys, err := storageext.NewObjectKeys("", s.Config.ObjectKeyScheme, template)
+	if err != nil {
+		return err
+	}
+	s.keys = keys
+	if s.Config.SignedURLs && len(s.Config.SigningSecret) < minSecretLen {
+		return fmt.Errorf("signing secret must have at least %d characters", minSecretLen)
+	}
+	disk, err := NewDisk(s.Config.RootDir)
+	if err != nil {
+		return err
+	}
+	s.disk = disk
+	return nil
+}
@@ -0,0 +1,202 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package local
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apache/incubator-answer/plugin"
+	"github.com/gin-gonic/gin"
+)
+
+func TestDisk(t *testing.T) {
+	disk, err := NewDisk(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = disk.Write("post/ab/cd/file.txt", strings.NewReader("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := disk.Exists("post/ab/cd/file.txt"); !exists || err != nil {
+		t.Errorf("Exists() = %v, %v", exists, err)
+	}
+	if exists, _ := disk.Exists("post/ab/cd/other.txt"); exists {
+		t.Error("Exists(missing) = true")
+	}
+	file, info, err := disk.Open("post/ab/cd/file.txt")
+	if err != nil || info.Size() != 5 {
+		t.Fatalf("Open() = %v, %v", info, err)
+	}
+	_ = file.Close()
+	if _, _, err = disk.Open("post/ab"); err == nil {
+		t.Error("Open(directory) no error")
+	}
+
+	// no temporary file is left
+	entries, _ := os.ReadDir(filepath.Join(disk.root, "post", "ab", "cd"))
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d files, want 1", len(entries))
+	}
+
+	for _, key := range []string{"", "/etc/passwd", "../secret", "post/../../secret", "post//a", "post/.upload-123", `post\a`} {
+		if err = disk.Write(key, strings.NewReader("x")); err != errInvalidKey {
+			t.Errorf("Write(%q) = %v, want errInvalidKey", key, err)
+		}
+		if _, _, err = disk.Open(key); err == nil {
+			t.Errorf("Open(%q) no error", key)
+		}
+	}
+}
+
+func newTestStorage(t *testing.T, config StorageConfig) (*Storage, *gin.Engine) {
+	gin.SetMode(gin.TestMode)
+	s := &Storage{Config: &StorageConfig{}}
+	config.RootDir = t.TempDir()
+	data, _ := json.Marshal(config)
+	if err := s.ConfigReceiver(data); err != nil {
+		t.Fatal(err)
+	}
+	plugin.StatusManager.Enable(s.Info().SlugName, true)
+
+	r := gin.New()
+	group := r.Group("/answer/api/v1")
+	s.RegisterUnAuthRouter(group)
+	group.POST("/upload", func(ctx *gin.Context) {
+		resp := s.UploadFile(ctx, plugin.UserPost)
+		if resp.OriginalError != nil {
+			ctx.String(http.StatusBadRequest, resp.OriginalError.Error())
+			return
+		}
+		ctx.String(http.StatusOK, resp.FullURL)
+	})
+	return s, r
+}
+
+func upload(t *testing.T, r *gin.Engine, filename, content string) string {
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	part, _ := form.CreateFormFile("file", filename)
+	_, _ = part.Write([]byte(content))
+	_ = form.Close()
+	req := httptest.NewRequest(http.MethodPost, "/answer/api/v1/upload", body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("upload %s: %s", filename, w.Body.String())
+	}
+	return w.Body.String()
+}
+
+func get(r *gin.Engine, url string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestUploadAndServe(t *testing.T) {
+	_, r := newTestStorage(t, StorageConfig{})
+	url := upload(t, r, "photo.png", "0123456789")
+	if !strings.HasPrefix(url, fileRoutePath+"post/") || !strings.HasSuffix(url, ".png") {
+		t.Fatalf("url = %s", url)
+	}
+	// {source}/{shard}/{hash}{ext}
+	if parts := strings.Split(strings.TrimPrefix(url, fileRoutePath), "/"); len(parts) != 4 {
+		t.Errorf("key of %s not sharded", url)
+	}
+
+	w := get(r, url, nil)
+	if w.Code != http.StatusOK || w.Body.String() != "0123456789" {
+		t.Fatalf("GET = %d %q", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Cache-Control") != defaultCacheControl || w.Header().Get("Content-Type") != "image/png" {
+		t.Errorf("headers = %v", w.Header())
+	}
+	etag := w.Header().Get("ETag")
+	if len(etag) == 0 {
+		t.Fatal("no ETag")
+	}
+	if w = get(r, url, map[string]string{"If-None-Match": etag}); w.Code != http.StatusNotModified {
+		t.Errorf("GET If-None-Match = %d, want 304", w.Code)
+	}
+	w = get(r, url, map[string]string{"Range": "bytes=2-4"})
+	if w.Code != http.StatusPartialContent || w.Body.String() != "234" {
+		t.Errorf("GET Range = %d %q", w.Code, w.Body.String())
+	}
+	if w = get(r, fileRoutePath+"post/missing.png", nil); w.Code != http.StatusNotFound {
+		t.Errorf("GET missing = %d, want 404", w.Code)
+	}
+}
+
+func TestContentAddressed(t *testing.T) {
+	s, r := newTestStorage(t, StorageConfig{ObjectKeyScheme: "content"})
+	first := upload(t, r, "logo.png", "same logo")
+	second := upload(t, r, "logo.png", "same logo")
+	if first != second {
+		t.Errorf("same content stored twice: %s, %s", first, second)
+	}
+	if other := upload(t, r, "logo.png", "other logo"); other == first {
+		t.Error("other content got the same key")
+	}
+	var files int
+	_ = filepath.Walk(s.disk.root, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			files++
+		}
+		return nil
+	})
+	if files != 2 {
+		t.Errorf("%d files stored, want 2", files)
+	}
+}
+
+func TestSignedURLs(t *testing.T) {
+	if err := (&Storage{Config: &StorageConfig{}}).ConfigReceiver([]byte(`{"root_dir":"` + t.TempDir() + `","signed_urls":true,"signing_secret":"short"}`)); err == nil {
+		t.Error("short secret accepted")
+	}
+
+	_, r := newTestStorage(t, StorageConfig{SignedURLs: true, SigningSecret: "0123456789abcdef"})
+	url := upload(t, r, "photo.png", "signed")
+	if !strings.Contains(url, "?sig=") {
+		t.Fatalf("url %s not signed", url)
+	}
+	if w := get(r, url, nil); w.Code != http.StatusOK || w.Body.String() != "signed" {
+		t.Errorf("GET signed = %d %q", w.Code, w.Body.String())
+	}
+	unsigned := url[:strings.Index(url, "?")]
+	if w := get(r, unsigned, nil); w.Code != http.StatusNotFound {
+		t.Errorf("GET unsigned = %d, want 404", w.Code)
+	}
+	if w := get(r, unsigned+"?sig=forged", nil); w.Code != http.StatusNotFound {
+		t.Errorf("GET forged = %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,118 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package local
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/apache/incubator-answer-plugins/util/storageext"
+	"github.com/apache/incubator-answer/plugin"
+	"github.com/gin-gonic/gin"
+	"github.com/segmentfault/pacman/log"
+)
+
+const (
+	// fileRoutePath is the path of the file route under the Answer API
+	fileRoutePath = "/answer/api/v1/local_storage/file/"
+
+	// the keys never change content, so the files can be cached for good
+	defaultCacheControl = "public, max-age=31536000, immutable"
+
+	// signatureParam is the query parameter of the URL signatures
+	signatureParam = "sig"
+
+	minSecretLen = 16
+)
+
+// fileURL returns the URL saved in the content for the file, signed when the URLs are
+func (s *Storage) fileURL(objectKey string) string {
+	if len(s.Config.VisitUrlPrefix) > 0 {
+		return s.Config.VisitUrlPrefix + objectKey
+	}
+	fileURL := strings.TrimSuffix(plugin.SiteURL(), "/") + fileRoutePath + escapeKey(objectKey)
+	if s.Config.SignedURLs {
+		fileURL += "?" + signatureParam + "=" + s.sign(objectKey)
+	}
+	return fileURL
+}
+
+// sign returns the HMAC-SHA256 of the key. The signature never expires,
+// since the URLs are saved in the contents, and changing the secret breaks all of them.
+func (s *Storage) sign(objectKey string) string {
+	mac := hmac.New(sha256.New, []byte(s.Config.SigningSecret))
+	mac.Write([]byte(objectKey))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Storage) validSignature(objectKey, signature string) bool {
+	return hmac.Equal([]byte(s.sign(objectKey)), []byte(signature))
+}
+
+// escapeKey escapes each part of the key, keeping the slashes
+func escapeKey(objectKey string) string {
+	parts := strings.Split(objectKey, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+// serveFile serves a stored file. http.ServeContent answers the conditional and range requests,
+// from the ETag and the modification time of the file.
+func (s *Storage) serveFile(ctx *gin.Context) {
+	if !plugin.StatusManager.IsEnabled(s.Info().SlugName) || s.disk == nil {
+		storageext.HandleNotFound(ctx)
+		return
+	}
+	objectKey := strings.TrimPrefix(ctx.Param("object_key"), "/")
+	if s.Config.SignedURLs && !s.validSignature(objectKey, ctx.Query(signatureParam)) {
+		storageext.HandleNotFound(ctx)
+		return
+	}
+	file, info, err := s.disk.Open(objectKey)
+	if err != nil {
+		log.Debugf("open file %s failed: %v", objectKey, err)
+		storageext.HandleNotFound(ctx)
+		return
+	}
+	defer file.Close()
+
+	cacheControl := s.Config.CacheControl
+	if len(cacheControl) == 0 {
+		cacheControl = defaultCacheControl
+	}
+	contentType := storageext.TypeByExtension(objectKey)
+	header := ctx.Writer.Header()
+	header.Set("Cache-Control", cacheControl)
+	header.Set("Content-Type", contentType)
+	header.Set("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()))
+	header.Set("X-Content-Type-Options", "nosniff")
+	if disposition := storageext.ContentDisposition(contentType, path.Base(objectKey)); len(disposition) > 0 {
+		header.Set("Content-Disposition", disposition)
+	}
+	http.ServeContent(ctx.Writer, ctx.Request, path.Base(objectKey), info.ModTime(), file)
+}
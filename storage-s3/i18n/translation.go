@@ -44,8 +44,112 @@ const (
 	ConfigDisableSSLTitle            = "plugin.s3_storage.backend.config.disable_ssl.title"
 	ConfigDisableSSLDescription      = "plugin.s3_storage.backend.config.disable_ssl.description"
 
+	ConfigMaxAvatarSizeTitle       = "plugin.s3_storage.backend.config.max_avatar_size.title"
+	ConfigMaxAvatarSizeDescription = "plugin.s3_storage.backend.config.max_avatar_size.description"
+
+	ConfigMaxBrandingSizeTitle       = "plugin.s3_storage.backend.config.max_branding_size.title"
+	ConfigMaxBrandingSizeDescription = "plugin.s3_storage.backend.config.max_branding_size.description"
+
+	ConfigAllowedExtensionsTitle       = "plugin.s3_storage.backend.config.allowed_extensions.title"
+	ConfigAllowedExtensionsDescription = "plugin.s3_storage.backend.config.allowed_extensions.description"
+
+	ConfigDirectUploadTitle       = "plugin.s3_storage.backend.config.direct_upload.title"
+	ConfigDirectUploadDescription = "plugin.s3_storage.backend.config.direct_upload.description"
+	ConfigDirectUploadLabel       = "plugin.s3_storage.backend.config.direct_upload.label"
+
+	ConfigACLTitle            = "plugin.s3_storage.backend.config.acl.title"
+	ConfigACLDescription      = "plugin.s3_storage.backend.config.acl.description"
+	ConfigACLOptionDefault    = "plugin.s3_storage.backend.config.acl.options.default"
+	ConfigACLOptionPrivate    = "plugin.s3_storage.backend.config.acl.options.private"
+	ConfigACLOptionPublicRead = "plugin.s3_storage.backend.config.acl.options.public_read"
+
+	ConfigAccessModeTitle         = "plugin.s3_storage.backend.config.access_mode.title"
+	ConfigAccessModeDescription   = "plugin.s3_storage.backend.config.access_mode.description"
+	ConfigAccessModeOptionPublic  = "plugin.s3_storage.backend.config.access_mode.options.public"
+	ConfigAccessModeOptionPrivate = "plugin.s3_storage.backend.config.access_mode.options.private"
+
+	ConfigPrivateDeliveryTitle          = "plugin.s3_storage.backend.config.private_delivery.title"
+	ConfigPrivateDeliveryDescription    = "plugin.s3_storage.backend.config.private_delivery.description"
+	ConfigPrivateDeliveryOptionRedirect = "plugin.s3_storage.backend.config.private_delivery.options.redirect"
+	ConfigPrivateDeliveryOptionProxy    = "plugin.s3_storage.backend.config.private_delivery.options.proxy"
+
+	ConfigSignedURLExpiryTitle       = "plugin.s3_storage.backend.config.signed_url_expiry.title"
+	ConfigSignedURLExpiryDescription = "plugin.s3_storage.backend.config.signed_url_expiry.description"
+
+	ConfigAddressingTitle         = "plugin.s3_storage.backend.config.addressing.title"
+	ConfigAddressingDescription   = "plugin.s3_storage.backend.config.addressing.description"
+	ConfigAddressingOptionPath    = "plugin.s3_storage.backend.config.addressing.options.path"
+	ConfigAddressingOptionVirtual = "plugin.s3_storage.backend.config.addressing.options.virtual"
+
+	ConfigServerSideEncryptionTitle        = "plugin.s3_storage.backend.config.server_side_encryption.title"
+	ConfigServerSideEncryptionDescription  = "plugin.s3_storage.backend.config.server_side_encryption.description"
+	ConfigServerSideEncryptionOptionNone   = "plugin.s3_storage.backend.config.server_side_encryption.options.none"
+	ConfigServerSideEncryptionOptionSSES3  = "plugin.s3_storage.backend.config.server_side_encryption.options.sse_s3"
+	ConfigServerSideEncryptionOptionSSEKMS = "plugin.s3_storage.backend.config.server_side_encryption.options.sse_kms"
+
+	ConfigSSEKMSKeyIDTitle       = "plugin.s3_storage.backend.config.sse_kms_key_id.title"
+	ConfigSSEKMSKeyIDDescription = "plugin.s3_storage.backend.config.sse_kms_key_id.description"
+
+	ConfigImageProcessingTitle       = "plugin.s3_storage.backend.config.image_processing.title"
+	ConfigImageProcessingDescription = "plugin.s3_storage.backend.config.image_processing.description"
+	ConfigImageProcessingLabel       = "plugin.s3_storage.backend.config.image_processing.label"
+
+	ConfigAvatarMaxDimensionTitle       = "plugin.s3_storage.backend.config.avatar_max_dimension.title"
+	ConfigAvatarMaxDimensionDescription = "plugin.s3_storage.backend.config.avatar_max_dimension.description"
+
+	ConfigPostMaxDimensionTitle       = "plugin.s3_storage.backend.config.post_max_dimension.title"
+	ConfigPostMaxDimensionDescription = "plugin.s3_storage.backend.config.post_max_dimension.description"
+
+	ConfigBrandingMaxDimensionTitle       = "plugin.s3_storage.backend.config.branding_max_dimension.title"
+	ConfigBrandingMaxDimensionDescription = "plugin.s3_storage.backend.config.branding_max_dimension.description"
+
+	ConfigThumbnailSizeTitle       = "plugin.s3_storage.backend.config.thumbnail_size.title"
+	ConfigThumbnailSizeDescription = "plugin.s3_storage.backend.config.thumbnail_size.description"
+
+	ConfigConvertWebPTitle       = "plugin.s3_storage.backend.config.convert_webp.title"
+	ConfigConvertWebPDescription = "plugin.s3_storage.backend.config.convert_webp.description"
+	ConfigConvertWebPLabel       = "plugin.s3_storage.backend.config.convert_webp.label"
+
+	ConfigObjectKeySchemeTitle         = "plugin.s3_storage.backend.config.object_key_scheme.title"
+	ConfigObjectKeySchemeDescription   = "plugin.s3_storage.backend.config.object_key_scheme.description"
+	ConfigObjectKeySchemeOptionRandom  = "plugin.s3_storage.backend.config.object_key_scheme.options.random"
+	ConfigObjectKeySchemeOptionContent = "plugin.s3_storage.backend.config.object_key_scheme.options.content"
+
+	ConfigObjectKeyTemplateTitle       = "plugin.s3_storage.backend.config.object_key_template.title"
+	ConfigObjectKeyTemplateDescription = "plugin.s3_storage.backend.config.object_key_template.description"
+
+	ConfigMalwareScanTitle       = "plugin.s3_storage.backend.config.malware_scan.title"
+	ConfigMalwareScanDescription = "plugin.s3_storage.backend.config.malware_scan.description"
+	ConfigMalwareScanLabel       = "plugin.s3_storage.backend.config.malware_scan.label"
+
+	ConfigClamdAddressTitle       = "plugin.s3_storage.backend.config.clamd_address.title"
+	ConfigClamdAddressDescription = "plugin.s3_storage.backend.config.clamd_address.description"
+
+	ConfigScanFailurePolicyTitle        = "plugin.s3_storage.backend.config.scan_failure_policy.title"
+	ConfigScanFailurePolicyDescription  = "plugin.s3_storage.backend.config.scan_failure_policy.description"
+	ConfigScanFailurePolicyOptionClosed = "plugin.s3_storage.backend.config.scan_failure_policy.options.closed"
+	ConfigScanFailurePolicyOptionOpen   = "plugin.s3_storage.backend.config.scan_failure_policy.options.open"
+
+	ConfigGCModeTitle            = "plugin.s3_storage.backend.config.gc_mode.title"
+	ConfigGCModeDescription      = "plugin.s3_storage.backend.config.gc_mode.description"
+	ConfigGCModeOptionOff        = "plugin.s3_storage.backend.config.gc_mode.options.off"
+	ConfigGCModeOptionReport     = "plugin.s3_storage.backend.config.gc_mode.options.report"
+	ConfigGCModeOptionQuarantine = "plugin.s3_storage.backend.config.gc_mode.options.quarantine"
+
+	ConfigGCIntervalTitle       = "plugin.s3_storage.backend.config.gc_interval.title"
+	ConfigGCIntervalDescription = "plugin.s3_storage.backend.config.gc_interval.description"
+
+	ConfigGCGracePeriodTitle       = "plugin.s3_storage.backend.config.gc_grace_period.title"
+	ConfigGCGracePeriodDescription = "plugin.s3_storage.backend.config.gc_grace_period.description"
+
+	ConfigGCQuarantinePeriodTitle       = "plugin.s3_storage.backend.config.gc_quarantine_period.title"
+	ConfigGCQuarantinePeriodDescription = "plugin.s3_storage.backend.config.gc_quarantine_period.description"
+
+	ErrMisStorageConfig    = "plugin.s3_storage.backend.err.mis_storage_config"
 	ErrFileNotFound        = "plugin.s3_storage.backend.err.file_not_found"
 	ErrUnsupportedFileType = "plugin.s3_storage.backend.err.unsupported_file_type"
 	ErrOverFileSizeLimit   = "plugin.s3_storage.backend.err.over_file_size_limit"
 	ErrUploadFileFailed    = "plugin.s3_storage.backend.err.upload_file_failed"
+	ErrInfectedFile        = "plugin.s3_storage.backend.err.infected_file"
+	ErrScanUnavailable     = "plugin.s3_storage.backend.err.scan_unavailable"
 )
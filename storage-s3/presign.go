@@ -0,0 +1,264 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package s3
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/incubator-answer-plugins/storage-s3/i18n"
+	"github.com/apache/incubator-answer-plugins/util/imageproc"
+	"github.com/apache/incubator-answer-plugins/util/storageext"
+	"github.com/apache/incubator-answer/plugin"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/gin-gonic/gin"
+)
+
+// presignExpiry is how long a presigned upload URL can be used
+const presignExpiry = 10 * time.Minute
+
+type PresignReq struct {
+	Filename string              `json:"filename" binding:"required"`
+	Size     int64               `json:"size" binding:"required"`
+	Source   plugin.UploadSource `json:"source"`
+}
+
+type PresignResp struct {
+	ObjectKey string            `json:"object_key"`
+	Method    string            `json:"method"`
+	URL       string            `json:"url"`
+	Fields    map[string]string `json:"fields"`
+	ExpiresAt int64             `json:"expires_at"`
+	Token     string            `json:"token"`
+}
+
+type ConfirmReq struct {
+	ObjectKey string              `json:"object_key" binding:"required"`
+	Source    plugin.UploadSource `json:"source"`
+	Token     string              `json:"token" binding:"required"`
+}
+
+type ConfirmResp struct {
+	URL string `json:"url"`
+}
+
+// directUploadEnabled reports whether presigned uploads can be used
+func (s *Storage) directUploadEnabled() bool {
+	return plugin.StatusManager.IsEnabled(s.Info().SlugName) && s.Config.DirectUpload && s.Client != nil
+}
+
+// userSource returns the upload source of a user request, posts by default
+func userSource(source plugin.UploadSource) (plugin.UploadSource, bool) {
+	switch source {
+	case "":
+		return plugin.UserPost, true
+	case plugin.UserPost, plugin.UserAvatar:
+		return source, true
+	default:
+		return "", false
+	}
+}
+
+// presign checks the file like UploadFile does and presigns its upload
+func (s *Storage) presign(ctx *gin.Context) {
+	if !s.directUploadEnabled() {
+		storageext.HandleNotFound(ctx)
+		return
+	}
+	req := &PresignReq{}
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		storageext.HandleUploadError(ctx, http.StatusBadRequest, plugin.UploadFileResponse{
+			OriginalError:   err,
+			DisplayErrorMsg: plugin.MakeTranslator(i18n.ErrFileNotFound),
+		})
+		return
+	}
+	source, ok := userSource(req.Source)
+	if !ok || !s.rules.AllowExt(req.Filename, source) {
+		storageext.HandleUploadError(ctx, http.StatusBadRequest, plugin.UploadFileResponse{
+			OriginalError:   fmt.Errorf("file type not allowed"),
+			DisplayErrorMsg: plugin.MakeTranslator(i18n.ErrUnsupportedFileType),
+		})
+		return
+	}
+	if req.Size <= 0 || req.Size > s.rules.MaxFileSize(source) {
+		storageext.HandleUploadError(ctx, http.StatusBadRequest, plugin.UploadFileResponse{
+			OriginalError:   fmt.Errorf("file size too large"),
+			DisplayErrorMsg: plugin.MakeTranslator(i18n.ErrOverFileSizeLimit),
+		})
+		return
+	}
+
+	contentType := storageext.TypeByExtension(req.Filename)
+	if _, ok := s.uploader().Images.Options(source); ok && imageproc.Supported(contentType) {
+		// the images must go through UploadFile to be processed
+		storageext.HandleUploadError(ctx, http.StatusBadRequest, plugin.UploadFileResponse{
+			OriginalError:   fmt.Errorf("images are processed, direct upload not allowed"),
+			DisplayErrorMsg: plugin.MakeTranslator(i18n.ErrUnsupportedFileType),
+		})
+		return
+	}
+	objectKey := s.keys.RandomKey(source, req.Filename)
+	url, fields, err := s.Client.PresignPostObject(objectKey, contentType,
+		storageext.ContentDisposition(contentType, req.Filename), req.Size, presignExpiry)
+	if err != nil {
+		storageext.HandleUploadError(ctx, http.StatusInternalServerError, plugin.UploadFileResponse{
+			OriginalError:   err,
+			DisplayErrorMsg: plugin.MakeTranslator(i18n.ErrUploadFileFailed),
+		})
+		return
+	}
+	expiresAt := time.Now().Add(presignExpiry).Unix()
+	resp := &PresignResp{
+		ObjectKey: objectKey,
+		Method:    http.MethodPost,
+		URL:       url,
+		Fields:    fields,
+		ExpiresAt: expiresAt,
+		Token:     signUpload(s.uploadKey, objectKey, source, expiresAt),
+	}
+	storageext.HandleResponse(ctx, resp)
+}
+
+// confirm checks the uploaded object against the upload rules and returns its URL.
+// Only the objects presigned for the user are confirmed, as the token of presign proves,
+// and such an object breaking the rules, like a script uploaded as an image, is deleted.
+func (s *Storage) confirm(ctx *gin.Context) {
+	if !s.directUploadEnabled() {
+		storageext.HandleNotFound(ctx)
+		return
+	}
+	req := &ConfirmReq{}
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		storageext.HandleUploadError(ctx, http.StatusBadRequest, plugin.UploadFileResponse{
+			OriginalError:   err,
+			DisplayErrorMsg: plugin.MakeTranslator(i18n.ErrFileNotFound),
+		})
+		return
+	}
+	source, ok := userSource(req.Source)
+	if !ok || !verifyUpload(s.uploadKey, req.ObjectKey, source, req.Token, time.Now()) {
+		storageext.HandleUploadError(ctx, http.StatusBadRequest, plugin.UploadFileResponse{
+			OriginalError:   fmt.Errorf("object key %s not issued for %s", req.ObjectKey, req.Source),
+			DisplayErrorMsg: plugin.MakeTranslator(i18n.ErrFileNotFound),
+		})
+		return
+	}
+
+	head, err := s.Client.HeadObject(req.ObjectKey)
+	if err != nil {
+		storageext.HandleUploadError(ctx, http.StatusBadRequest, plugin.UploadFileResponse{
+			OriginalError:   fmt.Errorf("head object failed: %v", err),
+			DisplayErrorMsg: plugin.MakeTranslator(i18n.ErrFileNotFound),
+		})
+		return
+	}
+	resp := s.checkUploadedObject(req.ObjectKey, source, head.ContentLength, aws.StringValue(head.ContentType))
+	if resp.OriginalError != nil {
+		if err = s.Client.DeleteObject(req.ObjectKey); err != nil {
+			resp.OriginalError = fmt.Errorf("%v, %v", resp.OriginalError, err)
+		}
+		storageext.HandleUploadError(ctx, http.StatusBadRequest, resp)
+		return
+	}
+	storageext.HandleResponse(ctx, &ConfirmResp{URL: s.fileURL(req.ObjectKey)})
+}
+
+// newUploadKey returns the key signing the upload tokens, derived from the access key secret so that
+// all the instances of Answer share it, or random when there is no secret.
+func newUploadKey(secret string) []byte {
+	if len(secret) > 0 {
+		return hmacSHA256([]byte(secret), "answer-direct-upload")
+	}
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	return key
+}
+
+// signUpload returns the token of a presigned upload, its expiry and the signature of the object key,
+// source and expiry, which confirm requires.
+func signUpload(key []byte, objectKey string, source plugin.UploadSource, expiresAt int64) string {
+	expiry := strconv.FormatInt(expiresAt, 10)
+	mac := hmacSHA256(key, objectKey+"\n"+string(source)+"\n"+expiry)
+	return expiry + "." + base64.RawURLEncoding.EncodeToString(mac)
+}
+
+// verifyUpload reports whether the token was issued by presign for the object key and source. The token stays
+// valid for one more presign expiry after the presigned URL expires, for an upload started just before.
+func verifyUpload(key []byte, objectKey string, source plugin.UploadSource, token string, now time.Time) bool {
+	expiry, _, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || now.After(time.Unix(expiresAt, 0).Add(presignExpiry)) {
+		return false
+	}
+	return hmac.Equal([]byte(token), []byte(signUpload(key, objectKey, source, expiresAt)))
+}
+
+// checkUploadedObject checks the extension, size and content of an object uploaded with a presigned URL
+func (s *Storage) checkUploadedObject(objectKey string, source plugin.UploadSource, size *int64, contentType string) (
+	resp plugin.UploadFileResponse) {
+	if !s.rules.AllowExt(objectKey, source) {
+		resp.OriginalError = fmt.Errorf("file type not allowed")
+		resp.DisplayErrorMsg = plugin.MakeTranslator(i18n.ErrUnsupportedFileType)
+		return resp
+	}
+	if size == nil || *size > s.rules.MaxFileSize(source) {
+		resp.OriginalError = fmt.Errorf("file size too large")
+		resp.DisplayErrorMsg = plugin.MakeTranslator(i18n.ErrOverFileSizeLimit)
+		return resp
+	}
+	head, err := s.Client.GetObjectHead(objectKey, storageext.SniffLen)
+	if err != nil {
+		resp.OriginalError = fmt.Errorf("read object failed: %v", err)
+		resp.DisplayErrorMsg = plugin.MakeTranslator(i18n.ErrUploadFileFailed)
+		return resp
+	}
+	sniffed := storageext.DetectContentType(objectKey, head)
+	if storageext.MediaType(sniffed) != storageext.MediaType(contentType) {
+		resp.OriginalError = fmt.Errorf("content type %s of the object is %s", contentType, sniffed)
+		resp.DisplayErrorMsg = plugin.MakeTranslator(i18n.ErrUnsupportedFileType)
+		return resp
+	}
+	if s.scanner != nil {
+		if err = s.scanObject(objectKey); err != nil {
+			return storageext.ScanError(err, uploadMessages)
+		}
+	}
+	return resp
+}
+
+// scanObject streams the uploaded object to the scanner, for the direct uploads
+func (s *Storage) scanObject(objectKey string) error {
+	output, err := s.Client.GetObject(objectKey, "")
+	if err != nil {
+		return fmt.Errorf("get object failed: %v", err)
+	}
+	defer output.Body.Close()
+	return s.scanner.CheckStream(objectKey, output.Body)
+}
@@ -0,0 +1,142 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package s3
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/incubator-answer-plugins/util/storageext"
+	"github.com/apache/incubator-answer-plugins/util/storagegc"
+	"github.com/apache/incubator-answer/plugin"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/gin-gonic/gin"
+	"github.com/segmentfault/pacman/log"
+)
+
+const (
+	accessModePublic  = "public"
+	accessModePrivate = "private"
+
+	deliveryRedirect = "redirect"
+	deliveryProxy    = "proxy"
+
+	// fileRoutePath is the path of the file route under the Answer API
+	fileRoutePath = "/answer/api/v1/s3_storage/file/"
+
+	defaultSignedURLExpiry = 15 * time.Minute
+)
+
+// fileURL returns the URL saved in the content for the object.
+// In private mode it is a stable URL of the file route, which never expires.
+func (s *Storage) fileURL(objectKey string) string {
+	if s.Config.AccessMode == accessModePrivate {
+		return strings.TrimSuffix(plugin.SiteURL(), "/") + fileRoutePath + objectKey
+	}
+	return s.Config.VisitUrlPrefix + objectKey
+}
+
+func (s *Storage) signedURLExpiry() time.Duration {
+	minutes, _ := strconv.Atoi(s.Config.SignedURLExpiry)
+	if minutes <= 0 {
+		return defaultSignedURLExpiry
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// serveFile serves a file of the private bucket to the logged in users, with a redirect to a presigned GET
+// or through Answer.
+// It redirects to the public URL when the bucket is public again, so that saved URLs keep working.
+func (s *Storage) serveFile(ctx *gin.Context) {
+	if !plugin.StatusManager.IsEnabled(s.Info().SlugName) || s.Client == nil {
+		storageext.HandleNotFound(ctx)
+		return
+	}
+	objectKey := strings.TrimPrefix(ctx.Param("object_key"), "/")
+	if len(objectKey) == 0 || !strings.HasPrefix(objectKey, s.Config.ObjectKeyPrefix) || strings.Contains(objectKey, "..") ||
+		strings.HasPrefix(objectKey, s.Config.ObjectKeyPrefix+storagegc.QuarantineDir) {
+		storageext.HandleNotFound(ctx)
+		return
+	}
+	if s.Config.AccessMode != accessModePrivate {
+		ctx.Redirect(http.StatusFound, s.Config.VisitUrlPrefix+objectKey)
+		return
+	}
+	if !storageext.LoggedIn(ctx) {
+		ctx.Status(http.StatusForbidden)
+		return
+	}
+
+	if s.Config.PrivateDelivery == deliveryProxy {
+		s.proxyFile(ctx, objectKey)
+		return
+	}
+	expiry := s.signedURLExpiry()
+	url, err := s.Client.PresignGetObject(objectKey, expiry)
+	if err != nil {
+		log.Errorf("presign object %s failed: %v", objectKey, err)
+		ctx.Status(http.StatusBadGateway)
+		return
+	}
+	// the browser may reuse the redirect while the presigned URL is still valid
+	ctx.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", int(expiry.Seconds())/2))
+	ctx.Redirect(http.StatusFound, url)
+}
+
+// proxyFile streams the object, the byte range of the request is forwarded for the videos
+func (s *Storage) proxyFile(ctx *gin.Context, objectKey string) {
+	output, err := s.Client.GetObject(objectKey, ctx.GetHeader("Range"))
+	if err != nil {
+		log.Warnf("get object %s failed: %v", objectKey, err)
+		ctx.Status(http.StatusNotFound)
+		return
+	}
+	defer output.Body.Close()
+
+	status := http.StatusOK
+	if output.ContentRange != nil {
+		status = http.StatusPartialContent
+		ctx.Header("Content-Range", aws.StringValue(output.ContentRange))
+	}
+	if output.ContentLength != nil {
+		ctx.Header("Content-Length", strconv.FormatInt(*output.ContentLength, 10))
+	}
+	if output.ContentDisposition != nil {
+		ctx.Header("Content-Disposition", *output.ContentDisposition)
+	}
+	if output.ETag != nil {
+		ctx.Header("ETag", *output.ETag)
+	}
+	if output.LastModified != nil {
+		ctx.Header("Last-Modified", output.LastModified.UTC().Format(http.TimeFormat))
+	}
+	ctx.Header("Accept-Ranges", "bytes")
+	ctx.Header("Cache-Control", "private, max-age=3600")
+	ctx.Header("X-Content-Type-Options", "nosniff")
+	ctx.Header("Content-Type", aws.StringValue(output.ContentType))
+	ctx.Status(status)
+	if _, err = io.Copy(ctx.Writer, output.Body); err != nil {
+		log.Debugf("stream object %s interrupted: %v", objectKey, err)
+	}
+}
@@ -20,51 +20,90 @@
 package s3
 
 import (
-	"crypto/rand"
 	"embed"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/apache/incubator-answer-plugins/util"
-	"path/filepath"
-	"strconv"
-	"strings"
-	"time"
 
 	"github.com/apache/incubator-answer-plugins/storage-s3/i18n"
+	"github.com/apache/incubator-answer-plugins/util/clamav"
+	"github.com/apache/incubator-answer-plugins/util/storageext"
+	"github.com/apache/incubator-answer-plugins/util/storagegc"
 	"github.com/apache/incubator-answer/plugin"
+	"github.com/gin-gonic/gin"
 )
 
 //go:embed  info.yaml
 var Info embed.FS
 
-const (
-	// 10MB
-	defaultMaxFileSize int64 = 10 * 1024 * 1024
-)
+// uploadMessages are the upload errors shown to the users
+var uploadMessages = storageext.Messages{
+	FileNotFound:        i18n.ErrFileNotFound,
+	UnsupportedFileType: i18n.ErrUnsupportedFileType,
+	OverFileSizeLimit:   i18n.ErrOverFileSizeLimit,
+	UploadFileFailed:    i18n.ErrUploadFileFailed,
+	InfectedFile:        i18n.ErrInfectedFile,
+	ScanUnavailable:     i18n.ErrScanUnavailable,
+}
 
 type Storage struct {
-	Config *StorageConfig
-	Client *Client
+	Config    *StorageConfig
+	Client    *Client
+	rules     *storageext.Rules
+	keys      *storageext.ObjectKeys
+	scanner   *clamav.Guard
+	collector *storagegc.Collector
+	uploadKey []byte
 }
 
 type StorageConfig struct {
-	Endpoint        string `json:"endpoint"`
-	BucketName      string `json:"bucket_name"`
-	ObjectKeyPrefix string `json:"object_key_prefix"`
-	AccessKeyID     string `json:"access_key_id"`
-	AccessKeySecret string `json:"access_key_secret"`
-	AccessToken     string `json:"access_token"`
-	VisitUrlPrefix  string `json:"visit_url_prefix"`
-	MaxFileSize     string `json:"max_file_size"`
-	Region          string `json:"region"`
-	DisableSSL      bool   `json:"disable_ssl"`
+	Endpoint             string `json:"endpoint"`
+	BucketName           string `json:"bucket_name"`
+	ObjectKeyPrefix      string `json:"object_key_prefix"`
+	ObjectKeyScheme      string `json:"object_key_scheme"`
+	ObjectKeyTemplate    string `json:"object_key_template"`
+	AccessKeyID          string `json:"access_key_id"`
+	AccessKeySecret      string `json:"access_key_secret"`
+	AccessToken          string `json:"access_token"`
+	VisitUrlPrefix       string `json:"visit_url_prefix"`
+	MaxFileSize          string `json:"max_file_size"`
+	MaxAvatarSize        string `json:"max_avatar_size"`
+	MaxBrandingSize      string `json:"max_branding_size"`
+	AllowedExts          string `json:"allowed_extensions"`
+	DirectUpload         bool   `json:"direct_upload"`
+	ImageProcessing      bool   `json:"image_processing"`
+	AvatarMaxDimension   string `json:"avatar_max_dimension"`
+	PostMaxDimension     string `json:"post_max_dimension"`
+	BrandingMaxDimension string `json:"branding_max_dimension"`
+	ThumbnailSize        string `json:"thumbnail_size"`
+	ConvertWebP          bool   `json:"convert_webp"`
+	Region               string `json:"region"`
+	DisableSSL           bool   `json:"disable_ssl"`
+	ACL                  string `json:"acl"`
+	AccessMode           string `json:"access_mode"`
+	PrivateDelivery      string `json:"private_delivery"`
+	SignedURLExpiry      string `json:"signed_url_expiry"`
+	Addressing           string `json:"addressing"`
+	SSE                  string `json:"server_side_encryption"`
+	SSEKMSKeyID          string `json:"sse_kms_key_id"`
+	MalwareScan          bool   `json:"malware_scan"`
+	ClamdAddress         string `json:"clamd_address"`
+	ScanFailurePolicy    string `json:"scan_failure_policy"`
+	GCMode               string `json:"gc_mode"`
+	GCInterval           string `json:"gc_interval"`
+	GCGracePeriod        string `json:"gc_grace_period"`
+	GCQuarantinePeriod   string `json:"gc_quarantine_period"`
 }
 
 func init() {
-	plugin.Register(&Storage{
+	keys, _ := storageext.NewObjectKeys("", storageext.KeySchemeRandom, "")
+	s := &Storage{
 		Config: &StorageConfig{},
-	})
+		rules:  &storageext.Rules{},
+		keys:   keys,
+	}
+	s.collector = storagegc.NewCollector("s3_storage", s)
+	plugin.Register(s)
 }
 
 func (s *Storage) Info() plugin.Info {
@@ -82,84 +121,65 @@ func (s *Storage) Info() plugin.Info {
 }
 
 func (s *Storage) UploadFile(ctx *plugin.GinContext, source plugin.UploadSource) (resp plugin.UploadFileResponse) {
-	resp = plugin.UploadFileResponse{}
-
-	file, err := ctx.FormFile("file")
-	if err != nil {
-		resp.OriginalError = fmt.Errorf("get bucket failed: %v", err)
-		resp.DisplayErrorMsg = plugin.MakeTranslator(i18n.ErrFileNotFound)
-		return resp
-	}
-
-	if !s.checkFileType(file.Filename, source) {
-		resp.OriginalError = fmt.Errorf("file type not allowed")
-		resp.DisplayErrorMsg = plugin.MakeTranslator(i18n.ErrUnsupportedFileType)
-		return resp
-	}
-
-	if file.Size > s.maxFileSizeLimit() {
-		resp.OriginalError = fmt.Errorf("file size too large")
-		resp.DisplayErrorMsg = plugin.MakeTranslator(i18n.ErrOverFileSizeLimit)
-		return resp
-	}
-
-	openFile, err := file.Open()
-	if err != nil {
-		resp.OriginalError = fmt.Errorf("get file failed: %v", err)
-		resp.DisplayErrorMsg = plugin.MakeTranslator(i18n.ErrFileNotFound)
+	if s.Client == nil {
+		resp.OriginalError = fmt.Errorf("storage is not configured")
+		resp.DisplayErrorMsg = plugin.MakeTranslator(i18n.ErrMisStorageConfig)
 		return resp
 	}
-	defer openFile.Close()
+	return s.uploader().Upload(ctx, source)
+}
 
-	objectKey := s.createObjectKey(file.Filename, source)
-	err = s.Client.PutObject(objectKey, strings.ToLower(filepath.Ext(file.Filename)), openFile)
-	if err != nil {
-		resp.OriginalError = fmt.Errorf("get file failed: %v", err)
-		resp.DisplayErrorMsg = plugin.MakeTranslator(i18n.ErrUploadFileFailed)
-		return resp
+// uploader returns the upload pipeline with the current config, only the bucket belongs to the plugin
+func (s *Storage) uploader() *storageext.Uploader {
+	return &storageext.Uploader{
+		Rules: s.rules,
+		Keys:  s.keys,
+		Images: storageext.ImageConfig{
+			Enabled:              s.Config.ImageProcessing,
+			WebP:                 s.Config.ConvertWebP,
+			AvatarMaxDimension:   s.Config.AvatarMaxDimension,
+			PostMaxDimension:     s.Config.PostMaxDimension,
+			BrandingMaxDimension: s.Config.BrandingMaxDimension,
+			ThumbnailSize:        s.Config.ThumbnailSize,
+		},
+		Scanner:  s.scanner,
+		Messages: uploadMessages,
+		Store:    s.Client,
+		URL:      s.fileURL,
 	}
-	resp.FullURL = s.Config.VisitUrlPrefix + objectKey
-	return resp
 }
 
-func (s *Storage) createObjectKey(originalFilename string, source plugin.UploadSource) string {
-	ext := strings.ToLower(filepath.Ext(originalFilename))
-	randomString := s.randomObjectKey()
-	switch source {
-	case plugin.UserAvatar:
-		return s.Config.ObjectKeyPrefix + "avatar/" + randomString + ext
-	case plugin.UserPost:
-		return s.Config.ObjectKeyPrefix + "post/" + randomString + ext
-	case plugin.AdminBranding:
-		return s.Config.ObjectKeyPrefix + "branding/" + randomString + ext
-	default:
-		return s.Config.ObjectKeyPrefix + "other/" + randomString + ext
-	}
+func (s *Storage) RegisterUnAuthRouter(r *gin.RouterGroup) {
+	r.GET("/s3_storage/file/*object_key", s.serveFile)
 }
 
-func (s *Storage) randomObjectKey() string {
-	bytes := make([]byte, 4)
-	_, _ = rand.Read(bytes)
-	return fmt.Sprintf("%d", time.Now().UnixNano()) + hex.EncodeToString(bytes)
+func (s *Storage) RegisterAuthUserRouter(r *gin.RouterGroup) {
+	r.POST("/s3_storage/upload/presign", s.presign)
+	r.POST("/s3_storage/upload/confirm", s.confirm)
 }
 
-func (s *Storage) checkFileType(originalFilename string, source plugin.UploadSource) bool {
-	ext := strings.ToLower(filepath.Ext(originalFilename))
-	if _, ok := plugin.DefaultFileTypeCheckMapping[source][ext]; ok {
-		return true
-	}
-	return false
+func (s *Storage) RegisterAuthAdminRouter(r *gin.RouterGroup) {
+	r.GET("/s3_storage/gc", storagegc.StatusHandler(s.Info().SlugName, s.collector))
+	r.POST("/s3_storage/gc", storagegc.StartHandler(s.Info().SlugName, s.collector))
 }
 
-func (s *Storage) maxFileSizeLimit() int64 {
-	if len(s.Config.MaxFileSize) == 0 {
-		return defaultMaxFileSize
+// buildRules builds the upload rules from the config, the empty fields keep the defaults
+func buildRules(c *StorageConfig) *storageext.Rules {
+	rules := &storageext.Rules{
+		Extensions: make(map[plugin.UploadSource]map[string]bool),
+		MaxSizes:   make(map[plugin.UploadSource]int64),
+		MaxSize:    storageext.ParseSize(c.MaxFileSize),
 	}
-	limit, _ := strconv.Atoi(s.Config.MaxFileSize)
-	if limit <= 0 {
-		return defaultMaxFileSize
+	if exts := storageext.ParseExtensions(c.AllowedExts); exts != nil {
+		rules.Extensions[plugin.UserPost] = exts
 	}
-	return int64(limit) * 1024 * 1024
+	if size := storageext.ParseSize(c.MaxAvatarSize); size > 0 {
+		rules.MaxSizes[plugin.UserAvatar] = size
+	}
+	if size := storageext.ParseSize(c.MaxBrandingSize); size > 0 {
+		rules.MaxSizes[plugin.AdminBranding] = size
+	}
+	return rules
 }
 
 func (s *Storage) ConfigFields() []plugin.ConfigField {
@@ -169,7 +189,7 @@ func (s *Storage) ConfigFields() []plugin.ConfigField {
 			Type:        plugin.ConfigTypeInput,
 			Title:       plugin.MakeTranslator(i18n.ConfigEndpointTitle),
 			Description: plugin.MakeTranslator(i18n.ConfigEndpointDescription),
-			Required:    true,
+			Required:    false,
 			UIOptions: plugin.ConfigFieldUIOptions{
 				InputType: plugin.InputTypeText,
 			},
@@ -197,6 +217,34 @@ func (s *Storage) ConfigFields() []plugin.ConfigField {
 			},
 			Value: s.Config.ObjectKeyPrefix,
 		},
+		{
+			Name:        "object_key_scheme",
+			Type:        plugin.ConfigTypeSelect,
+			Title:       plugin.MakeTranslator(i18n.ConfigObjectKeySchemeTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigObjectKeySchemeDescription),
+			Value:       s.Config.ObjectKeyScheme,
+			Options: []plugin.ConfigFieldOption{
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigObjectKeySchemeOptionRandom),
+					Value: storageext.KeySchemeRandom,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigObjectKeySchemeOptionContent),
+					Value: storageext.KeySchemeContent,
+				},
+			},
+		},
+		{
+			Name:        "object_key_template",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigObjectKeyTemplateTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigObjectKeyTemplateDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.ObjectKeyTemplate,
+		},
 		{
 			Name:        "access_key_id",
 			Type:        plugin.ConfigTypeInput,
@@ -252,6 +300,39 @@ func (s *Storage) ConfigFields() []plugin.ConfigField {
 			},
 			Value: s.Config.MaxFileSize,
 		},
+		{
+			Name:        "max_avatar_size",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigMaxAvatarSizeTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigMaxAvatarSizeDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeNumber,
+			},
+			Value: s.Config.MaxAvatarSize,
+		},
+		{
+			Name:        "max_branding_size",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigMaxBrandingSizeTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigMaxBrandingSizeDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeNumber,
+			},
+			Value: s.Config.MaxBrandingSize,
+		},
+		{
+			Name:        "allowed_extensions",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigAllowedExtensionsTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigAllowedExtensionsDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.AllowedExts,
+		},
 		{
 			Name:        "region",
 			Type:        plugin.ConfigTypeInput,
@@ -263,6 +344,80 @@ func (s *Storage) ConfigFields() []plugin.ConfigField {
 			},
 			Value: s.Config.Region,
 		},
+		{
+			Name:        "direct_upload",
+			Type:        plugin.ConfigTypeSwitch,
+			Title:       plugin.MakeTranslator(i18n.ConfigDirectUploadTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigDirectUploadDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				Label: plugin.MakeTranslator(i18n.ConfigDirectUploadLabel),
+			},
+			Value: s.Config.DirectUpload,
+		},
+		{
+			Name:        "image_processing",
+			Type:        plugin.ConfigTypeSwitch,
+			Title:       plugin.MakeTranslator(i18n.ConfigImageProcessingTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigImageProcessingDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				Label: plugin.MakeTranslator(i18n.ConfigImageProcessingLabel),
+			},
+			Value: s.Config.ImageProcessing,
+		},
+		{
+			Name:        "avatar_max_dimension",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigAvatarMaxDimensionTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigAvatarMaxDimensionDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeNumber,
+			},
+			Value: s.Config.AvatarMaxDimension,
+		},
+		{
+			Name:        "post_max_dimension",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigPostMaxDimensionTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigPostMaxDimensionDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeNumber,
+			},
+			Value: s.Config.PostMaxDimension,
+		},
+		{
+			Name:        "branding_max_dimension",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigBrandingMaxDimensionTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigBrandingMaxDimensionDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeNumber,
+			},
+			Value: s.Config.BrandingMaxDimension,
+		},
+		{
+			Name:        "thumbnail_size",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigThumbnailSizeTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigThumbnailSizeDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeNumber,
+			},
+			Value: s.Config.ThumbnailSize,
+		},
+		{
+			Name:        "convert_webp",
+			Type:        plugin.ConfigTypeSwitch,
+			Title:       plugin.MakeTranslator(i18n.ConfigConvertWebPTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigConvertWebPDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				Label: plugin.MakeTranslator(i18n.ConfigConvertWebPLabel),
+			},
+			Value: s.Config.ConvertWebP,
+		},
 		{
 			Name:  "disable_ssl",
 			Type:  plugin.ConfigTypeSwitch,
@@ -272,21 +427,255 @@ func (s *Storage) ConfigFields() []plugin.ConfigField {
 				Label: plugin.MakeTranslator(i18n.ConfigDisableSSLDescription),
 			},
 		},
+		{
+			Name:        "acl",
+			Type:        plugin.ConfigTypeSelect,
+			Title:       plugin.MakeTranslator(i18n.ConfigACLTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigACLDescription),
+			Value:       s.Config.ACL,
+			Options: []plugin.ConfigFieldOption{
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigACLOptionDefault),
+					Value: ACLDefault,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigACLOptionPrivate),
+					Value: ACLPrivate,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigACLOptionPublicRead),
+					Value: ACLPublicRead,
+				},
+			},
+		},
+		{
+			Name:        "access_mode",
+			Type:        plugin.ConfigTypeSelect,
+			Title:       plugin.MakeTranslator(i18n.ConfigAccessModeTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigAccessModeDescription),
+			Value:       s.Config.AccessMode,
+			Options: []plugin.ConfigFieldOption{
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigAccessModeOptionPublic),
+					Value: accessModePublic,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigAccessModeOptionPrivate),
+					Value: accessModePrivate,
+				},
+			},
+		},
+		{
+			Name:        "private_delivery",
+			Type:        plugin.ConfigTypeSelect,
+			Title:       plugin.MakeTranslator(i18n.ConfigPrivateDeliveryTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigPrivateDeliveryDescription),
+			Value:       s.Config.PrivateDelivery,
+			Options: []plugin.ConfigFieldOption{
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigPrivateDeliveryOptionRedirect),
+					Value: deliveryRedirect,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigPrivateDeliveryOptionProxy),
+					Value: deliveryProxy,
+				},
+			},
+		},
+		{
+			Name:        "signed_url_expiry",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigSignedURLExpiryTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigSignedURLExpiryDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeNumber,
+			},
+			Value: s.Config.SignedURLExpiry,
+		},
+		{
+			Name:        "addressing",
+			Type:        plugin.ConfigTypeSelect,
+			Title:       plugin.MakeTranslator(i18n.ConfigAddressingTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigAddressingDescription),
+			Value:       s.Config.Addressing,
+			Options: []plugin.ConfigFieldOption{
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigAddressingOptionPath),
+					Value: AddressingPath,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigAddressingOptionVirtual),
+					Value: AddressingVirtual,
+				},
+			},
+		},
+		{
+			Name:        "server_side_encryption",
+			Type:        plugin.ConfigTypeSelect,
+			Title:       plugin.MakeTranslator(i18n.ConfigServerSideEncryptionTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigServerSideEncryptionDescription),
+			Value:       s.Config.SSE,
+			Options: []plugin.ConfigFieldOption{
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigServerSideEncryptionOptionNone),
+					Value: SSENone,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigServerSideEncryptionOptionSSES3),
+					Value: SSES3,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigServerSideEncryptionOptionSSEKMS),
+					Value: SSEKMS,
+				},
+			},
+		},
+		{
+			Name:        "sse_kms_key_id",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigSSEKMSKeyIDTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigSSEKMSKeyIDDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.SSEKMSKeyID,
+		},
+		{
+			Name:        "malware_scan",
+			Type:        plugin.ConfigTypeSwitch,
+			Title:       plugin.MakeTranslator(i18n.ConfigMalwareScanTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigMalwareScanDescription),
+			UIOptions: plugin.ConfigFieldUIOptions{
+				Label: plugin.MakeTranslator(i18n.ConfigMalwareScanLabel),
+			},
+			Value: s.Config.MalwareScan,
+		},
+		{
+			Name:        "clamd_address",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigClamdAddressTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigClamdAddressDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeText,
+			},
+			Value: s.Config.ClamdAddress,
+		},
+		{
+			Name:        "scan_failure_policy",
+			Type:        plugin.ConfigTypeSelect,
+			Title:       plugin.MakeTranslator(i18n.ConfigScanFailurePolicyTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigScanFailurePolicyDescription),
+			Value:       s.Config.ScanFailurePolicy,
+			Options: []plugin.ConfigFieldOption{
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigScanFailurePolicyOptionClosed),
+					Value: clamav.PolicyFailClosed,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigScanFailurePolicyOptionOpen),
+					Value: clamav.PolicyFailOpen,
+				},
+			},
+		},
+		{
+			Name:        "gc_mode",
+			Type:        plugin.ConfigTypeSelect,
+			Title:       plugin.MakeTranslator(i18n.ConfigGCModeTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigGCModeDescription),
+			Value:       s.Config.GCMode,
+			Options: []plugin.ConfigFieldOption{
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigGCModeOptionOff),
+					Value: storagegc.ModeOff,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigGCModeOptionReport),
+					Value: storagegc.ModeReport,
+				},
+				{
+					Label: plugin.MakeTranslator(i18n.ConfigGCModeOptionQuarantine),
+					Value: storagegc.ModeQuarantine,
+				},
+			},
+		},
+		{
+			Name:        "gc_interval",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigGCIntervalTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigGCIntervalDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeNumber,
+			},
+			Value: s.Config.GCInterval,
+		},
+		{
+			Name:        "gc_grace_period",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigGCGracePeriodTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigGCGracePeriodDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeNumber,
+			},
+			Value: s.Config.GCGracePeriod,
+		},
+		{
+			Name:        "gc_quarantine_period",
+			Type:        plugin.ConfigTypeInput,
+			Title:       plugin.MakeTranslator(i18n.ConfigGCQuarantinePeriodTitle),
+			Description: plugin.MakeTranslator(i18n.ConfigGCQuarantinePeriodDescription),
+			Required:    false,
+			UIOptions: plugin.ConfigFieldUIOptions{
+				InputType: plugin.InputTypeNumber,
+			},
+			Value: s.Config.GCQuarantinePeriod,
+		},
 	}
 }
 
 func (s *Storage) ConfigReceiver(config []byte) error {
 	c := &StorageConfig{}
 	_ = json.Unmarshal(config, c)
+	// nothing is applied until the whole config is valid, so a failed save keeps the previous one working
+	keys, err := storageext.NewObjectKeys(c.ObjectKeyPrefix, c.ObjectKeyScheme, c.ObjectKeyTemplate)
+	if err != nil {
+		return err
+	}
+	scanner, err := storageext.NewScanner(s.Info().SlugName, c.MalwareScan, c.ClamdAddress, c.ScanFailurePolicy)
+	if err != nil {
+		return err
+	}
+	if c.AccessMode == accessModePrivate {
+		if err = storageext.CheckLoginCache(); err != nil {
+			return err
+		}
+	}
+	client, err := NewS3Client(ClientConfig{
+		AccessKeyID:     c.AccessKeyID,
+		AccessKeySecret: c.AccessKeySecret,
+		AccessToken:     c.AccessToken,
+		Endpoint:        c.Endpoint,
+		Region:          c.Region,
+		Bucket:          c.BucketName,
+		DisableSSL:      c.DisableSSL,
+		Addressing:      c.Addressing,
+		ACL:             c.ACL,
+		SSE:             c.SSE,
+		KMSKeyID:        c.SSEKMSKeyID,
+	})
+	if err != nil {
+		return err
+	}
 	s.Config = c
-	s.Client = NewS3Client(
-		s.Config.AccessKeyID,
-		s.Config.AccessKeySecret,
-		s.Config.AccessToken,
-		s.Config.Endpoint,
-		s.Config.Region,
-		s.Config.BucketName,
-		s.Config.DisableSSL,
-	)
+	s.rules = buildRules(c)
+	s.keys = keys
+	s.scanner = scanner
+	s.Client = client
+	s.uploadKey = newUploadKey(c.AccessKeySecret)
+	s.configureCollector()
 	return nil
 }
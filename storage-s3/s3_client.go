@@ -20,62 +20,338 @@
 package s3
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
 	"os"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 )
 
+const (
+	ACLDefault    = "default"
+	ACLPrivate    = "private"
+	ACLPublicRead = "public-read"
+
+	AddressingPath    = "path"
+	AddressingVirtual = "virtual"
+
+	SSENone = "none"
+	SSES3   = s3.ServerSideEncryptionAes256
+	SSEKMS  = s3.ServerSideEncryptionAwsKms
+
+	// uploadPartSize is the part size of the multipart uploads, smaller files are sent in one request
+	uploadPartSize = 8 * 1024 * 1024
+	// uploadConcurrency is the number of parts uploaded at once
+	uploadConcurrency = 3
+)
+
+// ClientConfig is the connection config of the S3 client
+type ClientConfig struct {
+	AccessKeyID     string
+	AccessKeySecret string
+	AccessToken     string
+	Endpoint        string
+	Region          string
+	Bucket          string
+	DisableSSL      bool
+	// Addressing is path-style by default, like the older versions
+	Addressing string
+	ACL        string
+	// SSE is the server-side encryption, SSE-S3 or SSE-KMS with the key id
+	SSE      string
+	KMSKeyID string
+}
+
+// Client is a long-lived S3 client, safe for concurrent use
 type Client struct {
-	s3Config *aws.Config
+	svc      *s3.S3
+	uploader *s3manager.Uploader
 	bucket   string
+	acl      *string
+	sse      *string
+	kmsKeyID *string
 }
 
-func NewS3Client(id, secret, token, endpoint, region, bucket string, disableSSL bool) *Client {
-	s3Client := &Client{
-		s3Config: &aws.Config{
-			Credentials:      credentials.NewStaticCredentials(id, secret, token),
-			Endpoint:         aws.String(endpoint),
-			Region:           aws.String(region),
-			DisableSSL:       aws.Bool(disableSSL),
-			S3ForcePathStyle: aws.Bool(true),
-		},
-		bucket: bucket,
+// NewS3Client creates the client. Without static keys, the credentials come from the default chain:
+// the environment, the shared config, web identity (IRSA) and the EC2/ECS role.
+func NewS3Client(conf ClientConfig) (*Client, error) {
+	awsConfig := &aws.Config{
+		Region:           aws.String(conf.Region),
+		DisableSSL:       aws.Bool(conf.DisableSSL),
+		S3ForcePathStyle: aws.Bool(conf.Addressing != AddressingVirtual),
+	}
+	if len(conf.Endpoint) > 0 {
+		awsConfig.Endpoint = aws.String(conf.Endpoint)
+	}
+	if len(conf.AccessKeyID) > 0 && len(conf.AccessKeySecret) > 0 {
+		awsConfig.Credentials = credentials.NewStaticCredentials(conf.AccessKeyID, conf.AccessKeySecret, conf.AccessToken)
 	}
-	return s3Client
+	newSession, err := session.NewSessionWithOptions(session.Options{
+		Config:            *awsConfig,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session, %s", err.Error())
+	}
+	svc := s3.New(newSession)
+	client := &Client{
+		svc: svc,
+		uploader: s3manager.NewUploaderWithClient(svc, func(u *s3manager.Uploader) {
+			u.PartSize = uploadPartSize
+			u.Concurrency = uploadConcurrency
+		}),
+		bucket: conf.Bucket,
+		acl:    getACLConfig(conf.ACL),
+	}
+	switch conf.SSE {
+	case SSES3:
+		client.sse = aws.String(SSES3)
+	case SSEKMS:
+		client.sse = aws.String(SSEKMS)
+		if len(conf.KMSKeyID) > 0 {
+			client.kmsKeyID = aws.String(conf.KMSKeyID)
+		}
+	}
+	return client, nil
 }
 
-func (s *Client) PutObject(key, ext string, file io.ReadSeeker) (err error) {
-	newSession, err := session.NewSession(s.s3Config)
+// PutObject uploads the object, in parts when it is large
+func (s *Client) PutObject(key, contentType, contentDisposition string, file io.Reader) (err error) {
+	input := &s3manager.UploadInput{
+		ACL:                  s.acl,
+		Body:                 file,
+		Bucket:               aws.String(s.bucket),
+		Key:                  aws.String(key),
+		ContentType:          aws.String(contentType),
+		ServerSideEncryption: s.sse,
+		SSEKMSKeyId:          s.kmsKeyID,
+	}
+	if len(contentDisposition) > 0 {
+		input.ContentDisposition = aws.String(contentDisposition)
+	}
+	_, err = s.uploader.Upload(input)
 	if err != nil {
-		return fmt.Errorf("failed to create session, %s", err.Error())
-	}
-	contentType := fmt.Sprintf("image/%s", strings.TrimPrefix(ext, "."))
-	_, err = s3.New(newSession).PutObject(&s3.PutObjectInput{
-		ACL:         getACLConfig(),
-		Body:        file,
-		Bucket:      aws.String(s.bucket),
-		Key:         aws.String(key),
-		ContentType: aws.String(contentType),
+		return fmt.Errorf("failed to put object, %s", err.Error())
+	}
+	return nil
+}
+
+// PresignPostObject presigns a form POST of the object, the browser must send the returned fields with the file.
+// The policy pins the key, the content type and disposition, and limits the length to the given size,
+// so S3 refuses an upload that differs. A presigned PUT can't do that, its Content-Length isn't signed.
+func (s *Client) PresignPostObject(key, contentType, contentDisposition string, size int64, expiry time.Duration) (
+	url string, fields map[string]string, err error) {
+	req, _ := s.svc.HeadBucketRequest(&s3.HeadBucketInput{Bucket: aws.String(s.bucket)})
+	if err = req.Build(); err != nil {
+		return "", nil, fmt.Errorf("failed to presign object, %s", err.Error())
+	}
+	creds, err := s.svc.Config.Credentials.Get()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to presign object, %s", err.Error())
+	}
+
+	now := time.Now().UTC()
+	date := now.Format("20060102")
+	scope := strings.Join([]string{date, s.svc.SigningRegion, s.svc.SigningName, "aws4_request"}, "/")
+	fields = map[string]string{
+		"key":              key,
+		"Content-Type":     contentType,
+		"x-amz-algorithm":  "AWS4-HMAC-SHA256",
+		"x-amz-credential": creds.AccessKeyID + "/" + scope,
+		"x-amz-date":       now.Format("20060102T150405Z"),
+	}
+	if len(contentDisposition) > 0 {
+		fields["Content-Disposition"] = contentDisposition
+	}
+	if s.acl != nil {
+		fields["acl"] = *s.acl
+	}
+	if s.sse != nil {
+		fields["x-amz-server-side-encryption"] = *s.sse
+	}
+	if s.kmsKeyID != nil {
+		fields["x-amz-server-side-encryption-aws-kms-key-id"] = *s.kmsKeyID
+	}
+	if len(creds.SessionToken) > 0 {
+		fields["x-amz-security-token"] = creds.SessionToken
+	}
+
+	conditions := []interface{}{
+		map[string]string{"bucket": s.bucket},
+		[]interface{}{"content-length-range", size, size},
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		conditions = append(conditions, map[string]string{name: fields[name]})
+	}
+	policy, err := json.Marshal(map[string]interface{}{
+		"expiration": now.Add(expiry).Format("2006-01-02T15:04:05.000Z"),
+		"conditions": conditions,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to put object, %s", err.Error())
+		return "", nil, fmt.Errorf("failed to presign object, %s", err.Error())
+	}
+	fields["policy"] = base64.StdEncoding.EncodeToString(policy)
+
+	signingKey := []byte("AWS4" + creds.SecretAccessKey)
+	for _, part := range []string{date, s.svc.SigningRegion, s.svc.SigningName, "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, part)
+	}
+	fields["x-amz-signature"] = hex.EncodeToString(hmacSHA256(signingKey, fields["policy"]))
+	return req.HTTPRequest.URL.String(), fields, nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// PresignGetObject presigns a GET of the object
+func (s *Client) PresignGetObject(key string, expiry time.Duration) (string, error) {
+	req, _ := s.svc.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	url, err := req.Presign(expiry)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign object, %s", err.Error())
+	}
+	return url, nil
+}
+
+// GetObject gets the object, or the part of it in the byte range when it is not empty.
+// The caller must close the body of the output.
+func (s *Client) GetObject(key, byteRange string) (*s3.GetObjectOutput, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}
+	if len(byteRange) > 0 {
+		input.Range = aws.String(byteRange)
+	}
+	return s.svc.GetObject(input)
+}
+
+// HeadObject returns the metadata of the object
+func (s *Client) HeadObject(key string) (*s3.HeadObjectOutput, error) {
+	return s.svc.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+}
+
+// ObjectExists reports whether the object exists
+func (s *Client) ObjectExists(key string) (bool, error) {
+	_, err := s.HeadObject(key)
+	if err == nil {
+		return true, nil
+	}
+	if reqErr, ok := err.(awserr.RequestFailure); ok && reqErr.StatusCode() == http.StatusNotFound {
+		return false, nil
+	}
+	return false, fmt.Errorf("failed to head object, %s", err.Error())
+}
+
+// GetObjectHead returns the first n bytes of the object
+func (s *Client) GetObjectHead(key string, n int) ([]byte, error) {
+	output, err := s.GetObject(key, fmt.Sprintf("bytes=0-%d", n-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object, %s", err.Error())
+	}
+	defer output.Body.Close()
+	return io.ReadAll(io.LimitReader(output.Body, int64(n)))
+}
+
+// DeleteObject deletes the object
+func (s *Client) DeleteObject(key string) error {
+	_, err := s.svc.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete object, %s", err.Error())
+	}
+	return nil
+}
+
+// ListObjects calls fn with each page of the objects under the prefix
+func (s *Client) ListObjects(ctx aws.Context, prefix string, fn func(objects []*s3.Object) error) error {
+	var fnErr error
+	err := s.svc.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		fnErr = fn(page.Contents)
+		return fnErr == nil
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list objects, %s", err.Error())
 	}
 	return nil
 }
 
+// CopyObject copies the object in the bucket, with its metadata and the ACL and encryption of the uploads
+func (s *Client) CopyObject(ctx aws.Context, from, to string) error {
+	_, err := s.svc.CopyObjectWithContext(ctx, &s3.CopyObjectInput{
+		ACL:                  s.acl,
+		Bucket:               aws.String(s.bucket),
+		CopySource:           aws.String(url.PathEscape(s.bucket) + "/" + escapeKey(from)),
+		Key:                  aws.String(to),
+		ServerSideEncryption: s.sse,
+		SSEKMSKeyId:          s.kmsKeyID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy object, %s", err.Error())
+	}
+	return nil
+}
+
+// escapeKey escapes the key in the copy source, keeping its slashes
+func escapeKey(key string) string {
+	parts := strings.Split(key, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
+
 var (
 	// aclPublicRead is the environment variable for some special platforms such as digital ocean
 	// https://github.com/apache/incubator-answer-plugins/issues/97
+	// It is only read when the ACL is not configured, which replaces it.
 	aclPublicRead = os.Getenv("ACL_PUBLIC_READ")
 )
 
-func getACLConfig() *string {
+func getACLConfig(acl string) *string {
+	switch acl {
+	case ACLPrivate, ACLPublicRead:
+		return aws.String(acl)
+	case ACLDefault:
+		return nil
+	}
 	if len(aclPublicRead) > 0 {
 		return aws.String("public-read")
 	}
@@ -0,0 +1,109 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package i18n
+
+const (
+	InfoName        = "plugin.tencentcos_storage.backend.info.name"
+	InfoDescription = "plugin.tencentcos_storage.backend.info.description"
+
+	ConfigEndpointTitle       = "plugin.tencentcos_storage.backend.config.endpoint.title"
+	ConfigEndpointDescription = "plugin.tencentcos_storage.backend.config.endpoint.description"
+
+	ConfigBucketNameTitle       = "plugin.tencentcos_storage.backend.config.bucket_name.title"
+	ConfigBucketNameDescription = "plugin.tencentcos_storage.backend.config.bucket_name.description"
+
+	ConfigRegionTitle       = "plugin.tencentcos_storage.backend.config.region.title"
+	ConfigRegionDescription = "plugin.tencentcos_storage.backend.config.region.description"
+
+	ConfigSecretIDTitle       = "plugin.tencentcos_storage.backend.config.secret_id.title"
+	ConfigSecretIDDescription = "plugin.tencentcos_storage.backend.config.secret_id.description"
+
+	ConfigSecretKeyTitle       = "plugin.tencentcos_storage.backend.config.secret_key.title"
+	ConfigSecretKeyDescription = "plugin.tencentcos_storage.backend.config.secret_key.description"
+
+	ConfigSessionTokenTitle       = "plugin.tencentcos_storage.backend.config.session_token.title"
+	ConfigSessionTokenDescription = "plugin.tencentcos_storage.backend.config.session_token.description"
+
+	ConfigObjectKeyPrefixTitle       = "plugin.tencentcos_storage.backend.config.object_key_prefix.title"
+	ConfigObjectKeyPrefixDescription = "plugin.tencentcos_storage.backend.config.object_key_prefix.description"
+
+	ConfigObjectKeySchemeTitle         = "plugin.tencentcos_storage.backend.config.object_key_scheme.title"
+	ConfigObjectKeySchemeDescription   = "plugin.tencentcos_storage.backend.config.object_key_scheme.description"
+	ConfigObjectKeySchemeOptionRandom  = "plugin.tencentcos_storage.backend.config.object_key_scheme.options.random"
+	ConfigObjectKeySchemeOptionContent = "plugin.tencentcos_storage.backend.config.object_key_scheme.options.content"
+
+	ConfigObjectKeyTemplateTitle       = "plugin.tencentcos_storage.backend.config.object_key_template.title"
+	ConfigObjectKeyTemplateDescription = "plugin.tencentcos_storage.backend.config.object_key_template.description"
+
+	ConfigVisitUrlPrefixTitle       = "plugin.tencentcos_storage.backend.config.visit_url_prefix.title"
+	ConfigVisitUrlPrefixDescription = "plugin.tencentcos_storage.backend.config.visit_url_prefix.description"
+
+	ConfigMaxFileSizeTitle       = "plugin.tencentcos_storage.backend.config.max_file_size.title"
+	ConfigMaxFileSizeDescription = "plugin.tencentcos_storage.backend.config.max_file_size.description"
+
+	ConfigMaxAvatarSizeTitle       = "plugin.tencentcos_storage.backend.config.max_avatar_size.title"
+	ConfigMaxAvatarSizeDescription = "plugin.tencentcos_storage.backend.config.max_avatar_size.description"
+
+	ConfigMaxBrandingSizeTitle       = "plugin.tencentcos_storage.backend.config.max_branding_size.title"
+	ConfigMaxBrandingSizeDescription = "plugin.tencentcos_storage.backend.config.max_branding_size.description"
+
+	ConfigAllowedExtensionsTitle       = "plugin.tencentcos_storage.backend.config.allowed_extensions.title"
+	ConfigAllowedExtensionsDescription = "plugin.tencentcos_storage.backend.config.allowed_extensions.description"
+
+	ConfigImageProcessingTitle       = "plugin.tencentcos_storage.backend.config.image_processing.title"
+	ConfigImageProcessingDescription = "plugin.tencentcos_storage.backend.config.image_processing.description"
+	ConfigImageProcessingLabel       = "plugin.tencentcos_storage.backend.config.image_processing.label"
+
+	ConfigAvatarMaxDimensionTitle       = "plugin.tencentcos_storage.backend.config.avatar_max_dimension.title"
+	ConfigAvatarMaxDimensionDescription = "plugin.tencentcos_storage.backend.config.avatar_max_dimension.description"
+
+	ConfigPostMaxDimensionTitle       = "plugin.tencentcos_storage.backend.config.post_max_dimension.title"
+	ConfigPostMaxDimensionDescription = "plugin.tencentcos_storage.backend.config.post_max_dimension.description"
+
+	ConfigBrandingMaxDimensionTitle       = "plugin.tencentcos_storage.backend.config.branding_max_dimension.title"
+	ConfigBrandingMaxDimensionDescription = "plugin.tencentcos_storage.backend.config.branding_max_dimension.description"
+
+	ConfigThumbnailSizeTitle       = "plugin.tencentcos_storage.backend.config.thumbnail_size.title"
+	ConfigThumbnailSizeDescription = "plugin.tencentcos_storage.backend.config.thumbnail_size.description"
+
+	ConfigConvertWebPTitle       = "plugin.tencentcos_storage.backend.config.convert_webp.title"
+	ConfigConvertWebPDescription = "plugin.tencentcos_storage.backend.config.convert_webp.description"
+	ConfigConvertWebPLabel       = "plugin.tencentcos_storage.backend.config.convert_webp.label"
+
+	ConfigMalwareScanTitle       = "plugin.tencentcos_storage.backend.config.malware_scan.title"
+	ConfigMalwareScanDescription = "plugin.tencentcos_storage.backend.config.malware_scan.description"
+	ConfigMalwareScanLabel       = "plugin.tencentcos_storage.backend.config.malware_scan.label"
+
+	ConfigClamdAddressTitle       = "plugin.tencentcos_storage.backend.config.clamd_address.title"
+	ConfigClamdAddressDescription = "plugin.tencentcos_storage.backend.config.clamd_address.description"
+
+	ConfigScanFailurePolicyTitle        = "plugin.tencentcos_storage.backend.config.scan_failure_policy.title"
+	ConfigScanFailurePolicyDescription  = "plugin.tencentcos_storage.backend.config.scan_failure_policy.description"
+	ConfigScanFailurePolicyOptionClosed = "plugin.tencentcos_storage.backend.config.scan_failure_policy.options.closed"
+	ConfigScanFailurePolicyOptionOpen   = "plugin.tencentcos_storage.backend.config.scan_failure_policy.options.open"
+
+	ErrMisStorageConfig    = "plugin.tencentcos_storage.backend.err.mis_storage_config"
+	ErrFileNotFound        = "plugin.tencentcos_storage.backend.err.file_not_found"
+	ErrUnsupportedFileType = "plugin.tencentcos_storage.backend.err.unsupported_file_type"
+	ErrOverFileSizeLimit   = "plugin.tencentcos_storage.backend.err.over_file_size_limit"
+	ErrUploadFileFailed    = "plugin.tencentcos_storage.backend.err.upload_file_failed"
+	ErrInfectedFile        = "plugin.tencentcos_storage.backend.err.infected_file"
+	ErrScanUnavailable     = "plugin.tencentcos_storage.backend.err.scan_unavailable"
+)
@@ -0,0 +1,107 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package tencentcos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/tencentyun/cos-go-sdk-v5"
+)
+
+// ClientConfig is the connection config of the COS client
+type ClientConfig struct {
+	// Endpoint is the URL of the bucket, such as a custom domain, https://<bucket>.cos.<region>.myqcloud.com by default
+	Endpoint string
+	// Bucket is the bucket name with the APPID, such as answer-1250000000
+	Bucket    string
+	Region    string
+	SecretID  string
+	SecretKey string
+	// SessionToken is the token of the temporary keys
+	SessionToken string
+}
+
+// Client is a long-lived client of a bucket, safe for concurrent use
+type Client struct {
+	client    *cos.Client
+	bucketURL string
+}
+
+// NewCOSClient creates the client
+func NewCOSClient(conf ClientConfig) (*Client, error) {
+	if len(conf.SecretID) == 0 || len(conf.SecretKey) == 0 {
+		return nil, errors.New("secret id and secret key are required")
+	}
+	var bucketURL *url.URL
+	var err error
+	if len(conf.Endpoint) > 0 {
+		bucketURL, err = url.ParseRequestURI(strings.TrimSuffix(conf.Endpoint, "/"))
+		if err != nil || len(bucketURL.Host) == 0 {
+			return nil, fmt.Errorf("invalid endpoint %s", conf.Endpoint)
+		}
+	} else {
+		bucketURL, err = cos.NewBucketURL(conf.Bucket, conf.Region, true)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bucket or region, %s", err.Error())
+		}
+	}
+	client := cos.NewClient(&cos.BaseURL{BucketURL: bucketURL}, &http.Client{
+		Transport: &cos.AuthorizationTransport{
+			SecretID:     conf.SecretID,
+			SecretKey:    conf.SecretKey,
+			SessionToken: conf.SessionToken,
+		},
+	})
+	return &Client{client: client, bucketURL: bucketURL.String()}, nil
+}
+
+// PutObject uploads the object
+func (c *Client) PutObject(key, contentType, contentDisposition string, file io.Reader) error {
+	_, err := c.client.Object.Put(context.Background(), key, file, &cos.ObjectPutOptions{
+		ObjectPutHeaderOptions: &cos.ObjectPutHeaderOptions{
+			ContentType:        contentType,
+			ContentDisposition: contentDisposition,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put object, %s", err.Error())
+	}
+	return nil
+}
+
+// ObjectExists reports whether the object exists
+func (c *Client) ObjectExists(key string) (bool, error) {
+	exists, err := c.client.Object.IsExist(context.Background(), key)
+	if err != nil {
+		return false, fmt.Errorf("failed to head object, %s", err.Error())
+	}
+	return exists, nil
+}
+
+// URL returns the URL of the object, readable when the bucket is public read
+func (c *Client) URL(key string) string {
+	return c.bucketURL + "/" + key
+}
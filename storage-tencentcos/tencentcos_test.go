@@ -0,0 +1,197 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package tencentcos
+
+import (
+	"bytes"
+	"encoding/json"
+	"hash/crc64"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/apache/incubator-answer/plugin"
+	"github.com/gin-gonic/gin"
+)
+
+// fakeObject is an object stored in the fake COS
+type fakeObject struct {
+	data               []byte
+	contentType        string
+	contentDisposition string
+}
+
+// fakeCOS serves the PUT and HEAD object requests of a bucket, like COS does, in memory
+type fakeCOS struct {
+	sync.Mutex
+	objects map[string]*fakeObject
+	puts    int
+}
+
+func (f *fakeCOS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.Contains(r.Header.Get("Authorization"), "q-sign-algorithm=sha1") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	f.Lock()
+	defer f.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = &fakeObject{
+			data:               data,
+			contentType:        r.Header.Get("Content-Type"),
+			contentDisposition: r.Header.Get("Content-Disposition"),
+		}
+		f.puts++
+		w.Header().Set("ETag", `"etag"`)
+		// the SDK checks the CRC64 of the upload
+		w.Header().Set("x-cos-hash-crc64ecma", strconv.FormatUint(crc64.Checksum(data, crc64.MakeTable(crc64.ECMA)), 10))
+	case http.MethodHead:
+		if f.objects[r.URL.Path] == nil {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestNewCOSClient(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    ClientConfig
+		wantURL string
+		wantErr bool
+	}{
+		{"bucket", ClientConfig{Bucket: "answer-1250000000", Region: "ap-guangzhou", SecretID: "id", SecretKey: "key"},
+			"https://answer-1250000000.cos.ap-guangzhou.myqcloud.com/post/a.png", false},
+		{"endpoint", ClientConfig{Endpoint: "https://static.example.com/", SecretID: "id", SecretKey: "key"},
+			"https://static.example.com/post/a.png", false},
+		{"no appid", ClientConfig{Bucket: "answer", Region: "ap-guangzhou", SecretID: "id", SecretKey: "key"}, "", true},
+		{"no region", ClientConfig{Bucket: "answer-1250000000", SecretID: "id", SecretKey: "key"}, "", true},
+		{"no key", ClientConfig{Bucket: "answer-1250000000", Region: "ap-guangzhou", SecretID: "id"}, "", true},
+		{"invalid endpoint", ClientConfig{Endpoint: "static.example.com", SecretID: "id", SecretKey: "key"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewCOSClient(tt.conf)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewCOSClient() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && client.URL("post/a.png") != tt.wantURL {
+				t.Errorf("URL() = %s, want %s", client.URL("post/a.png"), tt.wantURL)
+			}
+		})
+	}
+}
+
+func newTestStorage(t *testing.T, config map[string]interface{}) (*Storage, *fakeCOS) {
+	fake := &fakeCOS{objects: make(map[string]*fakeObject)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	config["endpoint"] = server.URL
+	config["secret_id"] = "id"
+	config["secret_key"] = "key"
+	s := &Storage{Config: &StorageConfig{}}
+	data, _ := json.Marshal(config)
+	if err := s.ConfigReceiver(data); err != nil {
+		t.Fatal(err)
+	}
+	return s, fake
+}
+
+func upload(t *testing.T, s *Storage, filename, content string) string {
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	part, _ := form.CreateFormFile("file", filename)
+	_, _ = part.Write([]byte(content))
+	_ = form.Close()
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/upload", body)
+	ctx.Request.Header.Set("Content-Type", form.FormDataContentType())
+	resp := s.UploadFile(ctx, plugin.UserPost)
+	if resp.OriginalError != nil {
+		t.Fatalf("UploadFile(%s) = %v", filename, resp.OriginalError)
+	}
+	return resp.FullURL
+}
+
+func TestUploadFile(t *testing.T) {
+	s, fake := newTestStorage(t, map[string]interface{}{
+		"object_key_prefix":  "answer/",
+		"object_key_scheme":  "content",
+		"allowed_extensions": "pdf,svg",
+	})
+
+	url := upload(t, s, "report.pdf", "%PDF-1.7\n1 0 obj")
+	prefix := s.Client.URL("")
+	if !strings.HasPrefix(url, prefix+"answer/post/") || !strings.HasSuffix(url, ".pdf") {
+		t.Fatalf("url = %s", url)
+	}
+	object := fake.objects["/"+strings.TrimPrefix(url, prefix)]
+	if object == nil || string(object.data) != "%PDF-1.7\n1 0 obj" || object.contentType != "application/pdf" {
+		t.Fatalf("object = %+v", object)
+	}
+	if again := upload(t, s, "copy.pdf", "%PDF-1.7\n1 0 obj"); again != url || fake.puts != 1 {
+		t.Errorf("same content stored twice: %s, %s", url, again)
+	}
+
+	// the files running scripts are downloaded
+	url = upload(t, s, "logo.svg", `<svg xmlns="http://www.w3.org/2000/svg"></svg>`)
+	if object = fake.objects["/"+strings.TrimPrefix(url, prefix)]; object == nil || object.contentDisposition != "attachment; filename=logo.svg" {
+		t.Errorf("object = %+v", object)
+	}
+}
+
+func TestUploadFileRules(t *testing.T) {
+	s, fake := newTestStorage(t, map[string]interface{}{
+		"visit_url_prefix": "https://cdn.example.com/",
+		"max_file_size":    "1",
+	})
+	if url := upload(t, s, "photo.png", "\x89PNG\r\n\x1a\n"); !strings.HasPrefix(url, "https://cdn.example.com/post/") {
+		t.Errorf("url = %s", url)
+	}
+
+	for filename, content := range map[string]string{
+		"report.pdf": "%PDF-1.7",
+		"large.png":  strings.Repeat("x", 1024*1024+1),
+	} {
+		body := &bytes.Buffer{}
+		form := multipart.NewWriter(body)
+		part, _ := form.CreateFormFile("file", filename)
+		_, _ = part.Write([]byte(content))
+		_ = form.Close()
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/upload", body)
+		ctx.Request.Header.Set("Content-Type", form.FormDataContentType())
+		if resp := s.UploadFile(ctx, plugin.UserPost); resp.OriginalError == nil {
+			t.Errorf("UploadFile(%s) no error", filename)
+		}
+	}
+	if fake.puts != 1 {
+		t.Errorf("%d objects stored, want 1", fake.puts)
+	}
+}
@@ -0,0 +1,80 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+// Package aliyunrpc signs the requests of the Aliyun RPC APIs, like STS and CDN, with the signature 1.0.
+package aliyunrpc
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SignedQuery returns the signed query of a GET of the RPC API. The params are the action and its parameters,
+// the common parameters of the access key are added.
+func SignedQuery(accessKeyID, accessKeySecret string, params map[string]string) string {
+	nonce := make([]byte, 16)
+	_, _ = rand.Read(nonce)
+	signed := map[string]string{
+		"Format":           "JSON",
+		"AccessKeyId":      accessKeyID,
+		"SignatureMethod":  "HMAC-SHA1",
+		"SignatureVersion": "1.0",
+		"SignatureNonce":   hex.EncodeToString(nonce),
+		"Timestamp":        time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+	}
+	for name, value := range params {
+		signed[name] = value
+	}
+	query := CanonicalQuery(signed)
+	return query + "&Signature=" + PercentEncode(Signature(accessKeySecret, http.MethodGet, query))
+}
+
+// CanonicalQuery returns the query sorted by name and percent encoded, the string signed by the RPC APIs.
+func CanonicalQuery(params map[string]string) string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, PercentEncode(name)+"="+PercentEncode(params[name]))
+	}
+	return strings.Join(pairs, "&")
+}
+
+// Signature signs the canonical query with HMAC-SHA1.
+func Signature(accessKeySecret, method, query string) string {
+	stringToSign := method + "&" + PercentEncode("/") + "&" + PercentEncode(query)
+	mac := hmac.New(sha1.New, []byte(accessKeySecret+"&"))
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// PercentEncode encodes like RFC 3986, as the signature of the RPC APIs requires.
+func PercentEncode(s string) string {
+	return strings.NewReplacer("+", "%20", "*", "%2A", "%7E", "~").Replace(url.QueryEscape(s))
+}
@@ -0,0 +1,67 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+package aliyunrpc
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestPercentEncode(t *testing.T) {
+	if got := PercentEncode("a b*c~d/é"); got != "a%20b%2Ac~d%2F%C3%A9" {
+		t.Errorf("PercentEncode = %s", got)
+	}
+}
+
+func TestSignature(t *testing.T) {
+	// the example of the signature 1.0 in the documentation of Aliyun
+	query := CanonicalQuery(map[string]string{
+		"AccessKeyId":      "testid",
+		"Action":           "DescribeRegions",
+		"Format":           "XML",
+		"SignatureMethod":  "HMAC-SHA1",
+		"SignatureNonce":   "3ee8c1b8-83d3-44af-a94f-4e0ad82fd6cf",
+		"SignatureVersion": "1.0",
+		"Timestamp":        "2016-02-23T12:46:24Z",
+		"Version":          "2014-05-26",
+	})
+	if got := Signature("testsecret", http.MethodGet, query); got != "OLeaidS1JvxuMvnyHOwuJ+uX5qY=" {
+		t.Errorf("Signature = %s", got)
+	}
+}
+
+func TestSignedQuery(t *testing.T) {
+	query, err := url.ParseQuery(SignedQuery("key", "secret", map[string]string{"Action": "AssumeRole", "RoleArn": "acs:ram::1:role/answer"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := make(map[string]string)
+	for name := range query {
+		if name != "Signature" {
+			params[name] = query.Get(name)
+		}
+	}
+	if params["Action"] != "AssumeRole" || params["AccessKeyId"] != "key" || len(params["SignatureNonce"]) == 0 {
+		t.Errorf("params = %v", params)
+	}
+	if query.Get("Signature") != Signature("secret", http.MethodGet, CanonicalQuery(params)) {
+		t.Errorf("signature %s doesn't match", query.Get("Signature"))
+	}
+}
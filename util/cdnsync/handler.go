@@ -0,0 +1,99 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package cdnsync
+
+import (
+	"context"
+	"sync"
+
+	"github.com/apache/incubator-answer-plugins/util/storageext"
+	"github.com/apache/incubator-answer/plugin"
+	"github.com/gin-gonic/gin"
+)
+
+// Status is the state of the publications of a CDN plugin.
+type Status struct {
+	Running    bool    `json:"running"`
+	LastReport *Report `json:"last_report"`
+}
+
+// Tracker keeps the status of the publications of a CDN plugin, and runs them one at a time.
+type Tracker struct {
+	lock   sync.Mutex
+	status Status
+	// running is held by the running publication, cancel cancels the last one started
+	running sync.Mutex
+	cancel  context.CancelFunc
+}
+
+// Run cancels the previous publication and runs publish in the background once it has ended,
+// so the publications of the successive configs never run at once. A publication replaced
+// before it started doesn't run.
+func (t *Tracker) Run(publish func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.lock.Lock()
+	if t.cancel != nil {
+		t.cancel()
+	}
+	t.cancel = cancel
+	t.lock.Unlock()
+
+	go func() {
+		defer cancel()
+		t.running.Lock()
+		defer t.running.Unlock()
+		if ctx.Err() == nil {
+			publish(ctx)
+		}
+	}()
+}
+
+// Begin marks a publication running.
+func (t *Tracker) Begin() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.status.Running = true
+}
+
+// End records the report of the finished publication.
+func (t *Tracker) End(report *Report) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.status.Running = false
+	t.status.LastReport = report
+}
+
+// Status returns the status of the publications.
+func (t *Tracker) Status() Status {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.status
+}
+
+// StatusHandler responds the status of the publications, with the report of the last one and its purge.
+func StatusHandler(slugName string, tracker *Tracker) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !plugin.StatusManager.IsEnabled(slugName) {
+			storageext.HandleNotFound(ctx)
+			return
+		}
+		storageext.HandleResponse(ctx, tracker.Status())
+	}
+}
@@ -0,0 +1,165 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package cdnsync
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+// The encodings of the precompressed variants, which are uploaded next to the files with the extensions in
+// encodingExts. The Precompression config is one of them or both joined by a comma.
+const (
+	EncodingGzip   = "gzip"
+	EncodingBrotli = "br"
+)
+
+const (
+	// ImmutableCacheControl is the Cache-Control of the files with a content hash in their names,
+	// which never change under the same name.
+	ImmutableCacheControl = "public, max-age=31536000, immutable"
+	// DefaultMaxAge is the max age of the other files, such as asset-manifest.json, index.html and favicon.ico.
+	DefaultMaxAge = 5 * time.Minute
+	// minCompressSize is the size of the smallest file precompressed.
+	minCompressSize = 1024
+)
+
+var encodingExts = map[string]string{
+	EncodingGzip:   ".gz",
+	EncodingBrotli: ".br",
+}
+
+// contentTypes are the types of the files of the build, the others are looked up by mime.TypeByExtension.
+var contentTypes = map[string]string{
+	".html":  "text/html; charset=utf-8",
+	".js":    "text/javascript; charset=utf-8",
+	".mjs":   "text/javascript; charset=utf-8",
+	".css":   "text/css; charset=utf-8",
+	".json":  "application/json",
+	".map":   "application/json",
+	".txt":   "text/plain; charset=utf-8",
+	".svg":   "image/svg+xml",
+	".png":   "image/png",
+	".jpg":   "image/jpeg",
+	".jpeg":  "image/jpeg",
+	".gif":   "image/gif",
+	".webp":  "image/webp",
+	".ico":   "image/x-icon",
+	".woff":  "font/woff",
+	".woff2": "font/woff2",
+	".ttf":   "font/ttf",
+	".eot":   "application/vnd.ms-fontobject",
+}
+
+// compressibleTypes are the text types worth precompressing, the images and fonts are compressed already.
+var compressibleTypes = []string{"text/", "application/json", "application/javascript", "image/svg+xml"}
+
+// hashedName matches the content hash in the names of the bundler output, like main.3f2a9c1b.js,
+// 123.3f2a9c1b.chunk.css or logo.3f2a9c1b5d6e7f80a1b2.svg.
+var hashedName = regexp.MustCompile(`\.[0-9a-f]{8,}\.`)
+
+// Headers are the HTTP headers of an object.
+type Headers struct {
+	ContentType     string
+	CacheControl    string
+	ContentEncoding string
+}
+
+// ContentType returns the MIME type of the file by its extension.
+func ContentType(filePath string) string {
+	ext := strings.ToLower(path.Ext(filePath))
+	if contentType, ok := contentTypes[ext]; ok {
+		return contentType
+	}
+	if contentType := mime.TypeByExtension(ext); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// IsHashed reports whether the name of the file has a content hash.
+func IsHashed(filePath string) bool {
+	return hashedName.MatchString(path.Base(filePath))
+}
+
+// CacheControl returns the Cache-Control of the file: a long immutable max age if the name of the file has a
+// content hash, or the max age otherwise, so that the new versions of the files are picked up soon.
+func CacheControl(filePath string, maxAge time.Duration) string {
+	if IsHashed(filePath) {
+		return ImmutableCacheControl
+	}
+	return "public, max-age=" + strconv.Itoa(int(maxAge.Seconds()))
+}
+
+// ParseMaxAge parses the max age config, a number of seconds. An empty or invalid config is DefaultMaxAge.
+func ParseMaxAge(seconds string) time.Duration {
+	n, err := strconv.Atoi(strings.TrimSpace(seconds))
+	if err != nil || n < 0 {
+		return DefaultMaxAge
+	}
+	return time.Duration(n) * time.Second
+}
+
+// ParseEncodings parses the precompression config, the encodings joined by commas. The unknown ones are ignored.
+func ParseEncodings(config string) (encodings []string) {
+	for _, encoding := range strings.Split(config, ",") {
+		encoding = strings.TrimSpace(encoding)
+		if _, ok := encodingExts[encoding]; ok {
+			encodings = append(encodings, encoding)
+		}
+	}
+	return encodings
+}
+
+func compressible(contentType string) bool {
+	for _, prefix := range compressibleTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// compress returns the content of r compressed with the encoding.
+func compress(r io.Reader, encoding string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	var w io.WriteCloser
+	if encoding == EncodingBrotli {
+		w = brotli.NewWriterLevel(buf, brotli.BestCompression)
+	} else {
+		w, _ = gzip.NewWriterLevel(buf, gzip.BestCompression)
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
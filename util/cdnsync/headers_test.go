@@ -0,0 +1,144 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package cdnsync
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestContentType(t *testing.T) {
+	tests := map[string]string{
+		"static/js/main.3f2a9c1b.js":        "text/javascript; charset=utf-8",
+		"static/js/main.3f2a9c1b.js.map":    "application/json",
+		"static/css/main.3f2a9c1b.css":      "text/css; charset=utf-8",
+		"static/media/logo.3f2a9c1b.JPG":    "image/jpeg",
+		"static/media/font.3f2a9c1b.woff2":  "font/woff2",
+		"favicon.ico":                       "image/x-icon",
+		"robots.txt":                        "text/plain; charset=utf-8",
+		"static/media/unknown.3f2a9c1b.zzz": "application/octet-stream",
+	}
+	for filePath, want := range tests {
+		if got := ContentType(filePath); got != want {
+			t.Errorf("content type of %s: got %q, want %q", filePath, got, want)
+		}
+	}
+}
+
+func TestCacheControl(t *testing.T) {
+	tests := map[string]string{
+		"static/js/main.3f2a9c1b.js":          ImmutableCacheControl,
+		"static/js/123.3f2a9c1b.chunk.js":     ImmutableCacheControl,
+		"static/media/logo.3f2a9c1b5d6e7.svg": ImmutableCacheControl,
+		"asset-manifest.json":                 "public, max-age=300",
+		"index.html":                          "public, max-age=300",
+		"static/js/main.js":                   "public, max-age=300",
+		"static/media/cafe.svg":               "public, max-age=300",
+	}
+	for filePath, want := range tests {
+		if got := CacheControl(filePath, DefaultMaxAge); got != want {
+			t.Errorf("cache control of %s: got %q, want %q", filePath, got, want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	if got := ParseMaxAge(""); got != DefaultMaxAge {
+		t.Errorf("empty max age: got %v", got)
+	}
+	if got := ParseMaxAge("60"); got != time.Minute {
+		t.Errorf("max age: got %v", got)
+	}
+	if got := ParseEncodings("gzip, br,deflate"); !reflect.DeepEqual(got, []string{EncodingGzip, EncodingBrotli}) {
+		t.Errorf("encodings: got %v", got)
+	}
+	if got := ParseEncodings(""); got != nil {
+		t.Errorf("empty encodings: got %v", got)
+	}
+}
+
+func TestPublishHeadersAndVariants(t *testing.T) {
+	bucket := newMemBucket()
+	srv, _ := serve(t, bucket)
+	js := strings.Repeat("console.log('answer');\n", 100)
+	fsys := fstest.MapFS{
+		"asset-manifest.json":         {Data: []byte(`{}`)},
+		"static/js/main.3f2a9c1b.js":  {Data: []byte(js)},
+		"static/media/x.3f2a9c1b.png": {Data: bytes.Repeat([]byte{1}, 2048)},
+	}
+	publishFS := func(opts Options) *Report {
+		opts.VisitURLPrefix = srv.URL + "/"
+		p := NewPublisher(context.Background(), bucket, opts)
+		p.Scan(fsys, ScanOptions{})
+		report, err := p.Finish()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return report
+	}
+
+	report := publishFS(Options{Encodings: []string{EncodingGzip, EncodingBrotli}})
+	// the manifest is too small and the image is not compressible
+	if report.Uploaded != 3 || report.Variants != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	want := map[string]Headers{
+		"asset-manifest.json":           {ContentType: "application/json", CacheControl: "public, max-age=300"},
+		"static/js/main.3f2a9c1b.js":    {ContentType: "text/javascript; charset=utf-8", CacheControl: ImmutableCacheControl},
+		"static/js/main.3f2a9c1b.js.gz": {ContentType: "text/javascript; charset=utf-8", CacheControl: ImmutableCacheControl, ContentEncoding: EncodingGzip},
+		"static/js/main.3f2a9c1b.js.br": {ContentType: "text/javascript; charset=utf-8", CacheControl: ImmutableCacheControl, ContentEncoding: EncodingBrotli},
+		"static/media/x.3f2a9c1b.png":   {ContentType: "image/png", CacheControl: ImmutableCacheControl},
+	}
+	for key, headers := range want {
+		if got := bucket.headers[key]; got != headers {
+			t.Errorf("headers of %s: got %+v, want %+v", key, got, headers)
+		}
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(bucket.objects["static/js/main.3f2a9c1b.js.gz"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(gz); string(data) != js {
+		t.Error("gzip variant differs from the file")
+	}
+	br := brotli.NewReader(bytes.NewReader(bucket.objects["static/js/main.3f2a9c1b.js.br"]))
+	if data, _ := io.ReadAll(br); string(data) != js {
+		t.Error("brotli variant differs from the file")
+	}
+
+	// the changed max age uploads the files without a content hash again
+	report = publishFS(Options{MaxAge: time.Minute, Encodings: []string{EncodingGzip, EncodingBrotli}})
+	if report.Uploaded != 1 || report.Unchanged != 2 {
+		t.Fatalf("unexpected report after the max age changed %+v", report)
+	}
+	if got := bucket.headers["asset-manifest.json"].CacheControl; got != "public, max-age=60" {
+		t.Errorf("cache control of the manifest: got %q", got)
+	}
+}
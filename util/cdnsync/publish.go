@@ -0,0 +1,397 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+// Package cdnsync publishes the static files of the Answer build to the bucket behind a CDN.
+// The hashes of the published files are kept in a manifest in the bucket, so only the changed
+// files are uploaded again each time the plugin is configured.
+package cdnsync
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentfault/pacman/log"
+)
+
+const (
+	// ManifestName is the name of the manifest under the object key prefix.
+	ManifestName = ".answer-cdn-manifest.json"
+	// DefaultConcurrency is the number of the concurrent uploads and verifications.
+	DefaultConcurrency = 8
+	// MaxReportedErrors is the max number of errors listed in a report.
+	MaxReportedErrors = 20
+)
+
+// ErrNotFound is returned by Bucket.GetObject when the object does not exist.
+var ErrNotFound = errors.New("object not found")
+
+// Bucket is the storage behind the CDN.
+type Bucket interface {
+	// GetObject reads the whole object, or returns ErrNotFound.
+	GetObject(ctx context.Context, key string) ([]byte, error)
+	// PutObject writes the object with the headers.
+	PutObject(ctx context.Context, key string, body io.ReadSeeker, headers Headers) error
+}
+
+// Manifest maps the paths of the published files to the SHA-256 of their contents and headers,
+// so the files are uploaded again when the headers are changed by the config.
+type Manifest struct {
+	Files map[string]string `json:"files"`
+}
+
+// Options are the options of a Publisher.
+type Options struct {
+	// Prefix is the object key prefix of the files and the manifest.
+	Prefix string
+	// VisitURLPrefix is followed by the object keys to visit the files through the CDN.
+	VisitURLPrefix string
+	// MaxAge is the max age of the files without a content hash in their names, see CacheControl.
+	MaxAge time.Duration
+	// Encodings are the encodings of the precompressed variants uploaded next to the text files.
+	Encodings []string
+	// Purger purges the CDN after a publication uploading files without failures, nothing is purged if it is nil.
+	Purger Purger
+	// PurgePaths are the paths purged, relative to the prefix. The ones ending with a slash are directories.
+	// By default the uploaded files without a content hash in their names are purged, as the hashed ones are new.
+	PurgePaths []string
+	// Concurrency is the number of the concurrent uploads, DefaultConcurrency if it is not positive.
+	Concurrency int
+	// HTTPClient verifies the files, http.DefaultClient if it is nil.
+	HTTPClient *http.Client
+}
+
+// Report is the result of a publication.
+type Report struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Files      int       `json:"files"`
+	// Uploaded files are new or changed since the last publication, Unchanged ones match the manifest.
+	Uploaded  int `json:"uploaded"`
+	Unchanged int `json:"unchanged"`
+	Failed    int `json:"failed"`
+	// Variants is the number of the precompressed variants uploaded.
+	Variants int `json:"variants"`
+	// Verified is the number of files visited through the CDN after the uploads.
+	Verified int `json:"verified"`
+	// Purged is the number of the URLs purged, and PurgeError fails the purge but not the publication.
+	Purged     int      `json:"purged"`
+	PurgeError string   `json:"purge_error,omitempty"`
+	Errors     []string `json:"errors,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// Publisher uploads the scanned files that differ from the manifest, then verifies them through the CDN.
+type Publisher struct {
+	ctx      context.Context
+	bucket   Bucket
+	opts     Options
+	previous map[string]string
+	assets   chan asset
+	wg       sync.WaitGroup
+
+	lock     sync.Mutex
+	files    map[string]string
+	uploaded []string
+	report   *Report
+}
+
+// NewPublisher reads the manifest of the last publication and starts the uploaders.
+// A missing or broken manifest makes all the files uploaded again.
+func NewPublisher(ctx context.Context, bucket Bucket, opts Options) *Publisher {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultConcurrency
+	}
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = DefaultMaxAge
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	p := &Publisher{
+		ctx:      ctx,
+		bucket:   bucket,
+		opts:     opts,
+		previous: map[string]string{},
+		assets:   make(chan asset),
+		files:    map[string]string{},
+		report:   &Report{StartedAt: time.Now()},
+	}
+
+	data, err := bucket.GetObject(ctx, opts.Prefix+ManifestName)
+	if err == nil {
+		manifest := &Manifest{}
+		if err = json.Unmarshal(data, manifest); err == nil && manifest.Files != nil {
+			p.previous = manifest.Files
+		}
+	}
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		log.Warnf("cdn: read manifest failed, all the files will be uploaded: %v", err)
+	}
+
+	for i := 0; i < opts.Concurrency; i++ {
+		p.wg.Add(1)
+		go p.upload()
+	}
+	return p
+}
+
+func (p *Publisher) upload() {
+	defer p.wg.Done()
+	for a := range p.assets {
+		if err := p.publish(a); err != nil {
+			p.fail(err)
+		}
+	}
+}
+
+// publish hashes the file, and uploads it if the hash differs from the manifest.
+func (p *Publisher) publish(a asset) error {
+	p.lock.Lock()
+	p.report.Files++
+	p.lock.Unlock()
+
+	hash, size, err := a.hash()
+	if err != nil {
+		return fmt.Errorf("read %s failed: %w", a.path, err)
+	}
+	headers := Headers{
+		ContentType:  ContentType(a.path),
+		CacheControl: CacheControl(a.path, p.opts.MaxAge),
+	}
+	encodings := p.opts.Encodings
+	if size < minCompressSize || !compressible(headers.ContentType) {
+		encodings = nil
+	}
+	hash = fingerprint(hash, headers, encodings)
+
+	p.lock.Lock()
+	unchanged := p.previous[a.path] == hash
+	if unchanged {
+		p.files[a.path] = hash
+		p.report.Unchanged++
+	}
+	p.lock.Unlock()
+	if unchanged {
+		return nil
+	}
+
+	file, err := a.open()
+	if err != nil {
+		return fmt.Errorf("read %s failed: %w", a.path, err)
+	}
+	defer file.Close()
+	// the files are seekable, except the rewritten ones which are small enough to be read in memory
+	body, ok := file.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return fmt.Errorf("read %s failed: %w", a.path, err)
+		}
+		body = bytes.NewReader(data)
+	}
+	if err = p.bucket.PutObject(p.ctx, p.opts.Prefix+a.path, body, headers); err != nil {
+		return fmt.Errorf("upload %s failed: %w", a.path, err)
+	}
+	for _, encoding := range encodings {
+		if err = p.publishVariant(a, headers, encoding, size); err != nil {
+			return err
+		}
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.files[a.path] = hash
+	p.uploaded = append(p.uploaded, a.path)
+	p.report.Uploaded++
+	return nil
+}
+
+// publishVariant uploads the file compressed with the encoding, unless compression does not make it smaller.
+func (p *Publisher) publishVariant(a asset, headers Headers, encoding string, size int64) error {
+	file, err := a.open()
+	if err != nil {
+		return fmt.Errorf("read %s failed: %w", a.path, err)
+	}
+	defer file.Close()
+	data, err := compress(file, encoding)
+	if err != nil {
+		return fmt.Errorf("compress %s failed: %w", a.path, err)
+	}
+	if int64(len(data)) >= size {
+		return nil
+	}
+
+	headers.ContentEncoding = encoding
+	key := p.opts.Prefix + a.path + encodingExts[encoding]
+	if err = p.bucket.PutObject(p.ctx, key, bytes.NewReader(data), headers); err != nil {
+		return fmt.Errorf("upload %s failed: %w", key, err)
+	}
+	p.lock.Lock()
+	p.report.Variants++
+	p.lock.Unlock()
+	return nil
+}
+
+func (p *Publisher) fail(err error) {
+	log.Error("cdn: ", err)
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.report.Failed++
+	if len(p.report.Errors) < MaxReportedErrors {
+		p.report.Errors = append(p.report.Errors, err.Error())
+	}
+}
+
+// Finish waits for the uploads of the scanned files, saves the manifest and verifies the uploaded files through the CDN in one pass.
+// When nothing was uploaded, a single file is verified to make sure the CDN serves the files.
+// The failed files are left out of the manifest, so they are uploaded again next time, and the uploaded ones are purged.
+func (p *Publisher) Finish() (*Report, error) {
+	close(p.assets)
+	p.wg.Wait()
+
+	if !sameFiles(p.previous, p.files) {
+		data, _ := json.Marshal(&Manifest{Files: p.files})
+		headers := Headers{ContentType: "application/json", CacheControl: "no-cache"}
+		if err := p.bucket.PutObject(p.ctx, p.opts.Prefix+ManifestName, bytes.NewReader(data), headers); err != nil {
+			p.fail(fmt.Errorf("save manifest failed: %w", err))
+		}
+	}
+
+	paths := p.uploaded
+	if len(paths) == 0 && len(p.files) > 0 {
+		for path := range p.files {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		paths = paths[:1]
+	}
+	p.verify(paths)
+
+	// the uploaded files are purged even when others failed, they are in the manifest and won't be uploaded again
+	if p.report.Uploaded > 0 && p.opts.Purger != nil {
+		p.purge()
+	}
+
+	report := p.report
+	report.FinishedAt = time.Now()
+	if report.Failed > 0 {
+		err := fmt.Errorf("%d failures in publishing %d files, the first: %s", report.Failed, report.Files, report.Errors[0])
+		report.Error = err.Error()
+		return report, err
+	}
+	return report, nil
+}
+
+// purge purges the purge paths, or the uploaded files without a content hash.
+func (p *Publisher) purge() {
+	paths := append([]string(nil), p.opts.PurgePaths...)
+	if len(paths) == 0 {
+		for _, path := range p.uploaded {
+			if !IsHashed(path) {
+				paths = append(paths, path)
+			}
+		}
+	}
+	if len(paths) == 0 {
+		return
+	}
+	sort.Strings(paths)
+
+	urls := make([]string, 0, len(paths))
+	for _, path := range paths {
+		urls = append(urls, p.opts.VisitURLPrefix+p.opts.Prefix+path)
+	}
+	if err := p.opts.Purger.Purge(p.ctx, urls); err != nil {
+		log.Warnf("cdn: purge failed: %v", err)
+		p.report.PurgeError = err.Error()
+		return
+	}
+	p.report.Purged = len(urls)
+}
+
+// verify visits the files through the CDN with the concurrency of the uploads.
+func (p *Publisher) verify(paths []string) {
+	sem := make(chan struct{}, p.opts.Concurrency)
+	wg := sync.WaitGroup{}
+	for _, path := range paths {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(path string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := p.check(p.opts.VisitURLPrefix + p.opts.Prefix + path); err != nil {
+				p.fail(fmt.Errorf("verify %s failed: %w", path, err))
+				return
+			}
+			p.lock.Lock()
+			p.report.Verified++
+			p.lock.Unlock()
+		}(path)
+	}
+	wg.Wait()
+}
+
+func (p *Publisher) check(url string) error {
+	req, err := http.NewRequestWithContext(p.ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.opts.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// fingerprint is the hash of the content and how it is published.
+func fingerprint(hash string, headers Headers, encodings []string) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		hash, headers.ContentType, headers.CacheControl, strings.Join(encodings, ","),
+	}, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+func sameFiles(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, hash := range a {
+		if b[path] != hash {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,212 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package cdnsync
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+type memBucket struct {
+	lock    sync.Mutex
+	objects map[string][]byte
+	headers map[string]Headers
+	puts    []string
+	fail    string
+}
+
+func newMemBucket() *memBucket {
+	return &memBucket{objects: map[string][]byte{}, headers: map[string]Headers{}}
+}
+
+func (b *memBucket) GetObject(ctx context.Context, key string) ([]byte, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	data, ok := b.objects[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return data, nil
+}
+
+func (b *memBucket) PutObject(ctx context.Context, key string, body io.ReadSeeker, headers Headers) error {
+	if key == b.fail {
+		return errors.New("denied")
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.objects[key] = data
+	b.headers[key] = headers
+	b.puts = append(b.puts, key)
+	return nil
+}
+
+// serve serves the objects of the bucket like a CDN, and counts the visits.
+func serve(t *testing.T, bucket *memBucket) (*httptest.Server, *int) {
+	visits := 0
+	lock := sync.Mutex{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		visits++
+		lock.Unlock()
+		if _, err := bucket.GetObject(r.Context(), strings.TrimPrefix(r.URL.Path, "/")); err != nil {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &visits
+}
+
+func publish(t *testing.T, bucket Bucket, url string, files map[string]string) (*Report, error) {
+	fsys := fstest.MapFS{}
+	for path, content := range files {
+		fsys[path] = &fstest.MapFile{Data: []byte(content)}
+	}
+	p := NewPublisher(context.Background(), bucket, Options{Prefix: "static/", VisitURLPrefix: url + "/", Concurrency: 2})
+	p.Scan(fsys, ScanOptions{})
+	return p.Finish()
+}
+
+func TestPublishIncremental(t *testing.T) {
+	bucket := newMemBucket()
+	srv, visits := serve(t, bucket)
+	files := map[string]string{
+		"index.html":          "<html></html>",
+		"static/js/main.js":   "console.log(1)",
+		"static/css/main.css": "body{}",
+	}
+
+	report, err := publish(t, bucket, srv.URL, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Uploaded != 3 || report.Unchanged != 0 || report.Verified != 3 || *visits != 3 {
+		t.Fatalf("unexpected first report %+v, %d visits", report, *visits)
+	}
+	if _, ok := bucket.objects["static/"+ManifestName]; !ok {
+		t.Fatal("manifest is not saved")
+	}
+
+	// nothing changed, only one file is verified and the manifest is kept
+	bucket.puts = nil
+	*visits = 0
+	report, err = publish(t, bucket, srv.URL, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Uploaded != 0 || report.Unchanged != 3 || report.Verified != 1 || *visits != 1 || len(bucket.puts) != 0 {
+		t.Fatalf("unexpected second report %+v, %d visits, puts %v", report, *visits, bucket.puts)
+	}
+
+	files["static/js/main.js"] = "console.log(2)"
+	report, err = publish(t, bucket, srv.URL, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Uploaded != 1 || report.Unchanged != 2 {
+		t.Fatalf("unexpected third report %+v", report)
+	}
+	if string(bucket.objects["static/static/js/main.js"]) != "console.log(2)" {
+		t.Fatal("changed file is not uploaded")
+	}
+}
+
+func TestPublishFailure(t *testing.T) {
+	bucket := newMemBucket()
+	srv, _ := serve(t, bucket)
+	files := map[string]string{"a.js": "a", "b.js": "b"}
+
+	bucket.fail = "static/b.js"
+	report, err := publish(t, bucket, srv.URL, files)
+	if err == nil || report.Failed != 1 || report.Uploaded != 1 {
+		t.Fatalf("expected the failure to be reported, got %+v, %v", report, err)
+	}
+
+	// the failed file is left out of the manifest and uploaded again
+	bucket.fail = ""
+	bucket.puts = nil
+	report, err = publish(t, bucket, srv.URL, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Uploaded != 1 || report.Unchanged != 1 || bucket.puts[0] != "static/b.js" {
+		t.Fatalf("unexpected retry report %+v, puts %v", report, bucket.puts)
+	}
+}
+
+func TestPublishVerifyFailure(t *testing.T) {
+	bucket := newMemBucket()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	report, err := publish(t, bucket, srv.URL, map[string]string{"a.js": "a"})
+	if err == nil || report.Verified != 0 || report.Failed != 1 {
+		t.Fatalf("expected the verification to fail, got %+v, %v", report, err)
+	}
+}
+
+func TestTrackerRun(t *testing.T) {
+	tracker := &Tracker{}
+	var running, ran int32
+	done := make(chan struct{}, 3)
+	publish := func(ctx context.Context) {
+		defer func() { done <- struct{}{} }()
+		if atomic.AddInt32(&running, 1) != 1 {
+			t.Error("publications running at once")
+		}
+		defer atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&ran, 1)
+		<-ctx.Done()
+	}
+
+	tracker.Run(publish)
+	for atomic.LoadInt32(&ran) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// the first one is canceled, the second is replaced before it starts
+	tracker.Run(publish)
+	tracker.Run(publish)
+	<-done
+	for atomic.LoadInt32(&ran) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	tracker.lock.Lock()
+	tracker.cancel()
+	tracker.lock.Unlock()
+	<-done
+	if got := atomic.LoadInt32(&ran); got != 2 {
+		t.Fatalf("%d publications ran, want 2", got)
+	}
+}
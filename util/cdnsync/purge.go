@@ -0,0 +1,103 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package cdnsync
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Purger invalidates the URLs in the edge caches of the CDN. The URLs ending with a slash are directories,
+// whose files are all invalidated.
+type Purger interface {
+	Purge(ctx context.Context, urls []string) error
+}
+
+// SignatureHeader is the header of the signature of the webhook requests.
+const SignatureHeader = "X-Answer-Signature"
+
+// ParsePaths parses the purge paths config, one path relative to the object key prefix per line.
+func ParsePaths(config string) (paths []string) {
+	for _, line := range strings.Split(config, "\n") {
+		if line = strings.TrimPrefix(strings.TrimSpace(line), "/"); line != "" {
+			paths = append(paths, line)
+		}
+	}
+	return paths
+}
+
+// Batches splits the URLs into the batches of the size, for the APIs limiting the URLs of a request.
+func Batches(urls []string, size int) (batches [][]string) {
+	for len(urls) > size {
+		batches = append(batches, urls[:size])
+		urls = urls[size:]
+	}
+	if len(urls) > 0 {
+		batches = append(batches, urls)
+	}
+	return batches
+}
+
+// WebhookPurger purges the CDNs without a provider integration. It posts the URLs to the webhook as
+// {"urls": [...]}, and any 2xx response is a success. With a secret, the hex HMAC-SHA256 of the body
+// is sent in SignatureHeader.
+type WebhookPurger struct {
+	URL    string
+	Secret string
+	// Client is http.DefaultClient if it is nil.
+	Client *http.Client
+}
+
+func (w *WebhookPurger) Purge(ctx context.Context, urls []string) error {
+	body, _ := json.Marshal(map[string][]string{"urls": urls})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(w.Secret))
+		mac.Write(body)
+		req.Header.Set(SignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("purge webhook responded %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package cdnsync
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+type recordPurger struct {
+	calls [][]string
+	err   error
+}
+
+func (r *recordPurger) Purge(ctx context.Context, urls []string) error {
+	r.calls = append(r.calls, urls)
+	return r.err
+}
+
+func TestPurge(t *testing.T) {
+	bucket := newMemBucket()
+	srv, _ := serve(t, bucket)
+	fsys := fstest.MapFS{
+		"asset-manifest.json":        {Data: []byte(`{}`)},
+		"favicon.ico":                {Data: []byte("ico")},
+		"static/js/main.3f2a9c1b.js": {Data: []byte("js")},
+	}
+	publishWith := func(opts Options) *Report {
+		opts.Prefix = "static/"
+		opts.VisitURLPrefix = srv.URL + "/"
+		p := NewPublisher(context.Background(), bucket, opts)
+		p.Scan(fsys, ScanOptions{})
+		report, err := p.Finish()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return report
+	}
+
+	// the files without a content hash are purged
+	purger := &recordPurger{}
+	report := publishWith(Options{Purger: purger})
+	want := [][]string{{srv.URL + "/static/asset-manifest.json", srv.URL + "/static/favicon.ico"}}
+	if !reflect.DeepEqual(purger.calls, want) || report.Purged != 2 {
+		t.Fatalf("unexpected purge %v, report %+v", purger.calls, report)
+	}
+
+	// nothing is uploaded, nothing is purged
+	purger = &recordPurger{}
+	publishWith(Options{Purger: purger})
+	if len(purger.calls) != 0 {
+		t.Fatalf("unexpected purge without uploads %v", purger.calls)
+	}
+
+	// the configured paths are purged, and the failed purge does not fail the publication
+	fsys["favicon.ico"] = &fstest.MapFile{Data: []byte("new")}
+	purger = &recordPurger{err: errors.New("quota exceeded")}
+	report = publishWith(Options{Purger: purger, PurgePaths: ParsePaths("/asset-manifest.json\n\nstatic/js/\n")})
+	want = [][]string{{srv.URL + "/static/asset-manifest.json", srv.URL + "/static/static/js/"}}
+	if !reflect.DeepEqual(purger.calls, want) || report.Purged != 0 || report.PurgeError != "quota exceeded" {
+		t.Fatalf("unexpected purge %v, report %+v", purger.calls, report)
+	}
+}
+
+func TestPurgeAfterFailure(t *testing.T) {
+	bucket := newMemBucket()
+	bucket.fail = "b.js"
+	srv, _ := serve(t, bucket)
+	purger := &recordPurger{}
+	p := NewPublisher(context.Background(), bucket, Options{VisitURLPrefix: srv.URL + "/", Purger: purger})
+	p.Scan(fstest.MapFS{"a.js": {Data: []byte("a")}, "b.js": {Data: []byte("b")}}, ScanOptions{})
+	report, err := p.Finish()
+	if err == nil || report.Error == "" {
+		t.Fatalf("expected the failure, got %+v", report)
+	}
+	// the uploaded file is in the manifest, so it is purged now or never
+	want := [][]string{{srv.URL + "/a.js"}}
+	if !reflect.DeepEqual(purger.calls, want) || report.Purged != 1 {
+		t.Fatalf("unexpected purge %v, report %+v", purger.calls, report)
+	}
+}
+
+func TestWebhookPurger(t *testing.T) {
+	var got struct {
+		URLs []string `json:"urls"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write(body)
+		if r.Header.Get(SignatureHeader) != hex.EncodeToString(mac.Sum(nil)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.Unmarshal(body, &got)
+	}))
+	defer srv.Close()
+
+	urls := []string{"https://cdn.example.com/asset-manifest.json"}
+	if err := (&WebhookPurger{URL: srv.URL, Secret: "secret"}).Purge(context.Background(), urls); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.URLs, urls) {
+		t.Fatalf("unexpected webhook urls %v", got.URLs)
+	}
+	if err := (&WebhookPurger{URL: srv.URL, Secret: "wrong"}).Purge(context.Background(), urls); err == nil {
+		t.Fatal("expected the rejected webhook to fail")
+	}
+}
+
+func TestBatches(t *testing.T) {
+	got := Batches([]string{"a", "b", "c", "d", "e"}, 2)
+	want := [][]string{{"a", "b"}, {"c", "d"}, {"e"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if Batches(nil, 2) != nil {
+		t.Fatal("expected no batches")
+	}
+}
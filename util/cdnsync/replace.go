@@ -0,0 +1,109 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package cdnsync
+
+import (
+	"bytes"
+	"io"
+)
+
+const replaceBufferSize = 32 * 1024
+
+// Replacement substitutes New for each Old in a file, the literal rewrite rules.
+type Replacement struct {
+	Old string
+	New string
+}
+
+// replaceReader substitutes the replacements in the stream in one pass. At each position the leftmost match wins,
+// and the earlier replacement wins if several match at the same position. The replaced text is not scanned again.
+type replaceReader struct {
+	src          io.ReadCloser
+	replacements []Replacement
+	// keep is the tail of the input held back, as a match may continue in the next read.
+	keep int
+	in   []byte
+	out  []byte
+	err  error
+}
+
+func newReplaceReader(src io.ReadCloser, replacements []Replacement) *replaceReader {
+	r := &replaceReader{src: src}
+	for _, rep := range replacements {
+		if rep.Old == "" {
+			continue
+		}
+		r.replacements = append(r.replacements, rep)
+		if len(rep.Old)-1 > r.keep {
+			r.keep = len(rep.Old) - 1
+		}
+	}
+	return r
+}
+
+func (r *replaceReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		buf := make([]byte, replaceBufferSize)
+		n, err := r.src.Read(buf)
+		r.in = append(r.in, buf[:n]...)
+		r.err = err
+		r.replace()
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// replace moves the input to the output, except the tail that may start a match before the end of the stream.
+func (r *replaceReader) replace() {
+	limit := len(r.in)
+	if r.err == nil {
+		limit -= r.keep
+	}
+	i := 0
+	for i < limit {
+		pos, match := -1, -1
+		for k, rep := range r.replacements {
+			j := bytes.Index(r.in[i:], []byte(rep.Old))
+			if j >= 0 && (pos < 0 || j < pos) {
+				pos, match = j, k
+			}
+		}
+		// a match starting before the limit is complete, the ones after it are found in the next round
+		if pos < 0 || i+pos >= limit {
+			break
+		}
+		r.out = append(r.out, r.in[i:i+pos]...)
+		r.out = append(r.out, r.replacements[match].New...)
+		i += pos + len(r.replacements[match].Old)
+	}
+	if i < limit {
+		r.out = append(r.out, r.in[i:limit]...)
+		i = limit
+	}
+	r.in = append(r.in[:0], r.in[i:]...)
+}
+
+func (r *replaceReader) Close() error {
+	return r.src.Close()
+}
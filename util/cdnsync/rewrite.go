@@ -0,0 +1,200 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package cdnsync
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// PrefixPlaceholder is replaced by the prefix of the files on the CDN in the replacement templates,
+// the visit URL prefix and the object key prefix without the trailing slash.
+const PrefixPlaceholder = "{prefix}"
+
+// Rule rewrites the files of the build, pointing the paths in them to the CDN.
+type Rule struct {
+	// Glob matches the base name of the files, or their path if it has a slash. It is a path.Match pattern
+	// with the alternatives in braces, like main{,.*}.{js,map} for main.js, main.3f2a9c1b.js and their maps.
+	Glob string `json:"glob"`
+	// Find is a literal string, or a regular expression if Regex is true.
+	Find  string `json:"find"`
+	Regex bool   `json:"regex,omitempty"`
+	// Replace is the replacement template with PrefixPlaceholder, and the submatches like $1 of the regular expressions.
+	Replace string `json:"replace"`
+}
+
+// DefaultRules point the static paths in the asset manifest and the main bundles of the Answer UI to the CDN.
+func DefaultRules() []Rule {
+	return []Rule{
+		{Glob: "asset-manifest.json", Find: `"/static`, Replace: `"` + PrefixPlaceholder + `/static`},
+		{Glob: "main{,.*}.{js,map}", Find: `"static`, Replace: `"` + PrefixPlaceholder + `/static`},
+		{Glob: "main{,.*}.{js,map}", Find: `="/",`, Replace: `="",`},
+		{Glob: "main{,.*}.css", Find: `url(/static`, Replace: `url(../../static`},
+	}
+}
+
+// ParseRules parses the rewrite rules config, a JSON array of the rules. An empty config is DefaultRules.
+func ParseRules(config string) ([]Rule, error) {
+	if strings.TrimSpace(config) == "" {
+		return DefaultRules(), nil
+	}
+	var rules []Rule
+	if err := json.Unmarshal([]byte(config), &rules); err != nil {
+		return nil, fmt.Errorf("parse rewrite rules failed: %w", err)
+	}
+	if _, err := NewRewriter(rules, ""); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+type rule struct {
+	globs   []string
+	literal *Replacement
+	re      *regexp.Regexp
+	replace string
+}
+
+func (r *rule) match(filePath string) bool {
+	name := filePath
+	if !strings.Contains(r.globs[0], "/") {
+		name = path.Base(filePath)
+	}
+	for _, glob := range r.globs {
+		if ok, _ := path.Match(glob, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Rewriter applies the rules to the files with the prefix of the files on the CDN.
+type Rewriter struct {
+	rules []*rule
+}
+
+// NewRewriter compiles the rules with the prefix, whose trailing slash is trimmed.
+func NewRewriter(rules []Rule, prefix string) (*Rewriter, error) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	w := &Rewriter{}
+	for i, r := range rules {
+		if r.Glob == "" || r.Find == "" {
+			return nil, fmt.Errorf("rewrite rule %d: glob and find are required", i+1)
+		}
+		compiled := &rule{
+			globs:   expandBraces(r.Glob),
+			replace: strings.ReplaceAll(r.Replace, PrefixPlaceholder, prefix),
+		}
+		for _, glob := range compiled.globs {
+			if _, err := path.Match(glob, ""); err != nil {
+				return nil, fmt.Errorf("rewrite rule %d: bad glob %s", i+1, r.Glob)
+			}
+		}
+		if r.Regex {
+			re, err := regexp.Compile(r.Find)
+			if err != nil {
+				return nil, fmt.Errorf("rewrite rule %d: %w", i+1, err)
+			}
+			compiled.re = re
+		} else {
+			compiled.literal = &Replacement{Old: r.Find, New: compiled.replace}
+		}
+		w.rules = append(w.rules, compiled)
+	}
+	return w, nil
+}
+
+// rulesOf returns the rules matching the file, in order.
+func (w *Rewriter) rulesOf(filePath string) (rules []*rule) {
+	if w == nil {
+		return nil
+	}
+	for _, r := range w.rules {
+		if r.match(filePath) {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// rewrite streams the file through the rules. The consecutive literal rules are applied together in one pass,
+// and a regular expression reads the whole file, as it may match any length.
+func rewrite(file io.ReadCloser, rules []*rule) io.ReadCloser {
+	var literals []Replacement
+	for _, r := range rules {
+		if r.literal != nil {
+			literals = append(literals, *r.literal)
+			continue
+		}
+		if len(literals) > 0 {
+			file = newReplaceReader(file, literals)
+			literals = nil
+		}
+		file = &regexpReader{src: file, re: r.re, replace: r.replace}
+	}
+	if len(literals) > 0 {
+		file = newReplaceReader(file, literals)
+	}
+	return file
+}
+
+// regexpReader replaces the matches of a regular expression in the whole content.
+type regexpReader struct {
+	src     io.ReadCloser
+	re      *regexp.Regexp
+	replace string
+	out     *bytes.Reader
+}
+
+func (r *regexpReader) Read(p []byte) (int, error) {
+	if r.out == nil {
+		data, err := io.ReadAll(r.src)
+		if err != nil {
+			return 0, err
+		}
+		r.out = bytes.NewReader(r.re.ReplaceAll(data, []byte(r.replace)))
+	}
+	return r.out.Read(p)
+}
+
+func (r *regexpReader) Close() error {
+	return r.src.Close()
+}
+
+// expandBraces expands the alternatives in braces, a{b,c}d is abd and acd. The braces do not nest.
+func expandBraces(glob string) []string {
+	start := strings.Index(glob, "{")
+	end := strings.Index(glob, "}")
+	if start < 0 || end < start {
+		return []string{glob}
+	}
+	var globs []string
+	for _, alt := range strings.Split(glob[start+1:end], ",") {
+		for _, rest := range expandBraces(glob[end+1:]) {
+			globs = append(globs, glob[:start]+alt+rest)
+		}
+	}
+	return globs
+}
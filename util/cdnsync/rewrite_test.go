@@ -0,0 +1,147 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package cdnsync
+
+import (
+	"flag"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files of the rewrite rules")
+
+// TestDefaultRulesGolden rewrites the sample build in testdata/build with the default rules,
+// and compares the files with testdata/golden. Run with -update to regenerate them.
+func TestDefaultRulesGolden(t *testing.T) {
+	rewriter, err := NewRewriter(DefaultRules(), "https://cdn.example.com/answer/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	build := os.DirFS("testdata/build")
+	err = fs.WalkDir(build, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		a := asset{fsys: build, path: path, rules: rewriter.rulesOf(path)}
+		file, err := a.open()
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		got, err := io.ReadAll(file)
+		if err != nil {
+			return err
+		}
+
+		golden := filepath.Join("testdata", "golden", filepath.FromSlash(path))
+		if *update {
+			if err = os.MkdirAll(filepath.Dir(golden), 0o755); err != nil {
+				return err
+			}
+			return os.WriteFile(golden, got, 0o644)
+		}
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			return err
+		}
+		if string(got) != string(want) {
+			t.Errorf("%s differs from the golden file:\n%s", path, got)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRulesOf(t *testing.T) {
+	rewriter, err := NewRewriter(DefaultRules(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]int{
+		"asset-manifest.json":              1,
+		"static/js/main.4b1c2d3e.js":       2,
+		"static/js/main.js":                2,
+		"static/js/main.4b1c2d3e.js.map":   2,
+		"static/css/main.8d3e3a1c.css.map": 2,
+		"static/css/main.8d3e3a1c.css":     1,
+		"static/js/787.2f4a5b6c.chunk.js":  0,
+		"static/js/mainly.js":              0,
+		"index.html":                       0,
+	}
+	for path, want := range tests {
+		if got := len(rewriter.rulesOf(path)); got != want {
+			t.Errorf("rules of %s: got %d, want %d", path, got, want)
+		}
+	}
+}
+
+func TestRegexRule(t *testing.T) {
+	rewriter, err := NewRewriter([]Rule{
+		{Glob: "static/js/*.chunk.js", Find: `n\.p\+"(static/[^"]+)"`, Regex: true, Replace: `"{prefix}/$1"`},
+		{Glob: "*.js", Find: `n.p="/"`, Replace: `n.p="{prefix}/"`},
+	}, "https://cdn.example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := `e.exports=n.p+"static/media/a.png",n.p="/"`
+	got, err := io.ReadAll(rewrite(io.NopCloser(strings.NewReader(file)), rewriter.rulesOf("static/js/1.chunk.js")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `e.exports="https://cdn.example.com/static/media/a.png",n.p="https://cdn.example.com/"`; string(got) != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules(" ")
+	if err != nil || !reflect.DeepEqual(rules, DefaultRules()) {
+		t.Fatalf("expected the default rules, got %v, %v", rules, err)
+	}
+	rules, err = ParseRules(`[{"glob": "*.js", "find": "a(b)", "regex": true, "replace": "$1"}]`)
+	if err != nil || len(rules) != 1 || !rules[0].Regex {
+		t.Fatalf("unexpected rules %v, %v", rules, err)
+	}
+	for _, config := range []string{
+		`{"glob": "*.js"}`,
+		`[{"glob": "*.js", "replace": "x"}]`,
+		`[{"glob": "*.js", "find": "(", "regex": true}]`,
+		`[{"glob": "[", "find": "x"}]`,
+	} {
+		if _, err = ParseRules(config); err == nil {
+			t.Errorf("expected %s to be rejected", config)
+		}
+	}
+}
+
+func TestExpandBraces(t *testing.T) {
+	got := expandBraces("main{,.*}.{js,map}")
+	want := []string{"main.js", "main.map", "main.*.js", "main.*.map"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
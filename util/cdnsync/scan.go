@@ -0,0 +1,107 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package cdnsync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+// ScanOptions are the options of Publisher.Scan.
+type ScanOptions struct {
+	// Filter skips the files it returns false for, such as the unsupported types or the files over the size limit.
+	Filter func(path string, size int64) bool
+	// Rewriter rewrites the files matching its rules, the others are uploaded as they are.
+	Rewriter *Rewriter
+}
+
+// asset is a file to publish, which is opened only while it is hashed or uploaded.
+type asset struct {
+	fsys  fs.FS
+	path  string
+	rules []*rule
+}
+
+// open opens the file, through the rewrite rules matching it.
+func (a *asset) open() (io.ReadCloser, error) {
+	file, err := a.fsys.Open(a.path)
+	if err != nil {
+		return nil, err
+	}
+	if len(a.rules) == 0 {
+		return file, nil
+	}
+	return rewrite(file, a.rules), nil
+}
+
+// hash returns the SHA-256 and the size of the published content of the file.
+func (a *asset) hash() (string, int64, error) {
+	file, err := a.open()
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, file)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// Scan walks the files of fsys and hands them to the uploaders, the paths of the files relative to the root of fsys
+// follow the object key prefix. It blocks while all the uploaders are busy. The files and directories that fail are
+// recorded in the report, and the others are still published.
+func (p *Publisher) Scan(fsys fs.FS, opts ScanOptions) {
+	_ = fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			p.fail(fmt.Errorf("scan %s failed: %w", path, err))
+			if d != nil && d.IsDir() && path != "." {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if opts.Filter != nil {
+			info, err := d.Info()
+			if err != nil {
+				p.fail(fmt.Errorf("scan %s failed: %w", path, err))
+				return nil
+			}
+			if !opts.Filter(path, info.Size()) {
+				return nil
+			}
+		}
+
+		a := asset{fsys: fsys, path: path, rules: opts.Rewriter.rulesOf(path)}
+		select {
+		case p.assets <- a:
+			return nil
+		case <-p.ctx.Done():
+			p.fail(p.ctx.Err())
+			return p.ctx.Err()
+		}
+	})
+}
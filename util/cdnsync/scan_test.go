@@ -0,0 +1,184 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package cdnsync
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"testing/iotest"
+)
+
+func TestReplaceReader(t *testing.T) {
+	replacements := []Replacement{
+		{Old: `"static`, New: `"https://cdn.example.com/static`},
+		{Old: `="/",`, New: `="",`},
+		{Old: `url(/static`, New: `url(../../static`},
+	}
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"no match", "no match"},
+		{`a="static/js";b="/",c`, `a="https://cdn.example.com/static/js";b="",c`},
+		{`"static"static`, `"https://cdn.example.com/static"https://cdn.example.com/static`},
+		{`url(/static/a.png) url(/static/b.png)`, `url(../../static/a.png) url(../../static/b.png)`},
+		// a partial match at the end is kept as it is
+		{`x="stat`, `x="stat`},
+		// the replaced text is not replaced again
+		{`"static="/",`, `"https://cdn.example.com/static="",`},
+	}
+	for _, tt := range tests {
+		// one byte per read splits every match across the reads
+		for _, src := range []io.Reader{strings.NewReader(tt.in), iotest.OneByteReader(strings.NewReader(tt.in))} {
+			got, err := io.ReadAll(newReplaceReader(io.NopCloser(src), replacements))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("replace %q: got %q, want %q", tt.in, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestReplaceReaderLarge(t *testing.T) {
+	in := strings.Repeat(`src="static/x.js";`, 10000)
+	got, err := io.ReadAll(newReplaceReader(io.NopCloser(strings.NewReader(in)), []Replacement{{Old: `"static`, New: `"/cdn/static`}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Repeat(`src="/cdn/static/x.js";`, 10000); string(got) != want {
+		t.Fatal("large file is not replaced")
+	}
+}
+
+// trackFS counts the open files, and fails the paths in broken.
+type trackFS struct {
+	fs.FS
+	lock   sync.Mutex
+	open   int
+	opened int
+	broken map[string]bool
+}
+
+type trackFile struct {
+	fs.File
+	fsys *trackFS
+}
+
+func (f *trackFile) Close() error {
+	f.fsys.lock.Lock()
+	f.fsys.open--
+	f.fsys.lock.Unlock()
+	return f.File.Close()
+}
+
+func (t *trackFS) Open(name string) (fs.File, error) {
+	if t.broken[name] {
+		return nil, errors.New("broken file")
+	}
+	f, err := t.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := f.(fs.ReadDirFile); ok {
+		return f, nil
+	}
+	t.lock.Lock()
+	t.open++
+	t.opened++
+	t.lock.Unlock()
+	return &trackFile{File: f, fsys: t}, nil
+}
+
+func TestScan(t *testing.T) {
+	bucket := newMemBucket()
+	srv, _ := serve(t, bucket)
+	fsys := &trackFS{
+		FS: fstest.MapFS{
+			"asset-manifest.json":          {Data: []byte(`{"main.js": "/static/js/main.1.js"}`)},
+			"static/js/main.1.js":          {Data: []byte(`a="static/media/x.png"`)},
+			"static/js/vendor.js":          {Data: []byte(`"static`)},
+			"static/media/x.png":           {Data: []byte("png")},
+			"static/media/broken.png":      {Data: []byte("png")},
+			"static/media/too-large.png":   {Data: []byte(strings.Repeat("large", 20))},
+			"static/media/unsupported.exe": {Data: []byte("exe")},
+		},
+		broken: map[string]bool{"static/media/broken.png": true},
+	}
+
+	rewriter, err := NewRewriter([]Rule{
+		{Glob: "asset-manifest.json", Find: `"/static`, Replace: `"{prefix}/static`},
+		{Glob: "static/js/main.*.js", Find: `"static`, Replace: `"{prefix}/static`},
+	}, "https://cdn/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := NewPublisher(context.Background(), bucket, Options{VisitURLPrefix: srv.URL + "/", Concurrency: 2})
+	p.Scan(fsys, ScanOptions{
+		Filter: func(path string, size int64) bool {
+			return !strings.HasSuffix(path, ".exe") && size < 100
+		},
+		Rewriter: rewriter,
+	})
+	report, err := p.Finish()
+
+	// the broken file is reported, and the others are still published
+	if err == nil || report.Failed != 1 || !strings.Contains(report.Errors[0], "broken.png") {
+		t.Fatalf("expected the broken file to be reported, got %+v, %v", report, err)
+	}
+	if report.Files != 5 || report.Uploaded != 4 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	want := map[string]string{
+		"asset-manifest.json": `{"main.js": "https://cdn/static/js/main.1.js"}`,
+		"static/js/main.1.js": `a="https://cdn/static/media/x.png"`,
+		"static/js/vendor.js": `"static`,
+		"static/media/x.png":  "png",
+	}
+	for key, content := range want {
+		if got := string(bucket.objects[key]); got != content {
+			t.Errorf("object %s: got %q, want %q", key, got, content)
+		}
+	}
+	if _, ok := bucket.objects["static/media/too-large.png"]; ok {
+		t.Error("filtered file is uploaded")
+	}
+	// each file is opened to be hashed and to be uploaded, and closed after that
+	if fsys.open != 0 || fsys.opened != 8 {
+		t.Fatalf("expected all the files to be closed, %d open, %d opened", fsys.open, fsys.opened)
+	}
+}
+
+func TestScanCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p := NewPublisher(ctx, newMemBucket(), Options{Concurrency: 1})
+	p.Scan(fstest.MapFS{"a.js": {Data: []byte("a")}, "b.js": {Data: []byte("b")}}, ScanOptions{})
+	if _, err := p.Finish(); err == nil {
+		t.Fatal("expected the canceled publication to fail")
+	}
+}
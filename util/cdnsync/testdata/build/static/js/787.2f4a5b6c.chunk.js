@@ -0,0 +1 @@
+"use strict";(self.webpackChunkanswer=self.webpackChunkanswer||[]).push([[787],{787:function(e,t,n){e.exports=n.p+"static/media/avatar.1a2b3c4d.png",n.p="/",void 0}}]);
@@ -0,0 +1,3 @@
+/*! For license information please see main.4b1c2d3e.js.LICENSE.txt */
+!function(){var e={},t={};function n(r){var o=t[r];if(void 0!==o)return o.exports;var a=t[r]={exports:{}};return e[r](a,a.exports,n),a.exports}n.u=function(e){return"https://cdn.example.com/answer/static/js/"+e+"."+{787:"2f4a5b6c"}[e]+".chunk.js"},n.miniCssF=function(e){return"https://cdn.example.com/answer/static/css/"+e+".chunk.css"},n.p="",function(){var e=n.p+"https://cdn.example.com/answer/static/media/logo.6ce24c58.svg";document.title="Answer"}();var r={basename:"/",routes:[{path:"/",element:"Home"}]},o=window.location.pathname==="/";console.log(r,o)}();
+//# sourceMappingURL=main.4b1c2d3e.js.map
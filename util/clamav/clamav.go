@@ -0,0 +1,169 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+// Package clamav scans the uploads with a clamd daemon, streaming them with the INSTREAM command.
+package clamav
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// DefaultAddress is the TCP address clamd listens to by default.
+const DefaultAddress = "tcp://127.0.0.1:3310"
+
+// DefaultTimeout bounds a whole scan, from the connection to the reply.
+const DefaultTimeout = 30 * time.Second
+
+// chunkSize is the size of the INSTREAM chunks, far below the StreamMaxLength of clamd.
+const chunkSize = 64 * 1024
+
+// Result is the reply of clamd for a clean or infected file.
+type Result struct {
+	Infected bool
+	// Signature is the name of the malware found, like Win.Test.EICAR_HDB-1.
+	Signature string
+}
+
+// Client talks to clamd.
+type Client struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClient parses the address of clamd: unix:///run/clamav/clamd.ctl, a socket path,
+// tcp://host:port or host:port. The empty address is DefaultAddress.
+func NewClient(address string, timeout time.Duration) (*Client, error) {
+	address = strings.TrimSpace(address)
+	if len(address) == 0 {
+		address = DefaultAddress
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	c := &Client{timeout: timeout}
+	switch {
+	case strings.HasPrefix(address, "unix://"):
+		c.network, c.address = "unix", strings.TrimPrefix(address, "unix://")
+	case strings.HasPrefix(address, "tcp://"):
+		c.network, c.address = "tcp", strings.TrimPrefix(address, "tcp://")
+	case strings.HasPrefix(address, "/"):
+		c.network, c.address = "unix", address
+	default:
+		c.network, c.address = "tcp", address
+	}
+	if len(c.address) == 0 {
+		return nil, fmt.Errorf("invalid clamd address %s", address)
+	}
+	return c, nil
+}
+
+// Ping checks that clamd answers.
+func (c *Client) Ping() error {
+	reply, err := c.command("zPING\x00", nil)
+	if err != nil {
+		return err
+	}
+	if reply != "PONG" {
+		return fmt.Errorf("unexpected clamd reply: %s", reply)
+	}
+	return nil
+}
+
+// Scan streams the file to clamd. The error is about clamd, not the file: unreachable,
+// timed out, or refusing the file like when it is over its StreamMaxLength.
+func (c *Client) Scan(file io.Reader) (*Result, error) {
+	reply, err := c.command("zINSTREAM\x00", file)
+	if err != nil {
+		return nil, err
+	}
+	return parseReply(reply)
+}
+
+func (c *Client) command(command string, file io.Reader) (string, error) {
+	conn, err := net.DialTimeout(c.network, c.address, c.timeout)
+	if err != nil {
+		return "", fmt.Errorf("connect to clamd failed: %w", err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(c.timeout))
+
+	if _, err = io.WriteString(conn, command); err != nil {
+		return "", fmt.Errorf("write to clamd failed: %w", err)
+	}
+	if file != nil {
+		if err = writeChunks(conn, file); err != nil {
+			return "", err
+		}
+	}
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && !(err == io.EOF && len(reply) > 0) {
+		return "", fmt.Errorf("read clamd reply failed: %w", err)
+	}
+	return strings.TrimSpace(strings.TrimRight(reply, "\x00")), nil
+}
+
+// writeChunks sends the file as chunks prefixed by their length, then the zero length chunk ending the stream
+func writeChunks(conn net.Conn, file io.Reader) error {
+	buf := make([]byte, 4+chunkSize)
+	for {
+		n, err := io.ReadFull(file, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf, uint32(n))
+			if _, werr := conn.Write(buf[:4+n]); werr != nil {
+				// clamd closes the connection when the stream is too long, its reply tells why
+				return nil
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read file failed: %w", err)
+		}
+	}
+	// a failed write is told by the reply too
+	_, _ = conn.Write([]byte{0, 0, 0, 0})
+	return nil
+}
+
+// parseReply parses "stream: OK", "stream: <signature> FOUND" and "<message> ERROR"
+func parseReply(reply string) (*Result, error) {
+	message := reply
+	if i := strings.Index(reply, ": "); i >= 0 {
+		message = reply[i+2:]
+	}
+	switch {
+	case message == "OK":
+		return &Result{}, nil
+	case strings.HasSuffix(message, " FOUND"):
+		return &Result{Infected: true, Signature: strings.TrimSuffix(message, " FOUND")}, nil
+	case strings.HasSuffix(reply, " ERROR"):
+		return nil, errors.New("clamd error: " + strings.TrimSuffix(reply, " ERROR"))
+	default:
+		return nil, fmt.Errorf("unexpected clamd reply: %s", reply)
+	}
+}
@@ -0,0 +1,184 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package clamav
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// fakeClamd answers like clamd: PONG, FOUND for the EICAR test file, ERROR past maxLength
+func fakeClamd(t *testing.T, maxLength int) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveClamd(conn, maxLength)
+		}
+	}()
+	return "tcp://" + listener.Addr().String()
+}
+
+func serveClamd(conn net.Conn, maxLength int) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	command, err := r.ReadString(0)
+	if err != nil {
+		return
+	}
+	switch command {
+	case "zPING\x00":
+		_, _ = conn.Write([]byte("PONG\x00"))
+		return
+	case "zINSTREAM\x00":
+	default:
+		_, _ = conn.Write([]byte("UNKNOWN COMMAND\x00"))
+		return
+	}
+	stream := &bytes.Buffer{}
+	size := make([]byte, 4)
+	for {
+		if _, err = io.ReadFull(r, size); err != nil {
+			return
+		}
+		n := binary.BigEndian.Uint32(size)
+		if n == 0 {
+			break
+		}
+		if _, err = io.CopyN(stream, r, int64(n)); err != nil {
+			return
+		}
+		if stream.Len() > maxLength {
+			_, _ = conn.Write([]byte("INSTREAM size limit exceeded. ERROR\x00"))
+			return
+		}
+	}
+	if strings.Contains(stream.String(), eicar) {
+		_, _ = conn.Write([]byte("stream: Win.Test.EICAR_HDB-1 FOUND\x00"))
+		return
+	}
+	_, _ = conn.Write([]byte("stream: OK\x00"))
+}
+
+func TestNewClient(t *testing.T) {
+	tests := []struct {
+		address string
+		network string
+		addr    string
+	}{
+		{"", "tcp", "127.0.0.1:3310"},
+		{"tcp://clamav:3310", "tcp", "clamav:3310"},
+		{"clamav:3310", "tcp", "clamav:3310"},
+		{"unix:///run/clamav/clamd.ctl", "unix", "/run/clamav/clamd.ctl"},
+		{"/run/clamav/clamd.ctl", "unix", "/run/clamav/clamd.ctl"},
+	}
+	for _, tt := range tests {
+		c, err := NewClient(tt.address, 0)
+		if err != nil || c.network != tt.network || c.address != tt.addr {
+			t.Errorf("NewClient(%q) = %+v, %v", tt.address, c, err)
+		}
+	}
+	if _, err := NewClient("unix://", 0); err == nil {
+		t.Error("NewClient(unix://) no error")
+	}
+}
+
+func TestScan(t *testing.T) {
+	client, _ := NewClient(fakeClamd(t, 1<<20), time.Second)
+	if err := client.Ping(); err != nil {
+		t.Fatalf("Ping() = %v", err)
+	}
+
+	result, err := client.Scan(strings.NewReader("hello"))
+	if err != nil || result.Infected {
+		t.Errorf("Scan(clean) = %+v, %v", result, err)
+	}
+	// a file larger than one chunk, with the test signature across chunks
+	large := strings.Repeat("a", chunkSize-10) + eicar + strings.Repeat("b", chunkSize)
+	result, err = client.Scan(strings.NewReader(large))
+	if err != nil || !result.Infected || result.Signature != "Win.Test.EICAR_HDB-1" {
+		t.Errorf("Scan(eicar) = %+v, %v", result, err)
+	}
+}
+
+func TestScanErrors(t *testing.T) {
+	client, _ := NewClient(fakeClamd(t, 100), time.Second)
+	if _, err := client.Scan(strings.NewReader(strings.Repeat("a", 1000))); err == nil {
+		t.Error("Scan(too long) no error")
+	}
+
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	address := listener.Addr().String()
+	_ = listener.Close()
+	client, _ = NewClient(address, time.Second)
+	if _, err := client.Scan(strings.NewReader("hello")); err == nil {
+		t.Error("Scan(down) no error")
+	}
+}
+
+func TestGuard(t *testing.T) {
+	address := fakeClamd(t, 1<<20)
+	guard, err := NewGuard("test", address, PolicyFailClosed, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := strings.NewReader("hello")
+	if err = guard.Check("hello.txt", file); err != nil {
+		t.Errorf("Check(clean) = %v", err)
+	}
+	if rest, _ := io.ReadAll(file); string(rest) != "hello" {
+		t.Errorf("file not rewound, read %q", rest)
+	}
+	if err = guard.Check("eicar.txt", strings.NewReader(eicar)); !errors.Is(err, ErrInfected) {
+		t.Errorf("Check(eicar) = %v, want ErrInfected", err)
+	}
+
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	down := listener.Addr().String()
+	_ = listener.Close()
+	closed, _ := NewGuard("test", down, PolicyFailClosed, time.Second)
+	if err = closed.Check("hello.txt", strings.NewReader("hello")); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Check(down, closed) = %v, want ErrUnavailable", err)
+	}
+	open, _ := NewGuard("test", down, PolicyFailOpen, time.Second)
+	if err = open.Check("hello.txt", strings.NewReader("hello")); err != nil {
+		t.Errorf("Check(down, open) = %v", err)
+	}
+
+	if _, err = NewGuard("test", address, "maybe", time.Second); err == nil {
+		t.Error("NewGuard(unknown policy) no error")
+	}
+}
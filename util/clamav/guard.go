@@ -0,0 +1,93 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package clamav
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/segmentfault/pacman/log"
+)
+
+// The policies when a file can't be scanned, because clamd is down or refuses it:
+// fail closed rejects the upload, fail open stores it unscanned.
+const (
+	PolicyFailClosed = "closed"
+	PolicyFailOpen   = "open"
+)
+
+var (
+	// ErrInfected is returned for the files with malware.
+	ErrInfected = errors.New("infected file")
+	// ErrUnavailable is returned when the file can't be scanned and the policy is to fail closed.
+	ErrUnavailable = errors.New("malware scanner unavailable")
+)
+
+// Guard scans the uploads of a plugin and applies its policy.
+type Guard struct {
+	name     string
+	client   *Client
+	failOpen bool
+}
+
+// NewGuard returns the guard of the plugin named name, which prefixes the logs.
+func NewGuard(name, address, policy string, timeout time.Duration) (*Guard, error) {
+	client, err := NewClient(address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	switch policy {
+	case "", PolicyFailClosed, PolicyFailOpen:
+	default:
+		return nil, fmt.Errorf("unknown scan failure policy %s", policy)
+	}
+	return &Guard{name: name, client: client, failOpen: policy == PolicyFailOpen}, nil
+}
+
+// Check scans the file and rewinds it, see CheckStream.
+func (g *Guard) Check(filename string, file io.ReadSeeker) error {
+	err := g.CheckStream(filename, file)
+	if _, serr := file.Seek(0, io.SeekStart); serr != nil && err == nil {
+		return serr
+	}
+	return err
+}
+
+// CheckStream scans the stream. It returns an error wrapping ErrInfected for malware,
+// and ErrUnavailable when the file can't be scanned, unless the policy is to fail open.
+func (g *Guard) CheckStream(filename string, file io.Reader) error {
+	result, err := g.client.Scan(file)
+	if err != nil {
+		if g.failOpen {
+			log.Warnf("%s: %s stored without scan: %v", g.name, filename, err)
+			return nil
+		}
+		log.Errorf("%s: %s rejected without scan: %v", g.name, filename, err)
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	if result.Infected {
+		log.Warnf("%s: %s rejected, %s found", g.name, filename, result.Signature)
+		return fmt.Errorf("%w: %s", ErrInfected, result.Signature)
+	}
+	log.Infof("%s: %s scanned, clean", g.name, filename)
+	return nil
+}
@@ -0,0 +1,212 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+// Package imageproc cleans up the uploaded images before the storage plugins store them.
+// The images are decoded and encoded again, which drops EXIF and the other metadata,
+// turned upright by their EXIF orientation and scaled down to the configured size.
+package imageproc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"path"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// DefaultQuality is the JPEG and WebP quality when none is configured.
+const DefaultQuality = 85
+
+// MaxPixels is the largest image decoded, to refuse the small files expanding to huge images.
+const MaxPixels = 40 * 1000 * 1000
+
+var (
+	// ErrUnsupported is returned for the types not processed, like GIF, which can be animated, and SVG.
+	ErrUnsupported = errors.New("image type not supported")
+	// ErrTooLarge is returned for the images with more than MaxPixels pixels.
+	ErrTooLarge = errors.New("image too large")
+)
+
+// Options are the processing steps of one image.
+type Options struct {
+	// MaxDimension is the max width and height, 0 keeps the size.
+	MaxDimension int
+	// WebP encodes the image as WebP, when this build has the encoder.
+	WebP bool
+	// Quality is the JPEG and WebP quality, DefaultQuality when 0.
+	Quality int
+	// ThumbnailSize is the max width and height of the thumbnail, 0 for none.
+	ThumbnailSize int
+}
+
+// Image is an encoded image.
+type Image struct {
+	Data        []byte
+	ContentType string
+	// Ext is the file extension of the content type, with the dot.
+	Ext    string
+	Width  int
+	Height int
+}
+
+// Result is the processed image and its thumbnail, which is nil unless asked for.
+type Result struct {
+	Image
+	Thumbnail *Image
+}
+
+// Supported reports whether the images of the content type can be processed.
+func Supported(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/webp":
+		return true
+	default:
+		return false
+	}
+}
+
+// Process decodes the image, orients and scales it, then encodes it again without its metadata.
+// The image keeps its format, except when WebP is asked for, and WebP images are stored
+// as PNG when this build can't encode WebP.
+func Process(data []byte, contentType string, opts Options) (*Result, error) {
+	if !Supported(contentType) {
+		return nil, ErrUnsupported
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image config failed: %w", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image failed: %w", err)
+	}
+	orientation := 1
+	if contentType == "image/jpeg" {
+		orientation = Orientation(data)
+	}
+	img = Orient(Fit(img, opts.MaxDimension), orientation)
+
+	format := outputFormat(contentType, opts.WebP)
+	quality := opts.Quality
+	if quality <= 0 || quality > 100 {
+		quality = DefaultQuality
+	}
+	encoded, err := encode(img, format, quality)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{Image: *encoded}
+	if opts.ThumbnailSize > 0 {
+		if result.Thumbnail, err = encode(Fit(img, opts.ThumbnailSize), format, quality); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// ParseDimension parses a configured dimension in pixels, the empty or invalid ones are def.
+func ParseDimension(value string, def int) int {
+	dimension, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || dimension < 0 {
+		return def
+	}
+	return dimension
+}
+
+// ThumbnailKey returns the object key of the thumbnail of the image, next to the image.
+func ThumbnailKey(key string) string {
+	ext := path.Ext(key)
+	return strings.TrimSuffix(key, ext) + "_thumb" + ext
+}
+
+// Fit scales the image down so that its width and height are at most size, 0 keeps it.
+func Fit(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if size <= 0 || (width <= size && height <= size) {
+		return img
+	}
+	if width >= height {
+		height = max(1, height*size/width)
+		width = size
+	} else {
+		width = max(1, width*size/height)
+		height = size
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func outputFormat(contentType string, webp bool) string {
+	if (webp || contentType == "image/webp") && WebPSupported {
+		return "image/webp"
+	}
+	if contentType == "image/webp" {
+		return "image/png"
+	}
+	return contentType
+}
+
+func encode(img image.Image, contentType string, quality int) (*Image, error) {
+	buf := &bytes.Buffer{}
+	var err error
+	ext := ""
+	switch contentType {
+	case "image/jpeg":
+		ext = ".jpg"
+		err = jpeg.Encode(buf, img, &jpeg.Options{Quality: quality})
+	case "image/png":
+		ext = ".png"
+		err = png.Encode(buf, img)
+	case "image/webp":
+		ext = ".webp"
+		err = encodeWebP(buf, img, quality)
+	default:
+		return nil, ErrUnsupported
+	}
+	if err != nil {
+		return nil, fmt.Errorf("encode image failed: %w", err)
+	}
+	bounds := img.Bounds()
+	return &Image{
+		Data:        buf.Bytes(),
+		ContentType: contentType,
+		Ext:         ext,
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+	}, nil
+}
@@ -0,0 +1,233 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package imageproc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+// exifSegment returns an APP1 segment holding the orientation and a text tag, like the camera model
+func exifSegment(order binary.ByteOrder, orientation uint16) []byte {
+	tiff := &bytes.Buffer{}
+	if order == binary.LittleEndian {
+		tiff.WriteString("II")
+	} else {
+		tiff.WriteString("MM")
+	}
+	_ = binary.Write(tiff, order, uint16(42))
+	_ = binary.Write(tiff, order, uint32(8))
+	_ = binary.Write(tiff, order, uint16(2))
+	// model, ASCII, 4 bytes inline
+	_ = binary.Write(tiff, order, []uint16{0x0110, 2})
+	_ = binary.Write(tiff, order, uint32(4))
+	tiff.WriteString("GPS\x00")
+	// orientation, SHORT
+	_ = binary.Write(tiff, order, []uint16{orientationTag, 3})
+	_ = binary.Write(tiff, order, uint32(1))
+	_ = binary.Write(tiff, order, []uint16{orientation, 0})
+	_ = binary.Write(tiff, order, uint32(0))
+
+	payload := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+func jpegWithExif(t *testing.T, img image.Image, order binary.ByteOrder, orientation uint16) []byte {
+	buf := &bytes.Buffer{}
+	if err := jpeg.Encode(buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	out := append([]byte{}, data[:2]...)
+	out = append(out, exifSegment(order, orientation)...)
+	return append(out, data[2:]...)
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestOrientation(t *testing.T) {
+	img := testImage(4, 2)
+	if got := Orientation(jpegWithExif(t, img, binary.LittleEndian, 6)); got != 6 {
+		t.Errorf("little endian orientation = %d, want 6", got)
+	}
+	if got := Orientation(jpegWithExif(t, img, binary.BigEndian, 3)); got != 3 {
+		t.Errorf("big endian orientation = %d, want 3", got)
+	}
+	buf := &bytes.Buffer{}
+	_ = jpeg.Encode(buf, img, nil)
+	if got := Orientation(buf.Bytes()); got != 1 {
+		t.Errorf("orientation without exif = %d, want 1", got)
+	}
+	if got := Orientation([]byte{0xFF, 0xD8, 0xFF, 0xE1, 0xFF}); got != 1 {
+		t.Errorf("orientation of a broken file = %d, want 1", got)
+	}
+}
+
+func TestOrient(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	left := color.RGBA{R: 255, A: 255}
+	right := color.RGBA{B: 255, A: 255}
+	img.Set(0, 0, left)
+	img.Set(1, 0, right)
+
+	cases := []struct {
+		orientation   int
+		width, height int
+		leftAt        image.Point
+	}{
+		{1, 2, 1, image.Pt(0, 0)},
+		{2, 2, 1, image.Pt(1, 0)},
+		{3, 2, 1, image.Pt(1, 0)},
+		{6, 1, 2, image.Pt(0, 0)},
+		{8, 1, 2, image.Pt(0, 1)},
+	}
+	for _, c := range cases {
+		got := Orient(img, c.orientation)
+		if got.Bounds().Dx() != c.width || got.Bounds().Dy() != c.height {
+			t.Errorf("orientation %d: size = %v, want %dx%d", c.orientation, got.Bounds().Size(), c.width, c.height)
+			continue
+		}
+		if r, _, _, _ := got.At(c.leftAt.X, c.leftAt.Y).RGBA(); r == 0 {
+			t.Errorf("orientation %d: left pixel not at %v", c.orientation, c.leftAt)
+		}
+	}
+}
+
+func TestProcessJPEG(t *testing.T) {
+	data := jpegWithExif(t, testImage(40, 20), binary.LittleEndian, 6)
+	result, err := Process(data, "image/jpeg", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ContentType != "image/jpeg" {
+		t.Errorf("content type = %s", result.ContentType)
+	}
+	if result.Width != 20 || result.Height != 40 {
+		t.Errorf("size = %dx%d, want 20x40", result.Width, result.Height)
+	}
+	if bytes.Contains(result.Data, []byte("Exif")) || bytes.Contains(result.Data, []byte("GPS")) {
+		t.Error("metadata not stripped")
+	}
+	if result.Thumbnail != nil {
+		t.Error("thumbnail not asked for")
+	}
+}
+
+func TestProcessFit(t *testing.T) {
+	result, err := Process(encodePNG(t, testImage(400, 200)), "image/png", Options{MaxDimension: 100, ThumbnailSize: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ContentType != "image/png" || result.Ext != ".png" {
+		t.Errorf("type = %s %s, want image/png .png", result.ContentType, result.Ext)
+	}
+	if result.Width != 100 || result.Height != 50 {
+		t.Errorf("size = %dx%d, want 100x50", result.Width, result.Height)
+	}
+	if result.Thumbnail == nil || result.Thumbnail.Width != 20 || result.Thumbnail.Height != 10 {
+		t.Errorf("thumbnail = %+v, want 20x10", result.Thumbnail)
+	}
+	if _, err = png.Decode(bytes.NewReader(result.Data)); err != nil {
+		t.Errorf("decode result: %v", err)
+	}
+
+	// the small images keep their size
+	result, err = Process(encodePNG(t, testImage(30, 60)), "image/png", Options{MaxDimension: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Width != 30 || result.Height != 60 {
+		t.Errorf("size = %dx%d, want 30x60", result.Width, result.Height)
+	}
+}
+
+func TestProcessWebP(t *testing.T) {
+	result, err := Process(encodePNG(t, testImage(10, 10)), "image/png", Options{WebP: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, ext := "image/png", ".png"
+	if WebPSupported {
+		want, ext = "image/webp", ".webp"
+	}
+	if result.ContentType != want || result.Ext != ext {
+		t.Errorf("type = %s %s, want %s %s", result.ContentType, result.Ext, want, ext)
+	}
+}
+
+func TestProcessRejects(t *testing.T) {
+	if _, err := Process([]byte("GIF89a"), "image/gif", Options{}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("gif: err = %v, want ErrUnsupported", err)
+	}
+
+	// a tiny PNG claiming to be 20000x20000
+	data := encodePNG(t, testImage(1, 1))
+	ihdr := data[8+8 : 8+8+13]
+	binary.BigEndian.PutUint32(ihdr[0:], 20000)
+	binary.BigEndian.PutUint32(ihdr[4:], 20000)
+	binary.BigEndian.PutUint32(data[8+8+13:], crc32.ChecksumIEEE(data[8+4:8+8+13]))
+	if _, err := Process(data, "image/png", Options{}); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("bomb: err = %v, want ErrTooLarge", err)
+	}
+
+	if _, err := Process([]byte("not an image"), "image/jpeg", Options{}); err == nil {
+		t.Error("broken file: no error")
+	}
+}
+
+func TestParseDimension(t *testing.T) {
+	cases := map[string]int{"": 256, " 512 ": 512, "0": 0, "-1": 256, "big": 256}
+	for value, want := range cases {
+		if got := ParseDimension(value, 256); got != want {
+			t.Errorf("ParseDimension(%q) = %d, want %d", value, got, want)
+		}
+	}
+}
+
+func TestThumbnailKey(t *testing.T) {
+	if got := ThumbnailKey("post/1700000000abcd.webp"); got != "post/1700000000abcd_thumb.webp" {
+		t.Errorf("ThumbnailKey = %s", got)
+	}
+}
@@ -0,0 +1,99 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package searchext
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/apache/incubator-answer/plugin"
+)
+
+// MaxFacetValues is the maximum number of values returned for a facet.
+const MaxFacetValues = 20
+
+// FacetCount is the number of results having a facet value.
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// Facets are the counts of the search results grouped by tag ID, type, accepted answer and creation time.
+type Facets struct {
+	Tags        []FacetCount `json:"tags"`
+	Type        []FacetCount `json:"type"`
+	HasAccepted []FacetCount `json:"has_accepted"`
+	// Created counts the results created within each of the CreatedBuckets, so the buckets overlap.
+	Created []FacetCount `json:"created"`
+}
+
+// CreatedBucket is a time window ending now.
+type CreatedBucket struct {
+	Name     string
+	Duration time.Duration
+}
+
+// From returns the unix time the bucket starts at.
+func (b CreatedBucket) From(now time.Time) int64 {
+	return now.Add(-b.Duration).Unix()
+}
+
+var CreatedBuckets = []CreatedBucket{
+	{Name: "day", Duration: 24 * time.Hour},
+	{Name: "week", Duration: 7 * 24 * time.Hour},
+	{Name: "month", Duration: 30 * 24 * time.Hour},
+	{Name: "year", Duration: 365 * 24 * time.Hour},
+}
+
+// FacetSearcher is implemented by search plugins that can count facets in the same query as the results.
+type FacetSearcher interface {
+	SearchFacets(ctx context.Context, scope SearchScope, cond *plugin.SearchBasicCond) (
+		res []Result, total int64, facets *Facets, err error)
+}
+
+// NewFacetCounts sorts the counts by count then value, and keeps the first MaxFacetValues.
+func NewFacetCounts(counts map[string]int64) []FacetCount {
+	res := make([]FacetCount, 0, len(counts))
+	for value, count := range counts {
+		if count > 0 {
+			res = append(res, FacetCount{Value: value, Count: count})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Count != res[j].Count {
+			return res[i].Count > res[j].Count
+		}
+		return res[i].Value < res[j].Value
+	})
+	if len(res) > MaxFacetValues {
+		res = res[:MaxFacetValues]
+	}
+	return res
+}
+
+// NewCreatedCounts returns the counts of the CreatedBuckets, in the same order.
+func NewCreatedCounts(counts map[string]int64) []FacetCount {
+	res := make([]FacetCount, 0, len(CreatedBuckets))
+	for _, bucket := range CreatedBuckets {
+		res = append(res, FacetCount{Value: bucket.Name, Count: counts[bucket.Name]})
+	}
+	return res
+}
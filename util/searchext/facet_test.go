@@ -0,0 +1,42 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package searchext
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestNewFacetCounts(t *testing.T) {
+	got := NewFacetCounts(map[string]int64{"go": 3, "js": 5, "css": 3, "empty": 0})
+	want := []FacetCount{{"js", 5}, {"css", 3}, {"go", 3}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NewFacetCounts() = %v, want %v", got, want)
+	}
+
+	counts := make(map[string]int64)
+	for i := 0; i < MaxFacetValues+5; i++ {
+		counts[strconv.Itoa(i)] = int64(i + 1)
+	}
+	if got := NewFacetCounts(counts); len(got) != MaxFacetValues {
+		t.Errorf("NewFacetCounts() returned %d values, want %d", len(got), MaxFacetValues)
+	}
+}
@@ -0,0 +1,149 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package searchext
+
+import (
+	"context"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/apache/incubator-answer/plugin"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// HighlightPreMarker and HighlightPostMarker are sent to the engines as highlight tags.
+	// They are private use characters, so they never appear in user content and survive
+	// HTML escaping, after which they are replaced by HighlightPreTag and HighlightPostTag.
+	HighlightPreMarker  = "\ue000"
+	HighlightPostMarker = "\ue001"
+
+	HighlightPreTag  = "<em>"
+	HighlightPostTag = "</em>"
+
+	// SnippetLength is the approximate length of a content snippet, in characters.
+	SnippetLength = 200
+	// SnippetWords is the approximate length of a content snippet, for engines that crop by words.
+	SnippetWords = 30
+	// SnippetEllipsis marks text cut out of a snippet.
+	SnippetEllipsis = "…"
+)
+
+type SearchScope string
+
+const (
+	ScopeContents  SearchScope = "contents"
+	ScopeQuestions SearchScope = "questions"
+	ScopeAnswers   SearchScope = "answers"
+)
+
+// Highlight holds the matching fragments of a search result.
+// Both fields are HTML escaped, with matched terms wrapped in HighlightPreTag and HighlightPostTag.
+type Highlight struct {
+	Title   string `json:"title"`
+	Snippet string `json:"snippet"`
+}
+
+// Result is a search result carrying the fragments that matched the query.
+type Result struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Highlight Highlight `json:"highlight"`
+}
+
+// HighlightSearcher is implemented by search plugins that can return highlights along with the results.
+type HighlightSearcher interface {
+	SearchHighlight(ctx context.Context, scope SearchScope, cond *plugin.SearchBasicCond) (
+		res []Result, total int64, err error)
+}
+
+// ToSearchResults drops the highlights, for callers that only need plugin.SearchResult.
+func ToSearchResults(res []Result) []plugin.SearchResult {
+	if res == nil {
+		return nil
+	}
+	results := make([]plugin.SearchResult, 0, len(res))
+	for _, r := range res {
+		results = append(results, plugin.SearchResult{ID: r.ID, Type: r.Type})
+	}
+	return results
+}
+
+// BuildHighlight escapes a fragment returned by the engine and turns the markers into highlight tags.
+func BuildHighlight(fragment string) string {
+	fragment = html.EscapeString(strings.TrimSpace(fragment))
+	fragment = strings.ReplaceAll(fragment, HighlightPreMarker, HighlightPreTag)
+	return strings.ReplaceAll(fragment, HighlightPostMarker, HighlightPostTag)
+}
+
+// HighlightHandler returns a handler that searches with highlights.
+// The query string accepts q, scope, order, page and page_size.
+// When facets=true and the searcher is a FacetSearcher, the facets are counted in the same query.
+func HighlightHandler(slugName string, searcher HighlightSearcher) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !plugin.StatusManager.IsEnabled(slugName) {
+			HandleNotFound(ctx)
+			return
+		}
+		scope := SearchScope(ctx.DefaultQuery("scope", string(ScopeContents)))
+		if scope != ScopeContents && scope != ScopeQuestions && scope != ScopeAnswers {
+			HandleBadRequest(ctx, "invalid scope")
+			return
+		}
+		cond := searchCond(ctx)
+		if facetSearcher, ok := searcher.(FacetSearcher); ok && ctx.Query("facets") == "true" {
+			res, total, facets, err := facetSearcher.SearchFacets(ctx, scope, cond)
+			HandleResponse(ctx, err, gin.H{"count": total, "list": res, "facets": facets})
+			return
+		}
+		res, total, err := searcher.SearchHighlight(ctx, scope, cond)
+		HandleResponse(ctx, err, gin.H{"count": total, "list": res})
+	}
+}
+
+// searchCond builds the search condition of the query string. The amounts are -1, unset, as Answer does,
+// so that the engines don't filter on zero votes, views or answers.
+func searchCond(ctx *gin.Context) *plugin.SearchBasicCond {
+	cond := &plugin.SearchBasicCond{
+		Page:         queryInt(ctx, "page", 1),
+		PageSize:     queryInt(ctx, "page_size", 20),
+		Words:        strings.Fields(ctx.Query("q")),
+		Order:        plugin.SearchOrderCond(ctx.DefaultQuery("order", string(plugin.SearchRelevanceOrder))),
+		VoteAmount:   -1,
+		ViewAmount:   -1,
+		AnswerAmount: -1,
+	}
+	if cond.Page < 1 {
+		cond.Page = 1
+	}
+	if cond.PageSize < 1 || cond.PageSize > 100 {
+		cond.PageSize = 20
+	}
+	return cond
+}
+
+func queryInt(ctx *gin.Context, key string, defaultValue int) int {
+	v, err := strconv.Atoi(ctx.Query(key))
+	if err != nil {
+		return defaultValue
+	}
+	return v
+}
@@ -0,0 +1,83 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package searchext
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/apache/incubator-answer/plugin"
+	"github.com/gin-gonic/gin"
+)
+
+func TestBuildHighlight(t *testing.T) {
+	tests := []struct {
+		name     string
+		fragment string
+		want     string
+	}{
+		{"empty", "", ""},
+		{"no match", "plain text", "plain text"},
+		{"match", "build a " + HighlightPreMarker + "plugin" + HighlightPostMarker, "build a <em>plugin</em>"},
+		{"html escaped", "<script>" + HighlightPreMarker + "alert" + HighlightPostMarker + "</script>",
+			"&lt;script&gt;<em>alert</em>&lt;/script&gt;"},
+		{"engine tags are not trusted", "<em>plugin</em>", "&lt;em&gt;plugin&lt;/em&gt;"},
+		{"trimmed", "  text \n", "text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BuildHighlight(tt.fragment); got != tt.want {
+				t.Errorf("BuildHighlight() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchCond(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  plugin.SearchBasicCond
+	}{
+		{"defaults", "", plugin.SearchBasicCond{
+			Page: 1, PageSize: 20, Words: []string{}, Order: plugin.SearchRelevanceOrder,
+			VoteAmount: -1, ViewAmount: -1, AnswerAmount: -1,
+		}},
+		{"query", "q=build+plugin&page=2&page_size=50&order=newest", plugin.SearchBasicCond{
+			Page: 2, PageSize: 50, Words: []string{"build", "plugin"}, Order: plugin.SearchNewestOrder,
+			VoteAmount: -1, ViewAmount: -1, AnswerAmount: -1,
+		}},
+		{"out of range", "page=0&page_size=1000", plugin.SearchBasicCond{
+			Page: 1, PageSize: 20, Words: []string{}, Order: plugin.SearchRelevanceOrder,
+			VoteAmount: -1, ViewAmount: -1, AnswerAmount: -1,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/search?"+tt.query, nil)
+			if got := searchCond(ctx); !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("searchCond() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
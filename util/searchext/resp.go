@@ -0,0 +1,70 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package searchext
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RespBody response body.
+type RespBody struct {
+	// http code
+	Code int `json:"code"`
+	// reason key
+	Reason string `json:"reason"`
+	// response message
+	Message string `json:"msg"`
+	// response data
+	Data interface{} `json:"data"`
+}
+
+// NewRespBodyData new response body with data
+func NewRespBodyData(code int, reason string, data interface{}) *RespBody {
+	return &RespBody{
+		Code:   code,
+		Reason: reason,
+		Data:   data,
+	}
+}
+
+// HandleResponse writes data as a successful response, or err as a failed one.
+func HandleResponse(ctx *gin.Context, err error, data interface{}) {
+	if err == nil {
+		ctx.JSON(http.StatusOK, NewRespBodyData(http.StatusOK, "success", data))
+		return
+	}
+	resp := NewRespBodyData(http.StatusInternalServerError, "error", nil)
+	resp.Message = err.Error()
+	ctx.JSON(http.StatusInternalServerError, resp)
+}
+
+// HandleBadRequest writes a bad request response with the given message.
+func HandleBadRequest(ctx *gin.Context, msg string) {
+	resp := NewRespBodyData(http.StatusBadRequest, "error", nil)
+	resp.Message = msg
+	ctx.JSON(http.StatusBadRequest, resp)
+}
+
+// HandleNotFound writes a not found response, used when the plugin is disabled or not configured.
+func HandleNotFound(ctx *gin.Context) {
+	ctx.JSON(http.StatusNotFound, NewRespBodyData(http.StatusNotFound, "error", nil))
+}
@@ -0,0 +1,98 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package searchext
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/apache/incubator-answer/plugin"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	DefaultSimilarSize = 5
+	MaxSimilarSize     = 20
+
+	// maxSimilarContentLength limits how much of the draft content is sent to the engine.
+	maxSimilarContentLength = 1000
+)
+
+// SimilarCond is the draft question used to look up similar questions.
+type SimilarCond struct {
+	// Title of the draft question.
+	Title string `json:"title"`
+	// Content of the draft question, optional.
+	Content string `json:"content"`
+	// Size is the maximum number of similar questions to return.
+	Size int `json:"size"`
+}
+
+// LikeText returns the text that similar questions should be matched against.
+func (c *SimilarCond) LikeText() string {
+	content := c.Content
+	if utf8.RuneCountInString(content) > maxSimilarContentLength {
+		content = string([]rune(content)[:maxSimilarContentLength])
+	}
+	return strings.TrimSpace(c.Title + "\n" + content)
+}
+
+// SimilarResult is a question that looks like the draft.
+type SimilarResult struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	// Score is the relevance score, zero when the engine does not expose one.
+	Score float64 `json:"score"`
+}
+
+// SimilarSearcher is implemented by search plugins that can find questions similar to a draft.
+// Only available questions are returned.
+type SimilarSearcher interface {
+	SearchSimilar(ctx context.Context, cond *SimilarCond) (res []SimilarResult, err error)
+}
+
+// SimilarHandler returns a handler that looks up questions similar to the draft in the request body.
+// The handler only responds when the plugin identified by slugName is enabled.
+func SimilarHandler(slugName string, searcher SimilarSearcher) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !plugin.StatusManager.IsEnabled(slugName) {
+			HandleNotFound(ctx)
+			return
+		}
+		cond := &SimilarCond{}
+		if err := ctx.ShouldBindJSON(cond); err != nil {
+			HandleBadRequest(ctx, err.Error())
+			return
+		}
+		cond.Title = strings.TrimSpace(cond.Title)
+		if len(cond.Title) == 0 {
+			HandleBadRequest(ctx, "title is required")
+			return
+		}
+		if cond.Size <= 0 {
+			cond.Size = DefaultSimilarSize
+		} else if cond.Size > MaxSimilarSize {
+			cond.Size = MaxSimilarSize
+		}
+		res, err := searcher.SearchSimilar(ctx, cond)
+		HandleResponse(ctx, err, res)
+	}
+}
@@ -0,0 +1,107 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package searchquery
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/segmentfault/pacman/log"
+)
+
+// QueryParam is the query string parameter of the search query, in Answer's search and in the search route of the plugins.
+const QueryParam = "q"
+
+var (
+	// answerOperator matches the operators Answer applies to the search condition itself, removing them from the words.
+	answerOperator = regexp.MustCompile(`^(\[.*\]|user:\S+|score:\d+|views:\d+|answers:\d+|inquestion:\d+|` +
+		`is:question|is:answer|isaccepted:yes|hasaccepted:no)$`)
+	// phrasePrefix matches what is left of the prefix of a phrase once Answer has moved the phrase away, like title:
+	phrasePrefix = regexp.MustCompile(`^-?(\w+:)?$`)
+)
+
+// ParseSearch parses the query of a search. Before the words reach the plugin, Answer's search turns the "-"
+// of the exclusions into spaces and moves the phrases ahead of the other words. Answer passes its request
+// as ctx, so the query is parsed from the request when it's there, leaving out the operators that Answer has
+// already applied to the search condition, the ones no longer in the words. Otherwise the words are parsed,
+// and a warning is logged as the exclusions and phrases may be lost.
+func ParseSearch(ctx context.Context, words []string) *Query {
+	ginCtx, ok := ctx.(*gin.Context)
+	if !ok || ginCtx.Request == nil {
+		warnWordsParsed(words, "the search context is not an HTTP request")
+		return Parse(words)
+	}
+	text := ginCtx.Query(QueryParam)
+	if len(strings.TrimSpace(text)) == 0 {
+		warnWordsParsed(words, "the search request has no "+QueryParam+" parameter")
+		return Parse(words)
+	}
+	remaining := make(map[string]bool, len(words))
+	for _, word := range words {
+		remaining[word] = true
+	}
+	query := &Query{}
+	for _, t := range tokenize(text) {
+		if !t.negate && !t.quoted && answerOperator.MatchString(t.text()) && !remaining[t.text()] {
+			continue
+		}
+		query.add(t)
+	}
+	return query
+}
+
+// restorePhrases puts back the phrases of words parsed by Answer. Answer moves the phrases ahead of the other words,
+// leaving their prefixes behind as words of their own, like the "title:" of title:"foo bar". When there are as many
+// prefixes as phrases, each prefix goes back on its phrase in order. Otherwise which phrase had a prefix is lost,
+// and the words are left as they are.
+func restorePhrases(words []string) []string {
+	var phrases, prefixes, rest []string
+	for _, word := range words {
+		switch {
+		case len(rest) == 0 && len(prefixes) == 0 && isPhrase(word):
+			phrases = append(phrases, word)
+		case len(word) > 0 && phrasePrefix.MatchString(word):
+			prefixes = append(prefixes, word)
+		default:
+			rest = append(rest, word)
+		}
+	}
+	if len(phrases) == 0 || len(prefixes) != len(phrases) {
+		return words
+	}
+	restored := make([]string, 0, len(phrases)+len(rest))
+	for i, phrase := range phrases {
+		restored = append(restored, prefixes[i]+phrase)
+	}
+	return append(restored, rest...)
+}
+
+func warnWordsParsed(words []string, reason string) {
+	if len(words) == 0 {
+		return
+	}
+	log.Warnf("search query parsed from Answer's words, exclusions and phrases may be lost: %s", reason)
+}
+
+func isPhrase(word string) bool {
+	return len(word) >= 2 && strings.HasPrefix(word, `"`) && strings.HasSuffix(word, `"`)
+}
@@ -0,0 +1,153 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package searchquery
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// answerWords returns the words Answer v1.3.6 passes to the search plugins for query: SearchDTO.Check,
+// then SearchParser.ParseStructure with the users in users and without the tag lookups.
+func answerWords(query string, users ...string) []string {
+	// SearchDTO.Check
+	keyValueRegex := regexp.MustCompile(`\w+:\S+`)
+	tagRegex := regexp.MustCompile(`\[\w+\]`)
+	patterns := append(keyValueRegex.FindAllString(query, -1), tagRegex.FindAllString(query, -1)...)
+	content := tagRegex.ReplaceAllString(keyValueRegex.ReplaceAllString(query, ""), "")
+	content = strings.TrimSpace(regexp.MustCompile(`[+#.<>\-_()*]`).ReplaceAllString(content, " "))
+	q := strings.Join(append(patterns, content), " ")
+
+	// SearchParser.ParseStructure
+	remove := func(expr string) bool {
+		re := regexp.MustCompile(expr)
+		found := re.MatchString(q)
+		q = strings.TrimSpace(re.ReplaceAllString(q, ""))
+		return found
+	}
+	remove(`\[(.*?)\]`)
+	if res := regexp.MustCompile(`user:(\S+)`).FindStringSubmatch(q); len(res) > 1 {
+		for _, user := range users {
+			if user == res[1] {
+				remove(`user:(\S+)`)
+			}
+		}
+	}
+	remove(`score:(\d+)`)
+	phraseRegex := regexp.MustCompile(`(?U)(".+")`)
+	words := phraseRegex.FindAllString(q, -1)
+	remove(`(?U)(".+")`)
+	for _, expr := range []string{`hasaccepted:no`, `views:(\d+)`, `answers:(\d+)`, `isaccepted:yes`,
+		`inquestion:(\d+)`, `is:question`, `is:answer`} {
+		remove(expr)
+	}
+	if len(q) > 0 {
+		words = append(words, strings.Split(q, " ")...)
+	}
+	if len(words) > 5 {
+		words = words[:5]
+	}
+	return words
+}
+
+func searchContext(query string) context.Context {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/answer/api/v1/search?q="+url.QueryEscape(query), nil)
+	return ctx
+}
+
+func TestParseAnswerWords(t *testing.T) {
+	tests := []struct {
+		name  string
+		words []string
+		want  *Query
+	}{
+		{"phrase", answerWords(`how "exact phrase"`),
+			&Query{Terms: []string{"how"}, Phrases: []string{"exact phrase"}}},
+		{"title phrase", answerWords(`title:"foo bar" baz`),
+			&Query{Terms: []string{"baz"}, TitleTerms: []string{"foo bar"}}},
+		{"title phrases", []string{`"foo bar"`, `"baz qux"`, "title:", "-", "go"},
+			&Query{Terms: []string{"go"}, TitleTerms: []string{"foo bar"}, ExcludePhrases: []string{"baz qux"}}},
+		{"excluded phrase moved", []string{`"some phrase"`, "-"}, &Query{ExcludePhrases: []string{"some phrase"}}},
+		{"excluded phrase with words", []string{`"some phrase"`, "go", "-", "plugin"},
+			&Query{Terms: []string{"go", "plugin"}, ExcludePhrases: []string{"some phrase"}}},
+		// which of the phrases was excluded is lost
+		{"ambiguous prefix", []string{`"a b"`, `"c d"`, "-"}, &Query{Phrases: []string{"a b", "c d"}}},
+		// Answer turns the "-" into a space, only the request has the exclusions
+		{"exclusion lost", answerWords(`go -java`), &Query{Terms: []string{"go", "java"}}},
+		{"operators", answerWords(`score:>5 created:>2024-01-01 is:accepted go`),
+			&Query{Terms: []string{"go"}, Accepted: true, Score: atLeast(6), Created: atLeast(day("2024-01-02"))}},
+		{"unknown user", answerWords(`user:bob go`), &Query{Terms: []string{"go"}, UserID: "bob"}},
+		{"resolved user", answerWords(`user:alice go`, "alice"), &Query{Terms: []string{"go"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.words); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.words, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSearch(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		words []string
+		want  *Query
+	}{
+		{"excluded phrase", `-"some phrase" go`, answerWords(`-"some phrase" go`),
+			&Query{Terms: []string{"go"}, ExcludePhrases: []string{"some phrase"}}},
+		{"excluded word", `go -java`, answerWords(`go -java`),
+			&Query{Terms: []string{"go"}, ExcludeTerms: []string{"java"}}},
+		{"title phrase", `title:"foo bar" baz`, answerWords(`title:"foo bar" baz`),
+			&Query{Terms: []string{"baz"}, TitleTerms: []string{"foo bar"}}},
+		{"applied by Answer", `[golang] user:alice score:5 is:question go`,
+			answerWords(`[golang] user:alice score:5 is:question go`, "alice"), &Query{Terms: []string{"go"}}},
+		{"unknown user", `user:bob go`, answerWords(`user:bob go`), &Query{Terms: []string{"go"}, UserID: "bob"}},
+		{"plugin operators", `score:>5 is:accepted go`, answerWords(`score:>5 is:accepted go`),
+			&Query{Terms: []string{"go"}, Accepted: true, Score: atLeast(6)}},
+		{"search route", `[golang] user:10010000000000001 -java`, strings.Fields(`[golang] user:10010000000000001 -java`),
+			&Query{Terms: []string{"[golang]"}, UserID: "10010000000000001", ExcludeTerms: []string{"java"}}},
+		{"no query", "", []string{`"exact phrase"`}, &Query{Phrases: []string{"exact phrase"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseSearch(searchContext(tt.query), tt.words); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSearch(%q, %q) = %+v, want %+v", tt.query, tt.words, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSearchWithoutRequest(t *testing.T) {
+	want := &Query{Terms: []string{"baz"}, TitleTerms: []string{"foo bar"}}
+	if got := ParseSearch(context.Background(), answerWords(`title:"foo bar" baz`)); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseSearch() = %+v, want %+v", got, want)
+	}
+}
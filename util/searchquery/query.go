@@ -0,0 +1,310 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+// Package searchquery parses the search operators shared by the search plugins.
+//
+// The syntax is:
+//
+//	word            the word must match
+//	"exact phrase"  the phrase must match as is
+//	-word           the word must not match
+//	-"some phrase"  the phrase must not match
+//	title:foo       the title must match foo, title:"foo bar" matches a phrase in the title
+//	user:id         the content is created by the user, Answer's search resolves user:name and user:me itself
+//	is:question     only questions
+//	is:answer       only answers
+//	is:accepted     only questions with an accepted answer, and accepted answers
+//	score:>5        the score is in range, the operator is one of >, >=, <, <= and =, no operator means >=
+//	created:>2024-01-01  the content is created in range of UTC dates, no operator means on that day
+//
+// Operator names are case-insensitive. Anything that isn't a valid operator, like a negated operator
+// or score:abc, is a plain word.
+//
+// The plugins receive the words of Answer's search after Answer has parsed them, see ParseSearch.
+package searchquery
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	TypeQuestion = "question"
+	TypeAnswer   = "answer"
+
+	dateLayout = "2006-01-02"
+)
+
+// Range is an inclusive range of integers, a nil bound is open.
+type Range struct {
+	Min *int64
+	Max *int64
+}
+
+// IsZero reports whether the range has no bound.
+func (r Range) IsZero() bool {
+	return r.Min == nil && r.Max == nil
+}
+
+// Query is a parsed search query.
+type Query struct {
+	Terms          []string
+	Phrases        []string
+	ExcludeTerms   []string
+	ExcludePhrases []string
+	// TitleTerms must match the title, each of them is a word or a phrase.
+	TitleTerms []string
+	UserID     string
+	// Type is TypeQuestion, TypeAnswer or empty for both.
+	Type     string
+	Accepted bool
+	Score    Range
+	// Created is a range of unix seconds.
+	Created Range
+}
+
+// Parse parses the words of a search condition. The words are joined back first,
+// so a phrase split into several words is parsed as one phrase.
+func Parse(words []string) *Query {
+	query := &Query{}
+	for _, t := range tokenize(strings.Join(restorePhrases(words), " ")) {
+		query.add(t)
+	}
+	return query
+}
+
+// HasText reports whether the query has anything to match in the title or content.
+func (q *Query) HasText() bool {
+	return len(q.Terms) > 0 || len(q.Phrases) > 0 || len(q.TitleTerms) > 0
+}
+
+// Text renders the words and phrases to match, title terms included as phrases, in the syntax
+// understood by Meilisearch and the Algolia advanced syntax: word "exact phrase" -word -"some phrase".
+func (q *Query) Text() string {
+	parts := make([]string, 0, len(q.Terms)+len(q.Phrases)+len(q.TitleTerms)+len(q.ExcludeTerms)+len(q.ExcludePhrases))
+	parts = append(parts, q.Terms...)
+	for _, phrase := range q.Phrases {
+		parts = append(parts, quote(phrase))
+	}
+	for _, title := range q.TitleTerms {
+		parts = append(parts, quote(title))
+	}
+	for _, term := range q.ExcludeTerms {
+		parts = append(parts, "-"+term)
+	}
+	for _, phrase := range q.ExcludePhrases {
+		parts = append(parts, "-"+quote(phrase))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (q *Query) add(t token) {
+	if len(t.key) > 0 && !t.negate && q.addOperator(strings.ToLower(t.key), t.value) {
+		return
+	}
+	switch {
+	case t.quoted && t.negate:
+		q.ExcludePhrases = append(q.ExcludePhrases, t.text())
+	case t.quoted:
+		q.Phrases = append(q.Phrases, t.text())
+	case t.negate:
+		q.ExcludeTerms = append(q.ExcludeTerms, t.text())
+	default:
+		q.Terms = append(q.Terms, t.text())
+	}
+}
+
+// addOperator applies the operator, and reports false if it isn't a valid operator.
+func (q *Query) addOperator(key, value string) bool {
+	if len(value) == 0 {
+		return false
+	}
+	switch key {
+	case "title":
+		q.TitleTerms = append(q.TitleTerms, value)
+	case "user":
+		q.UserID = value
+	case "is":
+		switch strings.ToLower(value) {
+		case TypeQuestion:
+			q.Type = TypeQuestion
+		case TypeAnswer:
+			q.Type = TypeAnswer
+		case "accepted":
+			q.Accepted = true
+		default:
+			return false
+		}
+	case "score":
+		r, ok := parseIntRange(value)
+		if !ok {
+			return false
+		}
+		q.Score = r
+	case "created":
+		r, ok := parseDateRange(value)
+		if !ok {
+			return false
+		}
+		q.Created = r
+	default:
+		return false
+	}
+	return true
+}
+
+// splitComparison splits value into its comparison operator and operand.
+func splitComparison(value string) (op, operand string) {
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(value, op) {
+			return op, value[len(op):]
+		}
+	}
+	return "", value
+}
+
+func parseIntRange(value string) (Range, bool) {
+	op, operand := splitComparison(value)
+	n, err := strconv.ParseInt(operand, 10, 64)
+	if err != nil {
+		return Range{}, false
+	}
+	switch op {
+	case ">":
+		return Range{Min: int64Ptr(n + 1)}, true
+	case "<":
+		return Range{Max: int64Ptr(n - 1)}, true
+	case "<=":
+		return Range{Max: int64Ptr(n)}, true
+	case "=":
+		return Range{Min: int64Ptr(n), Max: int64Ptr(n)}, true
+	default:
+		return Range{Min: int64Ptr(n)}, true
+	}
+}
+
+func parseDateRange(value string) (Range, bool) {
+	op, operand := splitComparison(value)
+	day, err := time.Parse(dateLayout, operand)
+	if err != nil {
+		return Range{}, false
+	}
+	start, end := day.Unix(), day.AddDate(0, 0, 1).Unix()
+	switch op {
+	case ">":
+		return Range{Min: int64Ptr(end)}, true
+	case ">=":
+		return Range{Min: int64Ptr(start)}, true
+	case "<":
+		return Range{Max: int64Ptr(start - 1)}, true
+	case "<=":
+		return Range{Max: int64Ptr(end - 1)}, true
+	default:
+		return Range{Min: int64Ptr(start), Max: int64Ptr(end - 1)}, true
+	}
+}
+
+func int64Ptr(n int64) *int64 {
+	return &n
+}
+
+func quote(phrase string) string {
+	return `"` + phrase + `"`
+}
+
+// token is a word, a phrase or an operator of the query.
+type token struct {
+	negate bool
+	quoted bool
+	key    string
+	value  string
+}
+
+// text is the token as a plain word, or a phrase when quoted.
+func (t token) text() string {
+	if len(t.key) > 0 {
+		return t.key + ":" + t.value
+	}
+	return t.value
+}
+
+// tokenize splits text into tokens. A phrase runs to the closing quote, or to the end of text.
+func tokenize(text string) []token {
+	var (
+		runes  = []rune(text)
+		tokens []token
+	)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+		t := token{}
+		if runes[i] == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			t.negate = true
+			i++
+		}
+		if runes[i] == '"' {
+			t.quoted = true
+			t.value, i = readQuoted(runes, i+1)
+		} else {
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '"' {
+				if runes[i] == ':' && len(t.key) == 0 && i > start {
+					t.key = string(runes[start:i])
+					start = i + 1
+					if start < len(runes) && runes[start] == '"' {
+						t.quoted = true
+						t.value, i = readQuoted(runes, start+1)
+						break
+					}
+				}
+				i++
+			}
+			if !t.quoted {
+				t.value = string(runes[start:i])
+			}
+		}
+		if len(t.key) == 0 && (len(strings.TrimSpace(t.value)) == 0 || t.value == "-") {
+			continue
+		}
+		if len(t.key) > 0 && len(t.value) == 0 && !t.quoted {
+			// a bare "key:" is a plain word
+			t.value, t.key = t.key+":", ""
+		}
+		t.value = strings.TrimSpace(t.value)
+		tokens = append(tokens, t)
+	}
+	return tokens
+}
+
+// readQuoted reads a phrase from runes[i:] to the closing quote, and returns it with the index after the quote.
+func readQuoted(runes []rune, i int) (string, int) {
+	start := i
+	for i < len(runes) && runes[i] != '"' {
+		i++
+	}
+	phrase := string(runes[start:i])
+	if i < len(runes) {
+		i++
+	}
+	return phrase, i
+}
@@ -0,0 +1,171 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package searchquery
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func day(s string) int64 {
+	d, _ := time.Parse(dateLayout, s)
+	return d.Unix()
+}
+
+func between(min, max int64) Range {
+	return Range{Min: int64Ptr(min), Max: int64Ptr(max)}
+}
+
+func atLeast(n int64) Range {
+	return Range{Min: int64Ptr(n)}
+}
+
+func atMost(n int64) Range {
+	return Range{Max: int64Ptr(n)}
+}
+
+func TestParse(t *testing.T) {
+	const dayLen = 24 * 60 * 60
+	tests := []struct {
+		name  string
+		query string
+		want  *Query
+	}{
+		{"empty", "", &Query{}},
+		{"spaces", "  \t ", &Query{}},
+		{"words", "build  a plugin", &Query{Terms: []string{"build", "a", "plugin"}}},
+		{"phrase", `"exact phrase"`, &Query{Phrases: []string{"exact phrase"}}},
+		{"phrase with words", `how "exact phrase" works`,
+			&Query{Terms: []string{"how", "works"}, Phrases: []string{"exact phrase"}}},
+		{"phrase next to word", `foo"bar baz"`, &Query{Terms: []string{"foo"}, Phrases: []string{"bar baz"}}},
+		{"phrase trimmed", `" padded "`, &Query{Phrases: []string{"padded"}}},
+		{"unclosed phrase", `"no end`, &Query{Phrases: []string{"no end"}}},
+		{"empty phrase", `"" ""`, &Query{}},
+		{"exclude", "plugin -java", &Query{Terms: []string{"plugin"}, ExcludeTerms: []string{"java"}}},
+		{"exclude phrase", `-"spring boot"`, &Query{ExcludePhrases: []string{"spring boot"}}},
+		{"dash in word", "e-mail", &Query{Terms: []string{"e-mail"}}},
+		{"lone dash", "a - b -", &Query{Terms: []string{"a", "b"}}},
+		{"title", "title:foo", &Query{TitleTerms: []string{"foo"}}},
+		{"title phrase", `title:"foo bar" baz`, &Query{Terms: []string{"baz"}, TitleTerms: []string{"foo bar"}}},
+		{"title case", "TITLE:Foo", &Query{TitleTerms: []string{"Foo"}}},
+		{"titles", "title:foo title:bar", &Query{TitleTerms: []string{"foo", "bar"}}},
+		{"empty title", "title:", &Query{Terms: []string{"title:"}}},
+		{"negated title", "-title:foo", &Query{ExcludeTerms: []string{"title:foo"}}},
+		{"user", "user:10010000000000001", &Query{UserID: "10010000000000001"}},
+		{"last user wins", "user:a user:b", &Query{UserID: "b"}},
+		{"is question", "is:question", &Query{Type: TypeQuestion}},
+		{"is answer", "is:Answer", &Query{Type: TypeAnswer}},
+		{"is accepted", "is:accepted", &Query{Accepted: true}},
+		{"is accepted answer", "is:answer is:accepted", &Query{Type: TypeAnswer, Accepted: true}},
+		{"is unknown", "is:closed", &Query{Terms: []string{"is:closed"}}},
+		{"score", "score:5", &Query{Score: atLeast(5)}},
+		{"score greater", "score:>5", &Query{Score: atLeast(6)}},
+		{"score greater or equal", "score:>=5", &Query{Score: atLeast(5)}},
+		{"score less", "score:<5", &Query{Score: atMost(4)}},
+		{"score less or equal", "score:<=5", &Query{Score: atMost(5)}},
+		{"score equal", "score:=5", &Query{Score: between(5, 5)}},
+		{"score negative", "score:<-1", &Query{Score: atMost(-2)}},
+		{"score invalid", "score:>abc", &Query{Terms: []string{"score:>abc"}}},
+		{"score empty operand", "score:>", &Query{Terms: []string{"score:>"}}},
+		{"created", "created:2024-01-01", &Query{Created: between(day("2024-01-01"), day("2024-01-02")-1)}},
+		{"created after", "created:>2024-01-01", &Query{Created: atLeast(day("2024-01-01") + dayLen)}},
+		{"created since", "created:>=2024-01-01", &Query{Created: atLeast(day("2024-01-01"))}},
+		{"created before", "created:<2024-01-01", &Query{Created: atMost(day("2024-01-01") - 1)}},
+		{"created until", "created:<=2024-01-01", &Query{Created: atMost(day("2024-01-02") - 1)}},
+		{"created on", "created:=2024-02-29", &Query{Created: between(day("2024-02-29"), day("2024-03-01")-1)}},
+		{"created invalid", "created:>2024-13-01", &Query{Terms: []string{"created:>2024-13-01"}}},
+		{"created not a date", "created:yesterday", &Query{Terms: []string{"created:yesterday"}}},
+		{"unknown operator", "lang:go", &Query{Terms: []string{"lang:go"}}},
+		{"unknown quoted operator", `lang:"go lang"`, &Query{Phrases: []string{"lang:go lang"}}},
+		{"url", "http://example.com", &Query{Terms: []string{"http://example.com"}}},
+		{"colon first", ":foo", &Query{Terms: []string{":foo"}}},
+		{"unicode", `插件 "开发 文档" -测试`,
+			&Query{Terms: []string{"插件"}, Phrases: []string{"开发 文档"}, ExcludeTerms: []string{"测试"}}},
+		{"everything", `plugin "exact phrase" -java -"spring boot" title:build user:1 is:answer is:accepted score:>5 created:>=2024-01-01`,
+			&Query{
+				Terms:          []string{"plugin"},
+				Phrases:        []string{"exact phrase"},
+				ExcludeTerms:   []string{"java"},
+				ExcludePhrases: []string{"spring boot"},
+				TitleTerms:     []string{"build"},
+				UserID:         "1",
+				Type:           TypeAnswer,
+				Accepted:       true,
+				Score:          atLeast(6),
+				Created:        atLeast(day("2024-01-01")),
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(strings.Split(tt.query, " "))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSplitPhrase(t *testing.T) {
+	got := Parse([]string{`"exact`, `phrase"`, "word"})
+	want := &Query{Terms: []string{"word"}, Phrases: []string{"exact phrase"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse() = %+v, want %+v", got, want)
+	}
+}
+
+func TestQueryText(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"empty", "", ""},
+		{"words", "build plugin", "build plugin"},
+		{"all", `-java plugin title:"foo bar" -"spring boot" "exact phrase" user:1 score:5`,
+			`plugin "exact phrase" "foo bar" -java -"spring boot"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse([]string{tt.query}).Text(); got != tt.want {
+				t.Errorf("Text() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryHasText(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"", false},
+		{"-java is:answer score:5", false},
+		{"plugin", true},
+		{`"exact phrase"`, true},
+		{"title:foo", true},
+	}
+	for _, tt := range tests {
+		if got := Parse([]string{tt.query}).HasText(); got != tt.want {
+			t.Errorf("Parse(%q).HasText() = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
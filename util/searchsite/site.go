@@ -0,0 +1,139 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+// Package searchsite isolates the indexes of several Answer sites sharing one search engine.
+package searchsite
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/apache/incubator-answer/plugin"
+	"github.com/segmentfault/pacman/log"
+)
+
+// Field is the document field holding the ID of the site that indexed it.
+const Field = "site_id"
+
+var ErrForeignIndex = errors.New("index holds documents of another site")
+
+// ID returns the configured site ID, or the hash of the site URL of Answer when it is empty.
+func ID(configured string) string {
+	if id := normalize(configured); id != "" {
+		return id
+	}
+	return Hash(plugin.SiteURL())
+}
+
+// Hash returns the default site ID of a site URL, the first 12 hex characters of its sha256.
+// The case and a trailing slash of the URL are ignored.
+func Hash(siteURL string) string {
+	siteURL = strings.TrimRight(strings.ToLower(strings.TrimSpace(siteURL)), "/")
+	sum := sha256.Sum256([]byte(siteURL))
+	return hex.EncodeToString(sum[:6])
+}
+
+// IndexName returns the index name of the site, the configured name, or the base name suffixed with the
+// configured site ID. A site without a configured site ID keeps the base name of the older versions.
+func IndexName(configured, base, configuredSiteID string) string {
+	if configured = strings.TrimSpace(configured); configured != "" {
+		return configured
+	}
+	if id := normalize(configuredSiteID); id != "" {
+		return base + "_" + id
+	}
+	return base
+}
+
+// normalize lowercases the ID and replaces the characters that index names don't accept.
+func normalize(id string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		default:
+			return '_'
+		}
+	}, strings.TrimSpace(id))
+}
+
+// Guard refuses writes to an index holding documents of another site.
+type Guard struct {
+	name   string
+	owners func(ctx context.Context) ([]string, error)
+
+	mu     sync.RWMutex
+	siteID string
+	err    error
+}
+
+// NewGuard creates the guard of a search plugin, owners lists the site IDs of the documents in the index.
+func NewGuard(name string, owners func(ctx context.Context) ([]string, error)) *Guard {
+	return &Guard{name: name, owners: owners}
+}
+
+// Configure sets the site ID and forgets the result of the last check.
+func (g *Guard) Configure(siteID string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.siteID = siteID
+	g.err = nil
+}
+
+// SiteID returns the configured site ID.
+func (g *Guard) SiteID() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.siteID
+}
+
+// Check lists the sites owning documents of the index and records whether another site is among them.
+// Documents without a site ID, indexed before the sites were told apart, are ignored.
+// The last result is kept when the owners can't be listed.
+func (g *Guard) Check(ctx context.Context) error {
+	owners, err := g.owners(ctx)
+	if err != nil {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.err = nil
+	for _, owner := range owners {
+		if owner != "" && owner != g.siteID {
+			g.err = fmt.Errorf("%w: site %s, this site is %s", ErrForeignIndex, owner, g.siteID)
+			log.Errorf("%s: %s, set another index name or site ID", g.name, g.err)
+			break
+		}
+	}
+	return g.err
+}
+
+// Err returns the result of the last check, writes are refused while it isn't nil.
+func (g *Guard) Err() error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.err
+}
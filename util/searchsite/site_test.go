@@ -0,0 +1,82 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package searchsite
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestID(t *testing.T) {
+	if got := ID(" Team.A "); got != "team_a" {
+		t.Errorf("ID = %q", got)
+	}
+	if got := Hash("https://example.com/"); got != Hash("HTTPS://example.com") || len(got) != 12 {
+		t.Errorf("Hash = %q", got)
+	}
+	if Hash("https://a.example.com") == Hash("https://b.example.com") {
+		t.Error("different sites share a hash")
+	}
+}
+
+func TestIndexName(t *testing.T) {
+	if got := IndexName("", "answer_post", " ABC "); got != "answer_post_abc" {
+		t.Errorf("IndexName = %q", got)
+	}
+	if got := IndexName("", "answer_post", ""); got != "answer_post" {
+		t.Errorf("IndexName = %q", got)
+	}
+	if got := IndexName(" posts ", "answer_post", "abc"); got != "posts" {
+		t.Errorf("IndexName = %q", got)
+	}
+}
+
+func TestGuard(t *testing.T) {
+	var owners []string
+	var lookupErr error
+	g := NewGuard("test", func(ctx context.Context) ([]string, error) {
+		return owners, lookupErr
+	})
+	g.Configure("a")
+
+	owners = []string{"a", ""}
+	if err := g.Check(context.Background()); err != nil {
+		t.Fatalf("own index refused: %v", err)
+	}
+
+	owners = []string{"a", "b"}
+	if err := g.Check(context.Background()); !errors.Is(err, ErrForeignIndex) {
+		t.Fatalf("foreign index accepted: %v", err)
+	}
+
+	lookupErr = errors.New("down")
+	if err := g.Check(context.Background()); err != lookupErr {
+		t.Fatalf("Check = %v", err)
+	}
+	if !errors.Is(g.Err(), ErrForeignIndex) {
+		t.Fatalf("failed lookup forgot the last result: %v", g.Err())
+	}
+
+	g.Configure("b")
+	if g.Err() != nil || g.SiteID() != "b" {
+		t.Fatalf("Configure kept the last result: %v", g.Err())
+	}
+}
@@ -0,0 +1,69 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package searchstats
+
+import (
+	"strings"
+
+	"github.com/apache/incubator-answer-plugins/util/searchext"
+	"github.com/apache/incubator-answer/plugin"
+	"github.com/gin-gonic/gin"
+)
+
+// ClickReq is a click on a search result.
+type ClickReq struct {
+	// Query is the query of the search, as in the q of the search route.
+	Query string `json:"q"`
+	// Scope is the scope of the search, contents by default.
+	Scope searchext.SearchScope `json:"scope"`
+	// ObjectID is the id of the clicked result.
+	ObjectID string `json:"object_id"`
+	// Position is the 1-based rank of the result.
+	Position int `json:"position"`
+}
+
+// ClickHandler returns a handler that records the click in the request body,
+// so that the report counts the click-through of the queries.
+func ClickHandler(slugName string, recorder *Recorder) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !plugin.StatusManager.IsEnabled(slugName) {
+			searchext.HandleNotFound(ctx)
+			return
+		}
+		req := &ClickReq{}
+		if err := ctx.ShouldBindJSON(req); err != nil {
+			searchext.HandleBadRequest(ctx, err.Error())
+			return
+		}
+		if len(strings.TrimSpace(req.Query)) == 0 || len(req.ObjectID) == 0 || req.Position < 1 {
+			searchext.HandleBadRequest(ctx, "q, object_id and a positive position are required")
+			return
+		}
+		if len(req.Scope) == 0 {
+			req.Scope = searchext.ScopeContents
+		}
+		if req.Scope != searchext.ScopeContents && req.Scope != searchext.ScopeQuestions && req.Scope != searchext.ScopeAnswers {
+			searchext.HandleBadRequest(ctx, "invalid scope")
+			return
+		}
+		recorder.RecordClick(req.Scope, strings.Fields(req.Query), req.ObjectID, req.Position)
+		searchext.HandleResponse(ctx, nil, nil)
+	}
+}
@@ -0,0 +1,272 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package searchstats
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/incubator-answer-plugins/util/searchext"
+	"github.com/apache/incubator-answer/plugin"
+	"github.com/segmentfault/pacman/log"
+)
+
+const (
+	SinkFile  = "file"
+	SinkCache = "cache"
+
+	// DefaultPath is the directory of the SinkFile files when no path is configured.
+	DefaultPath = "/data/search_analytics"
+	// DefaultRetention is how long the records are kept.
+	DefaultRetention = 30 * 24 * time.Hour
+
+	recordQueueSize = 1024
+	writeBatchSize  = 100
+)
+
+// Record is one search call, or one click on a search result when ObjectID is set.
+type Record struct {
+	Time  time.Time             `json:"time"`
+	Scope searchext.SearchScope `json:"scope"`
+	// Query is the normalized query text, or its SHA-256 when queries are hashed.
+	Query     string            `json:"query"`
+	Filters   map[string]string `json:"filters,omitempty"`
+	Results   int64             `json:"results"`
+	LatencyMs int64             `json:"latency_ms"`
+	Failed    bool              `json:"failed,omitempty"`
+	// ObjectID is the clicked result, and Position its 1-based rank in the results.
+	ObjectID string `json:"object_id,omitempty"`
+	Position int    `json:"position,omitempty"`
+}
+
+// Config is the analytics part of a search plugin config.
+type Config struct {
+	Enabled   bool
+	HashQuery bool
+	// Sink is SinkFile or SinkCache.
+	Sink string
+	// Path is the directory of the SinkFile files, DefaultPath if empty.
+	Path string
+	// SlugName namespaces the SinkCache keys.
+	SlugName string
+}
+
+// Recorder records the searches of a plugin into a sink. Records are written in the background,
+// so recording never slows down a search, and records are dropped when the sink can not keep up.
+type Recorder struct {
+	lock      sync.RWMutex
+	enabled   bool
+	hashQuery bool
+	sink      Sink
+	queue     chan Record
+}
+
+func NewRecorder() *Recorder {
+	r := &Recorder{queue: make(chan Record, recordQueueSize)}
+	go r.run()
+	return r
+}
+
+// Configure applies the config, replacing the sink.
+func (r *Recorder) Configure(conf Config) error {
+	var sink Sink
+	if conf.Enabled {
+		switch conf.Sink {
+		case SinkCache:
+			sink = NewCacheSink(conf.SlugName, DefaultRetention)
+		case SinkFile, "":
+			path := conf.Path
+			if len(path) == 0 {
+				path = DefaultPath
+			}
+			fileSink, err := NewFileSink(path, DefaultRetention)
+			if err != nil {
+				return fmt.Errorf("init search analytics file sink error: %w", err)
+			}
+			sink = fileSink
+		default:
+			return fmt.Errorf("unknown search analytics sink: %s", conf.Sink)
+		}
+	}
+
+	r.lock.Lock()
+	oldSink := r.sink
+	r.enabled = conf.Enabled
+	r.hashQuery = conf.HashQuery
+	r.sink = sink
+	r.lock.Unlock()
+
+	if oldSink != nil {
+		if err := oldSink.Close(); err != nil {
+			log.Errorf("close search analytics sink error: %v", err)
+		}
+	}
+	return nil
+}
+
+// Record records a search started at start. Searches without words are not recorded.
+func (r *Recorder) Record(scope searchext.SearchScope, cond *plugin.SearchBasicCond, total int64, err error, start time.Time) {
+	query, ok := r.query(cond.Words)
+	if !ok {
+		return
+	}
+	r.enqueue(Record{
+		Time:      start,
+		Scope:     scope,
+		Query:     query,
+		Filters:   buildFilters(cond),
+		Results:   total,
+		LatencyMs: time.Since(start).Milliseconds(),
+		Failed:    err != nil,
+	})
+}
+
+// RecordClick records a click on the result objectID, at the 1-based position in the results of the search of words.
+func (r *Recorder) RecordClick(scope searchext.SearchScope, words []string, objectID string, position int) {
+	query, ok := r.query(words)
+	if !ok {
+		return
+	}
+	r.enqueue(Record{
+		Time:     time.Now(),
+		Scope:    scope,
+		Query:    query,
+		ObjectID: objectID,
+		Position: position,
+	})
+}
+
+// query returns the recorded query of the words, false when recording is disabled or there are no words.
+func (r *Recorder) query(words []string) (string, bool) {
+	r.lock.RLock()
+	enabled, hashQuery := r.enabled, r.hashQuery
+	r.lock.RUnlock()
+	if !enabled {
+		return "", false
+	}
+	query := NormalizeQuery(words)
+	if len(query) == 0 {
+		return "", false
+	}
+	if hashQuery {
+		query = HashQuery(query)
+	}
+	return query, true
+}
+
+func (r *Recorder) enqueue(record Record) {
+	select {
+	case r.queue <- record:
+	default:
+		log.Warnf("search analytics queue is full, drop record")
+	}
+}
+
+// Read returns the records between from and to.
+func (r *Recorder) Read(ctx context.Context, from, to time.Time) ([]Record, error) {
+	r.lock.RLock()
+	sink := r.sink
+	r.lock.RUnlock()
+	if sink == nil {
+		return nil, fmt.Errorf("search analytics is not enabled")
+	}
+	return sink.Read(ctx, from, to)
+}
+
+func (r *Recorder) run() {
+	for record := range r.queue {
+		batch := []Record{record}
+	drain:
+		for len(batch) < writeBatchSize {
+			select {
+			case record := <-r.queue:
+				batch = append(batch, record)
+			default:
+				break drain
+			}
+		}
+
+		r.lock.RLock()
+		sink := r.sink
+		r.lock.RUnlock()
+		if sink == nil {
+			continue
+		}
+		if err := sink.Write(context.Background(), batch); err != nil {
+			log.Errorf("write search analytics error: %v", err)
+		}
+	}
+}
+
+// NormalizeQuery joins the words into a lower-case query, so that the same search is counted once.
+func NormalizeQuery(words []string) string {
+	return strings.ToLower(strings.Join(strings.Fields(strings.Join(words, " ")), " "))
+}
+
+// HashQuery returns the hex SHA-256 of the query.
+func HashQuery(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+func buildFilters(cond *plugin.SearchBasicCond) map[string]string {
+	filters := make(map[string]string)
+	if len(cond.TagIDs) > 0 {
+		groups := make([]string, 0, len(cond.TagIDs))
+		for _, tagGroup := range cond.TagIDs {
+			groups = append(groups, strings.Join(tagGroup, "|"))
+		}
+		filters["tags"] = strings.Join(groups, ",")
+	}
+	if len(cond.UserID) > 0 {
+		filters["user_id"] = cond.UserID
+	}
+	if len(cond.QuestionID) > 0 {
+		filters["question_id"] = cond.QuestionID
+	}
+	if len(cond.Order) > 0 {
+		filters["order"] = string(cond.Order)
+	}
+	if cond.QuestionAccepted != plugin.AcceptedCondAll {
+		filters["question_accepted"] = strconv.Itoa(int(cond.QuestionAccepted))
+	}
+	if cond.AnswerAccepted != plugin.AcceptedCondAll {
+		filters["answer_accepted"] = strconv.Itoa(int(cond.AnswerAccepted))
+	}
+	if cond.VoteAmount > 0 {
+		filters["votes"] = strconv.Itoa(cond.VoteAmount)
+	}
+	if cond.ViewAmount > 0 {
+		filters["views"] = strconv.Itoa(cond.ViewAmount)
+	}
+	if cond.AnswerAmount > 0 {
+		filters["answers"] = strconv.Itoa(cond.AnswerAmount)
+	}
+	if len(filters) == 0 {
+		return nil
+	}
+	return filters
+}
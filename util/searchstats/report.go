@@ -0,0 +1,191 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package searchstats
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/apache/incubator-answer-plugins/util/searchext"
+	"github.com/apache/incubator-answer/plugin"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	DefaultReportHours = 24
+	DefaultReportLimit = 20
+	MaxReportLimit     = 100
+)
+
+// QueryStat sums up the searches of one query.
+type QueryStat struct {
+	Query        string `json:"query"`
+	Count        int64  `json:"count"`
+	ZeroResults  int64  `json:"zero_results"`
+	Failed       int64  `json:"failed"`
+	AvgLatencyMs int64  `json:"avg_latency_ms"`
+	MaxLatencyMs int64  `json:"max_latency_ms"`
+	Clicks       int64  `json:"clicks"`
+	// ClickThroughRate is the clicks per search.
+	ClickThroughRate float64 `json:"click_through_rate"`
+	// AvgClickPosition is the average rank of the clicked results, zero without clicks.
+	AvgClickPosition float64 `json:"avg_click_position"`
+
+	totalPosition int64
+
+	totalLatencyMs int64
+}
+
+// Report sums up the searches of a time window.
+type Report struct {
+	From               time.Time `json:"from"`
+	To                 time.Time `json:"to"`
+	Searches           int64     `json:"searches"`
+	ZeroResultSearches int64     `json:"zero_result_searches"`
+	FailedSearches     int64     `json:"failed_searches"`
+	Clicks             int64     `json:"clicks"`
+	ClickThroughRate   float64   `json:"click_through_rate"`
+	// TopQueries are the most searched queries.
+	TopQueries []*QueryStat `json:"top_queries"`
+	// ZeroResultQueries are the most searched queries that found nothing.
+	ZeroResultQueries []*QueryStat `json:"zero_result_queries"`
+	// SlowestQueries are the queries with the highest average latency.
+	SlowestQueries []*QueryStat `json:"slowest_queries"`
+	// LowClickThroughQueries are the queries that found results with the lowest click-through rate,
+	// the searches whose results don't answer them.
+	LowClickThroughQueries []*QueryStat `json:"low_click_through_queries"`
+}
+
+// NewReport builds the report of the records, keeping up to limit queries in each list.
+func NewReport(records []Record, from, to time.Time, limit int) *Report {
+	report := &Report{From: from, To: to}
+	stats := make(map[string]*QueryStat)
+	for _, record := range records {
+		stat, ok := stats[record.Query]
+		if !ok {
+			stat = &QueryStat{Query: record.Query}
+			stats[record.Query] = stat
+		}
+		if len(record.ObjectID) > 0 {
+			stat.Clicks++
+			stat.totalPosition += int64(record.Position)
+			report.Clicks++
+			continue
+		}
+		stat.Count++
+		stat.totalLatencyMs += record.LatencyMs
+		if record.LatencyMs > stat.MaxLatencyMs {
+			stat.MaxLatencyMs = record.LatencyMs
+		}
+		report.Searches++
+		switch {
+		case record.Failed:
+			stat.Failed++
+			report.FailedSearches++
+		case record.Results == 0:
+			stat.ZeroResults++
+			report.ZeroResultSearches++
+		}
+	}
+
+	all := make([]*QueryStat, 0, len(stats))
+	zeroResults := make([]*QueryStat, 0)
+	found := make([]*QueryStat, 0)
+	for _, stat := range stats {
+		if stat.Clicks > 0 {
+			stat.AvgClickPosition = float64(stat.totalPosition) / float64(stat.Clicks)
+		}
+		// clicks on the results of searches before the window are not counted as searches
+		if stat.Count == 0 {
+			continue
+		}
+		stat.AvgLatencyMs = stat.totalLatencyMs / stat.Count
+		stat.ClickThroughRate = float64(stat.Clicks) / float64(stat.Count)
+		all = append(all, stat)
+		if stat.ZeroResults > 0 {
+			zeroResults = append(zeroResults, stat)
+		}
+		if stat.ZeroResults+stat.Failed < stat.Count {
+			found = append(found, stat)
+		}
+	}
+	if report.Searches > 0 {
+		report.ClickThroughRate = float64(report.Clicks) / float64(report.Searches)
+	}
+	report.TopQueries = topStats(all, limit, func(a, b *QueryStat) bool { return a.Count > b.Count })
+	report.ZeroResultQueries = topStats(zeroResults, limit, func(a, b *QueryStat) bool { return a.ZeroResults > b.ZeroResults })
+	report.SlowestQueries = topStats(all, limit, func(a, b *QueryStat) bool { return a.AvgLatencyMs > b.AvgLatencyMs })
+	report.LowClickThroughQueries = topStats(found, limit, func(a, b *QueryStat) bool {
+		if a.ClickThroughRate != b.ClickThroughRate {
+			return a.ClickThroughRate < b.ClickThroughRate
+		}
+		return a.Count > b.Count
+	})
+	return report
+}
+
+// topStats sorts a copy of stats by less, then by query, and keeps the first limit ones.
+func topStats(stats []*QueryStat, limit int, less func(a, b *QueryStat) bool) []*QueryStat {
+	sorted := make([]*QueryStat, len(stats))
+	copy(sorted, stats)
+	sort.Slice(sorted, func(i, j int) bool {
+		if less(sorted[i], sorted[j]) {
+			return true
+		}
+		if less(sorted[j], sorted[i]) {
+			return false
+		}
+		return sorted[i].Query < sorted[j].Query
+	})
+	if len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+	return sorted
+}
+
+// ReportHandler returns a handler that reports the searches recorded by the recorder.
+// The query string accepts hours, the size of the window ending now, and limit.
+func ReportHandler(slugName string, recorder *Recorder) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !plugin.StatusManager.IsEnabled(slugName) {
+			searchext.HandleNotFound(ctx)
+			return
+		}
+		hours, err := strconv.Atoi(ctx.DefaultQuery("hours", strconv.Itoa(DefaultReportHours)))
+		if err != nil || hours <= 0 || time.Duration(hours)*time.Hour > DefaultRetention {
+			searchext.HandleBadRequest(ctx, "invalid hours")
+			return
+		}
+		limit, err := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(DefaultReportLimit)))
+		if err != nil || limit <= 0 || limit > MaxReportLimit {
+			searchext.HandleBadRequest(ctx, "invalid limit")
+			return
+		}
+		to := time.Now()
+		from := to.Add(-time.Duration(hours) * time.Hour)
+		records, err := recorder.Read(ctx, from, to)
+		if err != nil {
+			searchext.HandleResponse(ctx, err, nil)
+			return
+		}
+		searchext.HandleResponse(ctx, nil, NewReport(records, from, to, limit))
+	}
+}
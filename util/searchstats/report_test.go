@@ -0,0 +1,100 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package searchstats
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewReport(t *testing.T) {
+	now := time.Now()
+	records := []Record{
+		{Time: now, Query: "go plugin", Results: 3, LatencyMs: 10},
+		{Time: now, Query: "go plugin", Results: 2, LatencyMs: 30},
+		{Time: now, Query: "go plugin", Results: 0, LatencyMs: 20},
+		{Time: now, Query: "missing docs", Results: 0, LatencyMs: 5},
+		{Time: now, Query: "slow", Results: 1, LatencyMs: 500},
+		{Time: now, Query: "broken", Failed: true, LatencyMs: 1},
+	}
+	report := NewReport(records, now.Add(-time.Hour), now, 2)
+
+	if report.Searches != 6 || report.ZeroResultSearches != 2 || report.FailedSearches != 1 {
+		t.Errorf("unexpected totals: %+v", report)
+	}
+	if len(report.TopQueries) != 2 || report.TopQueries[0].Query != "go plugin" || report.TopQueries[0].Count != 3 {
+		t.Errorf("unexpected top queries: %+v", report.TopQueries)
+	}
+	if report.TopQueries[0].AvgLatencyMs != 20 || report.TopQueries[0].MaxLatencyMs != 30 {
+		t.Errorf("unexpected latency: %+v", report.TopQueries[0])
+	}
+	if len(report.ZeroResultQueries) != 2 || report.ZeroResultQueries[0].Query != "go plugin" ||
+		report.ZeroResultQueries[1].Query != "missing docs" {
+		t.Errorf("unexpected zero result queries: %+v", report.ZeroResultQueries)
+	}
+	if report.SlowestQueries[0].Query != "slow" {
+		t.Errorf("unexpected slowest queries: %+v", report.SlowestQueries)
+	}
+}
+
+func TestNewReportClicks(t *testing.T) {
+	now := time.Now()
+	records := []Record{
+		{Time: now, Query: "go plugin", Results: 3},
+		{Time: now, Query: "go plugin", Results: 3},
+		{Time: now, Query: "go plugin", ObjectID: "10010000000000001", Position: 1},
+		{Time: now, Query: "go plugin", ObjectID: "10010000000000002", Position: 3},
+		{Time: now, Query: "install", Results: 5},
+		{Time: now, Query: "install", Results: 5},
+		{Time: now, Query: "install", ObjectID: "10010000000000003", Position: 2},
+		{Time: now, Query: "nothing clicked", Results: 4},
+		{Time: now, Query: "missing docs", Results: 0},
+		{Time: now, Query: "searched before", ObjectID: "10010000000000004", Position: 1},
+	}
+	report := NewReport(records, now.Add(-time.Hour), now, 10)
+
+	if report.Searches != 6 || report.Clicks != 4 {
+		t.Errorf("unexpected totals: %+v", report)
+	}
+	if len(report.TopQueries) != 4 {
+		t.Fatalf("clicks without searches should not be counted as queries: %+v", report.TopQueries)
+	}
+	top := report.TopQueries[0]
+	if top.Query != "go plugin" || top.Clicks != 2 || top.ClickThroughRate != 1 || top.AvgClickPosition != 2 {
+		t.Errorf("unexpected click stats: %+v", top)
+	}
+	var queries []string
+	for _, stat := range report.LowClickThroughQueries {
+		queries = append(queries, stat.Query)
+	}
+	if strings.Join(queries, ",") != "nothing clicked,install,go plugin" {
+		t.Errorf("unexpected low click-through queries: %v", queries)
+	}
+}
+
+func TestNormalizeQuery(t *testing.T) {
+	if got := NormalizeQuery([]string{" Go ", "PLUGIN  build"}); got != "go plugin build" {
+		t.Errorf("NormalizeQuery() = %q", got)
+	}
+	if got := NormalizeQuery(nil); got != "" {
+		t.Errorf("NormalizeQuery() = %q", got)
+	}
+}
@@ -0,0 +1,283 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package searchstats
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/incubator-answer-plugins/util"
+	"github.com/apache/incubator-answer/plugin"
+)
+
+// Sink stores the search records.
+type Sink interface {
+	Write(ctx context.Context, records []Record) error
+	// Read returns the records between from and to, oldest first.
+	Read(ctx context.Context, from, to time.Time) ([]Record, error)
+	Close() error
+}
+
+const (
+	fileSinkPrefix     = "search-"
+	fileSinkSuffix     = ".jsonl"
+	fileSinkDateLayout = "20060102"
+)
+
+// FileSink writes the records as JSON lines into one file per day,
+// and removes the files older than the retention when a new file is started.
+type FileSink struct {
+	dir       string
+	retention time.Duration
+	lock      sync.Mutex
+	file      *os.File
+	day       string
+}
+
+func NewFileSink(dir string, retention time.Duration) (*FileSink, error) {
+	if len(dir) == 0 {
+		return nil, fmt.Errorf("path is required")
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	return &FileSink{dir: dir, retention: retention}, nil
+}
+
+func (s *FileSink) Write(_ context.Context, records []Record) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, record := range records {
+		if err := s.rotate(record.Time); err != nil {
+			return err
+		}
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		if _, err = s.file.Write(append(data, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *FileSink) Read(ctx context.Context, from, to time.Time) ([]Record, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, fileSinkPrefix) || !strings.HasSuffix(name, fileSinkSuffix) {
+			continue
+		}
+		day, err := time.ParseInLocation(fileSinkDateLayout,
+			strings.TrimSuffix(strings.TrimPrefix(name, fileSinkPrefix), fileSinkSuffix), time.Local)
+		if err != nil || day.After(to) || day.AddDate(0, 0, 1).Before(from) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	records := make([]Record, 0)
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		fileRecords, err := s.readFile(filepath.Join(s.dir, name), from, to)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, fileRecords...)
+	}
+	return records, nil
+}
+
+func (s *FileSink) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file, s.day = nil, ""
+	return err
+}
+
+func (s *FileSink) rotate(t time.Time) error {
+	day := t.In(time.Local).Format(fileSinkDateLayout)
+	if s.file != nil && s.day == day {
+		return nil
+	}
+	if s.file != nil {
+		_ = s.file.Close()
+	}
+	file, err := os.OpenFile(filepath.Join(s.dir, fileSinkPrefix+day+fileSinkSuffix),
+		os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		s.file, s.day = nil, ""
+		return err
+	}
+	s.file, s.day = file, day
+	s.removeExpired(t)
+	return nil
+}
+
+func (s *FileSink) removeExpired(now time.Time) {
+	expired := now.Add(-s.retention).In(time.Local).Format(fileSinkDateLayout)
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, fileSinkPrefix) || !strings.HasSuffix(name, fileSinkSuffix) {
+			continue
+		}
+		// the names sort by date, so they can be compared as strings
+		if strings.TrimSuffix(strings.TrimPrefix(name, fileSinkPrefix), fileSinkSuffix) < expired {
+			_ = os.Remove(filepath.Join(s.dir, name))
+		}
+	}
+}
+
+func (s *FileSink) readFile(path string, from, to time.Time) ([]Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record Record
+		// skip a line that was partially written
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		if record.Time.Before(from) || record.Time.After(to) {
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// maxCacheRecordsPerHour bounds the size of one cache value.
+const maxCacheRecordsPerHour = 5000
+
+// CacheSink keeps the records in the enabled cache plugin, as one JSON list per hour.
+// Appending is a read-modify-write, so records can be lost when several Answer instances
+// write the same hour at the same time, and records beyond maxCacheRecordsPerHour are dropped.
+type CacheSink struct {
+	prefix    string
+	retention time.Duration
+	lock      sync.Mutex
+}
+
+func NewCacheSink(slugName string, retention time.Duration) *CacheSink {
+	return &CacheSink{prefix: "answer:plugin:" + slugName + ":search_stats:", retention: retention}
+}
+
+func (s *CacheSink) Write(ctx context.Context, records []Record) error {
+	cache, err := util.GetCache()
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	byHour := make(map[string][]Record)
+	for _, record := range records {
+		key := s.key(record.Time)
+		byHour[key] = append(byHour[key], record)
+	}
+	for key, hourRecords := range byHour {
+		stored, err := s.get(ctx, cache, key)
+		if err != nil {
+			return err
+		}
+		stored = append(stored, hourRecords...)
+		if len(stored) > maxCacheRecordsPerHour {
+			stored = stored[:maxCacheRecordsPerHour]
+		}
+		data, err := json.Marshal(stored)
+		if err != nil {
+			return err
+		}
+		if err = cache.SetString(ctx, key, string(data), s.retention); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *CacheSink) Read(ctx context.Context, from, to time.Time) ([]Record, error) {
+	cache, err := util.GetCache()
+	if err != nil {
+		return nil, err
+	}
+	records := make([]Record, 0)
+	for hour := from.Truncate(time.Hour); !hour.After(to); hour = hour.Add(time.Hour) {
+		stored, err := s.get(ctx, cache, s.key(hour))
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range stored {
+			if !record.Time.Before(from) && !record.Time.After(to) {
+				records = append(records, record)
+			}
+		}
+	}
+	return records, nil
+}
+
+func (s *CacheSink) Close() error {
+	return nil
+}
+
+func (s *CacheSink) key(t time.Time) string {
+	return s.prefix + t.UTC().Format("2006010215")
+}
+
+func (s *CacheSink) get(ctx context.Context, cache plugin.Cache, key string) ([]Record, error) {
+	data, exist, err := cache.GetString(ctx, key)
+	if err != nil || !exist {
+		return nil, err
+	}
+	var records []Record
+	if err = json.Unmarshal([]byte(data), &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
@@ -0,0 +1,72 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package searchstats
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileSink(t *testing.T) {
+	dir := t.TempDir()
+	sink, err := NewFileSink(dir, 48*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	// an expired file is removed when a new file is started
+	expired := filepath.Join(dir, fileSinkPrefix+time.Now().AddDate(0, 0, -5).Format(fileSinkDateLayout)+fileSinkSuffix)
+	if err := os.WriteFile(expired, []byte("{}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	yesterday := now.Add(-24 * time.Hour)
+	err = sink.Write(context.Background(), []Record{
+		{Time: yesterday, Query: "old", Results: 1},
+		{Time: now, Query: "new", Results: 0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(expired); !os.IsNotExist(err) {
+		t.Errorf("expired file is not removed")
+	}
+
+	records, err := sink.Read(context.Background(), now.Add(-time.Hour), now.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Query != "new" {
+		t.Errorf("unexpected records: %+v", records)
+	}
+
+	records, err = sink.Read(context.Background(), yesterday.Add(-time.Minute), now.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Query != "old" {
+		t.Errorf("unexpected records: %+v", records)
+	}
+}
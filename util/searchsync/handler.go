@@ -0,0 +1,66 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package searchsync
+
+import (
+	"errors"
+
+	"github.com/apache/incubator-answer-plugins/util/searchext"
+	"github.com/apache/incubator-answer/plugin"
+	"github.com/gin-gonic/gin"
+)
+
+// StartHandler starts a reconciliation and responds the status of the reconciler.
+func StartHandler(slugName string, reconciler *Reconciler) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !plugin.StatusManager.IsEnabled(slugName) {
+			searchext.HandleNotFound(ctx)
+			return
+		}
+		err := reconciler.Start()
+		if errors.Is(err, ErrRunning) || errors.Is(err, ErrNoSyncer) {
+			searchext.HandleBadRequest(ctx, err.Error())
+			return
+		}
+		searchext.HandleResponse(ctx, err, reconciler.Status())
+	}
+}
+
+// StatusHandler responds the status of the reconciler, with the report of the last reconciliation.
+func StatusHandler(slugName string, reconciler *Reconciler) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !plugin.StatusManager.IsEnabled(slugName) {
+			searchext.HandleNotFound(ctx)
+			return
+		}
+		searchext.HandleResponse(ctx, nil, reconciler.Status())
+	}
+}
+
+// OutboxHandler responds the status of the outbox, with its depth and the age of its oldest mutation.
+func OutboxHandler(slugName string, outbox *Outbox) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !plugin.StatusManager.IsEnabled(slugName) {
+			searchext.HandleNotFound(ctx)
+			return
+		}
+		searchext.HandleResponse(ctx, nil, outbox.Status())
+	}
+}
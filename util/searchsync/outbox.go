@@ -0,0 +1,346 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package searchsync
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/apache/incubator-answer/plugin"
+	"github.com/segmentfault/pacman/log"
+)
+
+const (
+	OpUpdate = "update"
+	OpDelete = "delete"
+
+	StoreFile  = "file"
+	StoreCache = "cache"
+
+	// DefaultOutboxPath is the directory of the StoreFile files when no path is configured.
+	DefaultOutboxPath = "/data/search_outbox"
+	// MaxOutboxSize is the max number of queued mutations, the errors of further mutations are returned.
+	MaxOutboxSize = 100000
+
+	outboxPollInterval = 5 * time.Second
+	outboxMinBackoff   = 5 * time.Second
+	outboxMaxBackoff   = 30 * time.Minute
+	outboxLockStripes  = 64
+)
+
+// Mutation is a failed change of the index, queued to be retried.
+type Mutation struct {
+	// Seq orders the mutations, a newer mutation of the same object replaces the queued one.
+	Seq           int64                 `json:"seq"`
+	Op            string                `json:"op"`
+	ObjectID      string                `json:"object_id"`
+	Content       *plugin.SearchContent `json:"content,omitempty"`
+	Attempts      int                   `json:"attempts"`
+	QueuedAt      time.Time             `json:"queued_at"`
+	NextAttemptAt time.Time             `json:"next_attempt_at"`
+	LastError     string                `json:"last_error"`
+}
+
+// OutboxConfig is the outbox part of a search plugin config.
+type OutboxConfig struct {
+	Enabled bool
+	// Store is StoreFile or StoreCache.
+	Store string
+	// Path is the directory of the StoreFile files, DefaultOutboxPath if empty.
+	Path string
+	// SlugName names the StoreFile file and namespaces the StoreCache key.
+	SlugName string
+}
+
+// OutboxStatus is the state of an outbox.
+type OutboxStatus struct {
+	Enabled bool `json:"enabled"`
+	Depth   int  `json:"depth"`
+	// OldestAge is the age in seconds of the oldest queued mutation.
+	OldestAge     int64      `json:"oldest_age"`
+	OldestAt      *time.Time `json:"oldest_at"`
+	NextAttemptAt *time.Time `json:"next_attempt_at"`
+	LastError     string     `json:"last_error"`
+}
+
+// Outbox applies the changes of the index, and when enabled, queues the failed ones in a durable store
+// and retries them in the background with exponential backoff. Only the newest change of an object is
+// kept, and the changes of an object are never applied at the same time, so they are applied in order.
+type Outbox struct {
+	name      string
+	update    func(ctx context.Context, content *plugin.SearchContent) error
+	delete    func(ctx context.Context, objectID string) error
+	lock      sync.Mutex
+	enabled   bool
+	store     OutboxStore
+	seq       int64
+	mutations map[string]*Mutation
+	objects   [outboxLockStripes]sync.Mutex
+}
+
+// NewOutbox returns an outbox applying the changes with update and delete, name prefixes its logs.
+func NewOutbox(name string,
+	update func(ctx context.Context, content *plugin.SearchContent) error,
+	delete func(ctx context.Context, objectID string) error) *Outbox {
+	o := &Outbox{name: name, update: update, delete: delete, mutations: make(map[string]*Mutation)}
+	go o.run()
+	return o
+}
+
+// Configure applies the config and loads the queued mutations from the store.
+func (o *Outbox) Configure(conf OutboxConfig) error {
+	var store OutboxStore
+	if conf.Enabled {
+		switch conf.Store {
+		case StoreCache:
+			store = NewCacheStore(conf.SlugName)
+		case StoreFile, "":
+			path := conf.Path
+			if len(path) == 0 {
+				path = DefaultOutboxPath
+			}
+			fileStore, err := NewFileStore(path, conf.SlugName)
+			if err != nil {
+				return fmt.Errorf("init search outbox file store error: %w", err)
+			}
+			store = fileStore
+		default:
+			return fmt.Errorf("unknown search outbox store: %s", conf.Store)
+		}
+	}
+
+	mutations := make(map[string]*Mutation)
+	var seq int64
+	if store != nil {
+		stored, err := store.Load(context.Background())
+		if err != nil {
+			log.Errorf("%s: load search outbox error: %v", o.name, err)
+		}
+		for _, m := range stored {
+			mutations[m.ObjectID] = m
+			if m.Seq > seq {
+				seq = m.Seq
+			}
+		}
+	}
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.enabled = conf.Enabled
+	o.store = store
+	o.seq = seq
+	o.mutations = mutations
+	return nil
+}
+
+// Update indexes the content. When it fails and the outbox is enabled, the change is queued and nil is returned.
+func (o *Outbox) Update(ctx context.Context, content *plugin.SearchContent) error {
+	return o.do(ctx, &Mutation{Op: OpUpdate, ObjectID: content.ObjectID, Content: content})
+}
+
+// Delete deletes the object. When it fails and the outbox is enabled, the change is queued and nil is returned.
+func (o *Outbox) Delete(ctx context.Context, objectID string) error {
+	return o.do(ctx, &Mutation{Op: OpDelete, ObjectID: objectID})
+}
+
+// Status returns the state of the outbox.
+func (o *Outbox) Status() *OutboxStatus {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	status := &OutboxStatus{Enabled: o.enabled, Depth: len(o.mutations)}
+	var oldest *Mutation
+	for _, m := range o.mutations {
+		if oldest == nil || m.Seq < oldest.Seq {
+			oldest = m
+		}
+		if status.NextAttemptAt == nil || m.NextAttemptAt.Before(*status.NextAttemptAt) {
+			next := m.NextAttemptAt
+			status.NextAttemptAt = &next
+		}
+	}
+	if oldest != nil {
+		queuedAt := oldest.QueuedAt
+		status.OldestAt = &queuedAt
+		status.OldestAge = int64(time.Since(queuedAt).Seconds())
+		status.LastError = oldest.LastError
+	}
+	return status
+}
+
+func (o *Outbox) do(ctx context.Context, m *Mutation) error {
+	unlock := o.lockObject(m.ObjectID)
+	defer unlock()
+
+	err := o.apply(ctx, m)
+	o.lock.Lock()
+	if !o.enabled {
+		o.lock.Unlock()
+		return err
+	}
+	store := o.store
+	queued, ok := o.mutations[m.ObjectID]
+	if err == nil {
+		// the queued change, if any, is older
+		if ok {
+			delete(o.mutations, m.ObjectID)
+		}
+		o.lock.Unlock()
+		if ok {
+			_ = o.append(store, &OutboxEntry{Remove: m.ObjectID})
+		}
+		return nil
+	}
+
+	if !ok && len(o.mutations) >= MaxOutboxSize {
+		o.lock.Unlock()
+		log.Errorf("%s: search outbox is full, drop %s of %s", o.name, m.Op, m.ObjectID)
+		return err
+	}
+	o.seq++
+	m.Seq = o.seq
+	m.QueuedAt = time.Now()
+	if ok {
+		m.QueuedAt = queued.QueuedAt
+	}
+	m.Attempts = 1
+	m.LastError = err.Error()
+	m.NextAttemptAt = time.Now().Add(backoff(m.Attempts))
+	o.mutations[m.ObjectID] = m
+	o.lock.Unlock()
+
+	if appendErr := o.append(store, &OutboxEntry{Put: m}); appendErr != nil {
+		o.lock.Lock()
+		if o.mutations[m.ObjectID] == m {
+			if ok {
+				o.mutations[m.ObjectID] = queued
+			} else {
+				delete(o.mutations, m.ObjectID)
+			}
+		}
+		o.lock.Unlock()
+		return err
+	}
+	log.Warnf("%s: queue %s of %s after error: %v", o.name, m.Op, m.ObjectID, err)
+	return nil
+}
+
+func (o *Outbox) apply(ctx context.Context, m *Mutation) error {
+	if m.Op == OpDelete {
+		return o.delete(ctx, m.ObjectID)
+	}
+	return o.update(ctx, m.Content)
+}
+
+// append records the change of the queue in the store. It's called without the lock,
+// holding the lock of the object, so the changes of an object are appended in order.
+func (o *Outbox) append(store OutboxStore, entry *OutboxEntry) error {
+	if store == nil {
+		return nil
+	}
+	if err := store.Append(context.Background(), entry); err != nil {
+		log.Errorf("%s: save search outbox error: %v", o.name, err)
+		return err
+	}
+	return nil
+}
+
+// lockObject locks the changes of the object, and returns the unlock function.
+func (o *Outbox) lockObject(objectID string) func() {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(objectID))
+	l := &o.objects[h.Sum32()%outboxLockStripes]
+	l.Lock()
+	return l.Unlock
+}
+
+func (o *Outbox) run() {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		o.retry(context.Background(), time.Now())
+	}
+}
+
+// retry applies the due mutations in order, and stops at the first failure as the engine is likely down.
+func (o *Outbox) retry(ctx context.Context, now time.Time) {
+	o.lock.Lock()
+	due := make([]*Mutation, 0)
+	for _, m := range o.mutations {
+		if !m.NextAttemptAt.After(now) {
+			due = append(due, m)
+		}
+	}
+	o.lock.Unlock()
+	sort.Slice(due, func(i, j int) bool { return due[i].Seq < due[j].Seq })
+
+	for _, m := range due {
+		if !o.retryOne(ctx, m) {
+			return
+		}
+	}
+}
+
+func (o *Outbox) retryOne(ctx context.Context, m *Mutation) bool {
+	unlock := o.lockObject(m.ObjectID)
+	defer unlock()
+
+	o.lock.Lock()
+	queued := o.mutations[m.ObjectID]
+	o.lock.Unlock()
+	if queued == nil || queued.Seq != m.Seq {
+		// applied or replaced meanwhile
+		return true
+	}
+
+	err := o.apply(ctx, m)
+	o.lock.Lock()
+	store := o.store
+	if err == nil {
+		delete(o.mutations, m.ObjectID)
+		o.lock.Unlock()
+		_ = o.append(store, &OutboxEntry{Remove: m.ObjectID})
+		log.Infof("%s: applied queued %s of %s after %d attempts", o.name, m.Op, m.ObjectID, m.Attempts)
+		return true
+	}
+	retried := *m
+	retried.Attempts++
+	retried.LastError = err.Error()
+	retried.NextAttemptAt = time.Now().Add(backoff(retried.Attempts))
+	o.mutations[m.ObjectID] = &retried
+	o.lock.Unlock()
+	_ = o.append(store, &OutboxEntry{Put: &retried})
+	return false
+}
+
+// backoff is the delay before the next attempt, doubling from outboxMinBackoff up to outboxMaxBackoff.
+func backoff(attempts int) time.Duration {
+	d := outboxMinBackoff
+	for i := 1; i < attempts && d < outboxMaxBackoff; i++ {
+		d *= 2
+	}
+	if d > outboxMaxBackoff {
+		d = outboxMaxBackoff
+	}
+	return d
+}
@@ -0,0 +1,352 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package searchsync
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/apache/incubator-answer-plugins/util"
+	"github.com/segmentfault/pacman/log"
+)
+
+const (
+	// outboxCacheTTL bounds how long queued mutations are kept in the cache,
+	// the reconciliation repairs whatever is left after that.
+	outboxCacheTTL = 30 * 24 * time.Hour
+	// outboxCompactMin is the number of stale log entries tolerated before the log is compacted,
+	// which happens once the stale entries also outnumber the queued ones.
+	outboxCompactMin = 1024
+)
+
+// OutboxEntry is a change of the queue, the mutation queued for an object or the removal of the object.
+type OutboxEntry struct {
+	Put    *Mutation `json:"put,omitempty"`
+	Remove string    `json:"remove,omitempty"`
+}
+
+func (e *OutboxEntry) objectID() string {
+	if e.Put != nil {
+		return e.Put.ObjectID
+	}
+	return e.Remove
+}
+
+// OutboxStore keeps the queued mutations as a log of the changes of the queue,
+// so that recording a change doesn't cost more with a longer queue.
+type OutboxStore interface {
+	// Load returns the queued mutations, ordered by Seq.
+	Load(ctx context.Context) ([]*Mutation, error)
+	// Append records the change of the queue. The changes of an object are appended in order.
+	Append(ctx context.Context, entry *OutboxEntry) error
+}
+
+// replay applies the log entries to the queued mutations of objects.
+func replay(queued map[string]*Mutation, entry *OutboxEntry) {
+	if entry.Put != nil {
+		queued[entry.Put.ObjectID] = entry.Put
+	} else {
+		delete(queued, entry.Remove)
+	}
+}
+
+func sortedMutations(queued map[string]*Mutation) []*Mutation {
+	mutations := make([]*Mutation, 0, len(queued))
+	for _, m := range queued {
+		mutations = append(mutations, m)
+	}
+	sort.Slice(mutations, func(i, j int) bool { return mutations[i].Seq < mutations[j].Seq })
+	return mutations
+}
+
+// FileStore keeps the log in a JSON lines file, synced after each entry.
+// The file is rewritten with the queued mutations only when most of its entries are stale.
+type FileStore struct {
+	path    string
+	lock    sync.Mutex
+	objects map[string]bool
+	entries int
+}
+
+func NewFileStore(dir, slugName string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileStore{path: filepath.Join(dir, slugName+"-outbox.jsonl"), objects: make(map[string]bool)}, nil
+}
+
+func (s *FileStore) Load(_ context.Context) ([]*Mutation, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	queued, entries, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	s.objects = make(map[string]bool, len(queued))
+	for objectID := range queued {
+		s.objects[objectID] = true
+	}
+	s.entries = entries
+	return sortedMutations(queued), nil
+}
+
+func (s *FileStore) Append(_ context.Context, entry *OutboxEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err = s.write(os.O_CREATE|os.O_WRONLY|os.O_APPEND, s.path, [][]byte{data}); err != nil {
+		return err
+	}
+	if entry.Put != nil {
+		s.objects[entry.Put.ObjectID] = true
+	} else {
+		delete(s.objects, entry.Remove)
+	}
+	s.entries++
+	if stale := s.entries - len(s.objects); stale > outboxCompactMin && stale > len(s.objects) {
+		if err = s.compact(); err != nil {
+			// the log is still whole, only longer
+			log.Errorf("compact search outbox %s error: %v", s.path, err)
+		}
+	}
+	return nil
+}
+
+// read replays the log, a partly written last entry is ignored.
+func (s *FileStore) read() (queued map[string]*Mutation, entries int, err error) {
+	queued = make(map[string]*Mutation)
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return queued, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for line := 1; scanner.Scan(); line++ {
+		entry := &OutboxEntry{}
+		if err = json.Unmarshal(scanner.Bytes(), entry); err != nil || len(entry.objectID()) == 0 {
+			if !bytes.HasSuffix(data, []byte("\n")) && bytes.HasSuffix(data, scanner.Bytes()) {
+				log.Warnf("ignore the partly written last entry of %s", s.path)
+				break
+			}
+			return nil, 0, fmt.Errorf("parse %s line %d error: %v", s.path, line, err)
+		}
+		replay(queued, entry)
+		entries++
+	}
+	return queued, entries, scanner.Err()
+}
+
+// compact replaces the log with the queued mutations, it's called with the lock held.
+func (s *FileStore) compact() error {
+	queued, _, err := s.read()
+	if err != nil {
+		return err
+	}
+	lines := make([][]byte, 0, len(queued))
+	for _, m := range sortedMutations(queued) {
+		data, err := json.Marshal(&OutboxEntry{Put: m})
+		if err != nil {
+			return err
+		}
+		lines = append(lines, data)
+	}
+	tmp := s.path + ".tmp"
+	if err = s.write(os.O_CREATE|os.O_WRONLY|os.O_TRUNC, tmp, lines); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+	s.entries = len(lines)
+	return nil
+}
+
+// write writes the lines to the file opened with flag, and syncs it.
+func (s *FileStore) write(flag int, path string, lines [][]byte) error {
+	f, err := os.OpenFile(path, flag, 0o644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, line := range lines {
+		_, _ = w.Write(line)
+		_ = w.WriteByte('\n')
+	}
+	if err = w.Flush(); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// CacheStore keeps the log in the enabled cache plugin, one key per entry numbered by a counter,
+// between the head and the tail counters. Entries are numbered by the cache, but compacted per process,
+// so the Answer instances sharing a cache should not share a slug name with the outbox enabled.
+type CacheStore struct {
+	prefix  string
+	lock    sync.Mutex
+	objects map[string]bool
+}
+
+func NewCacheStore(slugName string) *CacheStore {
+	return &CacheStore{prefix: "answer:plugin:" + slugName + ":search_outbox:", objects: make(map[string]bool)}
+}
+
+func (s *CacheStore) Load(ctx context.Context) ([]*Mutation, error) {
+	cache, err := util.GetCache()
+	if err != nil {
+		return nil, err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	queued, _, _, err := s.read(ctx, cache)
+	if err != nil {
+		return nil, err
+	}
+	s.objects = make(map[string]bool, len(queued))
+	for objectID := range queued {
+		s.objects[objectID] = true
+	}
+	return sortedMutations(queued), nil
+}
+
+func (s *CacheStore) Append(ctx context.Context, entry *OutboxEntry) error {
+	cache, err := util.GetCache()
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err = s.append(ctx, cache, entry); err != nil {
+		return err
+	}
+	if entry.Put != nil {
+		s.objects[entry.Put.ObjectID] = true
+	} else {
+		delete(s.objects, entry.Remove)
+	}
+
+	head, tail, err := s.bounds(ctx, cache)
+	if err != nil {
+		return nil
+	}
+	if stale := int(tail-head) - len(s.objects); stale > outboxCompactMin && stale > len(s.objects) {
+		if err = s.compact(ctx, cache); err != nil {
+			log.Errorf("compact search outbox %s error: %v", s.prefix, err)
+		}
+	}
+	return nil
+}
+
+// bounds returns the head and the tail counters, the entries after the head up to the tail are the log.
+func (s *CacheStore) bounds(ctx context.Context, cache cacheLog) (head, tail int64, err error) {
+	if head, _, err = cache.GetInt64(ctx, s.prefix+"head"); err != nil {
+		return 0, 0, err
+	}
+	tail, _, err = cache.GetInt64(ctx, s.prefix+"tail")
+	return head, tail, err
+}
+
+func (s *CacheStore) append(ctx context.Context, cache cacheLog, entry *OutboxEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	n, err := cache.Increase(ctx, s.prefix+"tail", 1)
+	if err != nil {
+		return err
+	}
+	return cache.SetString(ctx, s.entryKey(n), string(data), outboxCacheTTL)
+}
+
+// read replays the entries after the head, expired entries are skipped.
+func (s *CacheStore) read(ctx context.Context, cache cacheLog) (queued map[string]*Mutation, head, tail int64, err error) {
+	queued = make(map[string]*Mutation)
+	if head, tail, err = s.bounds(ctx, cache); err != nil {
+		return nil, 0, 0, err
+	}
+	for n := head + 1; n <= tail; n++ {
+		data, exist, err := cache.GetString(ctx, s.entryKey(n))
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		if !exist {
+			continue
+		}
+		entry := &OutboxEntry{}
+		if err = json.Unmarshal([]byte(data), entry); err != nil {
+			return nil, 0, 0, fmt.Errorf("parse search outbox entry %d error: %w", n, err)
+		}
+		replay(queued, entry)
+	}
+	return queued, head, tail, nil
+}
+
+// compact appends the queued mutations again and moves the head past the older entries,
+// it's called with the lock held.
+func (s *CacheStore) compact(ctx context.Context, cache cacheLog) error {
+	queued, head, tail, err := s.read(ctx, cache)
+	if err != nil {
+		return err
+	}
+	for _, m := range sortedMutations(queued) {
+		if err = s.append(ctx, cache, &OutboxEntry{Put: m}); err != nil {
+			return err
+		}
+	}
+	if err = cache.SetInt64(ctx, s.prefix+"head", tail, outboxCacheTTL); err != nil {
+		return err
+	}
+	for n := head + 1; n <= tail; n++ {
+		_ = cache.Del(ctx, s.entryKey(n))
+	}
+	return nil
+}
+
+func (s *CacheStore) entryKey(n int64) string {
+	return s.prefix + "entry:" + strconv.FormatInt(n, 10)
+}
+
+// cacheLog is the part of the cache used by the CacheStore.
+type cacheLog interface {
+	GetString(ctx context.Context, key string) (string, bool, error)
+	SetString(ctx context.Context, key, value string, ttl time.Duration) error
+	GetInt64(ctx context.Context, key string) (int64, bool, error)
+	SetInt64(ctx context.Context, key string, value int64, ttl time.Duration) error
+	Increase(ctx context.Context, key string, value int64) (int64, error)
+	Del(ctx context.Context, key string) error
+}
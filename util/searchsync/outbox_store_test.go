@@ -0,0 +1,172 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package searchsync
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileStoreCompact(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewFileStore(dir, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seq int64
+	put := func(objectID string) {
+		seq++
+		if err := store.Append(ctx, &OutboxEntry{Put: &Mutation{Seq: seq, Op: OpUpdate, ObjectID: objectID}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	put("kept")
+	for i := 0; i < 3*outboxCompactMin; i++ {
+		put("retried")
+	}
+	if err := store.Append(ctx, &OutboxEntry{Remove: "retried"}); err != nil {
+		t.Fatal(err)
+	}
+	put("last")
+
+	if store.entries > outboxCompactMin+2 {
+		t.Errorf("log is not compacted, %d entries", store.entries)
+	}
+	mutations, err := store.Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mutations) != 2 || mutations[0].ObjectID != "kept" || mutations[1].ObjectID != "last" {
+		t.Errorf("unexpected mutations after compaction: %+v", mutations)
+	}
+}
+
+func TestFileStorePartlyWrittenEntry(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileStore(t.TempDir(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Append(ctx, &OutboxEntry{Put: &Mutation{Seq: 1, Op: OpDelete, ObjectID: "1"}}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(store.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(`{"put":{"seq":2,"op":"del`)
+	_ = f.Close()
+
+	mutations, err := store.Load(ctx)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(mutations) != 1 || mutations[0].ObjectID != "1" {
+		t.Errorf("unexpected mutations: %+v", mutations)
+	}
+
+	// a broken entry in the middle is an error
+	data, _ := os.ReadFile(store.path)
+	_ = os.WriteFile(store.path, []byte("broken\n"+string(data)+"\n"), 0o644)
+	if _, err = store.Load(ctx); err == nil {
+		t.Errorf("Load() error is not returned for a broken entry")
+	}
+}
+
+type memCache struct {
+	strings map[string]string
+	ints    map[string]int64
+}
+
+func newMemCache() *memCache {
+	return &memCache{strings: make(map[string]string), ints: make(map[string]int64)}
+}
+
+func (c *memCache) GetString(_ context.Context, key string) (string, bool, error) {
+	v, ok := c.strings[key]
+	return v, ok, nil
+}
+
+func (c *memCache) SetString(_ context.Context, key, value string, _ time.Duration) error {
+	c.strings[key] = value
+	return nil
+}
+
+func (c *memCache) GetInt64(_ context.Context, key string) (int64, bool, error) {
+	v, ok := c.ints[key]
+	return v, ok, nil
+}
+
+func (c *memCache) SetInt64(_ context.Context, key string, value int64, _ time.Duration) error {
+	c.ints[key] = value
+	return nil
+}
+
+func (c *memCache) Increase(_ context.Context, key string, value int64) (int64, error) {
+	c.ints[key] += value
+	return c.ints[key], nil
+}
+
+func (c *memCache) Del(_ context.Context, key string) error {
+	delete(c.strings, key)
+	delete(c.ints, key)
+	return nil
+}
+
+func TestCacheStoreLog(t *testing.T) {
+	ctx := context.Background()
+	cache := newMemCache()
+	store := NewCacheStore("test")
+	for i := 1; i <= 10; i++ {
+		m := &Mutation{Seq: int64(i), Op: OpUpdate, ObjectID: strconv.Itoa(i % 3)}
+		if err := store.append(ctx, cache, &OutboxEntry{Put: m}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.append(ctx, cache, &OutboxEntry{Remove: "0"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.compact(ctx, cache); err != nil {
+		t.Fatal(err)
+	}
+	entries := 0
+	for key := range cache.strings {
+		if strings.Contains(key, ":entry:") {
+			entries++
+		}
+	}
+	if entries != 2 {
+		t.Errorf("%d entries after compaction, want 2", entries)
+	}
+	queued, _, _, err := store.read(ctx, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mutations := sortedMutations(queued)
+	if len(mutations) != 2 || mutations[0].Seq != 8 || mutations[1].Seq != 10 {
+		t.Errorf("unexpected mutations: %+v", mutations)
+	}
+}
@@ -0,0 +1,155 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package searchsync
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/apache/incubator-answer/plugin"
+)
+
+type fakeEngine struct {
+	down    bool
+	applied []string
+}
+
+func (e *fakeEngine) update(_ context.Context, content *plugin.SearchContent) error {
+	if e.down {
+		return errors.New("engine down")
+	}
+	e.applied = append(e.applied, OpUpdate+":"+content.ObjectID)
+	return nil
+}
+
+func (e *fakeEngine) delete(_ context.Context, objectID string) error {
+	if e.down {
+		return errors.New("engine down")
+	}
+	e.applied = append(e.applied, OpDelete+":"+objectID)
+	return nil
+}
+
+func newTestOutbox(t *testing.T, engine *fakeEngine, conf OutboxConfig) *Outbox {
+	o := &Outbox{name: "test", update: engine.update, delete: engine.delete, mutations: make(map[string]*Mutation)}
+	if err := o.Configure(conf); err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+func TestOutboxDisabled(t *testing.T) {
+	engine := &fakeEngine{down: true}
+	o := newTestOutbox(t, engine, OutboxConfig{})
+	if err := o.Update(context.Background(), &plugin.SearchContent{ObjectID: "1"}); err == nil {
+		t.Errorf("Update() error is not returned when the outbox is disabled")
+	}
+	if depth := o.Status().Depth; depth != 0 {
+		t.Errorf("depth = %d", depth)
+	}
+}
+
+func TestOutbox(t *testing.T) {
+	ctx := context.Background()
+	conf := OutboxConfig{Enabled: true, Store: StoreFile, Path: t.TempDir(), SlugName: "test"}
+	engine := &fakeEngine{down: true}
+	o := newTestOutbox(t, engine, conf)
+
+	if err := o.Update(ctx, &plugin.SearchContent{ObjectID: "1"}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if err := o.Update(ctx, &plugin.SearchContent{ObjectID: "2"}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	// the newer delete replaces the queued update
+	if err := o.Delete(ctx, "1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	status := o.Status()
+	if status.Depth != 2 || status.OldestAt == nil || status.LastError != "engine down" {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	// the queue survives a restart
+	o = newTestOutbox(t, engine, conf)
+	if depth := o.Status().Depth; depth != 2 {
+		t.Fatalf("depth after restart = %d", depth)
+	}
+
+	// still down, the oldest attempt is pushed back and the round stops
+	o.retry(ctx, time.Now().Add(time.Hour))
+	if m := o.mutations["2"]; m.Attempts != 2 || !m.NextAttemptAt.After(time.Now().Add(outboxMinBackoff)) {
+		t.Errorf("unexpected mutation after a failed retry: %+v", m)
+	}
+	if m := o.mutations["1"]; m.Attempts != 1 {
+		t.Errorf("retried after a failure: %+v", m)
+	}
+
+	engine.down = false
+	o.retry(ctx, time.Now().Add(time.Hour))
+	want := []string{"update:2", "delete:1"}
+	if !reflect.DeepEqual(engine.applied, want) {
+		t.Errorf("applied = %v, want %v", engine.applied, want)
+	}
+	if depth := o.Status().Depth; depth != 0 {
+		t.Errorf("depth after retry = %d", depth)
+	}
+	if depth := newTestOutbox(t, engine, conf).Status().Depth; depth != 0 {
+		t.Errorf("stored depth after retry = %d", depth)
+	}
+}
+
+func TestOutboxDirectChangeSupersedesQueued(t *testing.T) {
+	ctx := context.Background()
+	engine := &fakeEngine{down: true}
+	o := newTestOutbox(t, engine, OutboxConfig{Enabled: true, Path: t.TempDir(), SlugName: "test"})
+	if err := o.Update(ctx, &plugin.SearchContent{ObjectID: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	engine.down = false
+	if err := o.Delete(ctx, "1"); err != nil {
+		t.Fatal(err)
+	}
+	o.retry(ctx, time.Now().Add(time.Hour))
+	if want := []string{"delete:1"}; !reflect.DeepEqual(engine.applied, want) {
+		t.Errorf("applied = %v, want %v", engine.applied, want)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{3, 20 * time.Second},
+		{10, 30 * time.Minute},
+		{100, 30 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+// Package searchsync keeps a search index consistent with the contents of Answer.
+package searchsync
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/incubator-answer/plugin"
+	"github.com/segmentfault/pacman/log"
+)
+
+const (
+	// PageSize is the page size of the contents read from the syncer.
+	PageSize = 100
+	// DeleteBatchSize is the max number of orphans deleted at once.
+	DeleteBatchSize = 100
+)
+
+var (
+	ErrRunning  = errors.New("reconciliation is already running")
+	ErrNoSyncer = errors.New("search syncer is not registered yet")
+)
+
+// Doc is a document held by the search engine.
+type Doc struct {
+	ID          string
+	Fingerprint string
+}
+
+// Index is the search engine side of the reconciliation.
+type Index interface {
+	// ScanDocs calls fn with each page of the documents in the index.
+	ScanDocs(ctx context.Context, fn func(docs []Doc) error) error
+	// UpsertContents indexes the contents, with their Fingerprint.
+	UpsertContents(ctx context.Context, contents []*plugin.SearchContent) error
+	// DeleteDocs deletes the documents from the index.
+	DeleteDocs(ctx context.Context, ids []string) error
+}
+
+// Fingerprint is a hash of all the indexed fields of the content, stored with each document,
+// so a stale document is found without reading it back.
+func Fingerprint(content *plugin.SearchContent) string {
+	c := *content
+	c.Tags = append([]string(nil), content.Tags...)
+	sort.Strings(c.Tags)
+	data, _ := json.Marshal(c)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// ParseInterval parses the reconciliation interval config, a duration like 6h or 30m,
+// or a number of hours. An empty or zero config disables the schedule.
+func ParseInterval(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if len(value) == 0 {
+		return 0, nil
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		hours, atoiErr := strconv.Atoi(value)
+		if atoiErr != nil {
+			return 0, fmt.Errorf("invalid reconcile interval %q, expected a duration like 6h or 30m", value)
+		}
+		interval = time.Duration(hours) * time.Hour
+	}
+	if interval < 0 {
+		return 0, fmt.Errorf("invalid reconcile interval %q, it must not be negative", value)
+	}
+	return interval, nil
+}
+
+// Report is the result of a reconciliation.
+type Report struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	// Indexed is the number of documents in the index before the reconciliation.
+	Indexed int `json:"indexed"`
+	// Checked is the number of contents read from Answer.
+	Checked int `json:"checked"`
+	// Missing contents were not in the index, and Stale ones had another fingerprint, both are upserted.
+	Missing int `json:"missing"`
+	Stale   int `json:"stale"`
+	// Orphans were in the index without a content in Answer, and are deleted.
+	Orphans int    `json:"orphans"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Status is the state of a Reconciler.
+type Status struct {
+	Running    bool    `json:"running"`
+	Interval   string  `json:"interval"`
+	LastReport *Report `json:"last_report"`
+}
+
+// Reconciler pages through the contents of Answer, compares them with the documents of the index,
+// upserts the missing and stale ones and deletes the orphans.
+type Reconciler struct {
+	name     string
+	index    Index
+	lock     sync.Mutex
+	syncer   plugin.SearchSyncer
+	running  bool
+	interval time.Duration
+	last     *Report
+	stop     chan struct{}
+}
+
+// NewReconciler returns a reconciler of the index, name prefixes its logs.
+func NewReconciler(name string, index Index) *Reconciler {
+	return &Reconciler{name: name, index: index}
+}
+
+// SetSyncer sets the syncer registered by Answer.
+func (r *Reconciler) SetSyncer(syncer plugin.SearchSyncer) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.syncer = syncer
+}
+
+// Schedule starts a reconciliation every interval, replacing the previous schedule.
+// An interval of zero stops the schedule.
+func (r *Reconciler) Schedule(interval time.Duration) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+	r.interval = interval
+	if interval <= 0 {
+		return
+	}
+	stop := make(chan struct{})
+	r.stop = stop
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := r.Start(); err != nil {
+					log.Warnf("%s: skip scheduled reconciliation: %v", r.name, err)
+				}
+			}
+		}
+	}()
+}
+
+// Start starts a reconciliation in the background.
+func (r *Reconciler) Start() error {
+	syncer, err := r.acquire()
+	if err != nil {
+		return err
+	}
+	go func() {
+		_, _ = r.run(context.Background(), syncer)
+	}()
+	return nil
+}
+
+// Run reconciles the index and waits for the result.
+func (r *Reconciler) Run(ctx context.Context) (*Report, error) {
+	syncer, err := r.acquire()
+	if err != nil {
+		return nil, err
+	}
+	return r.run(ctx, syncer)
+}
+
+// Status returns the state of the reconciler.
+func (r *Reconciler) Status() *Status {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return &Status{Running: r.running, Interval: r.interval.String(), LastReport: r.last}
+}
+
+func (r *Reconciler) acquire() (plugin.SearchSyncer, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.syncer == nil {
+		return nil, ErrNoSyncer
+	}
+	if r.running {
+		return nil, ErrRunning
+	}
+	r.running = true
+	return r.syncer, nil
+}
+
+func (r *Reconciler) run(ctx context.Context, syncer plugin.SearchSyncer) (*Report, error) {
+	report := &Report{StartedAt: time.Now()}
+	log.Infof("%s: start reconciliation", r.name)
+	err := r.reconcile(ctx, syncer, report)
+	report.FinishedAt = time.Now()
+	if err != nil {
+		report.Error = err.Error()
+		log.Errorf("%s: reconciliation error: %v", r.name, err)
+	} else {
+		log.Infof("%s: reconciliation done, checked %d, missing %d, stale %d, orphans %d",
+			r.name, report.Checked, report.Missing, report.Stale, report.Orphans)
+	}
+
+	r.lock.Lock()
+	r.running = false
+	r.last = report
+	r.lock.Unlock()
+	return report, err
+}
+
+func (r *Reconciler) reconcile(ctx context.Context, syncer plugin.SearchSyncer, report *Report) error {
+	indexed := make(map[string]string)
+	err := r.index.ScanDocs(ctx, func(docs []Doc) error {
+		for _, doc := range docs {
+			indexed[doc.ID] = doc.Fingerprint
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	report.Indexed = len(indexed)
+
+	getPages := []func(ctx context.Context, page, pageSize int) ([]*plugin.SearchContent, error){
+		syncer.GetQuestionsPage,
+		syncer.GetAnswersPage,
+	}
+	for _, getPage := range getPages {
+		for page := 1; ; page++ {
+			contents, err := getPage(ctx, page, PageSize)
+			if err != nil {
+				// orphans are only known after reading all contents
+				return err
+			}
+			if len(contents) == 0 {
+				break
+			}
+			var upserts []*plugin.SearchContent
+			for _, content := range contents {
+				report.Checked++
+				fingerprint, ok := indexed[content.ObjectID]
+				delete(indexed, content.ObjectID)
+				switch {
+				case !ok:
+					report.Missing++
+				case fingerprint != Fingerprint(content):
+					report.Stale++
+				default:
+					continue
+				}
+				upserts = append(upserts, content)
+			}
+			if len(upserts) > 0 {
+				if err := r.index.UpsertContents(ctx, upserts); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if report.Checked == 0 && len(indexed) > 0 {
+		// an empty site with a filled index is more likely a broken syncer, keep the documents
+		log.Warnf("%s: no content read, skip deleting %d documents", r.name, len(indexed))
+		return nil
+	}
+	orphans := make([]string, 0, len(indexed))
+	for id := range indexed {
+		orphans = append(orphans, id)
+	}
+	sort.Strings(orphans)
+	for i := 0; i < len(orphans); i += DeleteBatchSize {
+		end := i + DeleteBatchSize
+		if end > len(orphans) {
+			end = len(orphans)
+		}
+		if err := r.index.DeleteDocs(ctx, orphans[i:end]); err != nil {
+			return err
+		}
+		report.Orphans += end - i
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package searchsync
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/apache/incubator-answer/plugin"
+)
+
+type fakeSyncer struct {
+	questions []*plugin.SearchContent
+	answers   []*plugin.SearchContent
+	err       error
+}
+
+func page(contents []*plugin.SearchContent, page, pageSize int) []*plugin.SearchContent {
+	start := (page - 1) * pageSize
+	if start >= len(contents) {
+		return nil
+	}
+	end := start + pageSize
+	if end > len(contents) {
+		end = len(contents)
+	}
+	return contents[start:end]
+}
+
+func (s *fakeSyncer) GetQuestionsPage(_ context.Context, p, pageSize int) ([]*plugin.SearchContent, error) {
+	return page(s.questions, p, pageSize), nil
+}
+
+func (s *fakeSyncer) GetAnswersPage(_ context.Context, p, pageSize int) ([]*plugin.SearchContent, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return page(s.answers, p, pageSize), nil
+}
+
+type fakeIndex struct {
+	docs     map[string]string
+	upserted []string
+	deleted  []string
+}
+
+func (i *fakeIndex) ScanDocs(_ context.Context, fn func(docs []Doc) error) error {
+	var docs []Doc
+	for id, fingerprint := range i.docs {
+		docs = append(docs, Doc{ID: id, Fingerprint: fingerprint})
+	}
+	return fn(docs)
+}
+
+func (i *fakeIndex) UpsertContents(_ context.Context, contents []*plugin.SearchContent) error {
+	for _, content := range contents {
+		i.docs[content.ObjectID] = Fingerprint(content)
+		i.upserted = append(i.upserted, content.ObjectID)
+	}
+	return nil
+}
+
+func (i *fakeIndex) DeleteDocs(_ context.Context, ids []string) error {
+	for _, id := range ids {
+		delete(i.docs, id)
+	}
+	i.deleted = append(i.deleted, ids...)
+	return nil
+}
+
+func TestFingerprint(t *testing.T) {
+	a := &plugin.SearchContent{ObjectID: "1", Title: "title", Tags: []string{"b", "a"}}
+	b := &plugin.SearchContent{ObjectID: "1", Title: "title", Tags: []string{"a", "b"}}
+	if Fingerprint(a) != Fingerprint(b) {
+		t.Errorf("fingerprint depends on the tags order")
+	}
+	if a.Tags[0] != "b" {
+		t.Errorf("fingerprint sorted the tags of the content")
+	}
+	b.Status = plugin.SearchContentStatusDeleted
+	if Fingerprint(a) == Fingerprint(b) {
+		t.Errorf("fingerprint ignores the status")
+	}
+}
+
+func TestParseInterval(t *testing.T) {
+	cases := map[string]time.Duration{
+		"":      0,
+		"0":     0,
+		" 6 ":   6 * time.Hour,
+		"90m":   90 * time.Minute,
+		"1h30m": 90 * time.Minute,
+	}
+	for value, want := range cases {
+		got, err := ParseInterval(value)
+		if err != nil || got != want {
+			t.Errorf("ParseInterval(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"abc", "-1", "-2h", "1.5"} {
+		if _, err := ParseInterval(value); err == nil {
+			t.Errorf("ParseInterval(%q) accepted an invalid interval", value)
+		}
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	question := &plugin.SearchContent{ObjectID: "q1", Type: "question", Status: plugin.SearchContentStatusAvailable}
+	deleted := &plugin.SearchContent{ObjectID: "q2", Type: "question", Status: plugin.SearchContentStatusDeleted}
+	missing := &plugin.SearchContent{ObjectID: "a1", Type: "answer"}
+	indexedDeleted := *deleted
+	indexedDeleted.Status = plugin.SearchContentStatusAvailable
+
+	index := &fakeIndex{docs: map[string]string{
+		"q1":     Fingerprint(question),
+		"q2":     Fingerprint(&indexedDeleted),
+		"orphan": "any",
+	}}
+	r := NewReconciler("test", index)
+	if _, err := r.Run(context.Background()); !errors.Is(err, ErrNoSyncer) {
+		t.Fatalf("Run() without syncer error = %v", err)
+	}
+	r.SetSyncer(&fakeSyncer{
+		questions: []*plugin.SearchContent{question, deleted},
+		answers:   []*plugin.SearchContent{missing},
+	})
+
+	report, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Indexed != 3 || report.Checked != 3 || report.Missing != 1 || report.Stale != 1 || report.Orphans != 1 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	sort.Strings(index.upserted)
+	if !reflect.DeepEqual(index.upserted, []string{"a1", "q2"}) {
+		t.Errorf("upserted = %v", index.upserted)
+	}
+	if !reflect.DeepEqual(index.deleted, []string{"orphan"}) {
+		t.Errorf("deleted = %v", index.deleted)
+	}
+	if status := r.Status(); status.Running || status.LastReport != report {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	// nothing to do the second time
+	index.upserted, index.deleted = nil, nil
+	report, err = r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Missing != 0 || report.Stale != 0 || report.Orphans != 0 || len(index.upserted) != 0 || len(index.deleted) != 0 {
+		t.Errorf("unexpected second report: %+v", report)
+	}
+}
+
+func TestReconcileKeepsDocsOnError(t *testing.T) {
+	index := &fakeIndex{docs: map[string]string{"q1": "any", "a1": "any"}}
+	r := NewReconciler("test", index)
+	r.SetSyncer(&fakeSyncer{
+		questions: []*plugin.SearchContent{{ObjectID: "q1"}},
+		err:       errors.New("db down"),
+	})
+	report, err := r.Run(context.Background())
+	if err == nil || report.Error != "db down" {
+		t.Fatalf("Run() error = %v, report %+v", err, report)
+	}
+	if len(index.deleted) != 0 {
+		t.Errorf("deleted %v after a syncer error", index.deleted)
+	}
+}
+
+func TestReconcileKeepsDocsWithoutContents(t *testing.T) {
+	index := &fakeIndex{docs: map[string]string{"q1": "any"}}
+	r := NewReconciler("test", index)
+	r.SetSyncer(&fakeSyncer{})
+	if _, err := r.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(index.deleted) != 0 {
+		t.Errorf("deleted %v without any content", index.deleted)
+	}
+}
@@ -0,0 +1,109 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+// Package storageext holds the upload pipeline, checks and metadata shared by the storage plugins,
+// which only bring their bucket.
+package storageext
+
+import (
+	"io"
+	"mime"
+	"path/filepath"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// SniffLen is the number of bytes read to detect the content type, as many as mimetype reads.
+const SniffLen = 3072
+
+// DetectContentType detects the content type of a file from its first bytes.
+// The type of the extension is used when the bytes say nothing more than plain text or binary data.
+func DetectContentType(filename string, head []byte) string {
+	sniffed := mimetype.Detect(head).String()
+	mediaType, _, _ := mime.ParseMediaType(sniffed)
+	if mediaType != "text/plain" && mediaType != "application/octet-stream" {
+		return sniffed
+	}
+	if byExt := mime.TypeByExtension(strings.ToLower(filepath.Ext(filename))); len(byExt) > 0 {
+		return byExt
+	}
+	return sniffed
+}
+
+// TypeByExtension returns the content type of the extension of the file, for the files not read yet.
+func TypeByExtension(filename string) string {
+	if byExt := mime.TypeByExtension(strings.ToLower(filepath.Ext(filename))); len(byExt) > 0 {
+		return byExt
+	}
+	return "application/octet-stream"
+}
+
+// MediaType returns the content type without its parameters, like the charset.
+func MediaType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(contentType))
+	}
+	return mediaType
+}
+
+// SniffContentType detects the content type of the file and rewinds it.
+func SniffContentType(filename string, file io.ReadSeeker) (string, error) {
+	head := make([]byte, SniffLen)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return DetectContentType(filename, head[:n]), nil
+}
+
+// Inline reports whether browsers can show the content type safely in the page.
+// SVG and HTML can run scripts, so they are downloaded like the other documents.
+func Inline(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "image/svg+xml":
+		return false
+	case strings.HasPrefix(mediaType, "image/"),
+		strings.HasPrefix(mediaType, "video/"),
+		strings.HasPrefix(mediaType, "audio/"):
+		return true
+	case mediaType == "application/pdf", mediaType == "text/plain":
+		return true
+	default:
+		return false
+	}
+}
+
+// ContentDisposition returns the Content-Disposition of the file, empty for the inline types.
+func ContentDisposition(contentType, filename string) string {
+	if Inline(contentType) {
+		return ""
+	}
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": filepath.Base(filename)})
+	if len(disposition) == 0 {
+		// the filename can't be encoded, the browser picks one from the URL
+		return "attachment"
+	}
+	return disposition
+}
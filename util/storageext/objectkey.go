@@ -0,0 +1,206 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package storageext
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/apache/incubator-answer/plugin"
+)
+
+// The key schemes: random keys are made of the upload time and random bytes,
+// content keys of the SHA-256 of the file, so that the same file is stored once.
+const (
+	KeySchemeRandom  = "random"
+	KeySchemeContent = "content"
+)
+
+// The default key templates of the schemes. The content keys are sharded by the first bytes of the hash,
+// so that no directory holds all the files.
+const (
+	DefaultKeyTemplate        = "{source}/{hash}{ext}"
+	DefaultContentKeyTemplate = ShardedKeyTemplate
+	ShardedKeyTemplate        = "{source}/{shard}/{hash}{ext}"
+)
+
+var keyPlaceholder = regexp.MustCompile(`\{[a-z]*\}`)
+
+// keyPatterns are the patterns of the placeholders of a key template, to check the keys made from it.
+var keyPatterns = map[string]string{
+	"{source}": `[a-z]+`,
+	"{yyyy}":   `[0-9]{4}`,
+	"{mm}":     `[0-9]{2}`,
+	"{dd}":     `[0-9]{2}`,
+	"{shard}":  `[0-9a-f]{2}/[0-9a-f]{2}`,
+	"{hash}":   `[0-9a-f]+`,
+	"{ext}":    `(\.[^/]*)?`,
+}
+
+// ObjectKeys makes the object keys of the uploads from a template like {source}/{yyyy}/{mm}/{hash}{ext}.
+//
+//	{source} the directory of the upload source: avatar, post, branding or other
+//	{yyyy}, {mm}, {dd} the upload date, in UTC
+//	{hash} the SHA-256 of the content, or the time and random bytes with the random scheme
+//	{shard} the first two bytes of {hash}, as two directories, or of the random bytes with the random scheme
+//	{ext} the lower case extension of the file
+type ObjectKeys struct {
+	prefix   string
+	scheme   string
+	template string
+}
+
+// NewObjectKeys checks the scheme and the template, the empty ones are the defaults.
+// The template must hold {hash}, or the keys would collide.
+func NewObjectKeys(prefix, scheme, template string) (*ObjectKeys, error) {
+	scheme = strings.TrimSpace(scheme)
+	if len(scheme) == 0 {
+		scheme = KeySchemeRandom
+	}
+	if scheme != KeySchemeRandom && scheme != KeySchemeContent {
+		return nil, fmt.Errorf("unknown object key scheme %s", scheme)
+	}
+	template = strings.Trim(strings.TrimSpace(template), "/")
+	if len(template) == 0 {
+		template = DefaultKeyTemplate
+		if scheme == KeySchemeContent {
+			template = DefaultContentKeyTemplate
+		}
+	}
+	if !strings.Contains(template, "{hash}") {
+		return nil, fmt.Errorf("object key template %s has no {hash}", template)
+	}
+	if strings.Contains(template, "..") {
+		return nil, fmt.Errorf("object key template %s has ..", template)
+	}
+
+	if _, err := keyPattern(prefix, template, keyPatterns["{source}"]); err != nil {
+		return nil, err
+	}
+	return &ObjectKeys{
+		prefix:   prefix,
+		scheme:   scheme,
+		template: template,
+	}, nil
+}
+
+// keyPattern returns the pattern of the keys made from the template, with the source matched by sourceExpr.
+func keyPattern(prefix, template, sourceExpr string) (*regexp.Regexp, error) {
+	pattern := &strings.Builder{}
+	pattern.WriteString("^" + regexp.QuoteMeta(prefix))
+	last := 0
+	for _, loc := range keyPlaceholder.FindAllStringIndex(template, -1) {
+		placeholder := template[loc[0]:loc[1]]
+		expr, ok := keyPatterns[placeholder]
+		if !ok {
+			return nil, fmt.Errorf("unknown placeholder %s in object key template %s", placeholder, template)
+		}
+		if placeholder == "{source}" {
+			expr = sourceExpr
+		}
+		pattern.WriteString(regexp.QuoteMeta(template[last:loc[0]]) + expr)
+		last = loc[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(template[last:]) + "$")
+	return regexp.Compile(pattern.String())
+}
+
+// ContentAddressed reports whether the keys are made from the content,
+// in which case an existing object with the key is the same file.
+func (k *ObjectKeys) ContentAddressed() bool {
+	return k.scheme == KeySchemeContent
+}
+
+// Key returns the key of the file. With the content scheme the file is read to hash it, then rewound.
+func (k *ObjectKeys) Key(source plugin.UploadSource, filename string, file io.ReadSeeker) (string, error) {
+	if !k.ContentAddressed() {
+		return k.RandomKey(source, filename), nil
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return k.Format(source, hex.EncodeToString(hash.Sum(nil)), filepath.Ext(filename), time.Now()), nil
+}
+
+// RandomKey returns a random key of the file, for the files not read yet like the direct uploads.
+func (k *ObjectKeys) RandomKey(source plugin.UploadSource, filename string) string {
+	bytes := make([]byte, 4)
+	_, _ = rand.Read(bytes)
+	random := hex.EncodeToString(bytes)
+	name := fmt.Sprintf("%d", time.Now().UnixNano()) + random
+	// the time changes too slowly to shard the files
+	return k.format(source, name, random, filepath.Ext(filename), time.Now())
+}
+
+// Format fills the template.
+func (k *ObjectKeys) Format(source plugin.UploadSource, hash, ext string, now time.Time) string {
+	return k.format(source, hash, hash, ext, now)
+}
+
+func (k *ObjectKeys) format(source plugin.UploadSource, hash, shard, ext string, now time.Time) string {
+	now = now.UTC()
+	if len(shard) >= 4 {
+		shard = shard[:2] + "/" + shard[2:4]
+	}
+	return k.prefix + strings.NewReplacer(
+		"{source}", SourceDir(source),
+		"{yyyy}", now.Format("2006"),
+		"{mm}", now.Format("01"),
+		"{dd}", now.Format("02"),
+		"{shard}", shard,
+		"{hash}", hash,
+		"{ext}", strings.ToLower(ext),
+	).Replace(k.template)
+}
+
+// Match reports whether the key could have been made by the template for the source,
+// to check the keys sent back by the browsers.
+func (k *ObjectKeys) Match(key string, source plugin.UploadSource) bool {
+	if strings.Contains(key, "..") {
+		return false
+	}
+	pattern, err := keyPattern(k.prefix, k.template, regexp.QuoteMeta(SourceDir(source)))
+	return err == nil && pattern.MatchString(key)
+}
+
+// SourceDir returns the directory of the upload source in the keys.
+func SourceDir(source plugin.UploadSource) string {
+	switch source {
+	case plugin.UserAvatar:
+		return "avatar"
+	case plugin.UserPost:
+		return "post"
+	case plugin.AdminBranding:
+		return "branding"
+	default:
+		return "other"
+	}
+}
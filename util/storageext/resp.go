@@ -0,0 +1,59 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package storageext
+
+import (
+	"net/http"
+
+	"github.com/apache/incubator-answer/plugin"
+	"github.com/gin-gonic/gin"
+	"github.com/segmentfault/pacman/log"
+)
+
+// respBody is the response body of the Answer API.
+type respBody struct {
+	Code    int         `json:"code"`
+	Reason  string      `json:"reason"`
+	Message string      `json:"msg"`
+	Data    interface{} `json:"data"`
+}
+
+// HandleResponse writes data as a successful response.
+func HandleResponse(ctx *gin.Context, data interface{}) {
+	ctx.JSON(http.StatusOK, &respBody{Code: http.StatusOK, Reason: "success", Data: data})
+}
+
+// HandleUploadError writes a failed upload, with the message shown to the user. The original error is logged.
+func HandleUploadError(ctx *gin.Context, code int, resp plugin.UploadFileResponse) {
+	if resp.OriginalError != nil {
+		log.Warnf("upload failed: %v", resp.OriginalError)
+	}
+	ctx.JSON(code, &respBody{Code: code, Reason: "error", Message: resp.DisplayErrorMsg.Translate(ctx)})
+}
+
+// HandleBadRequest writes a bad request response with the message.
+func HandleBadRequest(ctx *gin.Context, msg string) {
+	ctx.JSON(http.StatusBadRequest, &respBody{Code: http.StatusBadRequest, Reason: "error", Message: msg})
+}
+
+// HandleNotFound writes a not found response, used when the plugin is disabled or the feature is off.
+func HandleNotFound(ctx *gin.Context) {
+	ctx.JSON(http.StatusNotFound, &respBody{Code: http.StatusNotFound, Reason: "error"})
+}
@@ -0,0 +1,86 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package storageext
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/apache/incubator-answer/plugin"
+)
+
+// DefaultMaxFileSize is the max file size when none is configured, 10MB.
+const DefaultMaxFileSize int64 = 10 * 1024 * 1024
+
+// Rules are the extensions and sizes of the files accepted from each upload source.
+type Rules struct {
+	// Extensions replaces the extensions of plugin.DefaultFileTypeCheckMapping for the sources it holds.
+	Extensions map[plugin.UploadSource]map[string]bool
+	// MaxSizes holds the max file size in bytes of the sources with their own limit.
+	MaxSizes map[plugin.UploadSource]int64
+	// MaxSize is the max file size in bytes of the other sources.
+	MaxSize int64
+}
+
+// AllowExt reports whether the extension of the file is accepted from the source.
+func (r *Rules) AllowExt(filename string, source plugin.UploadSource) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if exts, ok := r.Extensions[source]; ok {
+		return exts[ext]
+	}
+	return plugin.DefaultFileTypeCheckMapping[source][ext]
+}
+
+// MaxFileSize returns the max file size in bytes accepted from the source.
+func (r *Rules) MaxFileSize(source plugin.UploadSource) int64 {
+	if size, ok := r.MaxSizes[source]; ok {
+		return size
+	}
+	if r.MaxSize > 0 {
+		return r.MaxSize
+	}
+	return DefaultMaxFileSize
+}
+
+// ParseExtensions parses a list of extensions separated by commas or spaces, like "jpg, .png pdf".
+// It returns nil for an empty list.
+func ParseExtensions(list string) map[string]bool {
+	fields := strings.FieldsFunc(list, func(r rune) bool {
+		return r == ',' || r == ' ' || r == ';' || r == '\n'
+	})
+	if len(fields) == 0 {
+		return nil
+	}
+	exts := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		exts["."+strings.TrimPrefix(strings.ToLower(field), ".")] = true
+	}
+	return exts
+}
+
+// ParseSize parses a size in MB, it returns 0 when the size is empty or not positive.
+func ParseSize(mb string) int64 {
+	size, _ := strconv.ParseInt(strings.TrimSpace(mb), 10, 64)
+	if size <= 0 {
+		return 0
+	}
+	return size * 1024 * 1024
+}
@@ -0,0 +1,334 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package storageext
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/incubator-answer/plugin"
+	"github.com/gin-gonic/gin"
+)
+
+func TestDetectContentType(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	pdf := []byte("%PDF-1.7\n1 0 obj")
+	tests := []struct {
+		name     string
+		filename string
+		head     []byte
+		want     string
+	}{
+		{"sniffed", "photo.png", png, "image/png"},
+		{"sniffed over extension", "photo.jpg", png, "image/png"},
+		{"pdf", "doc.pdf", pdf, "application/pdf"},
+		{"html disguised as image", "photo.png", []byte("<html><script>alert(1)</script></html>"), "text/html; charset=utf-8"},
+		{"text falls back to extension", "style.css", []byte("body { color: red; }"), "text/css; charset=utf-8"},
+		{"binary falls back to extension", "data.wasm", []byte{0x01, 0x02, 0x03}, "application/wasm"},
+		{"unknown", "data.unknown", []byte{0x01, 0x02, 0x03}, "application/octet-stream"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectContentType(tt.filename, tt.head); got != tt.want {
+				t.Errorf("DetectContentType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSniffContentType(t *testing.T) {
+	file := bytes.NewReader([]byte("%PDF-1.7\n1 0 obj"))
+	got, err := SniffContentType("doc.pdf", file)
+	if err != nil || got != "application/pdf" {
+		t.Fatalf("SniffContentType() = %q, %v", got, err)
+	}
+	if int64(file.Len()) != file.Size() {
+		t.Errorf("file not rewound")
+	}
+}
+
+func TestContentDisposition(t *testing.T) {
+	tests := []struct {
+		contentType string
+		filename    string
+		want        string
+	}{
+		{"image/png", "a.png", ""},
+		{"application/pdf", "a.pdf", ""},
+		{"image/svg+xml", "a.svg", `attachment; filename=a.svg`},
+		{"text/html; charset=utf-8", "a.html", `attachment; filename=a.html`},
+		{"application/zip", "my files.zip", `attachment; filename="my files.zip"`},
+		{"application/zip", "文件.zip", `attachment; filename*=utf-8''%E6%96%87%E4%BB%B6.zip`},
+	}
+	for _, tt := range tests {
+		if got := ContentDisposition(tt.contentType, tt.filename); got != tt.want {
+			t.Errorf("ContentDisposition(%q, %q) = %q, want %q", tt.contentType, tt.filename, got, tt.want)
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	r := &Rules{
+		Extensions: map[plugin.UploadSource]map[string]bool{plugin.UserPost: ParseExtensions("jpg, .PNG pdf")},
+		MaxSizes:   map[plugin.UploadSource]int64{plugin.UserAvatar: ParseSize("1")},
+	}
+	if !r.AllowExt("doc.PDF", plugin.UserPost) || r.AllowExt("anim.gif", plugin.UserPost) {
+		t.Error("configured extensions not applied")
+	}
+	if !r.AllowExt("me.webp", plugin.UserAvatar) || r.AllowExt("me.pdf", plugin.UserAvatar) {
+		t.Error("default extensions not applied")
+	}
+	if r.MaxFileSize(plugin.UserAvatar) != 1024*1024 || r.MaxFileSize(plugin.UserPost) != DefaultMaxFileSize {
+		t.Error("sizes not applied")
+	}
+	r.MaxSize = ParseSize("20")
+	if r.MaxFileSize(plugin.AdminBranding) != 20*1024*1024 {
+		t.Error("max size not applied")
+	}
+	if ParseExtensions(" , ") != nil || ParseSize("-1") != 0 || ParseSize("x") != 0 {
+		t.Error("empty values not ignored")
+	}
+	if !strings.HasPrefix(ContentDisposition("application/zip", "a/b.zip"), "attachment; filename=b.zip") {
+		t.Error("directory kept in the filename")
+	}
+}
+
+func TestNewObjectKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		scheme   string
+		template string
+		wantErr  bool
+	}{
+		{"defaults", "", "", false},
+		{"content", KeySchemeContent, "", false},
+		{"dated", KeySchemeContent, "{source}/{yyyy}/{mm}/{hash}{ext}", false},
+		{"unknown scheme", "uuid", "", true},
+		{"no hash", "", "{source}/{yyyy}/{ext}", true},
+		{"unknown placeholder", "", "{source}/{user}/{hash}{ext}", true},
+		{"parent directory", "", "../{hash}{ext}", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewObjectKeys("answer/", tt.scheme, tt.template); (err != nil) != tt.wantErr {
+				t.Errorf("NewObjectKeys() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestObjectKeys(t *testing.T) {
+	keys, err := NewObjectKeys("answer/", KeySchemeContent, "{source}/{yyyy}/{mm}/{shard}/{hash}{ext}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if got := keys.Format(plugin.UserPost, "abcdef", ".PNG", now); got != "answer/post/2024/05/ab/cd/abcdef.png" {
+		t.Errorf("Format() = %s", got)
+	}
+
+	file := bytes.NewReader([]byte("logo"))
+	key, err := keys.Key(plugin.AdminBranding, "logo.png", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// sha256("logo")
+	if !strings.HasSuffix(key, "/35/98/3598ce6f965b2481fe26316c06b30950c46ac7f8e7229f104aa78f579997668d.png") {
+		t.Errorf("Key() = %s", key)
+	}
+	if again, _ := keys.Key(plugin.AdminBranding, "logo.png", file); again != key {
+		t.Errorf("Key() of the rewound file = %s, want %s", again, key)
+	}
+	if !keys.Match(key, plugin.AdminBranding) {
+		t.Errorf("Match(%s, branding) = false", key)
+	}
+	if keys.Match(key, plugin.UserAvatar) {
+		t.Errorf("Match(%s, avatar) = true", key)
+	}
+	if keys.Match("answer/branding/2024/05/../../secret.png", plugin.AdminBranding) {
+		t.Error("Match() of a parent directory = true")
+	}
+
+	random, _ := NewObjectKeys("answer/", "", "")
+	if random.ContentAddressed() {
+		t.Error("random keys are content addressed")
+	}
+	key = random.RandomKey(plugin.UserAvatar, "me.JPG")
+	if !strings.HasPrefix(key, "answer/avatar/") || !strings.HasSuffix(key, ".jpg") || !random.Match(key, plugin.UserAvatar) {
+		t.Errorf("RandomKey() = %s", key)
+	}
+
+	// the random keys are sharded by the random bytes, not the time
+	sharded, _ := NewObjectKeys("", "", ShardedKeyTemplate)
+	key = sharded.RandomKey(plugin.UserPost, "a.png")
+	parts := strings.Split(key, "/")
+	if len(parts) != 4 || !strings.Contains(parts[3], parts[1]+parts[2]) || !sharded.Match(key, plugin.UserPost) {
+		t.Errorf("sharded RandomKey() = %s", key)
+	}
+}
+
+type tokenCache struct {
+	plugin.Cache
+	keys map[string]string
+}
+
+func (c *tokenCache) GetString(_ context.Context, key string) (string, bool, error) {
+	data, ok := c.keys[key]
+	return data, ok, nil
+}
+
+func TestCheckLoginCache(t *testing.T) {
+	if err := CheckLoginCache(); err == nil {
+		t.Error("CheckLoginCache accepted the login checks without a cache plugin")
+	}
+}
+
+func TestLoggedIn(t *testing.T) {
+	cache := &tokenCache{keys: map[string]string{
+		"answer:user:visit:v1": "a1",
+		"answer:user:token:a1": "{}",
+	}}
+	tests := []struct {
+		name   string
+		cookie string
+		header string
+		query  string
+		want   bool
+	}{
+		{"visit cookie", "visit=v1", "", "", true},
+		{"access token", "", "Bearer a1", "", true},
+		{"access token in query", "", "", "?Authorization=a1", true},
+		{"unknown visit cookie", "visit=v2", "", "", false},
+		{"empty visit cookie", "visit=", "", "", false},
+		{"anonymous", "", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/file/post/a.png"+tt.query, nil)
+			if len(tt.cookie) > 0 {
+				ctx.Request.Header.Set("Cookie", tt.cookie)
+			}
+			if len(tt.header) > 0 {
+				ctx.Request.Header.Set("Authorization", tt.header)
+			}
+			if got := loggedIn(ctx, cache); got != tt.want {
+				t.Errorf("loggedIn() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type memStore struct {
+	objects map[string]string
+	puts    int
+}
+
+func (m *memStore) ObjectExists(key string) (bool, error) {
+	_, ok := m.objects[key]
+	return ok, nil
+}
+
+func (m *memStore) PutObject(key, contentType, _ string, body io.Reader) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	m.objects[key] = contentType + ":" + string(data)
+	m.puts++
+	return nil
+}
+
+func uploadContext(filename, content string) *gin.Context {
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	part, _ := form.CreateFormFile("file", filename)
+	_, _ = part.Write([]byte(content))
+	_ = form.Close()
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/upload", body)
+	ctx.Request.Header.Set("Content-Type", form.FormDataContentType())
+	return ctx
+}
+
+func TestUploader(t *testing.T) {
+	keys, err := NewObjectKeys("answer/", KeySchemeContent, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &memStore{objects: make(map[string]string)}
+	u := &Uploader{
+		Rules: &Rules{
+			Extensions: map[plugin.UploadSource]map[string]bool{plugin.UserPost: ParseExtensions("pdf")},
+			MaxSize:    1024 * 1024,
+		},
+		Keys:  keys,
+		Store: store,
+		URL:   func(objectKey string) string { return "https://cdn.example.com/" + objectKey },
+	}
+
+	resp := u.Upload(uploadContext("report.pdf", "%PDF-1.7\n1 0 obj"), plugin.UserPost)
+	if resp.OriginalError != nil {
+		t.Fatal(resp.OriginalError)
+	}
+	objectKey := strings.TrimPrefix(resp.FullURL, "https://cdn.example.com/")
+	if !strings.HasPrefix(objectKey, "answer/post/") || store.objects[objectKey] != "application/pdf:%PDF-1.7\n1 0 obj" {
+		t.Fatalf("url = %s, objects = %v", resp.FullURL, store.objects)
+	}
+	if again := u.Upload(uploadContext("copy.pdf", "%PDF-1.7\n1 0 obj"), plugin.UserPost); again.FullURL != resp.FullURL || store.puts != 1 {
+		t.Errorf("same content stored twice: %s, %s", resp.FullURL, again.FullURL)
+	}
+
+	for filename, content := range map[string]string{
+		"script.exe": "MZ",
+		"large.pdf":  strings.Repeat("x", 1024*1024+1),
+	} {
+		if resp := u.Upload(uploadContext(filename, content), plugin.UserPost); resp.OriginalError == nil {
+			t.Errorf("Upload(%s) no error", filename)
+		}
+	}
+	if store.puts != 1 {
+		t.Errorf("%d objects stored, want 1", store.puts)
+	}
+}
+
+func TestImageConfigOptions(t *testing.T) {
+	if _, ok := (ImageConfig{}).Options(plugin.UserPost); ok {
+		t.Error("images processed while disabled")
+	}
+	c := ImageConfig{Enabled: true, WebP: true, PostMaxDimension: "1024", ThumbnailSize: "320"}
+	if opts, _ := c.Options(plugin.UserPost); opts.MaxDimension != 1024 || opts.ThumbnailSize != 320 || !opts.WebP {
+		t.Errorf("post options = %+v", opts)
+	}
+	if opts, _ := c.Options(plugin.UserAvatar); opts.MaxDimension != defaultAvatarMaxDimension || opts.ThumbnailSize != 0 {
+		t.Errorf("avatar options = %+v", opts)
+	}
+	if opts, _ := c.Options(plugin.AdminBranding); opts.MaxDimension != 0 {
+		t.Errorf("branding options = %+v", opts)
+	}
+}
@@ -0,0 +1,221 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+package storageext
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/apache/incubator-answer-plugins/util/clamav"
+	"github.com/apache/incubator-answer-plugins/util/imageproc"
+	"github.com/apache/incubator-answer/plugin"
+)
+
+// the max dimensions in pixels when none are configured, the branding images keep their size
+const (
+	defaultAvatarMaxDimension = 256
+	defaultPostMaxDimension   = 2048
+)
+
+// Store is the bucket of a storage plugin.
+type Store interface {
+	ObjectExists(key string) (bool, error)
+	PutObject(key, contentType, contentDisposition string, body io.Reader) error
+}
+
+// Messages are the i18n keys of the upload errors, each plugin translates its own.
+type Messages struct {
+	FileNotFound        string
+	UnsupportedFileType string
+	OverFileSizeLimit   string
+	UploadFileFailed    string
+	InfectedFile        string
+	ScanUnavailable     string
+}
+
+// ImageConfig is how the storage plugins process the uploaded images, the dimensions are in pixels.
+type ImageConfig struct {
+	Enabled              bool
+	WebP                 bool
+	AvatarMaxDimension   string
+	PostMaxDimension     string
+	BrandingMaxDimension string
+	ThumbnailSize        string
+}
+
+// Options returns how the images from the source are processed, false when they are stored as uploaded.
+func (c ImageConfig) Options(source plugin.UploadSource) (opts imageproc.Options, ok bool) {
+	if !c.Enabled {
+		return opts, false
+	}
+	opts.WebP = c.WebP
+	switch source {
+	case plugin.UserAvatar:
+		opts.MaxDimension = imageproc.ParseDimension(c.AvatarMaxDimension, defaultAvatarMaxDimension)
+	case plugin.UserPost:
+		opts.MaxDimension = imageproc.ParseDimension(c.PostMaxDimension, defaultPostMaxDimension)
+		opts.ThumbnailSize = imageproc.ParseDimension(c.ThumbnailSize, 0)
+	case plugin.AdminBranding:
+		opts.MaxDimension = imageproc.ParseDimension(c.BrandingMaxDimension, 0)
+	}
+	return opts, true
+}
+
+// NewScanner returns the malware scanner of the uploads, nil when the scan is disabled.
+func NewScanner(slugName string, enabled bool, address, failurePolicy string) (*clamav.Guard, error) {
+	if !enabled {
+		return nil, nil
+	}
+	return clamav.NewGuard(slugName, address, failurePolicy, clamav.DefaultTimeout)
+}
+
+// ScanError returns the upload response of a failed scan.
+func ScanError(err error, msgs Messages) plugin.UploadFileResponse {
+	resp := plugin.UploadFileResponse{
+		OriginalError:   fmt.Errorf("scan file failed: %v", err),
+		DisplayErrorMsg: plugin.MakeTranslator(msgs.ScanUnavailable),
+	}
+	if errors.Is(err, clamav.ErrInfected) {
+		resp.DisplayErrorMsg = plugin.MakeTranslator(msgs.InfectedFile)
+	}
+	return resp
+}
+
+// Uploader is the upload of the storage plugins: the file is checked against the rules and scanned,
+// the images are processed, then the file is stored under its object key, once for the content-addressed keys.
+type Uploader struct {
+	Rules  *Rules
+	Keys   *ObjectKeys
+	Images ImageConfig
+	// Scanner is nil when the scan is disabled
+	Scanner  *clamav.Guard
+	Messages Messages
+	Store    Store
+	// URL returns the URL of a stored object
+	URL func(objectKey string) string
+}
+
+// Upload stores the file of the form field "file" uploaded from the source.
+func (u *Uploader) Upload(ctx *plugin.GinContext, source plugin.UploadSource) (resp plugin.UploadFileResponse) {
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		resp.OriginalError = fmt.Errorf("get file failed: %v", err)
+		resp.DisplayErrorMsg = plugin.MakeTranslator(u.Messages.FileNotFound)
+		return resp
+	}
+
+	if !u.Rules.AllowExt(file.Filename, source) {
+		resp.OriginalError = fmt.Errorf("file type not allowed")
+		resp.DisplayErrorMsg = plugin.MakeTranslator(u.Messages.UnsupportedFileType)
+		return resp
+	}
+
+	if file.Size > u.Rules.MaxFileSize(source) {
+		resp.OriginalError = fmt.Errorf("file size too large")
+		resp.DisplayErrorMsg = plugin.MakeTranslator(u.Messages.OverFileSizeLimit)
+		return resp
+	}
+
+	openFile, err := file.Open()
+	if err != nil {
+		resp.OriginalError = fmt.Errorf("get file failed: %v", err)
+		resp.DisplayErrorMsg = plugin.MakeTranslator(u.Messages.FileNotFound)
+		return resp
+	}
+	defer openFile.Close()
+
+	if u.Scanner != nil {
+		if err = u.Scanner.Check(file.Filename, openFile); err != nil {
+			return ScanError(err, u.Messages)
+		}
+	}
+
+	contentType, err := SniffContentType(file.Filename, openFile)
+	if err != nil {
+		resp.OriginalError = fmt.Errorf("read file failed: %v", err)
+		resp.DisplayErrorMsg = plugin.MakeTranslator(u.Messages.UploadFileFailed)
+		return resp
+	}
+
+	var body io.ReadSeeker = openFile
+	filename := file.Filename
+	var thumbnail *imageproc.Image
+	if opts, ok := u.Images.Options(source); ok && imageproc.Supported(MediaType(contentType)) {
+		result, err := processImage(openFile, MediaType(contentType), opts)
+		if err != nil {
+			resp.OriginalError = fmt.Errorf("process image failed: %v", err)
+			resp.DisplayErrorMsg = plugin.MakeTranslator(u.Messages.UnsupportedFileType)
+			if errors.Is(err, imageproc.ErrTooLarge) {
+				resp.DisplayErrorMsg = plugin.MakeTranslator(u.Messages.OverFileSizeLimit)
+			}
+			return resp
+		}
+		body, contentType, thumbnail = bytes.NewReader(result.Data), result.ContentType, result.Thumbnail
+		filename = strings.TrimSuffix(filename, filepath.Ext(filename)) + result.Ext
+	}
+
+	objectKey, err := u.Keys.Key(source, filename, body)
+	if err != nil {
+		resp.OriginalError = fmt.Errorf("read file failed: %v", err)
+		resp.DisplayErrorMsg = plugin.MakeTranslator(u.Messages.UploadFileFailed)
+		return resp
+	}
+	if u.Keys.ContentAddressed() {
+		// the same content is stored once
+		exists, err := u.Store.ObjectExists(objectKey)
+		if err != nil {
+			resp.OriginalError = err
+			resp.DisplayErrorMsg = plugin.MakeTranslator(u.Messages.UploadFileFailed)
+			return resp
+		}
+		if exists {
+			resp.FullURL = u.URL(objectKey)
+			return resp
+		}
+	}
+	if thumbnail != nil {
+		err = u.Store.PutObject(imageproc.ThumbnailKey(objectKey), thumbnail.ContentType, "", bytes.NewReader(thumbnail.Data))
+		if err != nil {
+			resp.OriginalError = fmt.Errorf("upload thumbnail failed: %v", err)
+			resp.DisplayErrorMsg = plugin.MakeTranslator(u.Messages.UploadFileFailed)
+			return resp
+		}
+	}
+	err = u.Store.PutObject(objectKey, contentType, ContentDisposition(contentType, filename), body)
+	if err != nil {
+		resp.OriginalError = fmt.Errorf("upload file failed: %v", err)
+		resp.DisplayErrorMsg = plugin.MakeTranslator(u.Messages.UploadFileFailed)
+		return resp
+	}
+	resp.FullURL = u.URL(objectKey)
+	return resp
+}
+
+// processImage reads and processes the image
+func processImage(file io.Reader, contentType string, opts imageproc.Options) (*imageproc.Result, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	return imageproc.Process(data, contentType, opts)
+}
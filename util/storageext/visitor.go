@@ -0,0 +1,83 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+package storageext
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/apache/incubator-answer-plugins/util"
+	"github.com/apache/incubator-answer/plugin"
+	"github.com/gin-gonic/gin"
+	"github.com/segmentfault/pacman/log"
+)
+
+const (
+	// visitCookie is the cookie Answer sets after the login, sent with the image requests
+	visitCookie = "visit"
+	// visitTokenCacheKey and accessTokenCacheKey are the cache keys of the tokens of Answer
+	visitTokenCacheKey  = "answer:user:visit:"
+	accessTokenCacheKey = "answer:user:token:"
+)
+
+// LoggedIn reports whether the request comes from a user logged in to Answer, with the visit cookie
+// or the access token of the API. Answer keeps the tokens in the cache plugin when one is enabled,
+// which is the only cache plugins can read, so without a cache plugin no request is logged in.
+func LoggedIn(ctx *gin.Context) bool {
+	cache, err := util.GetCache()
+	if err != nil {
+		log.Warnf("check login failed: %v", err)
+		return false
+	}
+	return loggedIn(ctx, cache)
+}
+
+// CheckLoginCache returns an error when no cache plugin is enabled, since LoggedIn refuses every request
+// without one. The storage plugins check it before accepting the private access mode.
+func CheckLoginCache() error {
+	if _, err := util.GetCache(); err != nil {
+		return fmt.Errorf("private access mode needs a cache plugin to check the logins: %w", err)
+	}
+	return nil
+}
+
+func loggedIn(ctx *gin.Context, cache plugin.Cache) bool {
+	if token, err := ctx.Cookie(visitCookie); err == nil && tokenCached(ctx, cache, visitTokenCacheKey+token) {
+		return true
+	}
+	token := ctx.GetHeader("Authorization")
+	if len(token) == 0 {
+		token = ctx.Query("Authorization")
+	}
+	token = strings.TrimPrefix(token, "Bearer ")
+	return tokenCached(ctx, cache, accessTokenCacheKey+token)
+}
+
+func tokenCached(ctx context.Context, cache plugin.Cache, key string) bool {
+	if strings.HasSuffix(key, ":") {
+		return false
+	}
+	_, exist, err := cache.GetString(ctx, key)
+	if err != nil {
+		log.Warnf("check login failed: %v", err)
+		return false
+	}
+	return exist
+}
@@ -0,0 +1,441 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+// Package storagegc collects the uploads of the posts that no content references, such as the images
+// of the abandoned drafts. The orphans are moved to a quarantine prefix first, and deleted later.
+package storagegc
+
+import (
+	"context"
+	"errors"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/incubator-answer-plugins/util/storageext"
+	"github.com/apache/incubator-answer/plugin"
+	"github.com/segmentfault/pacman/log"
+)
+
+// The modes of the collector: off, report the orphans only, or quarantine and then delete them.
+const (
+	ModeOff        = "off"
+	ModeReport     = "report"
+	ModeQuarantine = "quarantine"
+)
+
+const (
+	// PageSize is the page size of the contents read from the referencer.
+	PageSize = 100
+	// QuarantineDir is the directory of the quarantined objects under the object key prefix.
+	QuarantineDir = ".quarantine/"
+	// MaxReportedKeys is the max number of orphan keys listed in a report.
+	MaxReportedKeys = 100
+
+	DefaultGracePeriod      = 7 * 24 * time.Hour
+	DefaultQuarantinePeriod = 30 * 24 * time.Hour
+)
+
+var (
+	ErrRunning      = errors.New("collection is already running")
+	ErrNoReferencer = errors.New("upload referencer is not registered yet")
+	ErrDisabled     = errors.New("collection is off")
+)
+
+// Referencer supplies the contents that reference the uploads, like plugin.SearchSyncer supplies the search contents.
+type Referencer interface {
+	// GetContentsPage returns a page of the contents, such as the original text of the questions, answers,
+	// revisions and drafts. The page starts from 1, and an empty page is the end.
+	GetContentsPage(ctx context.Context, page, pageSize int) ([]string, error)
+}
+
+// Object is an object of the bucket.
+type Object struct {
+	Key          string
+	LastModified time.Time
+}
+
+// Bucket is the storage side of the collection.
+type Bucket interface {
+	// ListObjects calls fn with each page of the objects under the prefix.
+	ListObjects(ctx context.Context, prefix string, fn func(objects []Object) error) error
+	// MoveObject copies the object to the new key and deletes it, the copy is modified now.
+	MoveObject(ctx context.Context, from, to string) error
+	// DeleteObject deletes the object.
+	DeleteObject(ctx context.Context, key string) error
+}
+
+// Config is the config of a collector.
+type Config struct {
+	Mode string
+	// Prefix is the object key prefix of the storage, and Keys makes the keys under it.
+	Prefix string
+	Keys   *storageext.ObjectKeys
+	// URLPrefixes are followed by the object keys in the contents, such as the visit URL prefix
+	// or the path of the file route of the private buckets.
+	URLPrefixes []string
+	// GracePeriod is the age of the objects before they are collected, so that the drafts in progress keep them.
+	GracePeriod time.Duration
+	// QuarantinePeriod is how long the orphans stay in quarantine before they are deleted.
+	QuarantinePeriod time.Duration
+	// Interval is the interval of the scheduled collections, zero disables the schedule.
+	Interval time.Duration
+}
+
+// ParseInterval parses the collection interval config, a number of hours.
+// An empty or invalid config is zero, which disables the schedule.
+func ParseInterval(hours string) time.Duration {
+	n, err := strconv.Atoi(strings.TrimSpace(hours))
+	if err != nil || n <= 0 {
+		return 0
+	}
+	return time.Duration(n) * time.Hour
+}
+
+// ParseDays parses a period config, a number of days. An empty or invalid config is zero, which is the default.
+func ParseDays(days string) time.Duration {
+	n, err := strconv.Atoi(strings.TrimSpace(days))
+	if err != nil || n <= 0 {
+		return 0
+	}
+	return time.Duration(n) * 24 * time.Hour
+}
+
+// Report is the result of a collection.
+type Report struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Mode       string    `json:"mode"`
+	// Contents is the number of contents read from the referencer, and References the number of keys found in them.
+	Contents   int `json:"contents"`
+	References int `json:"references"`
+	// Scanned is the number of the uploads of the posts older than the grace period.
+	Scanned int `json:"scanned"`
+	// Orphans were not referenced, and are quarantined unless the mode is report.
+	Orphans    int      `json:"orphans"`
+	OrphanKeys []string `json:"orphan_keys,omitempty"`
+	// Quarantined orphans were moved to the quarantine prefix, Restored ones were moved back as they
+	// are referenced again, and Deleted ones stayed in quarantine for the quarantine period.
+	Quarantined int    `json:"quarantined"`
+	Restored    int    `json:"restored"`
+	Deleted     int    `json:"deleted"`
+	Failed      int    `json:"failed"`
+	Error       string `json:"error,omitempty"`
+}
+
+// Status is the state of a Collector.
+type Status struct {
+	Running    bool    `json:"running"`
+	Mode       string  `json:"mode"`
+	Interval   string  `json:"interval"`
+	LastReport *Report `json:"last_report"`
+}
+
+// Collector compares the uploads of the posts with the references found in the contents,
+// quarantines the orphans and deletes them after the quarantine period.
+type Collector struct {
+	name       string
+	bucket     Bucket
+	lock       sync.Mutex
+	referencer Referencer
+	config     Config
+	running    bool
+	last       *Report
+	stop       chan struct{}
+}
+
+// NewCollector returns a collector of the bucket, name prefixes its logs.
+func NewCollector(name string, bucket Bucket) *Collector {
+	return &Collector{name: name, bucket: bucket, config: Config{Mode: ModeOff}}
+}
+
+// SetReferencer sets the referencer of the uploads.
+func (c *Collector) SetReferencer(referencer Referencer) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.referencer = referencer
+}
+
+// Configure replaces the config and the schedule of the collector.
+func (c *Collector) Configure(config Config) {
+	if config.Mode != ModeReport && config.Mode != ModeQuarantine {
+		config.Mode = ModeOff
+	}
+	if config.GracePeriod <= 0 {
+		config.GracePeriod = DefaultGracePeriod
+	}
+	if config.QuarantinePeriod <= 0 {
+		config.QuarantinePeriod = DefaultQuarantinePeriod
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.config = config
+	if c.stop != nil {
+		close(c.stop)
+		c.stop = nil
+	}
+	if config.Mode == ModeOff || config.Interval <= 0 {
+		return
+	}
+	stop := make(chan struct{})
+	c.stop = stop
+	go func() {
+		ticker := time.NewTicker(config.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := c.Start(); err != nil {
+					log.Warnf("%s: skip scheduled collection: %v", c.name, err)
+				}
+			}
+		}
+	}()
+}
+
+// Start starts a collection in the background.
+func (c *Collector) Start() error {
+	referencer, config, err := c.acquire()
+	if err != nil {
+		return err
+	}
+	go func() {
+		_, _ = c.run(context.Background(), referencer, config)
+	}()
+	return nil
+}
+
+// Run collects the orphans and waits for the result.
+func (c *Collector) Run(ctx context.Context) (*Report, error) {
+	referencer, config, err := c.acquire()
+	if err != nil {
+		return nil, err
+	}
+	return c.run(ctx, referencer, config)
+}
+
+// Status returns the state of the collector.
+func (c *Collector) Status() *Status {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return &Status{Running: c.running, Mode: c.config.Mode, Interval: c.config.Interval.String(), LastReport: c.last}
+}
+
+func (c *Collector) acquire() (Referencer, Config, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.config.Mode == ModeOff {
+		return nil, Config{}, ErrDisabled
+	}
+	if c.referencer == nil {
+		return nil, Config{}, ErrNoReferencer
+	}
+	if c.running {
+		return nil, Config{}, ErrRunning
+	}
+	c.running = true
+	return c.referencer, c.config, nil
+}
+
+func (c *Collector) run(ctx context.Context, referencer Referencer, config Config) (*Report, error) {
+	report := &Report{StartedAt: time.Now(), Mode: config.Mode}
+	log.Infof("%s: start collection in %s mode", c.name, config.Mode)
+	err := c.collect(ctx, referencer, config, report)
+	report.FinishedAt = time.Now()
+	if err != nil {
+		report.Error = err.Error()
+		log.Errorf("%s: collection error: %v", c.name, err)
+	} else {
+		log.Infof("%s: collection done, scanned %d, orphans %d, quarantined %d, restored %d, deleted %d, failed %d",
+			c.name, report.Scanned, report.Orphans, report.Quarantined, report.Restored, report.Deleted, report.Failed)
+	}
+
+	c.lock.Lock()
+	c.running = false
+	c.last = report
+	c.lock.Unlock()
+	return report, err
+}
+
+func (c *Collector) collect(ctx context.Context, referencer Referencer, config Config, report *Report) error {
+	if config.Keys == nil {
+		return errors.New("object keys are not configured")
+	}
+	references, err := readReferences(ctx, referencer, config.URLPrefixes, report)
+	if err != nil {
+		return err
+	}
+	if report.Contents == 0 {
+		// no content is more likely a broken referencer, which would quarantine every upload
+		log.Warnf("%s: no content read, skip collection", c.name)
+		return nil
+	}
+
+	quarantine := config.Prefix + QuarantineDir
+	now := time.Now()
+	var orphans []string
+	err = c.bucket.ListObjects(ctx, config.Prefix, func(objects []Object) error {
+		for _, object := range objects {
+			if strings.HasPrefix(object.Key, quarantine) || !isPostUpload(config.Keys, object.Key) ||
+				now.Sub(object.LastModified) < config.GracePeriod {
+				continue
+			}
+			report.Scanned++
+			if references[object.Key] || references[OriginalKey(object.Key)] {
+				continue
+			}
+			report.Orphans++
+			if len(report.OrphanKeys) < MaxReportedKeys {
+				report.OrphanKeys = append(report.OrphanKeys, object.Key)
+			}
+			orphans = append(orphans, object.Key)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(report.OrphanKeys)
+	if config.Mode != ModeQuarantine {
+		return nil
+	}
+
+	// the quarantine is checked before the new orphans join it
+	var quarantined []Object
+	err = c.bucket.ListObjects(ctx, quarantine, func(objects []Object) error {
+		quarantined = append(quarantined, objects...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, object := range quarantined {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		key := config.Prefix + strings.TrimPrefix(object.Key, quarantine)
+		switch {
+		case references[key] || references[OriginalKey(key)]:
+			if err = c.bucket.MoveObject(ctx, object.Key, key); err != nil {
+				log.Warnf("%s: restore %s failed: %v", c.name, key, err)
+				report.Failed++
+				continue
+			}
+			report.Restored++
+		case now.Sub(object.LastModified) >= config.QuarantinePeriod:
+			if err = c.bucket.DeleteObject(ctx, object.Key); err != nil {
+				log.Warnf("%s: delete %s failed: %v", c.name, object.Key, err)
+				report.Failed++
+				continue
+			}
+			report.Deleted++
+		}
+	}
+	for _, key := range orphans {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		if err = c.bucket.MoveObject(ctx, key, quarantine+strings.TrimPrefix(key, config.Prefix)); err != nil {
+			log.Warnf("%s: quarantine %s failed: %v", c.name, key, err)
+			report.Failed++
+			continue
+		}
+		report.Quarantined++
+	}
+	return nil
+}
+
+// readReferences reads all the contents and returns the keys referenced by them.
+func readReferences(ctx context.Context, referencer Referencer, urlPrefixes []string, report *Report) (
+	map[string]bool, error) {
+	var prefixes []string
+	for _, prefix := range urlPrefixes {
+		if len(prefix) > 0 {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	if len(prefixes) == 0 {
+		return nil, errors.New("no URL prefix of the uploads")
+	}
+	references := make(map[string]bool)
+	for page := 1; ; page++ {
+		contents, err := referencer.GetContentsPage(ctx, page, PageSize)
+		if err != nil {
+			// an orphan is only known after reading all contents
+			return nil, err
+		}
+		if len(contents) == 0 {
+			break
+		}
+		for _, content := range contents {
+			report.Contents++
+			for _, key := range FindKeys(content, prefixes) {
+				references[key] = true
+			}
+		}
+	}
+	report.References = len(references)
+	return references, nil
+}
+
+// FindKeys returns the object keys following the URL prefixes in the content,
+// up to the end of the URL in markdown, HTML or plain text.
+func FindKeys(content string, urlPrefixes []string) []string {
+	var keys []string
+	for _, prefix := range urlPrefixes {
+		rest := content
+		for {
+			i := strings.Index(rest, prefix)
+			if i < 0 {
+				break
+			}
+			rest = rest[i+len(prefix):]
+			end := strings.IndexAny(rest, " \t\r\n\"'()<>[]{}?#\\`")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end > 0 {
+				keys = append(keys, rest[:end])
+			}
+			rest = rest[end:]
+		}
+	}
+	return keys
+}
+
+// OriginalKey returns the key of the image of a thumbnail key, or the key itself.
+func OriginalKey(key string) string {
+	ext := path.Ext(key)
+	if base := strings.TrimSuffix(key, ext); strings.HasSuffix(base, "_thumb") {
+		return strings.TrimSuffix(base, "_thumb") + ext
+	}
+	return key
+}
+
+// isPostUpload reports whether the key is of an upload of the posts, or of its thumbnail.
+// The avatars and the branding are referenced by the users and the site info, never collected.
+func isPostUpload(keys *storageext.ObjectKeys, key string) bool {
+	return keys.Match(key, plugin.UserPost) || keys.Match(OriginalKey(key), plugin.UserPost)
+}
@@ -0,0 +1,222 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package storagegc
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/incubator-answer-plugins/util/storageext"
+)
+
+type fakeReferencer struct {
+	contents []string
+	err      error
+}
+
+func (r *fakeReferencer) GetContentsPage(_ context.Context, page, pageSize int) ([]string, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	start := (page - 1) * pageSize
+	if start >= len(r.contents) {
+		return nil, nil
+	}
+	end := start + pageSize
+	if end > len(r.contents) {
+		end = len(r.contents)
+	}
+	return r.contents[start:end], nil
+}
+
+type fakeBucket struct {
+	objects map[string]time.Time
+	failing map[string]bool
+}
+
+func (b *fakeBucket) ListObjects(_ context.Context, prefix string, fn func(objects []Object) error) error {
+	var objects []Object
+	for key, modified := range b.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, Object{Key: key, LastModified: modified})
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return fn(objects)
+}
+
+func (b *fakeBucket) MoveObject(_ context.Context, from, to string) error {
+	if b.failing[from] {
+		return errors.New("access denied")
+	}
+	delete(b.objects, from)
+	b.objects[to] = time.Now()
+	return nil
+}
+
+func (b *fakeBucket) DeleteObject(_ context.Context, key string) error {
+	delete(b.objects, key)
+	return nil
+}
+
+func (b *fakeBucket) keys() []string {
+	var keys []string
+	for key := range b.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+const visitURL = "https://cdn.example.com/"
+
+func newCollector(t *testing.T, mode string, bucket Bucket, referencer Referencer) *Collector {
+	keys, err := storageext.NewObjectKeys("answer/", storageext.KeySchemeRandom, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector := NewCollector("test", bucket)
+	collector.SetReferencer(referencer)
+	collector.Configure(Config{
+		Mode:        mode,
+		Prefix:      "answer/",
+		Keys:        keys,
+		URLPrefixes: []string{visitURL, "/answer/api/v1/s3_storage/file/"},
+		GracePeriod: 24 * time.Hour,
+	})
+	return collector
+}
+
+func newBucket() *fakeBucket {
+	old := time.Now().Add(-48 * time.Hour)
+	return &fakeBucket{objects: map[string]time.Time{
+		"answer/post/0a.png":       old,
+		"answer/post/0a_thumb.png": old,
+		"answer/post/1b.jpg":       old,
+		"answer/post/2c.pdf":       old,
+		"answer/post/3d.png":       old,
+		"answer/post/3d_thumb.png": old,
+		"answer/post/4e.png":       time.Now(),
+		"answer/avatar/5f.png":     old,
+		"answer/branding/6a.svg":   old,
+		"answer/post/notes.txt":    old,
+		"other/post/7b.png":        old,
+	}}
+}
+
+var contents = []string{
+	"![image](" + visitURL + "answer/post/0a.png)",
+	`<a href="https://answer.example.com/answer/api/v1/s3_storage/file/answer/post/2c.pdf?download=1">pdf</a>`,
+	"no upload",
+}
+
+func TestCollectReport(t *testing.T) {
+	bucket := newBucket()
+	report, err := newCollector(t, ModeReport, bucket, &fakeReferencer{contents: contents}).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"answer/post/1b.jpg", "answer/post/3d.png", "answer/post/3d_thumb.png"}
+	if report.Contents != 3 || report.References != 2 || report.Scanned != 6 || report.Orphans != 3 ||
+		!reflect.DeepEqual(report.OrphanKeys, want) {
+		t.Errorf("report = %+v", report)
+	}
+	if report.Quarantined != 0 || len(bucket.objects) != len(newBucket().objects) {
+		t.Errorf("report mode moved objects: %v", bucket.keys())
+	}
+}
+
+func TestCollectQuarantine(t *testing.T) {
+	bucket := newBucket()
+	bucket.failing = map[string]bool{"answer/post/3d_thumb.png": true}
+	referencer := &fakeReferencer{contents: contents}
+	collector := newCollector(t, ModeQuarantine, bucket, referencer)
+	report, err := collector.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Orphans != 3 || report.Quarantined != 2 || report.Failed != 1 {
+		t.Errorf("report = %+v", report)
+	}
+	for _, key := range []string{"answer/.quarantine/post/1b.jpg", "answer/.quarantine/post/3d.png", "answer/post/0a_thumb.png"} {
+		if _, ok := bucket.objects[key]; !ok {
+			t.Errorf("%s missing in %v", key, bucket.keys())
+		}
+	}
+
+	// a referenced object is restored, an expired one is deleted
+	bucket.failing = nil
+	bucket.objects["answer/.quarantine/post/3d.png"] = time.Now().Add(-31 * 24 * time.Hour)
+	referencer.contents = append(referencer.contents, visitURL+"answer/post/1b.jpg")
+	report, err = collector.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Restored != 1 || report.Deleted != 1 || report.Quarantined != 1 || report.Failed != 0 {
+		t.Errorf("second report = %+v", report)
+	}
+	if _, ok := bucket.objects["answer/post/1b.jpg"]; !ok {
+		t.Errorf("1b.jpg not restored: %v", bucket.keys())
+	}
+	if _, ok := bucket.objects["answer/.quarantine/post/3d.png"]; ok {
+		t.Errorf("3d.png not deleted: %v", bucket.keys())
+	}
+}
+
+func TestCollectSafety(t *testing.T) {
+	bucket := newBucket()
+	report, err := newCollector(t, ModeQuarantine, bucket, &fakeReferencer{}).Run(context.Background())
+	if err != nil || report.Scanned != 0 || report.Quarantined != 0 {
+		t.Errorf("empty referencer report = %+v, %v", report, err)
+	}
+	_, err = newCollector(t, ModeQuarantine, bucket, &fakeReferencer{err: errors.New("db down")}).Run(context.Background())
+	if err == nil {
+		t.Error("referencer error ignored")
+	}
+	if len(bucket.objects) != len(newBucket().objects) {
+		t.Errorf("objects moved: %v", bucket.keys())
+	}
+
+	collector := NewCollector("test", bucket)
+	if _, err = collector.Run(context.Background()); !errors.Is(err, ErrDisabled) {
+		t.Errorf("off collector error = %v", err)
+	}
+	collector.Configure(Config{Mode: ModeReport})
+	if _, err = collector.Run(context.Background()); !errors.Is(err, ErrNoReferencer) {
+		t.Errorf("collector without referencer error = %v", err)
+	}
+}
+
+func TestFindKeys(t *testing.T) {
+	content := "![a](https://cdn.example.com/answer/post/a.png \"title\") <img src='https://cdn.example.com/answer/post/b.webp'>" +
+		" https://cdn.example.com/answer/post/c.pdf#page=2 https://cdn.example.com/"
+	want := []string{"answer/post/a.png", "answer/post/b.webp", "answer/post/c.pdf"}
+	if got := FindKeys(content, []string{visitURL}); !reflect.DeepEqual(got, want) {
+		t.Errorf("FindKeys = %v", got)
+	}
+	if got := OriginalKey("answer/post/a_thumb.webp"); got != "answer/post/a.webp" {
+		t.Errorf("OriginalKey = %s", got)
+	}
+}
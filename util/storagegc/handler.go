@@ -0,0 +1,53 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package storagegc
+
+import (
+	"github.com/apache/incubator-answer-plugins/util/storageext"
+	"github.com/apache/incubator-answer/plugin"
+	"github.com/gin-gonic/gin"
+)
+
+// StartHandler starts a collection and responds the status of the collector.
+func StartHandler(slugName string, collector *Collector) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !plugin.StatusManager.IsEnabled(slugName) {
+			storageext.HandleNotFound(ctx)
+			return
+		}
+		if err := collector.Start(); err != nil {
+			// the collection is off, already running or without a referencer
+			storageext.HandleBadRequest(ctx, err.Error())
+			return
+		}
+		storageext.HandleResponse(ctx, collector.Status())
+	}
+}
+
+// StatusHandler responds the status of the collector, with the report of the last collection.
+func StatusHandler(slugName string, collector *Collector) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !plugin.StatusManager.IsEnabled(slugName) {
+			storageext.HandleNotFound(ctx)
+			return
+		}
+		storageext.HandleResponse(ctx, collector.Status())
+	}
+}
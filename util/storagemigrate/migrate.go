@@ -0,0 +1,239 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+// Package storagemigrate copies the uploads of Answer from a storage backend to another, keeping their keys,
+// and maps their old URLs to the new ones so that the contents can be rewritten.
+package storagemigrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/segmentfault/pacman/log"
+)
+
+// DefaultConcurrency is the number of objects copied at once when none is configured.
+const DefaultConcurrency = 4
+
+var ErrNotFound = errors.New("object not found")
+
+// Object is an object of a backend.
+type Object struct {
+	Key         string
+	Size        int64
+	ContentType string
+}
+
+// Backend is a storage holding the uploads, the source or the destination of a migration.
+type Backend interface {
+	// List calls fn with each object whose key starts with the prefix.
+	List(ctx context.Context, prefix string, fn func(object Object) error) error
+	// Open returns the content of the object, ErrNotFound when it doesn't exist.
+	Open(ctx context.Context, key string) (io.ReadCloser, Object, error)
+	// Put stores the object, replacing an object with the same key.
+	Put(ctx context.Context, object Object, content io.Reader) error
+}
+
+// Options are the options of a migration.
+type Options struct {
+	// Prefix is the key prefix of the objects to migrate, such as the object key prefix of the old storage plugin.
+	Prefix string
+	// FromURL and ToURL are the visit URL prefixes of the old and the new storage, they make the URL mapping.
+	FromURL string
+	ToURL   string
+	// DryRun lists the objects and writes the mapping without copying anything.
+	DryRun bool
+	// Concurrency is the number of objects copied at once, DefaultConcurrency if zero.
+	Concurrency int
+}
+
+// Failure is an object that could not be migrated.
+type Failure struct {
+	Key   string `json:"key"`
+	Error string `json:"error"`
+}
+
+// Report is the result of a migration.
+type Report struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	DryRun     bool      `json:"dry_run"`
+	// Listed is the number of objects found in the source.
+	Listed int `json:"listed"`
+	// Copied objects were copied and verified, Skipped ones were migrated by a previous run.
+	Copied  int   `json:"copied"`
+	Skipped int   `json:"skipped"`
+	Bytes   int64 `json:"bytes"`
+	// Failed objects are retried by the next run.
+	Failed   int       `json:"failed"`
+	Failures []Failure `json:"failures,omitempty"`
+}
+
+// Migrator copies the objects of a source to a destination.
+type Migrator struct {
+	src   Backend
+	dst   Backend
+	opts  Options
+	state *State
+}
+
+// NewMigrator returns a migrator recording the migrated objects in the state, which can be nil.
+func NewMigrator(src, dst Backend, opts Options, state *State) *Migrator {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultConcurrency
+	}
+	return &Migrator{src: src, dst: dst, opts: opts, state: state}
+}
+
+// Run lists the objects of the source under the prefix, copies the ones not migrated yet, verifies the
+// SHA-256 of each copy by reading it back, and writes the old and the new URL of each object to mapping
+// as CSV. The objects that fail are reported and left out of the mapping, so a run can be repeated until
+// none fails. Run stops at the first error of the listing, of the state or of the mapping.
+func (m *Migrator) Run(ctx context.Context, mapping io.Writer) (*Report, error) {
+	report := &Report{StartedAt: time.Now(), DryRun: m.opts.DryRun}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		lock     sync.Mutex
+		fatal    error
+		wg       sync.WaitGroup
+		objects  = make(chan Object)
+		csvOut   = csv.NewWriter(mapping)
+		finished = func(object Object, sum string, copied bool, err error) {
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				log.Warnf("storage migration: %s failed: %v", object.Key, err)
+				report.Failed++
+				report.Failures = append(report.Failures, Failure{Key: object.Key, Error: err.Error()})
+				return
+			}
+			if copied {
+				report.Copied++
+				report.Bytes += object.Size
+				if err = m.state.Done(object.Key, sum); err != nil && fatal == nil {
+					fatal = err
+					cancel()
+				}
+			} else if !m.opts.DryRun {
+				report.Skipped++
+			}
+			if err = csvOut.Write([]string{m.opts.FromURL + object.Key, m.opts.ToURL + object.Key}); err != nil && fatal == nil {
+				fatal = fmt.Errorf("write mapping: %w", err)
+				cancel()
+			}
+		}
+	)
+	for i := 0; i < m.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for object := range objects {
+				if m.opts.DryRun || m.state.IsDone(object.Key) {
+					finished(object, "", false, nil)
+					continue
+				}
+				sum, err := m.copy(ctx, object)
+				finished(object, sum, true, err)
+			}
+		}()
+	}
+
+	err := m.src.List(ctx, m.opts.Prefix, func(object Object) error {
+		lock.Lock()
+		report.Listed++
+		lock.Unlock()
+		select {
+		case objects <- object:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	close(objects)
+	wg.Wait()
+	csvOut.Flush()
+	report.FinishedAt = time.Now()
+	if fatal != nil {
+		return report, fatal
+	}
+	if err != nil {
+		return report, fmt.Errorf("list objects: %w", err)
+	}
+	if err = csvOut.Error(); err != nil {
+		return report, fmt.Errorf("write mapping: %w", err)
+	}
+	return report, nil
+}
+
+// copy copies the object and verifies the copy, it returns the hex SHA-256 of the content
+func (m *Migrator) copy(ctx context.Context, object Object) (string, error) {
+	content, info, err := m.src.Open(ctx, object.Key)
+	if err != nil {
+		return "", fmt.Errorf("open source: %w", err)
+	}
+	defer content.Close()
+	if len(info.ContentType) > 0 {
+		object.ContentType = info.ContentType
+	}
+	if info.Size > 0 {
+		object.Size = info.Size
+	}
+
+	srcHash := sha256.New()
+	if err = m.dst.Put(ctx, object, io.TeeReader(content, srcHash)); err != nil {
+		return "", fmt.Errorf("put destination: %w", err)
+	}
+	// a destination that stopped reading early is caught by the checksum
+	if _, err = io.Copy(srcHash, content); err != nil {
+		return "", fmt.Errorf("read source: %w", err)
+	}
+	want := hex.EncodeToString(srcHash.Sum(nil))
+
+	got, err := m.sum(ctx, object.Key)
+	if err != nil {
+		return "", fmt.Errorf("verify destination: %w", err)
+	}
+	if got != want {
+		return "", fmt.Errorf("checksum mismatch, source %s, destination %s", want, got)
+	}
+	return want, nil
+}
+
+// sum returns the hex SHA-256 of the object in the destination
+func (m *Migrator) sum(ctx context.Context, key string) (string, error) {
+	content, _, err := m.dst.Open(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	defer content.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, content); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
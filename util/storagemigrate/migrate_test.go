@@ -0,0 +1,221 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package storagemigrate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memoryBackend is a Backend in memory
+type memoryBackend struct {
+	lock    sync.Mutex
+	objects map[string]*memoryObject
+}
+
+type memoryObject struct {
+	data        []byte
+	contentType string
+}
+
+func newMemoryBackend() *memoryBackend {
+	return &memoryBackend{objects: make(map[string]*memoryObject)}
+}
+
+// List calls fn with the objects under the prefix in key order.
+func (b *memoryBackend) List(ctx context.Context, prefix string, fn func(object Object) error) error {
+	b.lock.Lock()
+	var objects []Object
+	for key, o := range b.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, Object{Key: key, Size: int64(len(o.data)), ContentType: o.contentType})
+		}
+	}
+	b.lock.Unlock()
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	for _, object := range objects {
+		if err := fn(object); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Open returns the content of the object.
+func (b *memoryBackend) Open(ctx context.Context, key string) (io.ReadCloser, Object, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	o, ok := b.objects[key]
+	if !ok {
+		return nil, Object{}, ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(o.data)), Object{Key: key, Size: int64(len(o.data)), ContentType: o.contentType}, nil
+}
+
+// Put stores the object.
+func (b *memoryBackend) Put(ctx context.Context, object Object, content io.Reader) error {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.objects[object.Key] = &memoryObject{data: data, contentType: object.ContentType}
+	return nil
+}
+
+// faultyBackend fails or corrupts the puts of some keys
+type faultyBackend struct {
+	*memoryBackend
+	fail    map[string]bool
+	corrupt map[string]bool
+}
+
+func (b *faultyBackend) Put(ctx context.Context, object Object, content io.Reader) error {
+	if b.fail[object.Key] {
+		return errors.New("connection reset")
+	}
+	if b.corrupt[object.Key] {
+		data, _ := io.ReadAll(content)
+		content = bytes.NewReader(append(data, '!'))
+	}
+	return b.memoryBackend.Put(ctx, object, content)
+}
+
+func newSource() *memoryBackend {
+	src := newMemoryBackend()
+	for key, content := range map[string]string{
+		"answer/avatar/a.png":         "\x89PNG avatar",
+		"answer/post/b.pdf":           "%PDF-1.7",
+		"answer/post/b_thumb.png":     "\x89PNG thumbnail",
+		"answer/branding/logo.svg":    "<svg/>",
+		"other/answer/post/not-moved": "outside the prefix",
+	} {
+		_ = src.Put(context.Background(), Object{Key: key, ContentType: "type/" + key}, strings.NewReader(content))
+	}
+	return src
+}
+
+var testOptions = Options{Prefix: "answer/", FromURL: "https://old.example.com/", ToURL: "https://new.example.com/", Concurrency: 2}
+
+func run(t *testing.T, src, dst Backend, opts Options, state *State) (*Report, []string) {
+	mapping := &bytes.Buffer{}
+	report, err := NewMigrator(src, dst, opts, state).Run(context.Background(), mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(mapping.String()), "\n")
+	if len(mapping.String()) == 0 {
+		lines = nil
+	}
+	sort.Strings(lines)
+	return report, lines
+}
+
+func TestMigrate(t *testing.T) {
+	src, dst := newSource(), newMemoryBackend()
+	report, mapping := run(t, src, dst, testOptions, nil)
+	if report.Listed != 4 || report.Copied != 4 || report.Failed != 0 || report.Bytes != 39 {
+		t.Errorf("report = %+v", report)
+	}
+	if len(dst.objects) != 4 || dst.objects["other/answer/post/not-moved"] != nil {
+		t.Fatalf("%d objects copied", len(dst.objects))
+	}
+	for key, o := range dst.objects {
+		if want := src.objects[key]; !bytes.Equal(o.data, want.data) || o.contentType != want.contentType {
+			t.Errorf("%s = %q %s, want %q %s", key, o.data, o.contentType, want.data, want.contentType)
+		}
+	}
+	want := []string{
+		"https://old.example.com/answer/avatar/a.png,https://new.example.com/answer/avatar/a.png",
+		"https://old.example.com/answer/branding/logo.svg,https://new.example.com/answer/branding/logo.svg",
+		"https://old.example.com/answer/post/b.pdf,https://new.example.com/answer/post/b.pdf",
+		"https://old.example.com/answer/post/b_thumb.png,https://new.example.com/answer/post/b_thumb.png",
+	}
+	if strings.Join(mapping, "\n") != strings.Join(want, "\n") {
+		t.Errorf("mapping = %v", mapping)
+	}
+}
+
+func TestMigrateDryRun(t *testing.T) {
+	dst := newMemoryBackend()
+	opts := testOptions
+	opts.DryRun = true
+	report, mapping := run(t, newSource(), dst, opts, nil)
+	if !report.DryRun || report.Listed != 4 || report.Copied != 0 || report.Skipped != 0 || len(mapping) != 4 {
+		t.Errorf("report = %+v, mapping = %v", report, mapping)
+	}
+	if len(dst.objects) != 0 {
+		t.Errorf("dry run copied %d objects", len(dst.objects))
+	}
+}
+
+func TestMigrateResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.jsonl")
+	src := newSource()
+	dst := &faultyBackend{memoryBackend: newMemoryBackend(), fail: map[string]bool{"answer/post/b.pdf": true}}
+	state, err := OpenState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, mapping := run(t, src, dst, testOptions, state)
+	_ = state.Close()
+	if report.Copied != 3 || report.Failed != 1 || report.Failures[0].Key != "answer/post/b.pdf" || len(mapping) != 3 {
+		t.Fatalf("first run report = %+v", report)
+	}
+
+	// the second run copies the failed object only
+	dst.fail = nil
+	state, err = OpenState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer state.Close()
+	if state.Len() != 3 || !state.IsDone("answer/avatar/a.png") || state.IsDone("answer/post/b.pdf") {
+		t.Fatalf("state holds %d objects", state.Len())
+	}
+	report, mapping = run(t, src, dst, testOptions, state)
+	if report.Copied != 1 || report.Skipped != 3 || report.Failed != 0 || len(mapping) != 4 {
+		t.Errorf("second run report = %+v", report)
+	}
+	if dst.objects["answer/post/b.pdf"] == nil {
+		t.Error("failed object not copied")
+	}
+}
+
+func TestMigrateChecksum(t *testing.T) {
+	dst := &faultyBackend{memoryBackend: newMemoryBackend(), corrupt: map[string]bool{"answer/branding/logo.svg": true}}
+	report, mapping := run(t, newSource(), dst, testOptions, nil)
+	if report.Failed != 1 || !strings.Contains(report.Failures[0].Error, "checksum mismatch") {
+		t.Errorf("report = %+v", report)
+	}
+	for _, line := range mapping {
+		if strings.Contains(line, "logo.svg") {
+			t.Errorf("corrupted object mapped: %s", line)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package storagemigrate
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// entry is a line of the state file
+type entry struct {
+	Key    string    `json:"key"`
+	SHA256 string    `json:"sha256"`
+	At     time.Time `json:"at"`
+}
+
+// State records the migrated objects in a file, one JSON line per object, so that an interrupted
+// migration resumes where it stopped. A nil State records nothing.
+type State struct {
+	lock sync.Mutex
+	file *os.File
+	done map[string]string
+}
+
+// OpenState opens the state file, creating it when it doesn't exist, and loads the migrated objects.
+func OpenState(path string) (*State, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open state: %w", err)
+	}
+	s := &State{file: file, done: make(map[string]string)}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		e := &entry{}
+		// a line cut by a crash is ignored, its object is copied again
+		if err = json.Unmarshal(scanner.Bytes(), e); err != nil || len(e.Key) == 0 {
+			continue
+		}
+		s.done[e.Key] = e.SHA256
+	}
+	if err = scanner.Err(); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("read state: %w", err)
+	}
+	return s, nil
+}
+
+// IsDone reports whether the object was migrated.
+func (s *State) IsDone(key string) bool {
+	if s == nil {
+		return false
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, ok := s.done[key]
+	return ok
+}
+
+// Done records the object as migrated, with the SHA-256 of its content.
+func (s *State) Done(key, sum string) error {
+	if s == nil {
+		return nil
+	}
+	data, _ := json.Marshal(&entry{Key: key, SHA256: sum, At: time.Now().UTC()})
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write state: %w", err)
+	}
+	s.done[key] = sum
+	return nil
+}
+
+// Len returns the number of migrated objects.
+func (s *State) Len() int {
+	if s == nil {
+		return 0
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.done)
+}
+
+// Close closes the state file.
+func (s *State) Close() error {
+	if s == nil {
+		return nil
+	}
+	return s.file.Close()
+}